@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const randStringAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// argFunction computes a value from the arguments of a $name(args) call
+type argFunction func(args []string, store *storage) (interface{}, error)
+
+var (
+	argFunctionExpr = regexp.MustCompile(`^\$([a-zA-Z]+)(?:\(([^()]*)\))?$`)
+
+	// argFunctions are the $name(args) values usable in args and expected
+	// values:
+	//
+	//	$uuid              a random version 4 uuid
+	//	$randInt(min,max)  a random int between min and max, inclusive
+	//	$randString(n)     n random alphanumeric characters
+	//	$now               the unix time in milliseconds, $now(s) in
+	//	                   seconds and $now(rfc3339) formatted
+	//	$botId             the id of the bot
+	argFunctions = map[string]argFunction{
+		"uuid":       argUUID,
+		"randInt":    argRandInt,
+		"randString": argRandString,
+		"now":        argNow,
+		"botId":      argBotID,
+	}
+)
+
+// callArgFunction evaluates s when it is a call of a known arg function
+func callArgFunction(s string, store *storage) (interface{}, bool, error) {
+	m := argFunctionExpr.FindStringSubmatch(s)
+	if m == nil {
+		return nil, false, nil
+	}
+
+	f, ok := argFunctions[m[1]]
+	if !ok {
+		return nil, false, nil
+	}
+
+	args := make([]string, 0)
+	if strings.TrimSpace(m[2]) != "" {
+		for _, arg := range strings.Split(m[2], ",") {
+			args = append(args, strings.TrimSpace(arg))
+		}
+	}
+
+	value, err := f(args, store)
+	if err != nil {
+		return nil, true, fmt.Errorf("%s: %s", s, err)
+	}
+
+	return value, true, nil
+}
+
+// withRandom runs f with the seeded source shared by the generators
+func withRandom(f func(r *rand.Rand)) {
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+	f(random)
+}
+
+func intArgs(args []string, count int) ([]int, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("expected %d arguments, got %d", count, len(args))
+	}
+
+	ret := make([]int, count)
+	for i, arg := range args {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("malformed argument %s", arg)
+		}
+		ret[i] = n
+	}
+
+	return ret, nil
+}
+
+func argUUID(args []string, store *storage) (interface{}, error) {
+	return generate("random.uuid")
+}
+
+func argRandInt(args []string, store *storage) (interface{}, error) {
+	bounds, err := intArgs(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	if bounds[1] < bounds[0] {
+		return nil, fmt.Errorf("max is lower than min")
+	}
+
+	var n int
+	withRandom(func(r *rand.Rand) {
+		n = bounds[0] + r.Intn(bounds[1]-bounds[0]+1)
+	})
+
+	return n, nil
+}
+
+func argRandString(args []string, store *storage) (interface{}, error) {
+	length, err := intArgs(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	if length[0] < 0 {
+		return nil, fmt.Errorf("length must not be negative")
+	}
+
+	b := make([]byte, length[0])
+	withRandom(func(r *rand.Rand) {
+		for i := range b {
+			b[i] = randStringAlphabet[r.Intn(len(randStringAlphabet))]
+		}
+	})
+
+	return string(b), nil
+}
+
+func argNow(args []string, store *storage) (interface{}, error) {
+	now := time.Now()
+	if len(args) == 0 {
+		return int(now.UnixNano() / int64(time.Millisecond)), nil
+	}
+
+	switch args[0] {
+	case "ms":
+		return int(now.UnixNano() / int64(time.Millisecond)), nil
+	case "s":
+		return int(now.Unix()), nil
+	case "rfc3339":
+		return now.UTC().Format(time.RFC3339), nil
+	}
+
+	return nil, fmt.Errorf("unknown unit %s", args[0])
+}
+
+func argBotID(args []string, store *storage) (interface{}, error) {
+	if id, ok := store.Get(botIDKey); ok {
+		return id, nil
+	}
+
+	return nil, fmt.Errorf("bot id not bound")
+}
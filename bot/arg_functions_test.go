@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgFunctions(t *testing.T) {
+	store := &storage{}
+	store.Set(botIDKey, 12)
+
+	uuid, err := tryGetValue("$uuid", store)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), uuid)
+
+	for i := 0; i < 50; i++ {
+		n, err := tryGetValue("$randInt(1, 3)", store)
+		assert.NoError(t, err)
+		assert.True(t, n.(int) >= 1 && n.(int) <= 3, "%v out of range", n)
+	}
+
+	s, err := tryGetValue("$randString(12)", store)
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[a-zA-Z0-9]{12}$`), s)
+
+	now, err := tryGetValue("$now", store)
+	assert.NoError(t, err)
+	assert.IsType(t, 0, now)
+
+	formatted, err := tryGetValue("$now(rfc3339)", store)
+	assert.NoError(t, err)
+	assert.IsType(t, "", formatted)
+
+	id, err := tryGetValue("$botId", store)
+	assert.NoError(t, err)
+	assert.Equal(t, 12, id)
+}
+
+func TestArgFunctionsInterpolated(t *testing.T) {
+	store := &storage{}
+	store.Set(botIDKey, 4)
+
+	value, err := tryGetValue("room_${randInt(7,7)}_${botId}", store)
+	assert.NoError(t, err)
+	assert.Equal(t, "room_7_4", value)
+}
+
+func TestArgFunctionErrors(t *testing.T) {
+	tables := []string{"$randInt(5)", "$randInt(5, 1)", "$randString(x)", "$now(h)"}
+
+	for _, value := range tables {
+		t.Run(value, func(t *testing.T) {
+			_, err := tryGetValue(value, &storage{})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUnknownArgFunctionIsLiteral(t *testing.T) {
+	value, err := tryGetValue("$price", &storage{})
+	assert.NoError(t, err)
+	assert.Nil(t, value)
+}
@@ -0,0 +1,310 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// defaultFuzzRequests is how many requests args fuzzing sends when its spec
+// has no requests, fuzzLongStringLength how long the longString mutation
+// strings are
+const (
+	defaultFuzzRequests  = 100
+	fuzzLongStringLength = 64 * 1024
+)
+
+// argMutation replaces an arg value, returning false when it does not apply
+// to it
+type argMutation func(r *rand.Rand, value interface{}) (interface{}, bool)
+
+var argMutations = map[string]argMutation{
+	"typeFlip":   flipArgType,
+	"boundary":   boundaryArg,
+	"longString": longStringArg,
+	"null":       func(*rand.Rand, interface{}) (interface{}, bool) { return nil, true },
+}
+
+// defaultArgMutations are the mutations args fuzzing picks from, in a fixed
+// order so seeded runs are reproducible
+var defaultArgMutations = []string{"typeFlip", "boundary", "longString", "null"}
+
+// argKind returns the json kind of an arg value
+func argKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "number"
+}
+
+// flipArgType replaces value by a value of another kind
+func flipArgType(r *rand.Rand, value interface{}) (interface{}, bool) {
+	candidates := []interface{}{"fuzz", 1, true, []interface{}{}, map[string]interface{}{}}
+	flipped := make([]interface{}, 0, len(candidates))
+	for _, candidate := range candidates {
+		if argKind(candidate) != argKind(value) {
+			flipped = append(flipped, candidate)
+		}
+	}
+
+	return flipped[r.Intn(len(flipped))], true
+}
+
+// boundaryArg replaces value by an edge value of its kind
+func boundaryArg(r *rand.Rand, value interface{}) (interface{}, bool) {
+	var boundaries []interface{}
+	switch argKind(value) {
+	case "number":
+		boundaries = []interface{}{0, -1, math.MaxInt32, math.MinInt32, int64(math.MaxInt64), int64(math.MinInt64), math.MaxFloat64}
+	case "string":
+		boundaries = []interface{}{"", " ", "\x00"}
+	case "array":
+		boundaries = []interface{}{[]interface{}{}}
+	case "object":
+		boundaries = []interface{}{map[string]interface{}{}}
+	default:
+		return nil, false
+	}
+
+	return boundaries[r.Intn(len(boundaries))], true
+}
+
+func longStringArg(r *rand.Rand, value interface{}) (interface{}, bool) {
+	b := make([]byte, fuzzLongStringLength)
+	for i := range b {
+		b[i] = randStringAlphabet[r.Intn(len(randStringAlphabet))]
+	}
+	return string(b), true
+}
+
+// argFields returns the paths of every field of value, keys of objects and
+// indexes of arrays, nested ones included
+func argFields(value interface{}, path []interface{}) [][]interface{} {
+	fields := [][]interface{}{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			field := append(append([]interface{}{}, path...), key)
+			fields = append(fields, field)
+			fields = append(fields, argFields(v[key], field)...)
+		}
+	case []interface{}:
+		for idx, item := range v {
+			field := append(append([]interface{}{}, path...), idx)
+			fields = append(fields, field)
+			fields = append(fields, argFields(item, field)...)
+		}
+	}
+
+	return fields
+}
+
+// fieldName writes a field path as in a.b[0]
+func fieldName(path []interface{}) string {
+	var name strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case string:
+			if name.Len() > 0 {
+				name.WriteByte('.')
+			}
+			name.WriteString(s)
+		case int:
+			fmt.Fprintf(&name, "[%d]", s)
+		}
+	}
+	return name.String()
+}
+
+// mutateField replaces the value at path in args by one of mutations,
+// picked at random among the ones that apply to it, returning the mutation
+// used, empty when none applies, and the new value
+func mutateField(r *rand.Rand, args map[string]interface{}, path []interface{}, mutations []string) (string, interface{}) {
+	var parent interface{} = args
+	for _, step := range path[:len(path)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			parent = p[step.(string)]
+		case []interface{}:
+			parent = p[step.(int)]
+		}
+	}
+
+	last := path[len(path)-1]
+	var value interface{}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		key, _ := last.(string)
+		value = p[key]
+	case []interface{}:
+		idx, ok := last.(int)
+		if !ok || idx >= len(p) {
+			return "", nil
+		}
+		value = p[idx]
+	default:
+		return "", nil
+	}
+
+	for _, i := range r.Perm(len(mutations)) {
+		mutated, ok := argMutations[mutations[i]](r, value)
+		if !ok {
+			continue
+		}
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[last.(string)] = mutated
+		case []interface{}:
+			p[last.(int)] = mutated
+		}
+		return mutations[i], mutated
+	}
+
+	return "", nil
+}
+
+// fuzzFindingResult describes why a fuzzed request is a finding: it failed
+// or the server answered with an internal error, which is what handler
+// panics turn into. It is empty for the other responses
+func fuzzFindingResult(err error, resp Response) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	code, ok := pitayaErrorCode(resp)
+	if !ok || !strings.HasPrefix(code, "PIT-5") {
+		return ""
+	}
+	if msg, ok := resp["msg"].(string); ok && msg != "" {
+		return fmt.Sprintf("%s %s", code, msg)
+	}
+	return code
+}
+
+// describeArg returns value as recorded in findings, long strings by their
+// length only
+func describeArg(value interface{}) interface{} {
+	if s, ok := value.(string); ok && len(s) > 64 {
+		return fmt.Sprintf("<string of %d bytes>", len(s))
+	}
+	return value
+}
+
+// fuzzArgs sends requests to the operation uri with its args mutated one
+// field at a time. Failed requests and internal error responses are
+// reported as fuzzFinding events and fail the operation once they all were
+// sent, or the connection dropped
+func (b *SequentialBot) fuzzArgs(ctx context.Context, op *models.Operation) error {
+	spec := op.Fuzz
+	requests := spec.Requests
+	if requests == 0 {
+		requests = defaultFuzzRequests
+	}
+	mutations := spec.Mutations
+	if len(mutations) == 0 {
+		mutations = defaultArgMutations
+	}
+	seed := spec.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+
+	sample, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+	fields := argFields(sample, nil)
+	if len(fields) == 0 {
+		return fmt.Errorf("Fuzz %s needs args to mutate", spec.Kind)
+	}
+
+	tags := metricsTags(op.URI, op.Tags)
+	sent, findings, first := 0, 0, ""
+	for i := 0; i < requests; i++ {
+		// args are built again for every request as mutations change them
+		// in place
+		args, err := buildArgs(op.Args, b.storage)
+		if err != nil {
+			return err
+		}
+		field := fields[r.Intn(len(fields))]
+		mutation, value := mutateField(r, args, field, mutations)
+		if mutation == "" {
+			continue
+		}
+		if _, err := client.marshal(op.URI, args); err != nil {
+			b.logger.Debugf("Skipping %s of %s, the serializer rejects it: %s", mutation, fieldName(field), err)
+			continue
+		}
+
+		if err := b.limit(ctx, op.URI, op.Tags); err != nil {
+			return err
+		}
+		if b.throughput != nil {
+			b.throughput.AddRequest()
+		}
+		resp, _, _, err := sendRequest(ctx, args, op.URI, time.Duration(op.Timeout)*time.Millisecond, client, b.metricsReporter, op.Tags)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		sent++
+
+		result := fuzzFindingResult(err, resp)
+		if result == "" {
+			continue
+		}
+		findings++
+		if first == "" {
+			first = fmt.Sprintf("%s of %s: %s", mutation, fieldName(field), result)
+		}
+
+		event := map[string]interface{}{
+			"bot":      b.id,
+			"route":    op.URI,
+			"field":    fieldName(field),
+			"mutation": mutation,
+			"value":    describeArg(value),
+			"result":   result,
+			"seed":     seed,
+		}
+		metrics.ReportEvent(b.metricsReporter, metrics.FuzzFindingEvent, tags, event)
+		b.logger.WithFields(event).Warn("Fuzzed request failed")
+
+		if !client.Connected() {
+			return fmt.Errorf("Connection dropped after %d fuzzed requests to %s, first failure: %s", sent, op.URI, first)
+		}
+	}
+
+	if findings > 0 {
+		return fmt.Errorf("%d of %d fuzzed requests to %s failed, first: %s", findings, sent, op.URI, first)
+	}
+	b.logger.Debugf("Sent %d fuzzed requests to %s without failures", sent, op.URI)
+	return nil
+}
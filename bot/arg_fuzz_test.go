@@ -0,0 +1,118 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestArgFields(t *testing.T) {
+	args := map[string]interface{}{
+		"name":  "bob",
+		"items": []interface{}{map[string]interface{}{"id": 1}},
+	}
+
+	names := []string{}
+	for _, field := range argFields(args, nil) {
+		names = append(names, fieldName(field))
+	}
+	assert.Equal(t, []string{"items", "items[0]", "items[0].id", "name"}, names)
+}
+
+func TestArgMutations(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tables := []struct {
+		name     string
+		mutation string
+		value    interface{}
+		applies  bool
+	}{
+		{"flip string", "typeFlip", "bob", true},
+		{"flip number", "typeFlip", 10, true},
+		{"number boundary", "boundary", 10, true},
+		{"string boundary", "boundary", "bob", true},
+		{"no bool boundary", "boundary", true, false},
+		{"long string", "longString", 10, true},
+		{"null", "null", "bob", true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			mutated, ok := argMutations[table.mutation](r, table.value)
+			assert.Equal(t, table.applies, ok)
+			if !ok {
+				return
+			}
+			switch table.mutation {
+			case "typeFlip":
+				assert.NotEqual(t, argKind(table.value), argKind(mutated))
+			case "boundary":
+				assert.Equal(t, argKind(table.value), argKind(mutated))
+			case "longString":
+				assert.Len(t, mutated, fuzzLongStringLength)
+			case "null":
+				assert.Nil(t, mutated)
+			}
+		})
+	}
+}
+
+func TestFuzzArgs(t *testing.T) {
+	transport := &recordingTransport{responses: []string{
+		`{"code": "200"}`,
+		`{"code": "PIT-500", "msg": "panic"}`,
+		`{"code": "PIT-400"}`,
+	}}
+	b := newTestBot(transport)
+	reporter := &eventReporter{countingReporter: countingReporter{counts: map[string]float64{}}}
+	b.metricsReporter = []metrics.Reporter{reporter}
+
+	op := &models.Operation{
+		Type: "fuzz",
+		URI:  "room.join",
+		Args: map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string", "value": "bob"},
+			"level": map[string]interface{}{"type": "int", "value": 10},
+		},
+		Fuzz: &models.FuzzSpec{Kind: "args", Requests: 6, Seed: 42},
+	}
+
+	err := b.runOperation(context.Background(), op)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2 of 6 fuzzed requests to room.join failed, first: ")
+	assert.Contains(t, err.Error(), "PIT-500 panic")
+	assert.Equal(t, []string{metrics.FuzzFindingEvent, metrics.FuzzFindingEvent}, reporter.events)
+	assert.Equal(t, int64(42), reporter.fields[0]["seed"])
+
+	for _, sent := range transport.sent {
+		var args map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(sent), &args))
+		unchanged := 0
+		if args["name"] == "bob" {
+			unchanged++
+		}
+		if args["level"] == float64(10) {
+			unchanged++
+		}
+		assert.Equal(t, 1, unchanged, sent)
+	}
+}
+
+func TestFuzzArgsWithoutFindings(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	op := &models.Operation{
+		Type: "fuzz",
+		URI:  "room.join",
+		Args: map[string]interface{}{"name": map[string]interface{}{"type": "string", "value": "bob"}},
+		Fuzz: &models.FuzzSpec{Kind: "args", Requests: 3, Mutations: []string{"null"}},
+	}
+	assert.NoError(t, b.runOperation(context.Background(), op))
+
+	op.Fuzz.Mutations = []string{"bitflip"}
+	assert.EqualError(t, b.runOperation(context.Background(), op), "Unknown fuzz mutation: bitflip")
+}
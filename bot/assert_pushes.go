@@ -0,0 +1,186 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// ValidateAssertPushes returns an error when spec is not an assertPushes
+// operation bots can run
+func ValidateAssertPushes(spec *models.AssertPushesSpec) error {
+	if spec == nil || len(spec.Routes) == 0 {
+		return fmt.Errorf("AssertPushes operation has no routes")
+	}
+
+	seen := map[string]bool{}
+	for _, r := range spec.Routes {
+		if r == nil || r.Route == "" {
+			return fmt.Errorf("AssertPushes route is missing")
+		}
+		if seen[r.Route] {
+			return fmt.Errorf("AssertPushes route %s is listed twice", r.Route)
+		}
+		seen[r.Route] = true
+
+		if r.Count < 0 || r.Min < 0 || r.Max < 0 {
+			return fmt.Errorf("AssertPushes counts of route %s must not be negative", r.Route)
+		}
+		if r.Max > 0 && r.Min > r.Max {
+			return fmt.Errorf("AssertPushes min of route %s is greater than its max", r.Route)
+		}
+	}
+
+	return nil
+}
+
+// minPushes returns how many pushes spec needs at least
+func minPushes(spec *models.PushCountSpec) int {
+	if spec.Min > 0 || spec.Max > 0 {
+		return spec.Min
+	}
+	return spec.Count
+}
+
+// checkPushCount returns an error unless count meets spec
+func checkPushCount(spec *models.PushCountSpec, count int) error {
+	var expected string
+	switch {
+	case spec.Min == 0 && spec.Max == 0:
+		if count == spec.Count {
+			return nil
+		}
+		expected = fmt.Sprintf("%d", spec.Count)
+	case spec.Max == 0:
+		if count >= spec.Min {
+			return nil
+		}
+		expected = fmt.Sprintf("at least %d", spec.Min)
+	default:
+		if count >= spec.Min && count <= spec.Max {
+			return nil
+		}
+		expected = fmt.Sprintf("%d to %d", spec.Min, spec.Max)
+	}
+
+	return fmt.Errorf("Expected %s pushes on route %s, got %d", expected, spec.Route, count)
+}
+
+// markPushes marks, under the name arg, the moment the following
+// assertPushes operations of the session count pushes from
+func (b *SequentialBot) markPushes(op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	name, _ := args["name"].(string)
+	if name == "" {
+		return fmt.Errorf("markPushes needs a name")
+	}
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+	client.MarkPushes(name)
+	return nil
+}
+
+// pushArrival is when a push counted by assertPushes arrived, on the route
+// at index route of its spec
+type pushArrival struct {
+	route int
+	at    time.Time
+}
+
+// assertPushes takes the pushes buffered on every route of the operation,
+// dropping those older than its since marker, and waits up to Timeout for
+// more until every route has the least pushes it expects. Then their counts,
+// and their order when asked to, are checked. Pushes arriving once every
+// count is met are left buffered
+func (b *SequentialBot) assertPushes(ctx context.Context, op *models.Operation) error {
+	spec := op.AssertPushes
+	if err := ValidateAssertPushes(spec); err != nil {
+		return err
+	}
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+
+	var since time.Time
+	if spec.Since != "" {
+		var ok bool
+		if since, ok = client.pushMarker(spec.Since); !ok {
+			return fmt.Errorf("Unknown push marker %s, it is set by markPushes functions", spec.Since)
+		}
+	}
+
+	routes := make([]string, len(spec.Routes))
+	for idx, r := range spec.Routes {
+		routes[idx] = r.Route
+	}
+
+	counts := make([]int, len(spec.Routes))
+	arrivals := make([]pushArrival, 0)
+	take := func(idx int, push *Push) {
+		if push.ReceivedAt.Before(since) {
+			return
+		}
+		counts[idx]++
+		arrivals = append(arrivals, pushArrival{route: idx, at: push.ReceivedAt})
+	}
+	met := func() bool {
+		for idx, r := range spec.Routes {
+			if counts[idx] < minPushes(r) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for idx, route := range routes {
+		for _, push := range client.bufferedPushes(route) {
+			take(idx, push)
+		}
+	}
+
+	deadline := time.After(time.Duration(op.Timeout) * time.Millisecond)
+	for !met() {
+		idx, push, err := client.ReceiveAnyPush(ctx, routes, deadline)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			break
+		}
+		take(idx, push)
+	}
+
+	for idx, r := range spec.Routes {
+		if err := checkPushCount(r, counts[idx]); err != nil {
+			if spec.Since != "" {
+				return fmt.Errorf("%s since marker %s", err, spec.Since)
+			}
+			return err
+		}
+	}
+
+	if spec.Ordered {
+		sort.SliceStable(arrivals, func(i, j int) bool { return arrivals[i].at.Before(arrivals[j].at) })
+		last := 0
+		for _, arrival := range arrivals {
+			if arrival.route < last {
+				return fmt.Errorf("Push on route %s arrived after one on %s", routes[arrival.route], routes[last])
+			}
+			last = arrival.route
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// newPushTestBot returns a test bot buffering pushes, as bots do by default
+func newPushTestBot() (*SequentialBot, *recordingTransport) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.sessions.clients[defaultSession].pushBufferSize = 10
+	return b, transport
+}
+
+func sendPushes(transport *recordingTransport, routes ...string) {
+	for _, route := range routes {
+		transport.handler(MsgPushType, 0, route, []byte(`{}`))
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAssertPushes(t *testing.T) {
+	tables := []struct {
+		name    string
+		pushes  []string
+		ordered bool
+		routes  []*models.PushCountSpec
+		err     string
+	}{
+		{"counts", []string{"room.message", "room.message", "room.joined"}, false,
+			[]*models.PushCountSpec{{Route: "room.message", Count: 2}, {Route: "room.joined", Count: 1}, {Route: "room.left", Count: 0}}, ""},
+		{"too few", []string{"room.message", "room.message"}, false,
+			[]*models.PushCountSpec{{Route: "room.message", Count: 3}}, "Expected 3 pushes on route room.message, got 2"},
+		{"too many", []string{"room.left", "room.left"}, false,
+			[]*models.PushCountSpec{{Route: "room.left", Count: 1}}, "Expected 1 pushes on route room.left, got 2"},
+		{"range", []string{"room.message", "room.message"}, false,
+			[]*models.PushCountSpec{{Route: "room.message", Min: 1, Max: 3}}, ""},
+		{"out of range", []string{"room.message"}, false,
+			[]*models.PushCountSpec{{Route: "room.message", Min: 2, Max: 3}}, "Expected 2 to 3 pushes on route room.message, got 1"},
+		{"at least", []string{"room.message"}, false,
+			[]*models.PushCountSpec{{Route: "room.message", Min: 2}}, "Expected at least 2 pushes on route room.message, got 1"},
+		{"ordered", []string{"room.joined", "room.message", "room.message", "room.left"}, true,
+			[]*models.PushCountSpec{{Route: "room.joined", Count: 1}, {Route: "room.message", Count: 2}, {Route: "room.left", Count: 1}}, ""},
+		{"out of order", []string{"room.joined", "room.message", "room.joined"}, true,
+			[]*models.PushCountSpec{{Route: "room.joined", Count: 2}, {Route: "room.message", Count: 1}},
+			"Push on route room.joined arrived after one on room.message"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b, transport := newPushTestBot()
+			sendPushes(transport, table.pushes...)
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:         "assertPushes",
+				AssertPushes: &models.AssertPushesSpec{Ordered: table.ordered, Routes: table.routes},
+			})
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
+
+func TestAssertPushesSinceMarker(t *testing.T) {
+	b, transport := newPushTestBot()
+	sendPushes(transport, "room.message", "room.message")
+
+	assert.NoError(t, b.runOperation(context.Background(), &models.Operation{
+		Type: "function",
+		URI:  "markPushes",
+		Args: map[string]interface{}{"name": map[string]interface{}{"type": "string", "value": "joined"}},
+	}))
+	sendPushes(transport, "room.message")
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type: "assertPushes",
+		AssertPushes: &models.AssertPushesSpec{
+			Since:  "joined",
+			Routes: []*models.PushCountSpec{{Route: "room.message", Count: 2}},
+		},
+	})
+	assert.EqualError(t, err, "Expected 2 pushes on route room.message, got 1 since marker joined")
+
+	err = b.runOperation(context.Background(), &models.Operation{
+		Type:         "assertPushes",
+		AssertPushes: &models.AssertPushesSpec{Since: "started", Routes: []*models.PushCountSpec{{Route: "room.message"}}},
+	})
+	assert.EqualError(t, err, "Unknown push marker started, it is set by markPushes functions")
+}
+
+func TestAssertPushesWaitsForLatePushes(t *testing.T) {
+	b, transport := newPushTestBot()
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		sendPushes(transport, "room.message", "room.message")
+	}()
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:         "assertPushes",
+		Timeout:      1000,
+		AssertPushes: &models.AssertPushesSpec{Routes: []*models.PushCountSpec{{Route: "room.message", Count: 2}}},
+	})
+	assert.NoError(t, err)
+}
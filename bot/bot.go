@@ -1,11 +1,18 @@
 package bot
 
+import (
+	"context"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+)
+
 // Bot defines the interface the bots must implement
 type Bot interface {
 	Initialize() error
-	Run() error
+	Run(ctx context.Context) error
 	Finalize() error
 	Connect(...string) error
 	Disconnect()
 	Reconnect()
+	Stats() *metrics.ConnectionStats
 }
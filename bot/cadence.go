@@ -0,0 +1,99 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+type cadenceStats struct {
+	count       int
+	outOfBounds int
+	min         time.Duration
+	max         time.Duration
+	mean        time.Duration
+	stddev      time.Duration
+	p50         time.Duration
+	p95         time.Duration
+}
+
+func (s *cadenceStats) String() string {
+	return fmt.Sprintf("min: %v, p50: %v, p95: %v, max: %v, mean: %v, stddev: %v",
+		s.min, s.p50, s.p95, s.max, s.mean, s.stddev)
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	return sorted[idx]
+}
+
+func pushIntervals(pushes []*Push) []time.Duration {
+	if len(pushes) < 2 {
+		return []time.Duration{}
+	}
+
+	intervals := make([]time.Duration, len(pushes)-1)
+	for i := 1; i < len(pushes); i++ {
+		intervals[i-1] = pushes[i].ReceivedAt.Sub(pushes[i-1].ReceivedAt)
+	}
+
+	return intervals
+}
+
+func newCadenceStats(intervals []time.Duration, lower, upper time.Duration) *cadenceStats {
+	sorted := make([]time.Duration, len(intervals))
+	copy(sorted, intervals)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := &cadenceStats{
+		count: len(sorted),
+		min:   sorted[0],
+		max:   sorted[len(sorted)-1],
+		p50:   percentile(sorted, 0.5),
+		p95:   percentile(sorted, 0.95),
+	}
+
+	var sum float64
+	for _, interval := range sorted {
+		sum += float64(interval)
+		if interval < lower || interval > upper {
+			stats.outOfBounds++
+		}
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	for _, interval := range sorted {
+		variance += math.Pow(float64(interval)-mean, 2)
+	}
+	variance /= float64(len(sorted))
+
+	stats.mean = time.Duration(mean)
+	stats.stddev = time.Duration(math.Sqrt(variance))
+	return stats
+}
+
+func validateCadence(pushes []*Push, spec *models.CadenceSpec) error {
+	if len(pushes) < 2 {
+		return fmt.Errorf("At least 2 pushes are needed to measure cadence, got %d", len(pushes))
+	}
+
+	interval := time.Duration(spec.Interval) * time.Millisecond
+	tolerance := time.Duration(spec.Tolerance) * time.Millisecond
+	lower, upper := interval-tolerance, interval+tolerance
+
+	stats := newCadenceStats(pushIntervals(pushes), lower, upper)
+	if stats.outOfBounds > 0 {
+		return fmt.Errorf("%d of %d intervals outside [%v, %v] (%s)",
+			stats.outOfBounds, stats.count, lower, upper, stats)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func pushesAt(offsets ...int) []*Push {
+	start := time.Now()
+	pushes := make([]*Push, len(offsets))
+	for i, offset := range offsets {
+		pushes[i] = &Push{ReceivedAt: start.Add(time.Duration(offset) * time.Millisecond)}
+	}
+
+	return pushes
+}
+
+var cadenceTable = map[string]struct {
+	pushes []*Push
+	spec   *models.CadenceSpec
+	hasErr bool
+}{
+	"success_steady":       {pushesAt(0, 100, 200, 300), &models.CadenceSpec{Interval: 100, Tolerance: 20}, false},
+	"success_in_tolerance": {pushesAt(0, 85, 200, 315), &models.CadenceSpec{Interval: 100, Tolerance: 20}, false},
+	"err_too_slow":         {pushesAt(0, 100, 250, 350), &models.CadenceSpec{Interval: 100, Tolerance: 20}, true},
+	"err_too_fast":         {pushesAt(0, 100, 150, 250), &models.CadenceSpec{Interval: 100, Tolerance: 20}, true},
+	"err_single_push":      {pushesAt(0), &models.CadenceSpec{Interval: 100, Tolerance: 20}, true},
+	"err_no_pushes":        {pushesAt(), &models.CadenceSpec{Interval: 100, Tolerance: 20}, true},
+}
+
+func TestValidateCadence(t *testing.T) {
+	for name, table := range cadenceTable {
+		t.Run(name, func(t *testing.T) {
+			err := validateCadence(table.pushes, table.spec)
+			assert.Equal(t, table.hasErr, err != nil)
+		})
+	}
+}
+
+func TestCadenceStats(t *testing.T) {
+	intervals := pushIntervals(pushesAt(0, 100, 250, 350))
+	stats := newCadenceStats(intervals, 80*time.Millisecond, 120*time.Millisecond)
+
+	assert.Equal(t, 3, stats.count)
+	assert.Equal(t, 1, stats.outOfBounds)
+	assert.Equal(t, 100*time.Millisecond, stats.min)
+	assert.Equal(t, 150*time.Millisecond, stats.max)
+	assert.Equal(t, 100*time.Millisecond, stats.p50)
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// checkpoint is the progress of a bot persisted so a later run can resume
+// it. Index is the position, in Order, of the next operation to run
+type checkpoint struct {
+	Spec    string                 `json:"spec"`
+	Bot     int                    `json:"bot"`
+	Index   int                    `json:"index"`
+	Order   []int                  `json:"order"`
+	Storage map[string]interface{} `json:"storage"`
+	Time    time.Time              `json:"time"`
+}
+
+// checkpointer persists checkpoints as json files in checkpoint.dir every
+// checkpoint.every operations. With checkpoint.resume set, bots reload
+// their checkpoint and continue from it
+type checkpointer struct {
+	dir    string
+	every  int
+	resume bool
+}
+
+func newCheckpointer(config *viper.Viper) *checkpointer {
+	dir := config.GetString("checkpoint.dir")
+	if dir == "" {
+		return nil
+	}
+
+	every := config.GetInt("checkpoint.every")
+	if every <= 0 {
+		every = 1
+	}
+
+	return &checkpointer{
+		dir:    dir,
+		every:  every,
+		resume: config.GetBool("checkpoint.resume"),
+	}
+}
+
+func (c *checkpointer) path(spec string, id int) string {
+	name := strings.NewReplacer("/", "_", "\\", "_", ":", "_").Replace(spec)
+	return filepath.Join(c.dir, fmt.Sprintf("%s-%d.json", name, id))
+}
+
+// load returns the bot checkpoint, nil if not resuming or there is none
+func (c *checkpointer) load(spec string, id int) (*checkpoint, error) {
+	if c == nil || !c.resume {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(c.path(spec, id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cp checkpoint
+	if err := json.Unmarshal(raw, &cp); err != nil {
+		return nil, fmt.Errorf("Malformed checkpoint %s: %s", c.path(spec, id), err)
+	}
+
+	return &cp, nil
+}
+
+// due returns whether a checkpoint should be saved after completed
+// operations were run since the bot started or resumed
+func (c *checkpointer) due(completed int) bool {
+	return c != nil && completed%c.every == 0
+}
+
+// save writes the checkpoint atomically, so a crash never leaves it half
+// written
+func (c *checkpointer) save(cp *checkpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	path := c.path(cp.Spec, cp.Bot)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// remove deletes the checkpoint of a bot that finished its spec
+func (c *checkpointer) remove(spec string, id int) {
+	if c != nil {
+		os.Remove(c.path(spec, id))
+	}
+}
@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestCheckpointResume(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ok := models.ExpectSpec{"code": {Type: "string", Value: "200"}}
+	spec := &models.Spec{
+		Name: "specs/long.json",
+		SequentialOperations: []*models.Operation{
+			{Type: "request", URI: "player.login", Args: map[string]interface{}{},
+				Store: models.StoreSpec{"token": {Type: "string", Value: "token"}}},
+			{Type: "request", URI: "match.join", Args: map[string]interface{}{}, Expect: ok},
+			{Type: "request", URI: "match.play", Args: map[string]interface{}{}, Expect: ok},
+		},
+		OnResume: []*models.Operation{
+			{Type: "request", URI: "player.authenticate", Args: map[string]interface{}{
+				"token": map[string]interface{}{"type": "string", "value": "$store.token"},
+			}},
+		},
+	}
+
+	transport := &recordingTransport{responses: []string{
+		`{"code": "200", "token": "t1"}`, `{"code": "200"}`, `{"code": "500"}`,
+	}}
+	b := newTestBot(transport)
+	b.spec = spec
+	b.checkpointer = &checkpointer{dir: dir, every: 1}
+	assert.Error(t, b.Run(context.Background()))
+
+	resumer := &checkpointer{dir: dir, every: 1, resume: true}
+	cp, err := resumer.load(spec.Name, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cp.Index)
+	assert.Equal(t, "t1", cp.Storage["token"])
+
+	transport = &recordingTransport{}
+	b = newTestBot(transport)
+	b.spec = spec
+	b.checkpointer = resumer
+	b.resumeFrom = cp
+	for k, v := range cp.Storage {
+		b.storage.Set(k, v)
+	}
+
+	assert.NoError(t, b.Run(context.Background()))
+	assert.Equal(t, []string{`{"token":"t1"}`, `{}`}, transport.sent)
+
+	cp, err = resumer.load(spec.Name, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, cp)
+}
@@ -1,14 +1,15 @@
 package bot
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
 )
@@ -18,12 +19,7 @@ func initializeDb(store *storage) error {
 }
 
 func valueFromUtil(fName string) (interface{}, error) {
-	switch fName {
-	case "uuid":
-		return uuid.New().String(), nil
-	default:
-		return nil, fmt.Errorf("util.%s undefined", fName)
-	}
+	return generate("util." + fName)
 }
 
 func tryGetValue(expr interface{}, store *storage) (interface{}, error) {
@@ -41,6 +37,20 @@ func tryGetValue(expr interface{}, store *storage) (interface{}, error) {
 			f := val[6:]
 			return valueFromUtil(f)
 		}
+
+		if value, ok, err := callArgFunction(val, store); ok {
+			return value, err
+		}
+
+		// a whole string reference keeps the type of its value, embedded
+		// ones are formatted into the string
+		if m := generatorExpr.FindStringSubmatch(val); m != nil {
+			return resolveRef(m[1], store)
+		}
+
+		if embeddedRef(val) {
+			return interpolate(val, store)
+		}
 	}
 
 	return nil, nil
@@ -80,6 +90,29 @@ func assertType(value interface{}, typ string) (interface{}, error) {
 		default:
 			return nil, fmt.Errorf("Int type assertion failed for field: %v", ret)
 		}
+	case "array", "setEqual":
+		if val, ok := ret.([]interface{}); ok {
+			ret = val
+		} else {
+			return nil, fmt.Errorf("Array type assertion failed for field: %v", ret)
+		}
+	case "datetime":
+		switch ret.(type) {
+		case string, float64, int, json.Number:
+		default:
+			return nil, fmt.Errorf("Datetime type assertion failed for field: %v", ret)
+		}
+	case "schema":
+		// any value, its schema says which
+	case "object":
+		switch val := ret.(type) {
+		case map[string]interface{}:
+			ret = val
+		case Response:
+			ret = map[string]interface{}(val)
+		default:
+			return nil, fmt.Errorf("Object type assertion failed for field: %v", ret)
+		}
 	default:
 		return nil, fmt.Errorf("Unknown type %s", typ)
 	}
@@ -167,47 +200,97 @@ func buildArgs(rawArgs map[string]interface{}, store *storage) (map[string]inter
 	return r, nil
 }
 
-func sendRequest(args map[string]interface{}, route string, pclient *PClient, metricsReporter []metrics.Reporter) (Response, []byte, error) {
-	encodedData, err := json.Marshal(args)
+// metricsTags builds the tags reported along with the metrics of an operation
+func metricsTags(route string, opTags map[string]string) map[string]string {
+	tags := map[string]string{"route": route}
+	for k, v := range opTags {
+		if k == "route" {
+			continue
+		}
+		tags[k] = v
+	}
+
+	return tags
+}
+
+// reportError counts a failure of typ, reported as the type label
+func reportError(reporters []metrics.Reporter, tags map[string]string, typ string) {
+	errorTags := map[string]string{"type": typ}
+	for k, v := range tags {
+		if k != "type" {
+			errorTags[k] = v
+		}
+	}
+
+	for _, mr := range reporters {
+		mr.ReportCount(metrics.ErrorCount, errorTags, 1)
+	}
+}
+
+// reportResponseTime reports the response time summary and histogram, the
+// latter labeled by the response status too
+func reportResponseTime(reporters []metrics.Reporter, tags map[string]string, elapsed time.Duration, status string) {
+	ms := float64(elapsed.Nanoseconds() / 1e6)
+	histogramTags := map[string]string{"status": status}
+	for k, v := range tags {
+		if k != "status" {
+			histogramTags[k] = v
+		}
+	}
+
+	for _, mr := range reporters {
+		mr.ReportSummary(metrics.ResponseTime, tags, ms)
+		mr.ReportHistogram(metrics.ResponseTimeHistogram, histogramTags, ms)
+	}
+}
+
+func sendRequest(ctx context.Context, args map[string]interface{}, route string, timeout time.Duration, pclient *PClient, metricsReporter []metrics.Reporter, opTags map[string]string) (Response, Metadata, []byte, error) {
+	encodedData, err := pclient.marshal(route, args)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
+	metricsReporterTags := metricsTags(route, opTags)
+
 	startTime := time.Now()
-	response, b, err := pclient.Request(route, encodedData)
-	if err != nil {
-		metricsReporterTags := map[string]string{"route": route}
+	response, meta, b, err := pclient.Request(ctx, route, encodedData, timeout)
+	if _, ok := err.(*TimeoutError); ok {
 		for _, mr := range metricsReporter {
-			mr.ReportCount(metrics.ErrorCount, metricsReporterTags, 1)
+			mr.ReportCount(metrics.TimeoutCount, metricsReporterTags, 1)
 		}
+		return response, meta, b, err
 	}
-
-	elapsed := time.Since(startTime)
-
-	metricsReporterTags := map[string]string{"route": route}
-	for _, mr := range metricsReporter {
-		mr.ReportSummary(metrics.ResponseTime, metricsReporterTags, float64(elapsed.Nanoseconds()/1e6))
+	status := metrics.StatusOK
+	if err != nil {
+		reportError(metricsReporter, metricsReporterTags, metrics.ResponseError)
+		status = metrics.StatusError
 	}
 
-	return response, b, err
+	reportResponseTime(metricsReporter, metricsReporterTags, time.Since(startTime), status)
+
+	return response, meta, b, err
 }
 
-func sendNotify(args map[string]interface{}, route string, pclient *PClient) error {
-	encodedData, err := json.Marshal(args)
+func sendNotify(ctx context.Context, args map[string]interface{}, route string, pclient *PClient) error {
+	encodedData, err := pclient.marshal(route, args)
 	if err != nil {
 		return err
 	}
 
-	return pclient.Notify(route, encodedData)
+	return pclient.Notify(ctx, route, encodedData)
 }
 
-func getValueFromSpec(spec models.ExpectSpecEntry, store *storage) (interface{}, error) {
+func getValueFromSpec(spec models.ExpectSpecEntry, store *storage, strict bool) (interface{}, error) {
 	value, err := tryGetValue(spec.Value, store)
 	if err != nil {
 		return nil, err
 	}
 
 	if value == nil {
+		if ref := unresolvedRef(spec.Value); strict && ref != "" {
+			return nil, fmt.Errorf("Unresolved reference %s in expected value", ref)
+		}
+
 		value, err = assertType(spec.Value, spec.Type)
 		if err != nil {
 			return nil, err
@@ -217,26 +300,211 @@ func getValueFromSpec(spec models.ExpectSpecEntry, store *storage) (interface{},
 	return value, nil
 }
 
-func validateExpectations(expectations models.ExpectSpec, resp Response, store *storage) error {
+func validateExpectations(expectations models.ExpectSpec, resp Response, meta Metadata, store *storage, strict bool) error {
+	for _, propertyExpr := range orderedExpectations(expectations) {
+		spec := expectations[propertyExpr]
+		if spec.Capture == "" || spec.Value != nil {
+			if err := validateExpectation(propertyExpr, spec, resp, meta, store, strict); err != nil {
+				return err
+			}
+		}
+
+		if spec.Capture != "" {
+			value, err := extractResponseValue(resp, meta, propertyExpr, spec.Type)
+			if err != nil {
+				return err
+			}
+			store.Set(spec.Capture, value)
+		}
+	}
+
+	return nil
+}
+
+// orderedExpectations returns the expectations keys, the capturing ones
+// first, each group sorted
+func orderedExpectations(expectations models.ExpectSpec) []string {
+	captures := make([]string, 0)
+	others := make([]string, 0, len(expectations))
 	for propertyExpr, spec := range expectations {
-		expectedValue, err := getValueFromSpec(spec, store)
-		if err != nil {
+		if spec.Capture != "" {
+			captures = append(captures, propertyExpr)
+		} else {
+			others = append(others, propertyExpr)
+		}
+	}
+	sort.Strings(captures)
+	sort.Strings(others)
+
+	return append(captures, others...)
+}
+
+func validateExpectation(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata, store *storage, strict bool) error {
+	if spec.Type == "connection" {
+		return validateConnection(spec, meta, store)
+	}
+
+	if spec.Type == "datetime" {
+		return validateDatetime(propertyExpr, spec, resp, meta)
+	}
+
+	if spec.Type == "flags" {
+		return validateFlags(propertyExpr, spec, resp, meta)
+	}
+
+	if spec.Type == "discriminator" {
+		return validateDiscriminated(propertyExpr, spec, resp, meta, store, strict)
+	}
+
+	if spec.Type == "sameAs" {
+		return validateSameAs(propertyExpr, spec, resp, meta, store)
+	}
+
+	if spec.Type == "schema" {
+		return validateSchema(propertyExpr, spec, resp, meta)
+	}
+
+	if len(spec.ExactKeys) > 0 {
+		if err := validateExactKeys(propertyExpr, spec.ExactKeys, resp); err != nil {
 			return err
 		}
 
-		gotValue, err := Response(resp).extractValue(Expr(propertyExpr), spec.Type)
+		if spec.Value == nil {
+			return nil
+		}
+	}
+
+	if ops, ok := operatorExpr(spec.Value); ok {
+		return validateOperators(propertyExpr, spec, ops, resp, meta, store, strict)
+	}
+
+	expectedValue, err := getValueFromSpec(spec, store, strict)
+	if err != nil {
+		return err
+	}
+
+	gotValue, err := extractResponseValue(resp, meta, propertyExpr, spec.Type)
+	if err != nil {
+		return err
+	}
+
+	if isObjectComparison(spec.Type) {
+		diffs, err := compareObjects(spec.Type, propertyExpr, expectedValue, gotValue)
 		if err != nil {
 			return err
 		}
+		if len(diffs) > 0 {
+			return &DiffError{Path: propertyExpr, Diffs: diffs}
+		}
+		return nil
+	}
 
-		if !equals(expectedValue, gotValue) {
-			return fmt.Errorf("%v != %v", expectedValue, gotValue)
+	if !equals(expectedValue, gotValue) {
+		return fmt.Errorf("%v != %v", expectedValue, gotValue)
+	}
+
+	return nil
+}
+
+// validateConnection checks the connection state exposed in meta by
+// function operations
+func validateConnection(spec models.ExpectSpecEntry, meta Metadata, store *storage) error {
+	gotState, ok := meta["connection"]
+	if !ok {
+		return fmt.Errorf("Connection expectations are only valid for function operations")
+	}
+
+	state := spec.State
+	if state == "" {
+		state = "connected"
+	}
+	if state != "connected" && state != "disconnected" {
+		return fmt.Errorf("Unknown connection state: %s", state)
+	}
+
+	if gotState != state {
+		return fmt.Errorf("expected connection %s, got %s", state, gotState)
+	}
+
+	if spec.Host == "" {
+		return nil
+	}
+
+	host, err := tryGetValue(spec.Host, store)
+	if err != nil {
+		return err
+	}
+	if host == nil {
+		host = spec.Host
+	}
+
+	if meta["host"] != host {
+		return fmt.Errorf("expected connection to %v, got %v", host, meta["host"])
+	}
+
+	return nil
+}
+
+// validateDiscriminated validates the expectations of the case selected by
+// the discriminator field value
+func validateDiscriminated(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata, store *storage, strict bool) error {
+	value, err := extractResponseValue(resp, meta, propertyExpr, "string")
+	if err != nil {
+		if value, err = extractResponseValue(resp, meta, propertyExpr, "int"); err != nil {
+			return fmt.Errorf("Discriminator %s must be a string or an int: %s", propertyExpr, err)
 		}
 	}
+	kind := fmt.Sprintf("%v", value)
+
+	expectations, ok := spec.Cases[kind]
+	if !ok {
+		kinds := make([]string, 0, len(spec.Cases))
+		for k := range spec.Cases {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		return fmt.Errorf("Unknown %s %s, expected one of %v", propertyExpr, kind, kinds)
+	}
+
+	return validateExpectations(expectations, resp, meta, store, strict)
+}
+
+// validateExactKeys checks the object has exactly the given keys, no
+// extra and none missing
+func validateExactKeys(propertyExpr string, keys []string, resp Response) error {
+	gotValue, err := Response(resp).extractValue(Expr(propertyExpr), "object")
+	if err != nil {
+		return err
+	}
+
+	if diffs := diffKeys(propertyExpr, keys, gotValue.(map[string]interface{})); len(diffs) > 0 {
+		return &DiffError{Path: propertyExpr, Diffs: diffs}
+	}
 
 	return nil
 }
 
+func isObjectComparison(typ string) bool {
+	return typ == "object" || typ == "array" || typ == "setEqual"
+}
+
+// compareObjects deep compares expected and got, returning their differences
+func compareObjects(typ, path string, expected, got interface{}) (ValueDiffs, error) {
+	if typ == "setEqual" {
+		expectedSet, err := assertType(expected, "array")
+		if err != nil {
+			return nil, err
+		}
+		gotSet, err := assertType(got, "array")
+		if err != nil {
+			return nil, err
+		}
+		return diffSets(path, expectedSet.([]interface{}), gotSet.([]interface{})), nil
+	}
+
+	return diffValues(path, expected, got), nil
+}
+
 func equals(lhs interface{}, rhs interface{}) bool {
 	t := reflect.TypeOf(lhs)
 
@@ -267,6 +535,14 @@ func equals(lhs interface{}, rhs interface{}) bool {
 
 		return lhsVal == rhsVal
 
+	case reflect.Slice:
+		rhsVal, err := assertType(rhs, "array")
+		if err != nil {
+			return false
+		}
+
+		return reflect.DeepEqual(lhs, rhsVal)
+
 	default:
 		fmt.Printf("Unknown type %s\n", t.Kind().String())
 		return false
@@ -275,18 +551,24 @@ func equals(lhs interface{}, rhs interface{}) bool {
 	return false
 }
 
-func storeData(storeSpec models.StoreSpec, store *storage, resp Response) error {
+func storeData(storeSpec models.StoreSpec, store *storage, resp Response, meta Metadata) error {
 	for name, spec := range storeSpec {
-		valueFromResponse, err := resp.tryExtractValue(Expr(spec.Value), spec.Type)
+		valueFromResponse, err := extractResponseValue(resp, meta, spec.Value, spec.Type)
 		if err != nil {
 			return err
 		}
+
+		var value interface{} = spec.Value
 		if valueFromResponse != nil {
-			store.Set(name, valueFromResponse)
-			continue
+			value = valueFromResponse
+		}
+
+		value, err = applyTransforms(value, spec.Transform, store)
+		if err != nil {
+			return fmt.Errorf("Unable to store %s: %s", name, err)
 		}
 
-		store.Set(name, spec.Value)
+		store.Set(name, value)
 	}
 
 	return nil
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// runCondition runs the Then operations when the If expectations hold and
+// the Else ones otherwise. If is checked against the storage, keyed by
+// storage key, or against the last request or listen response when Source
+// is response
+func (b *SequentialBot) runCondition(ctx context.Context, op *models.Operation) error {
+	cond := op.Condition
+	if cond == nil {
+		return fmt.Errorf("Missing condition spec")
+	}
+
+	var resp Response
+	var meta Metadata
+	switch cond.Source {
+	case "", "storage":
+		resp = Response(*b.storage)
+	case "response":
+		if b.lastResponse == nil {
+			return fmt.Errorf("Condition on the last response, but no response was received yet")
+		}
+		resp, meta = b.lastResponse, b.lastMeta
+	default:
+		return fmt.Errorf("Unknown condition source: %s", cond.Source)
+	}
+
+	branch, name := cond.Else, "else"
+	if validateExpectations(cond.If, resp, meta, b.storage, b.strictRefs) == nil {
+		branch, name = cond.Then, "then"
+	}
+	b.logger.Debugf("Condition took the %s branch", name)
+
+	for idx, branchOp := range branch {
+		if err := b.runOperation(ctx, branchOp); err != nil {
+			if _, ok := err.(*jumpError); ok {
+				return err
+			}
+			return fmt.Errorf("Condition %s operation %d (%s %s) failed: %s", name, idx, branchOp.Type, branchOp.URI, err)
+		}
+	}
+
+	return nil
+}
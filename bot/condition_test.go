@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestConditionOperation(t *testing.T) {
+	tutorial := &models.Operation{Type: "request", URI: "tutorial.start", Store: models.StoreSpec{"branch": {Type: "string", Value: "code"}}}
+	rewards := &models.Operation{Type: "request", URI: "rewards.claim", Store: models.StoreSpec{"branch": {Type: "string", Value: "code"}}}
+
+	tables := []struct {
+		name      string
+		condition *models.ConditionSpec
+		stored    map[string]interface{}
+		response  bool
+		sends     int
+		err       bool
+	}{
+		{"storage then", &models.ConditionSpec{If: models.ExpectSpec{"newPlayer": {Type: "bool", Value: true}}, Then: []*models.Operation{tutorial}, Else: []*models.Operation{rewards, rewards}},
+			map[string]interface{}{"newPlayer": true}, false, 1, false},
+		{"storage else", &models.ConditionSpec{If: models.ExpectSpec{"newPlayer": {Type: "bool", Value: true}}, Then: []*models.Operation{tutorial}, Else: []*models.Operation{rewards, rewards}},
+			map[string]interface{}{"newPlayer": false}, false, 2, false},
+		{"response then", &models.ConditionSpec{Source: "response", If: models.ExpectSpec{"code": {Type: "string", Value: "200"}}, Then: []*models.Operation{tutorial}},
+			nil, true, 2, false},
+		{"no response yet", &models.ConditionSpec{Source: "response", If: models.ExpectSpec{"code": {Type: "string", Value: "200"}}, Then: []*models.Operation{tutorial}},
+			nil, false, 0, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+			for k, v := range table.stored {
+				b.storage.Set(k, v)
+			}
+			if table.response {
+				assert.NoError(t, b.runOperation(context.Background(), &models.Operation{Type: "request", URI: "player.info"}))
+			}
+
+			err := b.runOperation(context.Background(), &models.Operation{Type: "condition", Condition: table.condition})
+			assert.Len(t, transport.sent, table.sends)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+const argsPrefix = "args."
+
+// correlations tracks, per group, the ids sent by requests still waiting for
+// their push and the pushes that matched no request
+type correlations struct {
+	mutex       sync.Mutex
+	outstanding map[string][]interface{}
+	unmatched   map[string][]interface{}
+}
+
+func newCorrelations() *correlations {
+	return &correlations{
+		outstanding: map[string][]interface{}{},
+		unmatched:   map[string][]interface{}{},
+	}
+}
+
+func (c *correlations) register(group string, id interface{}) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.outstanding[group] = append(c.outstanding[group], normalizeValue(id))
+}
+
+// match marks id as handled, returning false if no request is waiting for it
+func (c *correlations) match(group string, id interface{}) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	id = normalizeValue(id)
+	for i, candidate := range c.outstanding[group] {
+		if reflect.DeepEqual(candidate, id) {
+			c.outstanding[group] = append(c.outstanding[group][:i], c.outstanding[group][i+1:]...)
+			return true
+		}
+	}
+
+	c.unmatched[group] = append(c.unmatched[group], id)
+	return false
+}
+
+// report describes the requests without push and the pushes without
+// request, it is empty when everything matched
+func (c *correlations) report() string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	groups := make([]string, 0)
+	for group, ids := range c.outstanding {
+		if len(ids) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	for group, ids := range c.unmatched {
+		if len(ids) > 0 && len(c.outstanding[group]) == 0 {
+			groups = append(groups, group)
+		}
+	}
+	sort.Strings(groups)
+
+	lines := make([]string, 0, len(groups))
+	for _, group := range groups {
+		lines = append(lines, fmt.Sprintf("group %s: requests without push %v, pushes without request %v",
+			group, c.outstanding[group], c.unmatched[group]))
+	}
+
+	return strings.Join(lines, "; ")
+}
+
+func correlationType(spec *models.CorrelationSpec) string {
+	if spec.Type == "" {
+		return "string"
+	}
+
+	return spec.Type
+}
+
+// registerCorrelation stores the id sent by a request, taken from its args
+// or its response
+func (b *SequentialBot) registerCorrelation(spec *models.CorrelationSpec, args map[string]interface{}, resp Response, meta Metadata) error {
+	var (
+		id  interface{}
+		err error
+	)
+
+	if strings.HasPrefix(spec.Value, argsPrefix) {
+		id, err = extractValue(args, Expr(strings.TrimPrefix(spec.Value, argsPrefix)), correlationType(spec))
+	} else {
+		id, err = extractResponseValue(resp, meta, spec.Value, correlationType(spec))
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to extract correlation id %s: %s", spec.Value, err)
+	}
+
+	b.correlations.register(spec.Group, id)
+	return nil
+}
+
+// matchCorrelation checks the push correlation id against the outstanding
+// ids of its group
+func (b *SequentialBot) matchCorrelation(spec *models.CorrelationSpec, route string, resp Response, meta Metadata) error {
+	id, err := extractResponseValue(resp, meta, spec.Value, correlationType(spec))
+	if err != nil {
+		return fmt.Errorf("Unable to extract correlation id %s: %s", spec.Value, err)
+	}
+
+	if !b.correlations.match(spec.Group, id) {
+		return fmt.Errorf("Push on route %s with correlation id %v matches no outstanding request of group %s", route, id, spec.Group)
+	}
+
+	return nil
+}
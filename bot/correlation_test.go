@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func orderRequest(orderID string) *models.Operation {
+	return &models.Operation{
+		Type: "request",
+		URI:  "order.create",
+		Args: map[string]interface{}{
+			"orderId": map[string]interface{}{"type": "string", "value": orderID},
+		},
+		Correlation: &models.CorrelationSpec{Group: "orders", Value: "args.orderId"},
+	}
+}
+
+func orderPush() *models.Operation {
+	return &models.Operation{
+		Type:        "listen",
+		URI:         "order.done",
+		Timeout:     1000,
+		Correlation: &models.CorrelationSpec{Group: "orders", Value: "orderId"},
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+
+	assert.NoError(t, b.runOperation(context.Background(), orderRequest("o1")))
+	assert.NoError(t, b.runOperation(context.Background(), orderRequest("o2")))
+
+	go transport.handler(MsgPushType, 0, "order.done", []byte(`{"orderId": "o2"}`))
+	assert.NoError(t, b.runOperation(context.Background(), orderPush()))
+
+	go transport.handler(MsgPushType, 0, "order.done", []byte(`{"orderId": "o3"}`))
+	err := b.runOperation(context.Background(), orderPush())
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "matches no outstanding request"))
+
+	report := b.correlations.report()
+	assert.Equal(t, "group orders: requests without push [o1], pushes without request [o3]", report)
+}
+
+func TestCorrelationReportedAtSpecEnd(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.spec = &models.Spec{SequentialOperations: []*models.Operation{orderRequest("o1")}}
+
+	err := b.Run(context.Background())
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "Unmatched correlations: group orders"))
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// StatefulCycleBot runs the sequential operations over and over on the same
+// connection, for soak tests. Between iterations the storage is either kept
+// or reset to what the bot started with
+type StatefulCycleBot struct {
+	*SequentialBot
+	initial map[string]interface{}
+}
+
+// NewStatefulCycleBot returns a new cycle bot
+func NewStatefulCycleBot(app *state.App, config *viper.Viper, spec *models.Spec, id int, logger logrus.FieldLogger) (Bot, error) {
+	if spec.Cycle == nil {
+		return nil, fmt.Errorf("Missing cycle spec")
+	}
+	if spec.Cycle.Iterations < 0 {
+		return nil, fmt.Errorf("Cycle iterations must not be negative")
+	}
+	if spec.Cycle.Iterations == 0 && spec.MaxDuration <= 0 {
+		return nil, fmt.Errorf("Cycle without iterations limit needs maxDuration")
+	}
+
+	seq, err := NewSequentialBot(app, config, spec, id, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &StatefulCycleBot{
+		SequentialBot: seq.(*SequentialBot),
+		initial:       make(map[string]interface{}),
+	}
+	for k, v := range *b.storage {
+		b.initial[k] = v
+	}
+
+	return b, nil
+}
+
+// Run runs the operations Cycle.Iterations times, or until ctx is done when
+// it is 0. An iteration interrupted by ctx ends the run without failing it,
+// unless it abandoned an operation still running
+func (b *StatefulCycleBot) Run(ctx context.Context) error {
+	defer b.Disconnect()
+
+	return b.runWithHooks(ctx, b.runCycle)
+}
+
+func (b *StatefulCycleBot) runCycle(ctx context.Context) error {
+	order, err := b.operationOrder()
+	if err != nil {
+		return err
+	}
+
+	iterations := b.spec.Cycle.Iterations
+	iteration := 0
+	for ; iterations == 0 || iteration < iterations; iteration++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		if iteration > 0 && b.spec.Cycle.ResetStorage {
+			b.resetStorage()
+		}
+
+		if err := b.runIteration(ctx, order); err != nil {
+			if ctx.Err() != nil && !b.abandoned {
+				b.logger.Debugf("Iteration %d interrupted: %s", iteration, err)
+				break
+			}
+			return fmt.Errorf("Iteration %d: %s", iteration, err)
+		}
+	}
+	b.logger.Infof("Finished %d iterations", iteration)
+
+	if report := b.correlations.report(); report != "" {
+		return fmt.Errorf("Unmatched correlations: %s", report)
+	}
+
+	return b.continuedError()
+}
+
+// runIteration runs the operations once, applying their onError policies
+// and jumping to goto labels as sequential bots do. bot.maxJumps bounds the
+// jumps of each iteration, so endless cycles are not failed by them
+func (b *StatefulCycleBot) runIteration(ctx context.Context, order []int) error {
+	b.jumps = 0
+	for pos := 0; pos < len(order); {
+		next, err := b.runAt(ctx, order, pos)
+		if err != nil {
+			return err
+		}
+		pos = next
+	}
+
+	return nil
+}
+
+func (b *StatefulCycleBot) resetStorage() {
+	store := storage{}
+	for k, v := range b.initial {
+		store[k] = v
+	}
+	*b.storage = store
+}
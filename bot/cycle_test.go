@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func newTestCycleBot(transport *recordingTransport, cycle *models.CycleSpec) *StatefulCycleBot {
+	b := newTestBot(transport)
+	b.spec = &models.Spec{
+		SequentialOperations: []*models.Operation{
+			{
+				Type:  "request",
+				URI:   "auth.refresh",
+				Args:  map[string]interface{}{"token": map[string]interface{}{"type": "string", "value": "$store.token"}},
+				Store: models.StoreSpec{"token": {Type: "string", Value: "code"}},
+			},
+		},
+		Cycle: cycle,
+	}
+	b.storage.Set("token", "initial")
+
+	return &StatefulCycleBot{SequentialBot: b, initial: map[string]interface{}{"token": "initial"}}
+}
+
+func TestStatefulCycleBotIterations(t *testing.T) {
+	tables := []struct {
+		name   string
+		reset  bool
+		tokens []string
+	}{
+		{"preserve storage", false, []string{"initial", "200", "200"}},
+		{"reset storage", true, []string{"initial", "initial", "initial"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestCycleBot(transport, &models.CycleSpec{Iterations: 3, ResetStorage: table.reset})
+
+			assert.NoError(t, b.Run(context.Background()))
+			assert.Len(t, transport.sent, len(table.tokens))
+			for i, token := range table.tokens {
+				assert.Contains(t, transport.sent[i], `"token":"`+token+`"`)
+			}
+		})
+	}
+}
+
+func TestStatefulCycleBotUntilDone(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestCycleBot(transport, &models.CycleSpec{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	assert.NoError(t, b.Run(ctx))
+	assert.True(t, len(transport.sent) > 1)
+}
+
+func TestStatefulCycleBotOnError(t *testing.T) {
+	tables := []struct {
+		name      string
+		onError   string
+		responses []string
+		sent      int
+		err       string
+	}{
+		{"abort", "", []string{`{"code": "200"}`, `{"code": "500"}`}, 2, "Iteration 1: "},
+		{"continue", "continue", []string{`{"code": "200"}`, `{"code": "500"}`}, 3,
+			"1 operations failed and the spec continued, first: "},
+		{"retry", "retry(2)", []string{`{"code": "200"}`, `{"code": "500"}`, `{"code": "200"}`}, 4, ""},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: table.responses}
+			b := newTestCycleBot(transport, &models.CycleSpec{Iterations: 3})
+			b.spec.SequentialOperations[0].Expect = models.ExpectSpec{"code": {Type: "string", Value: "200"}}
+			b.spec.SequentialOperations[0].OnError = table.onError
+
+			err := b.Run(context.Background())
+			if table.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, transport.sent, table.sent)
+		})
+	}
+}
+
+func TestStatefulCycleBotGoto(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestCycleBot(transport, &models.CycleSpec{Iterations: 3})
+	b.config = viper.New()
+	b.config.Set("bot.maxJumps", 1)
+	b.spec.SequentialOperations = []*models.Operation{
+		gotoOp("end"),
+		{Type: "request", URI: "room.skipped"},
+		{Type: "request", URI: "room.join", Label: "end"},
+	}
+
+	// each iteration jumps once, within bot.maxJumps
+	assert.NoError(t, b.Run(context.Background()))
+	assert.Len(t, transport.sent, 3)
+	assert.Equal(t, 1, b.jumps)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// datetimeFormats are the named formats of the datetime expectation, any
+// other format is used as a go time layout. Layouts without a zone are
+// parsed as UTC
+var datetimeFormats = map[string]string{
+	"":            time.RFC3339,
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"date":        "2006-01-02",
+}
+
+// parseDatetime parses value according to format, unix and unixms parse
+// numeric (or numeric string) epoch timestamps
+func parseDatetime(value interface{}, format string) (time.Time, error) {
+	switch format {
+	case "unix", "unixms":
+		var epoch float64
+		switch val := value.(type) {
+		case float64:
+			epoch = val
+		case int:
+			epoch = float64(val)
+		case json.Number:
+			f, err := val.Float64()
+			if err != nil {
+				return time.Time{}, err
+			}
+			epoch = f
+		case string:
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("%q is not a %s timestamp", val, format)
+			}
+			epoch = f
+		}
+
+		if format == "unixms" {
+			return time.Unix(0, int64(epoch*float64(time.Millisecond))).UTC(), nil
+		}
+		return time.Unix(0, int64(epoch*float64(time.Second))).UTC(), nil
+	}
+
+	str, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%v is not a string", value)
+	}
+
+	layout, ok := datetimeFormats[format]
+	if !ok {
+		layout = format
+	}
+
+	t, err := time.Parse(layout, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q does not match format %s", str, layout)
+	}
+
+	return t.UTC(), nil
+}
+
+// validateDatetime checks the field parses with the expected format and,
+// when withinLast is set, that it is no older than withinLast and not in
+// the future
+func validateDatetime(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata) error {
+	value, err := extractResponseValue(resp, meta, propertyExpr, "datetime")
+	if err != nil {
+		return err
+	}
+
+	parsed, err := parseDatetime(value, spec.Format)
+	if err != nil {
+		return fmt.Errorf("%s: %s", propertyExpr, err)
+	}
+
+	if spec.WithinLast == "" {
+		return nil
+	}
+
+	window, err := time.ParseDuration(spec.WithinLast)
+	if err != nil {
+		return fmt.Errorf("Invalid withinLast %s: %s", spec.WithinLast, err)
+	}
+
+	now := time.Now().UTC()
+	if parsed.Before(now.Add(-window)) {
+		return fmt.Errorf("%s: %s is older than %s (now %s)",
+			propertyExpr, parsed.Format(time.RFC3339Nano), spec.WithinLast, now.Format(time.RFC3339Nano))
+	}
+	if parsed.After(now) {
+		return fmt.Errorf("%s: %s is in the future (now %s)",
+			propertyExpr, parsed.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano))
+	}
+
+	return nil
+}
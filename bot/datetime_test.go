@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestValidateDatetime(t *testing.T) {
+	now := time.Now()
+	tables := []struct {
+		name  string
+		value interface{}
+		spec  models.ExpectSpecEntry
+		err   string
+	}{
+		{"rfc3339", now.Format(time.RFC3339), models.ExpectSpecEntry{WithinLast: "1m"}, ""},
+		{"other timezone", now.In(time.FixedZone("BRT", -3*3600)).Format(time.RFC3339), models.ExpectSpecEntry{WithinLast: "1m"}, ""},
+		{"too old", now.Add(-2 * time.Minute).Format(time.RFC3339), models.ExpectSpecEntry{WithinLast: "1m"}, "is older than 1m"},
+		{"future", now.Add(time.Hour).Format(time.RFC3339), models.ExpectSpecEntry{WithinLast: "1m"}, "is in the future"},
+		{"bad format", "2020-01-02 10:00", models.ExpectSpecEntry{Format: "rfc3339"}, "does not match format"},
+		{"layout", "2020-01-02 10:00", models.ExpectSpecEntry{Format: "2006-01-02 15:04"}, ""},
+		{"unix", float64(now.Unix()), models.ExpectSpecEntry{Format: "unix", WithinLast: "1m"}, ""},
+		{"unixms", float64(now.Add(-time.Hour).UnixNano() / 1e6), models.ExpectSpecEntry{Format: "unixms", WithinLast: "1m"}, "is older than 1m"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			table.spec.Type = "datetime"
+			resp := Response{"updatedAt": table.value}
+
+			err := validateExpectations(models.ExpectSpec{"updatedAt": table.spec}, resp, nil, &storage{}, true)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.Error(t, err)
+			assert.True(t, strings.Contains(err.Error(), table.err), err.Error())
+		})
+	}
+}
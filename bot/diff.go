@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diff kinds
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// ValueDiff is a single difference found when deep comparing two values
+type ValueDiff struct {
+	Path     string      `json:"path"`
+	Kind     string      `json:"kind"`
+	Expected interface{} `json:"expected,omitempty"`
+	Got      interface{} `json:"got,omitempty"`
+}
+
+func (d ValueDiff) String() string {
+	switch d.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("+ %s: %s (unexpected)", d.Path, formatDiffValue(d.Got))
+	case DiffRemoved:
+		if d.Expected == nil {
+			return fmt.Sprintf("- %s (missing)", d.Path)
+		}
+		return fmt.Sprintf("- %s: %s (missing)", d.Path, formatDiffValue(d.Expected))
+	default:
+		return fmt.Sprintf("~ %s: expected %s, got %s", d.Path, formatDiffValue(d.Expected), formatDiffValue(d.Got))
+	}
+}
+
+// ValueDiffs is the list of differences between two values
+type ValueDiffs []ValueDiff
+
+func (d ValueDiffs) String() string {
+	lines := make([]string, len(d))
+	for i, diff := range d {
+		lines[i] = diff.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DiffError is returned by object comparison expectations
+type DiffError struct {
+	Path  string
+	Diffs ValueDiffs
+}
+
+func (e *DiffError) Error() string {
+	return fmt.Sprintf("%s differs from expected value:\n%s", e.Path, e.Diffs)
+}
+
+func formatDiffValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+
+	return string(b)
+}
+
+// normalizeValue converts numbers to float64 and responses to plain maps so
+// values decoded from the spec and from the server compare equally
+func normalizeValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case Response:
+		return normalizeValue(map[string]interface{}(val))
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			ret[k] = normalizeValue(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, item := range val {
+			ret[i] = normalizeValue(item)
+		}
+		return ret
+	case int:
+		return float64(val)
+	case int32:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case float32:
+		return float64(val)
+	}
+
+	return v
+}
+
+func diffValues(path string, expected, got interface{}) ValueDiffs {
+	return diffNormalized(path, normalizeValue(expected), normalizeValue(got))
+}
+
+func diffNormalized(path string, expected, got interface{}) ValueDiffs {
+	diffs := ValueDiffs{}
+
+	switch exp := expected.(type) {
+	case map[string]interface{}:
+		gotMap, ok := got.(map[string]interface{})
+		if !ok {
+			return append(diffs, ValueDiff{Path: path, Kind: DiffChanged, Expected: expected, Got: got})
+		}
+
+		keys := make([]string, 0, len(exp)+len(gotMap))
+		for k := range exp {
+			keys = append(keys, k)
+		}
+		for k := range gotMap {
+			if _, ok := exp[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			childPath := fmt.Sprintf("%s.%s", path, k)
+			expVal, inExp := exp[k]
+			gotVal, inGot := gotMap[k]
+			switch {
+			case !inGot:
+				diffs = append(diffs, ValueDiff{Path: childPath, Kind: DiffRemoved, Expected: expVal})
+			case !inExp:
+				diffs = append(diffs, ValueDiff{Path: childPath, Kind: DiffAdded, Got: gotVal})
+			default:
+				diffs = append(diffs, diffNormalized(childPath, expVal, gotVal)...)
+			}
+		}
+
+		return diffs
+	case []interface{}:
+		gotSlice, ok := got.([]interface{})
+		if !ok {
+			return append(diffs, ValueDiff{Path: path, Kind: DiffChanged, Expected: expected, Got: got})
+		}
+
+		for i := 0; i < len(exp) || i < len(gotSlice); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(gotSlice):
+				diffs = append(diffs, ValueDiff{Path: childPath, Kind: DiffRemoved, Expected: exp[i]})
+			case i >= len(exp):
+				diffs = append(diffs, ValueDiff{Path: childPath, Kind: DiffAdded, Got: gotSlice[i]})
+			default:
+				diffs = append(diffs, diffNormalized(childPath, exp[i], gotSlice[i])...)
+			}
+		}
+
+		return diffs
+	}
+
+	if !reflect.DeepEqual(expected, got) {
+		diffs = append(diffs, ValueDiff{Path: path, Kind: DiffChanged, Expected: expected, Got: got})
+	}
+
+	return diffs
+}
+
+// diffSets compares two slices ignoring the order of their elements
+func diffSets(path string, expected, got []interface{}) ValueDiffs {
+	diffs := ValueDiffs{}
+	remaining := normalizeValue(got).([]interface{})
+
+	for _, exp := range normalizeValue(expected).([]interface{}) {
+		found := false
+		for i, candidate := range remaining {
+			if reflect.DeepEqual(exp, candidate) {
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			diffs = append(diffs, ValueDiff{Path: path + "[*]", Kind: DiffRemoved, Expected: exp})
+		}
+	}
+
+	for _, extra := range remaining {
+		diffs = append(diffs, ValueDiff{Path: path + "[*]", Kind: DiffAdded, Got: extra})
+	}
+
+	return diffs
+}
+
+// diffKeys reports the keys missing from got and the ones it has in excess
+func diffKeys(path string, expected []string, got map[string]interface{}) ValueDiffs {
+	diffs := ValueDiffs{}
+	expectedSet := make(map[string]bool, len(expected))
+	for _, key := range expected {
+		expectedSet[key] = true
+		if _, ok := got[key]; !ok {
+			diffs = append(diffs, ValueDiff{Path: fmt.Sprintf("%s.%s", path, key), Kind: DiffRemoved})
+		}
+	}
+
+	unexpected := make([]string, 0)
+	for key := range got {
+		if !expectedSet[key] {
+			unexpected = append(unexpected, key)
+		}
+	}
+	sort.Strings(unexpected)
+
+	for _, key := range unexpected {
+		diffs = append(diffs, ValueDiff{Path: fmt.Sprintf("%s.%s", path, key), Kind: DiffAdded, Got: got[key]})
+	}
+
+	return diffs
+}
@@ -0,0 +1,205 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestDiffValues(t *testing.T) {
+	expected := map[string]interface{}{
+		"name":  "john",
+		"level": 7,
+		"tags":  []interface{}{"a", "b"},
+	}
+	got := map[string]interface{}{
+		"level": float64(8),
+		"tags":  []interface{}{"a", "b", "c"},
+		"extra": true,
+	}
+
+	diffs := diffValues("$response.player", expected, got)
+	assert.Equal(t, ValueDiffs{
+		{Path: "$response.player.extra", Kind: DiffAdded, Got: true},
+		{Path: "$response.player.level", Kind: DiffChanged, Expected: float64(7), Got: float64(8)},
+		{Path: "$response.player.name", Kind: DiffRemoved, Expected: "john"},
+		{Path: "$response.player.tags[2]", Kind: DiffAdded, Got: "c"},
+	}, diffs)
+}
+
+func TestDiffSets(t *testing.T) {
+	diffs := diffSets("$response.ids", []interface{}{"a", "b", "c"}, []interface{}{"c", "a", "d"})
+	assert.Equal(t, ValueDiffs{
+		{Path: "$response.ids[*]", Kind: DiffRemoved, Expected: "b"},
+		{Path: "$response.ids[*]", Kind: DiffAdded, Got: "d"},
+	}, diffs)
+
+	assert.Empty(t, diffSets("$response.ids", []interface{}{1, 2}, []interface{}{float64(2), float64(1)}))
+}
+
+func TestCompareObjectsNotArrays(t *testing.T) {
+	tables := []struct {
+		name     string
+		expected interface{}
+		got      interface{}
+	}{
+		{"expected", "a", []interface{}{"a"}},
+		{"got", []interface{}{"a"}, map[string]interface{}{"a": true}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			diffs, err := compareObjects("setEqual", "$response.ids", table.expected, table.got)
+			assert.Nil(t, diffs)
+			assert.Contains(t, err.Error(), "Array type assertion failed for field")
+		})
+	}
+}
+
+func TestValidateObjectExpectationAttachesDiff(t *testing.T) {
+	expect := models.ExpectSpec{
+		"$response.player": {Type: "object", Value: map[string]interface{}{"name": "john"}},
+	}
+	resp := Response{"player": map[string]interface{}{"name": "jane"}}
+
+	err := validateExpectations(expect, resp, nil, &storage{}, true)
+	expectErr := NewExpectError(err, []byte(`{}`), expect)
+	assert.Len(t, expectErr.Diffs, 1)
+	assert.Contains(t, expectErr.Error(), `~ $response.player.name: expected "john", got "jane"`)
+}
+
+func TestValidateExactKeys(t *testing.T) {
+	expect := models.ExpectSpec{
+		"$response.player": {Type: "object", ExactKeys: []string{"id", "name", "level"}},
+	}
+
+	resp := Response{"player": map[string]interface{}{"id": "1", "name": "john", "level": 3}}
+	assert.NoError(t, validateExpectations(expect, resp, nil, &storage{}, true))
+
+	resp = Response{"player": map[string]interface{}{"id": "1", "name": "john", "gold": 10}}
+	err := validateExpectations(expect, resp, nil, &storage{}, true)
+	assert.Equal(t, &DiffError{Path: "$response.player", Diffs: ValueDiffs{
+		{Path: "$response.player.level", Kind: DiffRemoved},
+		{Path: "$response.player.gold", Kind: DiffAdded, Got: 10},
+	}}, err)
+	assert.Contains(t, err.Error(), "- $response.player.level (missing)")
+}
+
+func TestCaptureExpectation(t *testing.T) {
+	resp := Response{
+		"player": map[string]interface{}{"id": "p1", "coins": float64(10)},
+		"owner":  "p1",
+	}
+
+	tables := []struct {
+		name   string
+		expect models.ExpectSpec
+		err    bool
+	}{
+		{"capture and match", models.ExpectSpec{
+			"player.id": {Type: "string", Value: "p1", Capture: "playerId"},
+			"owner":     {Type: "string", Value: "$store.playerId"},
+		}, false},
+		{"capture type only", models.ExpectSpec{
+			"player.coins": {Type: "int", Capture: "coins"},
+			"player.id":    {Type: "string", Value: "p1"},
+		}, false},
+		{"capture wrong type", models.ExpectSpec{
+			"player.coins": {Type: "string", Capture: "coins"},
+		}, true},
+		{"mismatch", models.ExpectSpec{
+			"player.id": {Type: "string", Value: "p2", Capture: "playerId"},
+		}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			store := &storage{}
+			err := validateExpectations(table.expect, resp, nil, store, true)
+			if table.err {
+				assert.Error(t, err)
+				assert.Empty(t, *store)
+				return
+			}
+
+			assert.NoError(t, err)
+			for _, spec := range table.expect {
+				if spec.Capture != "" {
+					_, ok := store.Get(spec.Capture)
+					assert.True(t, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestDiscriminatorExpectation(t *testing.T) {
+	expect := models.ExpectSpec{
+		"kind": {Type: "discriminator", Cases: map[string]models.ExpectSpec{
+			"item":     {"item.id": {Type: "string", Value: "sword"}},
+			"currency": {"amount": {Type: "int", Value: 10}},
+		}},
+	}
+
+	tables := []struct {
+		name string
+		resp Response
+		err  string
+	}{
+		{"item", Response{"kind": "item", "item": map[string]interface{}{"id": "sword"}}, ""},
+		{"currency", Response{"kind": "currency", "amount": float64(10)}, ""},
+		{"case mismatch", Response{"kind": "currency", "amount": float64(5)}, "10 != 5"},
+		{"unknown kind", Response{"kind": "chest"}, "Unknown kind chest, expected one of [currency item]"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			err := validateExpectations(expect, table.resp, nil, &storage{}, true)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
+
+func TestSameAsExpectation(t *testing.T) {
+	before := Response{
+		"items": []interface{}{
+			map[string]interface{}{"id": "sword", "updatedAt": float64(1)},
+		},
+		"timestamp": float64(1),
+	}
+
+	tables := []struct {
+		name   string
+		expect models.ExpectSpecEntry
+		resp   Response
+		err    bool
+	}{
+		{"same", models.ExpectSpecEntry{Type: "sameAs", Stored: "before", Ignore: []string{"timestamp", "items.updatedAt"}},
+			Response{"items": []interface{}{map[string]interface{}{"id": "sword", "updatedAt": float64(2)}}, "timestamp": float64(2)}, false},
+		{"not ignored", models.ExpectSpecEntry{Type: "sameAs", Stored: "before", Ignore: []string{"timestamp"}},
+			Response{"items": []interface{}{map[string]interface{}{"id": "sword", "updatedAt": float64(2)}}, "timestamp": float64(2)}, true},
+		{"item added", models.ExpectSpecEntry{Type: "sameAs", Stored: "before", Ignore: []string{"timestamp"}},
+			Response{"items": []interface{}{before["items"].([]interface{})[0], map[string]interface{}{"id": "shield"}}, "timestamp": float64(1)}, true},
+		{"nothing stored", models.ExpectSpecEntry{Type: "sameAs", Stored: "missing"}, before, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			store := &storage{}
+			store.Set("before", before)
+			err := validateExpectations(models.ExpectSpec{"$response": table.expect}, table.resp, nil, store, true)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
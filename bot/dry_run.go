@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// planner writes the operation plan of a dry run. Nothing is sent, the
+// storage keys operations would store hold placeholders instead
+type planner struct {
+	w          io.Writer
+	spec       *models.Spec
+	storage    *storage
+	callDepth  int
+	strictRefs bool
+}
+
+// DryRun writes the plan of the bot id of spec to w: every operation with
+// its args and expected values resolved against a storage seeded like the
+// bot one. Operations failing to resolve are reported inline, so the whole
+// plan is written. It returns the storage left by the plan
+func DryRun(config *viper.Viper, spec *models.Spec, id int, shared map[string]interface{}, w io.Writer) (map[string]interface{}, error) {
+	seed := time.Now().UnixNano()
+	if config.IsSet("bot.seed") {
+		seed = config.GetInt64("bot.seed") + int64(id)
+	}
+
+	p := &planner{w: w, spec: spec, storage: newStorage(config, shared), strictRefs: strictRefsFromConfig(config)}
+	if err := seedStorage(p.storage, config, spec, id, seed); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(w, "spec %s (bot %d)\n", spec.Name, id)
+	p.section("setupOperations", spec.SetupOperations)
+	p.section("sequentialOperations", spec.SequentialOperations)
+	if spec.Random != nil {
+		p.section("random.operations", spec.Random.Operations)
+	}
+	p.section("teardownOperations", spec.TeardownOperations)
+
+	return map[string]interface{}(*p.storage), nil
+}
+
+// DryRunSuite writes the plan of suite setup or teardown operations, like
+// DryRun, returning the storage the suite bots would share
+func DryRunSuite(config *viper.Viper, name string, ops []*models.Operation, w io.Writer) (map[string]interface{}, error) {
+	store, err := DryRun(config, &models.Spec{Name: name, SequentialOperations: ops}, suiteBotID, nil, w)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(store, botIDKey)
+	return store, nil
+}
+
+func (p *planner) section(name string, ops []*models.Operation) {
+	if len(ops) == 0 {
+		return
+	}
+
+	fmt.Fprintf(p.w, "  %s:\n", name)
+	p.operations(ops, 2)
+}
+
+func (p *planner) operations(ops []*models.Operation, depth int) {
+	for idx, op := range ops {
+		p.operation(idx, op, depth)
+	}
+}
+
+func (p *planner) operation(idx int, op *models.Operation, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if len(op.Vars) > 0 {
+		defer p.storage.withVars(op.Vars)()
+	}
+
+	uri, err := interpolate(op.URI, p.storage)
+	if err != nil {
+		uri = op.URI
+	}
+	fmt.Fprintf(p.w, "%s%d: %s\n", indent, idx, strings.TrimSpace(op.Type+" "+uri))
+	indent += "   "
+
+	if err := p.details(op, indent); err != nil {
+		fmt.Fprintf(p.w, "%serror: %s\n", indent, err)
+	}
+
+	if op.ExpectError != nil {
+		data, _ := planJSON(op.ExpectError)
+		fmt.Fprintf(p.w, "%sexpectError: %s\n", indent, data)
+	}
+	p.expectations(indent, "expect", op.Expect)
+	p.store(op.Store)
+	if op.Listen != nil {
+		for _, route := range op.Listen.Routes {
+			p.expectations(indent, "expect "+route.Route, route.Expect)
+			p.store(route.Store)
+		}
+	}
+	p.expectations(indent, "assertStorage", op.AssertStorage)
+
+	p.nested(op, depth+2)
+}
+
+// details writes the args, and headers or metadata, the operation would send
+func (p *planner) details(op *models.Operation, indent string) error {
+	if op.Type == "call" {
+		return nil
+	}
+
+	if len(op.Args) > 0 {
+		args, err := buildArgs(op.Args, p.storage)
+		if err != nil {
+			return err
+		}
+		data, err := planJSON(args)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.w, "%sargs: %s\n", indent, data)
+	}
+
+	headers := map[string]string{}
+	if op.HTTP != nil {
+		headers = op.HTTP.Headers
+	}
+	if op.GRPC != nil {
+		headers = op.GRPC.Metadata
+	}
+	for _, name := range sortedKeys(headers) {
+		value, err := interpolate(headers[name], p.storage)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(p.w, "%sheader %s: %s\n", indent, name, value)
+	}
+
+	return nil
+}
+
+func (p *planner) expectations(indent, name string, expectations models.ExpectSpec) {
+	for _, expr := range orderedExpectations(expectations) {
+		spec := expectations[expr]
+		if spec.Capture != "" {
+			p.storage.Set(spec.Capture, placeholder(spec.Type, spec.Capture))
+		}
+		if spec.Value == nil {
+			fmt.Fprintf(p.w, "%s%s %s: %s\n", indent, name, expr, spec.Type)
+			continue
+		}
+
+		value, err := getValueFromSpec(spec, p.storage, p.strictRefs)
+		if err != nil {
+			fmt.Fprintf(p.w, "%s%s %s: %s error: %s\n", indent, name, expr, spec.Type, err)
+			continue
+		}
+		data, _ := planJSON(value)
+		fmt.Fprintf(p.w, "%s%s %s: %s %s\n", indent, name, expr, spec.Type, data)
+	}
+}
+
+// store sets placeholders for the keys an operation would store, so the
+// refs of the following operations resolve
+func (p *planner) store(store models.StoreSpec) {
+	for key, entry := range store {
+		p.storage.Set(key, placeholder(entry.Type, key))
+	}
+}
+
+// nested writes the operations run by container operations, both branches
+// of conditions and a single loop iteration
+func (p *planner) nested(op *models.Operation, depth int) {
+	indent := strings.Repeat("  ", depth-1)
+	switch op.Type {
+	case "parallel":
+		p.operations(op.ParallelOperations, depth)
+	case "loop":
+		if op.Loop != nil {
+			fmt.Fprintf(p.w, "%srepeated %d times:\n", indent, op.Loop.Count)
+			p.operations(op.Loop.Operations, depth)
+		}
+	case "condition":
+		if op.Condition != nil {
+			fmt.Fprintf(p.w, "%sthen:\n", indent)
+			p.operations(op.Condition.Then, depth)
+			fmt.Fprintf(p.w, "%selse:\n", indent)
+			p.operations(op.Condition.Else, depth)
+		}
+	case "stateMachine":
+		if op.StateMachine != nil {
+			states := make([]string, 0, len(op.StateMachine.States))
+			for name := range op.StateMachine.States {
+				states = append(states, name)
+			}
+			sort.Strings(states)
+			for _, name := range states {
+				if trigger := op.StateMachine.States[name].Trigger; trigger != nil {
+					fmt.Fprintf(p.w, "%sstate %s:\n", indent, name)
+					p.operation(0, trigger, depth)
+				}
+			}
+		}
+	case "call":
+		p.call(op, depth)
+	}
+}
+
+func (p *planner) call(op *models.Operation, depth int) {
+	macro, ok := p.spec.Macros[op.URI]
+	if !ok || p.callDepth >= maxCallDepth {
+		return
+	}
+
+	params, err := buildArgs(op.Args, p.storage)
+	if err != nil {
+		fmt.Fprintf(p.w, "%serror: %s\n", strings.Repeat("  ", depth-1), err)
+		return
+	}
+
+	restore := p.storage.withParams(params)
+	p.callDepth++
+	defer func() {
+		p.callDepth--
+		restore()
+	}()
+
+	p.operations(macro, depth)
+}
+
+// placeholder is the dry run value of a stored key of type typ
+func placeholder(typ, key string) interface{} {
+	switch typ {
+	case "int":
+		return 0
+	case "bool":
+		return false
+	case "object":
+		return map[string]interface{}{}
+	case "array":
+		return []interface{}{}
+	}
+
+	return "<" + key + ">"
+}
+
+// planJSON encodes v without escaping the brackets of placeholders
+func planJSON(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
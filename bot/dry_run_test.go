@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestDryRun(t *testing.T) {
+	spec := &models.Spec{
+		Name: "login",
+		Vars: map[string]interface{}{"region": "eu"},
+		SequentialOperations: []*models.Operation{
+			{
+				Type: "request", URI: "player.login",
+				Args: map[string]interface{}{
+					"region": map[string]interface{}{"type": "string", "value": "${vars.region}"},
+					"bot":    map[string]interface{}{"type": "int", "value": "${bot.id}"},
+				},
+				Expect: models.ExpectSpec{"$response.code": {Type: "int", Value: 200}},
+				Store:  models.StoreSpec{"token": {Type: "string", Value: "$response.token"}},
+			},
+			{
+				Type: "loop", Loop: &models.LoopSpec{Count: 2, Operations: []*models.Operation{
+					{Type: "notify", URI: "room.ping", Args: map[string]interface{}{
+						"token": map[string]interface{}{"type": "string", "value": "$store.token"},
+					}},
+				}},
+			},
+			{
+				Type: "request", URI: "player.me",
+				Args: map[string]interface{}{
+					"missing": map[string]interface{}{"type": "string", "value": "$store.missing"},
+				},
+			},
+		},
+	}
+
+	var out bytes.Buffer
+	_, err := DryRun(viper.New(), spec, 3, nil, &out)
+	assert.NoError(t, err)
+	assert.Equal(t, `spec login (bot 3)
+  sequentialOperations:
+    0: request player.login
+       args: {"bot":3,"region":"eu"}
+       expect $response.code: int 200
+    1: loop
+      repeated 2 times:
+        0: notify room.ping
+           args: {"token":"<token>"}
+    2: request player.me
+       error: Variable missing not found
+`, out.String())
+}
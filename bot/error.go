@@ -3,8 +3,6 @@ package bot
 import (
 	"encoding/json"
 	"fmt"
-
-	"github.com/topfreegames/pitaya-bot/models"
 )
 
 // ExpectError ...
@@ -12,18 +10,29 @@ type ExpectError struct {
 	Err     error
 	RawData []byte
 	Expect  string
+	Diffs   ValueDiffs
 }
 
 func (b *ExpectError) Error() string {
+	if len(b.Diffs) > 0 {
+		return fmt.Sprintf("\nErr: %s \nRawData: %s \nExpected: %s\nDiff:\n%s\n", b.Err.Error(), string(b.RawData), b.Expect, b.Diffs)
+	}
+
 	return fmt.Sprintf("\nErr: %s \nRawData: %s \nExpected: %s\n", b.Err.Error(), string(b.RawData), b.Expect)
 }
 
-// NewExpectError ...
-func NewExpectError(err error, rawData []byte, expect models.ExpectSpec) *ExpectError {
+// NewExpectError returns the error of a failed expect or expectError spec
+func NewExpectError(err error, rawData []byte, expect interface{}) *ExpectError {
 	bexpect, _ := json.Marshal(expect)
-	return &ExpectError{
+	expectErr := &ExpectError{
 		Err:     err,
 		RawData: rawData,
 		Expect:  string(bexpect),
 	}
+
+	if diffErr, ok := err.(*DiffError); ok {
+		expectErr.Diffs = diffErr.Diffs
+	}
+
+	return expectErr
 }
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// errorResponse returns the code and message of pitaya error responses,
+// the ones with a code string and a msg, whatever the code prefix, as
+// handlers may return their own codes
+func errorResponse(resp Response) (string, string, bool) {
+	code, ok := resp["code"].(string)
+	if !ok || code == "" {
+		return "", "", false
+	}
+	msg, ok := resp["msg"]
+	if !ok {
+		return "", "", false
+	}
+
+	text, _ := msg.(string)
+	return code, text, true
+}
+
+// expectedError returns whether resp is the error response spec expects
+func expectedError(spec *models.ExpectErrorSpec, resp Response) bool {
+	return spec != nil && checkErrorResponse(spec, resp) == nil
+}
+
+// checkErrorResponse returns an error unless resp is a pitaya error
+// response with the code, message and metadata of spec
+func checkErrorResponse(spec *models.ExpectErrorSpec, resp Response) error {
+	expected := spec.Code
+	if expected == "" {
+		expected = "any"
+	}
+
+	code, msg, ok := errorResponse(resp)
+	if !ok {
+		return fmt.Errorf("Expected error response %s, got a successful response", expected)
+	}
+	if spec.Code != "" && !matchesErrorCode(spec.Code, code) {
+		return fmt.Errorf("Expected error response %s, got %s: %s", expected, code, msg)
+	}
+	if spec.Message != "" && msg != spec.Message {
+		return fmt.Errorf("Expected error message %q, got %q", spec.Message, msg)
+	}
+
+	metadata, _ := resp["metadata"].(map[string]interface{})
+	keys := make([]string, 0, len(spec.Metadata))
+	for key := range spec.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := metadata[key]
+		if !ok {
+			return fmt.Errorf("Expected error metadata %s %q, it is missing", key, spec.Metadata[key])
+		}
+		if got := fmt.Sprintf("%v", value); got != spec.Metadata[key] {
+			return fmt.Errorf("Expected error metadata %s %q, got %q", key, spec.Metadata[key], got)
+		}
+	}
+
+	return nil
+}
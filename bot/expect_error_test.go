@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestExpectError(t *testing.T) {
+	notFound := `{"code": "PIT-404", "msg": "room not found", "metadata": {"roomId": "r1"}}`
+
+	tables := []struct {
+		name     string
+		response string
+		spec     *models.ExpectErrorSpec
+		err      string
+	}{
+		{"code", notFound, &models.ExpectErrorSpec{Code: "PIT-404"}, ""},
+		{"code prefix", notFound, &models.ExpectErrorSpec{Code: "PIT-4*"}, ""},
+		{"any code", `{"code": "GAME-12", "msg": "not enough gold"}`, &models.ExpectErrorSpec{}, ""},
+		{"message and metadata", notFound, &models.ExpectErrorSpec{Code: "PIT-404", Message: "room not found", Metadata: map[string]string{"roomId": "r1"}}, ""},
+		{"successful response", `{"code": 200, "roomId": "r1"}`, &models.ExpectErrorSpec{Code: "PIT-404"},
+			"Expected error response PIT-404, got a successful response"},
+		{"wrong code", `{"code": "PIT-500", "msg": "internal error"}`, &models.ExpectErrorSpec{Code: "PIT-4*"},
+			"Expected error response PIT-4*, got PIT-500: internal error"},
+		{"wrong message", notFound, &models.ExpectErrorSpec{Message: "room is full"},
+			`Expected error message "room is full", got "room not found"`},
+		{"wrong metadata", notFound, &models.ExpectErrorSpec{Metadata: map[string]string{"roomId": "r2"}},
+			`Expected error metadata roomId "r2", got "r1"`},
+		{"missing metadata", notFound, &models.ExpectErrorSpec{Metadata: map[string]string{"reason": "closed"}},
+			`Expected error metadata reason "closed", it is missing`},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{responses: []string{table.response}})
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:        "request",
+				URI:         "room.join",
+				ExpectError: table.spec,
+			})
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			expectErr, ok := err.(*ExpectError)
+			if assert.True(t, ok, "expected an ExpectError, got %v", err) {
+				assert.EqualError(t, expectErr.Err, table.err)
+				expected, _ := json.Marshal(table.spec)
+				assert.Equal(t, string(expected), expectErr.Expect)
+			}
+		})
+	}
+}
+
+func TestExpectErrorValidatesAndStoresTheErrorResponse(t *testing.T) {
+	b := newTestBot(&recordingTransport{responses: []string{`{"code": "PIT-400", "msg": "invalid name: too long"}`}})
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:        "request",
+		URI:         "player.rename",
+		ExpectError: &models.ExpectErrorSpec{Code: "PIT-400"},
+		Expect:      models.ExpectSpec{"msg": {Type: "string", Value: map[string]interface{}{"$regex": "^invalid name"}}},
+		Store:       models.StoreSpec{"reason": {Type: "string", Value: "msg"}},
+	})
+	assert.NoError(t, err)
+
+	reason, _ := b.storage.Get("reason")
+	assert.Equal(t, "invalid name: too long", reason)
+}
+
+func TestExpectErrorIsNotRetried(t *testing.T) {
+	transport := &recordingTransport{responses: []string{`{"code": "PIT-503", "msg": "maintenance"}`}}
+	b := newTestBot(transport)
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:        "request",
+		URI:         "room.join",
+		Retry:       &models.RetrySpec{MaxAttempts: 3, RetryableErrors: []string{"PIT-5*"}},
+		ExpectError: &models.ExpectErrorSpec{Code: "PIT-503"},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, transport.sent, 1)
+}
@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Faker generators can be used as arg values with the ${faker.<name>} syntax:
+//
+//	faker.firstName   first name
+//	faker.lastName    last name
+//	faker.name        first and last name
+//	faker.email       e-mail built from a name and a locale domain
+//	faker.phone       phone number in the locale format
+//	faker.city        city name
+//	faker.country     country name
+//	faker.street      street address
+//	faker.company     company name
+//	faker.pastDate    RFC3339 date within the last year
+//	faker.recentDate  RFC3339 date within the last day
+//	faker.futureDate  RFC3339 date within the next year
+//	faker.birthday    YYYY-MM-DD date for someone between 18 and 80 years old
+//	faker.timestamp   unix timestamp (seconds) within the last year
+//
+// The locale is set through the faker.locale config and defaults to en_US.
+type fakerLocale struct {
+	firstNames   []string
+	lastNames    []string
+	cities       []string
+	countries    []string
+	streets      []string
+	companies    []string
+	emailDomains []string
+	phoneFormat  string
+}
+
+var fakerLocales = map[string]*fakerLocale{
+	"en_US": {
+		firstNames:   []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William", "Elizabeth"},
+		lastNames:    []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Miller", "Davis", "Wilson", "Anderson", "Taylor"},
+		cities:       []string{"New York", "Los Angeles", "Chicago", "Houston", "Phoenix", "Philadelphia", "San Antonio", "San Diego", "Dallas", "Seattle"},
+		countries:    []string{"United States", "Canada", "Mexico", "United Kingdom", "Australia"},
+		streets:      []string{"Main St", "Oak St", "Pine St", "Maple Ave", "Cedar Ln", "Elm St", "Washington Ave", "Lake View Dr"},
+		companies:    []string{"Acme Corp", "Globex", "Initech", "Umbrella Inc", "Stark Industries", "Wayne Enterprises"},
+		emailDomains: []string{"example.com", "mail.com", "test.org"},
+		phoneFormat:  "+1 (###) ###-####",
+	},
+	"pt_BR": {
+		firstNames:   []string{"João", "Maria", "José", "Ana", "Pedro", "Juliana", "Lucas", "Fernanda", "Gabriel", "Camila"},
+		lastNames:    []string{"Silva", "Santos", "Oliveira", "Souza", "Pereira", "Costa", "Rodrigues", "Almeida", "Lima", "Carvalho"},
+		cities:       []string{"São Paulo", "Rio de Janeiro", "Belo Horizonte", "Salvador", "Fortaleza", "Curitiba", "Recife", "Porto Alegre"},
+		countries:    []string{"Brasil", "Portugal", "Argentina", "Uruguai", "Paraguai"},
+		streets:      []string{"Rua das Flores", "Avenida Paulista", "Rua Augusta", "Avenida Atlântica", "Rua XV de Novembro"},
+		companies:    []string{"Comércio Ltda", "Tecnologia S.A.", "Serviços Gerais", "Indústrias Reunidas"},
+		emailDomains: []string{"exemplo.com.br", "correio.com.br", "teste.org"},
+		phoneFormat:  "+55 (##) 9####-####",
+	},
+	"es_ES": {
+		firstNames:   []string{"Antonio", "María", "Manuel", "Carmen", "Francisco", "Lucía", "David", "Isabel", "Javier", "Laura"},
+		lastNames:    []string{"García", "Fernández", "González", "Rodríguez", "López", "Martínez", "Sánchez", "Pérez", "Gómez", "Martín"},
+		cities:       []string{"Madrid", "Barcelona", "Valencia", "Sevilla", "Zaragoza", "Málaga", "Bilbao", "Murcia"},
+		countries:    []string{"España", "México", "Argentina", "Colombia", "Chile"},
+		streets:      []string{"Calle Mayor", "Gran Vía", "Paseo de la Castellana", "Calle de Alcalá", "Avenida Diagonal"},
+		companies:    []string{"Soluciones S.L.", "Grupo Ibérico", "Comercial del Sur", "Industrias Norte"},
+		emailDomains: []string{"ejemplo.es", "correo.es", "prueba.org"},
+		phoneFormat:  "+34 6## ### ###",
+	},
+	"fr_FR": {
+		firstNames:   []string{"Jean", "Marie", "Pierre", "Nathalie", "Michel", "Isabelle", "Philippe", "Sophie", "Nicolas", "Camille"},
+		lastNames:    []string{"Martin", "Bernard", "Dubois", "Thomas", "Robert", "Richard", "Petit", "Durand", "Leroy", "Moreau"},
+		cities:       []string{"Paris", "Marseille", "Lyon", "Toulouse", "Nice", "Nantes", "Strasbourg", "Bordeaux"},
+		countries:    []string{"France", "Belgique", "Suisse", "Canada", "Luxembourg"},
+		streets:      []string{"Rue de la Paix", "Avenue des Champs-Élysées", "Boulevard Saint-Germain", "Rue de Rivoli"},
+		companies:    []string{"Société Générale de Services", "Groupe Lumière", "Entreprise Dupont", "Compagnie du Nord"},
+		emailDomains: []string{"exemple.fr", "courriel.fr", "essai.org"},
+		phoneFormat:  "+33 6 ## ## ## ##",
+	},
+	"de_DE": {
+		firstNames:   []string{"Hans", "Anna", "Peter", "Ursula", "Klaus", "Monika", "Thomas", "Sabine", "Stefan", "Julia"},
+		lastNames:    []string{"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann"},
+		cities:       []string{"Berlin", "Hamburg", "München", "Köln", "Frankfurt", "Stuttgart", "Düsseldorf", "Leipzig"},
+		countries:    []string{"Deutschland", "Österreich", "Schweiz", "Liechtenstein"},
+		streets:      []string{"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße"},
+		companies:    []string{"Technik GmbH", "Handels AG", "Bau und Partner KG", "Nordwerk GmbH"},
+		emailDomains: []string{"beispiel.de", "post.de", "test.org"},
+		phoneFormat:  "+49 15# #######",
+	},
+}
+
+var currentFakerLocale = fakerLocales["en_US"]
+
+// SetFakerLocale selects the locale used by the faker generators
+func SetFakerLocale(name string) error {
+	if name == "" {
+		name = "en_US"
+	}
+
+	locale, ok := fakerLocales[name]
+	if !ok {
+		return fmt.Errorf("Unknown faker locale: %s", name)
+	}
+
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+	currentFakerLocale = locale
+	return nil
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.Intn(len(values))]
+}
+
+func fillDigits(r *rand.Rand, format string) string {
+	var b strings.Builder
+	for _, c := range format {
+		if c == '#' {
+			b.WriteByte(byte('0' + r.Intn(10)))
+			continue
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+func randomDuration(r *rand.Rand, max time.Duration) time.Duration {
+	return time.Duration(r.Int63n(int64(max)))
+}
+
+func init() {
+	year := 365 * 24 * time.Hour
+	fakers := map[string]generator{
+		"firstName": func(r *rand.Rand) interface{} { return pick(r, currentFakerLocale.firstNames) },
+		"lastName":  func(r *rand.Rand) interface{} { return pick(r, currentFakerLocale.lastNames) },
+		"name": func(r *rand.Rand) interface{} {
+			return pick(r, currentFakerLocale.firstNames) + " " + pick(r, currentFakerLocale.lastNames)
+		},
+		"email": func(r *rand.Rand) interface{} {
+			user := strings.ToLower(pick(r, currentFakerLocale.firstNames) + "." + pick(r, currentFakerLocale.lastNames))
+			return fmt.Sprintf("%s%d@%s", user, r.Intn(1000), pick(r, currentFakerLocale.emailDomains))
+		},
+		"phone":   func(r *rand.Rand) interface{} { return fillDigits(r, currentFakerLocale.phoneFormat) },
+		"city":    func(r *rand.Rand) interface{} { return pick(r, currentFakerLocale.cities) },
+		"country": func(r *rand.Rand) interface{} { return pick(r, currentFakerLocale.countries) },
+		"street": func(r *rand.Rand) interface{} {
+			return fmt.Sprintf("%d %s", 1+r.Intn(9999), pick(r, currentFakerLocale.streets))
+		},
+		"company": func(r *rand.Rand) interface{} { return pick(r, currentFakerLocale.companies) },
+		"pastDate": func(r *rand.Rand) interface{} {
+			return time.Now().Add(-randomDuration(r, year)).UTC().Format(time.RFC3339)
+		},
+		"recentDate": func(r *rand.Rand) interface{} {
+			return time.Now().Add(-randomDuration(r, 24*time.Hour)).UTC().Format(time.RFC3339)
+		},
+		"futureDate": func(r *rand.Rand) interface{} {
+			return time.Now().Add(randomDuration(r, year)).UTC().Format(time.RFC3339)
+		},
+		"birthday": func(r *rand.Rand) interface{} {
+			age := 18*year + randomDuration(r, 62*year)
+			return time.Now().Add(-age).UTC().Format("2006-01-02")
+		},
+		"timestamp": func(r *rand.Rand) interface{} {
+			return int(time.Now().Add(-randomDuration(r, year)).Unix())
+		},
+	}
+
+	for name, g := range fakers {
+		registerGenerator("faker."+name, g)
+	}
+}
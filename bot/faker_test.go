@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakerIsReproducibleWithSeed(t *testing.T) {
+	SeedGenerators(42)
+	first, err := generate("faker.name")
+	assert.NoError(t, err)
+
+	SeedGenerators(42)
+	second, err := generate("faker.name")
+	assert.NoError(t, err)
+
+	assert.Equal(t, first, second)
+}
+
+func TestFakerLocale(t *testing.T) {
+	defer SetFakerLocale("en_US")
+
+	assert.NoError(t, SetFakerLocale("pt_BR"))
+	city, err := generate("faker.city")
+	assert.NoError(t, err)
+	assert.Contains(t, fakerLocales["pt_BR"].cities, city)
+
+	assert.Error(t, SetFakerLocale("xx_XX"))
+}
+
+func TestFakerDates(t *testing.T) {
+	past, err := generate("faker.pastDate")
+	assert.NoError(t, err)
+	parsed, err := time.Parse(time.RFC3339, past.(string))
+	assert.NoError(t, err)
+	assert.True(t, parsed.Before(time.Now()))
+
+	future, err := generate("faker.futureDate")
+	assert.NoError(t, err)
+	parsed, err = time.Parse(time.RFC3339, future.(string))
+	assert.NoError(t, err)
+	assert.True(t, parsed.After(time.Now()))
+}
+
+func TestBuildArgsWithFaker(t *testing.T) {
+	rawArgs := map[string]interface{}{
+		"name": map[string]interface{}{"type": "string", "value": "${faker.name}"},
+	}
+
+	args, err := buildArgs(rawArgs, &storage{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, args["name"])
+
+	rawArgs["name"] = map[string]interface{}{"type": "string", "value": "${faker.unknown}"}
+	_, err = buildArgs(rawArgs, &storage{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+package bot
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// Up to proxyChunks chunks of proxyChunkSize bytes read from one side of a
+// proxied connection are in flight, blackholed or delayed by the network
+// conditions, before the proxy stops reading it, about a socket buffer worth
+// of data. Past that the sender sees the backpressure a slow network causes
+const (
+	proxyChunks    = 16
+	proxyChunkSize = 4 * 1024
+)
+
+// chunk is data read from one side of a proxied connection and when it was
+// read
+type chunk struct {
+	data []byte
+	at   time.Time
+}
+
+// faultProxy sits between the pitaya client and the server so faults can be
+// injected into their connection, which the pitaya client does not expose,
+// network conditions emulated, raw packets sent and the heartbeat interval
+// of the handshake overridden. It listens on a local port, accepts the
+// client connection and forwards it to the server
+type faultProxy struct {
+	listener  net.Listener
+	upstream  string
+	network   *models.NetworkSpec
+	handshake *heartbeatRewriter
+
+	mutex     sync.Mutex
+	client    net.Conn
+	server    net.Conn
+	holdUntil time.Time
+	released  chan struct{}
+	broken    bool
+	waiters   map[uint]chan []byte
+
+	// writeMutex keeps injected packets from interleaving with the ones
+	// of the client
+	writeMutex sync.Mutex
+	packets    packetReader
+}
+
+// newFaultProxy starts a proxy to upstream on a local port, shaping the
+// traffic both ways as network says when it is not nil and making the
+// client heartbeat every heartbeat seconds when it is positive
+func newFaultProxy(upstream string, network *models.NetworkSpec, heartbeat int) (*faultProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to start fault injection proxy: %s", err)
+	}
+
+	p := &faultProxy{
+		listener: listener,
+		upstream: upstream,
+		network:  network,
+	}
+	if heartbeat > 0 {
+		p.handshake = &heartbeatRewriter{seconds: heartbeat}
+	}
+	go p.accept()
+	return p, nil
+}
+
+// addr is the address the client connects to instead of the server
+func (p *faultProxy) addr() string {
+	return p.listener.Addr().String()
+}
+
+// accept proxies the first connection, the one of the pitaya client
+func (p *faultProxy) accept() {
+	client, err := p.listener.Accept()
+	p.listener.Close()
+	if err != nil {
+		return
+	}
+
+	server, err := net.Dial("tcp", p.upstream)
+	if err != nil {
+		client.Close()
+		p.setBroken()
+		return
+	}
+
+	p.mutex.Lock()
+	p.client, p.server = client, server
+	p.mutex.Unlock()
+
+	seed := time.Now().UnixNano()
+	go p.pipe(client, server, newShaper(p.network, seed), nil)
+	go p.pipe(server, client, newShaper(p.network, seed+1), p.fromServer)
+}
+
+// pipe forwards src to dst, delaying each chunk as shaper says and holding
+// the data read while the connection is blackholed. Every chunk read goes
+// through filter, when set, which returns the data to forward. When src
+// ends dst is closed too
+func (p *faultProxy) pipe(src, dst net.Conn, shaper *shaper, filter func([]byte) []byte) {
+	chunks := make(chan chunk, proxyChunks)
+	go func() {
+		defer close(chunks)
+		buf := make([]byte, proxyChunkSize)
+		for {
+			n, err := src.Read(buf)
+			if n > 0 {
+				data := append([]byte(nil), buf[:n]...)
+				if filter != nil {
+					data = filter(data)
+				}
+				if len(data) > 0 {
+					chunks <- chunk{data: data, at: time.Now()}
+				}
+			}
+			if err != nil {
+				p.setBroken()
+				return
+			}
+		}
+	}()
+
+	for c := range chunks {
+		if delay := time.Until(shaper.deliverAt(c.at, len(c.data))); delay > 0 {
+			time.Sleep(delay)
+		}
+		p.wait()
+		p.writeMutex.Lock()
+		_, err := dst.Write(c.data)
+		p.writeMutex.Unlock()
+		if err != nil {
+			p.setBroken()
+			break
+		}
+	}
+	dst.Close()
+}
+
+// wait blocks while the connection is blackholed
+func (p *faultProxy) wait() {
+	for {
+		p.mutex.Lock()
+		released := p.released
+		p.mutex.Unlock()
+		if released == nil {
+			return
+		}
+		<-released
+	}
+}
+
+// inject sends data to the server between the packets of the client
+func (p *faultProxy) inject(data []byte) error {
+	p.mutex.Lock()
+	server := p.server
+	p.mutex.Unlock()
+	if server == nil {
+		return fmt.Errorf("Fault injection proxy is not connected")
+	}
+
+	p.writeMutex.Lock()
+	defer p.writeMutex.Unlock()
+	_, err := server.Write(data)
+	return err
+}
+
+// awaitResponse returns a channel receiving the data of the server response
+// to the request of id. done stops waiting for it
+func (p *faultProxy) awaitResponse(id uint) (<-chan []byte, func()) {
+	ch := make(chan []byte, 1)
+	p.mutex.Lock()
+	if p.waiters == nil {
+		p.waiters = map[uint]chan []byte{}
+	}
+	p.waiters[id] = ch
+	p.mutex.Unlock()
+
+	return ch, func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		delete(p.waiters, id)
+	}
+}
+
+// fromServer rewrites the server handshake when asked to and sniffs what
+// the server sends
+func (p *faultProxy) fromServer(data []byte) []byte {
+	if p.handshake != nil {
+		data = p.handshake.rewrite(data)
+	}
+	p.sniff(data)
+	return data
+}
+
+// sniff follows the packets the server sends, handing the responses waited
+// for to their waiter
+func (p *faultProxy) sniff(data []byte) {
+	p.packets.feed(data, func(typ byte, body []byte) {
+		if typ != packetData {
+			return
+		}
+		id, data, ok, err := decodeResponseMessage(body)
+		if err != nil || !ok {
+			return
+		}
+
+		p.mutex.Lock()
+		ch, ok := p.waiters[id]
+		p.mutex.Unlock()
+		if ok {
+			select {
+			case ch <- append([]byte(nil), data...):
+			default:
+			}
+		}
+	})
+}
+
+func (p *faultProxy) setBroken() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.broken = true
+}
+
+// dropped returns whether either side closed the connection
+func (p *faultProxy) dropped() bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.broken
+}
+
+// blackhole holds every byte sent either way for d, as a network dropping
+// all packets would until TCP retransmits them once it is back
+func (p *faultProxy) blackhole(d time.Duration) {
+	p.mutex.Lock()
+	until := time.Now().Add(d)
+	if until.Before(p.holdUntil) {
+		p.mutex.Unlock()
+		return
+	}
+	p.holdUntil = until
+	if p.released == nil {
+		p.released = make(chan struct{})
+	}
+	released := p.released
+	p.mutex.Unlock()
+
+	go func() {
+		for {
+			p.mutex.Lock()
+			left := time.Until(p.holdUntil)
+			if left <= 0 {
+				p.released = nil
+				p.mutex.Unlock()
+				close(released)
+				return
+			}
+			p.mutex.Unlock()
+			time.Sleep(left)
+		}
+	}()
+}
+
+// reset aborts both sides of the connection with a TCP reset
+func (p *faultProxy) reset() {
+	p.mutex.Lock()
+	client, server := p.client, p.server
+	p.mutex.Unlock()
+
+	for _, conn := range []net.Conn{server, client} {
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			tcp.SetLinger(0)
+			tcp.Close()
+		}
+	}
+	p.setBroken()
+}
+
+// close closes both sides of the connection cleanly
+func (p *faultProxy) close() {
+	p.listener.Close()
+
+	p.mutex.Lock()
+	client, server := p.client, p.server
+	p.mutex.Unlock()
+
+	for _, conn := range []net.Conn{server, client} {
+		if conn != nil {
+			conn.Close()
+		}
+	}
+	p.setBroken()
+}
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// echoServer echoes what its connections send until they close, reporting
+// how each connection ended
+func echoServer(t *testing.T) (string, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ended := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			return
+		}
+		_, err = io.Copy(conn, conn)
+		conn.Close()
+		ended <- err
+	}()
+
+	return listener.Addr().String(), ended
+}
+
+func dialProxy(t *testing.T, p *faultProxy) net.Conn {
+	conn, err := net.Dial("tcp", p.addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func echo(conn net.Conn, msg string) (string, error) {
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, len(msg))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := io.ReadFull(conn, buf)
+	return string(buf), err
+}
+
+func TestFaultProxyForwards(t *testing.T) {
+	upstream, ended := echoServer(t)
+	p, err := newFaultProxy(upstream, nil, 0)
+	assert.NoError(t, err)
+	conn := dialProxy(t, p)
+
+	reply, err := echo(conn, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", reply)
+	assert.False(t, p.dropped())
+
+	p.close()
+	assert.NoError(t, <-ended)
+	assert.True(t, p.dropped())
+}
+
+func TestFaultProxyBlackhole(t *testing.T) {
+	upstream, _ := echoServer(t)
+	p, err := newFaultProxy(upstream, nil, 0)
+	assert.NoError(t, err)
+	defer p.close()
+	conn := dialProxy(t, p)
+	echo(conn, "warm up")
+
+	p.blackhole(100 * time.Millisecond)
+	start := time.Now()
+	reply, err := echo(conn, "held")
+	assert.NoError(t, err)
+	assert.Equal(t, "held", reply)
+	assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	assert.False(t, p.dropped())
+}
+
+func TestFaultProxyReset(t *testing.T) {
+	upstream, ended := echoServer(t)
+	p, err := newFaultProxy(upstream, nil, 0)
+	assert.NoError(t, err)
+	conn := dialProxy(t, p)
+	echo(conn, "warm up")
+
+	p.reset()
+	err = <-ended
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "reset")
+	assert.True(t, p.dropped())
+
+	_, err = echo(conn, "after reset")
+	assert.Error(t, err)
+}
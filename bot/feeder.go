@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+var (
+	feederRowsMutex sync.Mutex
+	feederRows      = map[string][]map[string]interface{}{}
+)
+
+// feederPath resolves the feeder file relative to the spec file
+func feederPath(spec *models.Spec) string {
+	if filepath.IsAbs(spec.Feeder.File) || spec.Name == "" {
+		return spec.Feeder.File
+	}
+
+	return filepath.Join(filepath.Dir(spec.Name), spec.Feeder.File)
+}
+
+// loadFeederRows reads the rows of a csv file, whose first line names the
+// columns, or of a json array of objects. Rows are read once per file
+func loadFeederRows(path string) ([]map[string]interface{}, error) {
+	feederRowsMutex.Lock()
+	defer feederRowsMutex.Unlock()
+
+	if rows, ok := feederRows[path]; ok {
+		return rows, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read feeder file: %s", err)
+	}
+
+	var rows []map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, fmt.Errorf("Malformed feeder file %s: %s", path, err)
+		}
+	} else {
+		if rows, err = parseCSVRows(string(raw)); err != nil {
+			return nil, fmt.Errorf("Malformed feeder file %s: %s", path, err)
+		}
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Feeder file %s has no rows", path)
+	}
+
+	feederRows[path] = rows
+	return rows, nil
+}
+
+func parseCSVRows(raw string) ([]map[string]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(raw)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	rows := make([]map[string]interface{}, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			row[strings.TrimSpace(column)] = record[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// feederRow returns the row assigned to the bot: cyclic (the default)
+// wraps around the rows, unique fails once every row was taken and random
+// picks one using the bot seed
+func feederRow(spec *models.Spec, id int, seed int64) (map[string]interface{}, error) {
+	rows, err := loadFeederRows(feederPath(spec))
+	if err != nil {
+		return nil, err
+	}
+
+	switch spec.Feeder.Strategy {
+	case "", "cyclic":
+		return rows[id%len(rows)], nil
+	case "unique":
+		if id >= len(rows) {
+			return nil, fmt.Errorf("Feeder %s has no row left for bot %d, it has %d rows", spec.Feeder.File, id, len(rows))
+		}
+		return rows[id], nil
+	case "random":
+		return rows[rand.New(rand.NewSource(seed)).Intn(len(rows))], nil
+	}
+
+	return nil, fmt.Errorf("Unknown feeder strategy: %s", spec.Feeder.Strategy)
+}
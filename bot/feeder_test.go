@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestFeederRow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	csvFile := filepath.Join(dir, "users.csv")
+	assert.NoError(t, ioutil.WriteFile(csvFile, []byte("user,password\nalice,a1\nbob,b2\n"), 0644))
+	jsonFile := filepath.Join(dir, "devices.json")
+	assert.NoError(t, ioutil.WriteFile(jsonFile, []byte(`[{"deviceId": "d1", "tier": 1}, {"deviceId": "d2", "tier": 2}]`), 0644))
+
+	tables := []struct {
+		name     string
+		feeder   *models.FeederSpec
+		id       int
+		expected map[string]interface{}
+		err      bool
+	}{
+		{"csv cyclic", &models.FeederSpec{File: "users.csv"}, 3, map[string]interface{}{"user": "bob", "password": "b2"}, false},
+		{"csv unique", &models.FeederSpec{File: "users.csv", Strategy: "unique"}, 0, map[string]interface{}{"user": "alice", "password": "a1"}, false},
+		{"unique exhausted", &models.FeederSpec{File: "users.csv", Strategy: "unique"}, 2, nil, true},
+		{"json", &models.FeederSpec{File: jsonFile, Strategy: "cyclic"}, 0, map[string]interface{}{"deviceId": "d1", "tier": float64(1)}, false},
+		{"missing file", &models.FeederSpec{File: "other.csv"}, 0, nil, true},
+		{"unknown strategy", &models.FeederSpec{File: "users.csv", Strategy: "shuffled"}, 0, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			spec := &models.Spec{Name: filepath.Join(dir, "spec.json"), Feeder: table.feeder}
+			row, err := feederRow(spec, table.id, 1)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.expected, row)
+		})
+	}
+}
+
+func TestFeederRandomIsSeeded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeder")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "ids.csv")
+	assert.NoError(t, ioutil.WriteFile(file, []byte("id\n1\n2\n3\n4\n5\n"), 0644))
+	spec := &models.Spec{Feeder: &models.FeederSpec{File: file, Strategy: "random"}}
+
+	first, err := feederRow(spec, 0, 42)
+	assert.NoError(t, err)
+	second, err := feederRow(spec, 7, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+var (
+	flagBitsMutex sync.RWMutex
+	flagBits      = map[string]uint{}
+)
+
+// SetFlagBits sets the bit position of each flag name used by flags
+// expectations. Names are case insensitive, as config keys are
+func SetFlagBits(bits map[string]interface{}) error {
+	parsed := make(map[string]uint, len(bits))
+	for name, raw := range bits {
+		bit, err := toBit(raw)
+		if err != nil || bit < 0 || bit > 63 {
+			return fmt.Errorf("Invalid bit %v for flag %s, expected 0 to 63", raw, name)
+		}
+		parsed[strings.ToLower(name)] = uint(bit)
+	}
+
+	flagBitsMutex.Lock()
+	defer flagBitsMutex.Unlock()
+	flagBits = parsed
+	return nil
+}
+
+func toBit(raw interface{}) (int, error) {
+	switch val := raw.(type) {
+	case int:
+		return val, nil
+	case int64:
+		return int(val), nil
+	case float64:
+		return int(val), nil
+	case string:
+		return strconv.Atoi(val)
+	}
+
+	return 0, fmt.Errorf("%v is not a number", raw)
+}
+
+func flagBit(name string) (uint, error) {
+	flagBitsMutex.RLock()
+	defer flagBitsMutex.RUnlock()
+
+	bit, ok := flagBits[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("Unknown flag %s, it must be mapped in expect.flags", name)
+	}
+
+	return bit, nil
+}
+
+// setFlags returns the names of the known flags set in value
+func setFlags(value int) []string {
+	flagBitsMutex.RLock()
+	defer flagBitsMutex.RUnlock()
+
+	names := make([]string, 0)
+	for name, bit := range flagBits {
+		if value&(1<<bit) != 0 {
+			names = append(names, strings.ToUpper(name))
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// validateFlags checks the Set flags are on and the Unset ones off in the
+// integer field
+func validateFlags(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata) error {
+	value, err := extractResponseValue(resp, meta, propertyExpr, "int")
+	if err != nil {
+		return err
+	}
+	bits := value.(int)
+
+	missing := make([]string, 0)
+	for _, name := range spec.Set {
+		bit, err := flagBit(name)
+		if err != nil {
+			return err
+		}
+		if bits&(1<<bit) == 0 {
+			missing = append(missing, name)
+		}
+	}
+
+	unexpected := make([]string, 0)
+	for _, name := range spec.Unset {
+		bit, err := flagBit(name)
+		if err != nil {
+			return err
+		}
+		if bits&(1<<bit) != 0 {
+			unexpected = append(unexpected, name)
+		}
+	}
+
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s flags mismatch (value %d, set %v): expected set %v, expected unset %v",
+		propertyExpr, bits, setFlags(bits), missing, unexpected)
+}
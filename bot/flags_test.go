@@ -0,0 +1,42 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestValidateFlags(t *testing.T) {
+	assert.NoError(t, SetFlagBits(map[string]interface{}{"can_trade": 0, "is_vip": 3, "banned": "5"}))
+	defer SetFlagBits(map[string]interface{}{})
+
+	tables := []struct {
+		name  string
+		value float64
+		spec  models.ExpectSpecEntry
+		err   string
+	}{
+		{"set", 9, models.ExpectSpecEntry{Set: []string{"CAN_TRADE", "IS_VIP"}, Unset: []string{"BANNED"}}, ""},
+		{"missing", 1, models.ExpectSpecEntry{Set: []string{"CAN_TRADE", "IS_VIP"}},
+			"flags flags mismatch (value 1, set [CAN_TRADE]): expected set [IS_VIP], expected unset []"},
+		{"unexpected", 41, models.ExpectSpecEntry{Unset: []string{"BANNED"}},
+			"flags flags mismatch (value 41, set [BANNED CAN_TRADE IS_VIP]): expected set [], expected unset [BANNED]"},
+		{"unknown flag", 1, models.ExpectSpecEntry{Set: []string{"ADMIN"}}, "Unknown flag ADMIN, it must be mapped in expect.flags"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			table.spec.Type = "flags"
+			err := validateExpectations(models.ExpectSpec{"flags": table.spec}, Response{"flags": table.value}, nil, &storage{}, true)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+
+	assert.Error(t, SetFlagBits(map[string]interface{}{"x": 64}))
+}
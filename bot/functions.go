@@ -0,0 +1,91 @@
+package bot
+
+import (
+	"fmt"
+	"plugin"
+	"sync"
+)
+
+// Storage is the bot storage as seen by custom functions
+type Storage interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, val interface{})
+}
+
+// Function is a custom function operation. It receives the operation args,
+// already resolved, and the bot storage. The returned values are validated
+// by the operation expectations and stored by its store spec
+type Function func(args map[string]interface{}, store Storage) (map[string]interface{}, error)
+
+// builtinFunctions are the functions runFunction implements itself
+var builtinFunctions = map[string]bool{
+	"connect":            true,
+	"disconnect":         true,
+	"reconnect":          true,
+	"publishKey":         true,
+	"waitForKey":         true,
+	"barrier":            true,
+	"simulateDisconnect": true,
+	"goto":               true,
+	"markPushes":         true,
+}
+
+var functions = struct {
+	sync.RWMutex
+	registry map[string]Function
+}{registry: map[string]Function{}}
+
+// RegisterFunction makes fn available to function operations with uri name.
+// It panics if the name is taken, like database/sql.Register does
+func RegisterFunction(name string, fn Function) {
+	functions.Lock()
+	defer functions.Unlock()
+
+	if fn == nil {
+		panic("bot: RegisterFunction fn is nil")
+	}
+	if builtinFunctions[name] {
+		panic("bot: RegisterFunction called for builtin function " + name)
+	}
+	if _, ok := functions.registry[name]; ok {
+		panic("bot: RegisterFunction called twice for function " + name)
+	}
+
+	functions.registry[name] = fn
+}
+
+func registeredFunction(name string) (Function, bool) {
+	functions.RLock()
+	defer functions.RUnlock()
+
+	fn, ok := functions.registry[name]
+	return fn, ok
+}
+
+// BuiltinFunction returns whether name is implemented by the bots, not by
+// a registered function
+func BuiltinFunction(name string) bool {
+	return builtinFunctions[name]
+}
+
+// KnownFunction returns whether function operations are able to run name
+func KnownFunction(name string) bool {
+	if builtinFunctions[name] {
+		return true
+	}
+
+	_, ok := registeredFunction(name)
+	return ok
+}
+
+// LoadPlugins opens the Go plugins at paths, which register their
+// functions with RegisterFunction in their init
+func LoadPlugins(paths []string) error {
+	for _, path := range paths {
+		if _, err := plugin.Open(path); err != nil {
+			return fmt.Errorf("Unable to load plugin %s: %s", path, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestRegisteredFunction(t *testing.T) {
+	RegisterFunction("testSign", func(args map[string]interface{}, store Storage) (map[string]interface{}, error) {
+		secret, _ := store.Get("secret")
+		if secret == nil {
+			return nil, errors.New("missing secret")
+		}
+		return map[string]interface{}{"signature": args["payload"].(string) + ":" + secret.(string)}, nil
+	})
+
+	assert.True(t, KnownFunction("testSign"))
+	assert.True(t, KnownFunction("reconnect"))
+	assert.False(t, KnownFunction("testUnknown"))
+	assert.Panics(t, func() { RegisterFunction("testSign", nil) })
+	assert.Panics(t, func() {
+		RegisterFunction("barrier", func(map[string]interface{}, Storage) (map[string]interface{}, error) { return nil, nil })
+	})
+
+	tables := []struct {
+		name   string
+		secret interface{}
+		expect models.ExpectSpec
+		err    bool
+	}{
+		{"stores result", "s3cr3t", nil, false},
+		{"expectation", "s3cr3t", models.ExpectSpec{"$response.signature": {Type: "string", Value: "other"}}, true},
+		{"function error", nil, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			if table.secret != nil {
+				b.storage.Set("secret", table.secret)
+			}
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:   "function",
+				URI:    "testSign",
+				Args:   map[string]interface{}{"payload": map[string]interface{}{"type": "string", "value": "data"}},
+				Expect: table.expect,
+				Store:  models.StoreSpec{"sig": {Type: "string", Value: "$response.signature"}},
+			})
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			sig, _ := b.storage.Get("sig")
+			assert.Equal(t, "data:s3cr3t", sig)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// fuzz kinds
+const (
+	fuzzTruncated     = "truncated"
+	fuzzPacketType    = "packetType"
+	fuzzMessageType   = "messageType"
+	fuzzOversized     = "oversized"
+	fuzzGarbage       = "garbage"
+	fuzzMalformedBody = "malformedBody"
+	fuzzArgs          = "args"
+)
+
+// fuzz outcomes
+const (
+	fuzzDisconnect = "disconnect"
+	fuzzResponse   = "response"
+	fuzzSurvive    = "survive"
+)
+
+// defaultFuzzTimeout is how long fuzz operations wait for the server reaction
+// when they have no timeout, defaultGarbageSize how many bytes garbage sends
+// when it has no size
+const (
+	defaultFuzzTimeout = 5 * time.Second
+	defaultGarbageSize = 64
+)
+
+// invalidPacketType and invalidMessageType are types pitaya does not know
+const (
+	invalidPacketType  byte = 0x7f
+	invalidMessageType byte = 0x07
+)
+
+// fuzzMessageID is the id of the last fuzz request, counting from far above
+// the ids the pitaya client gives its own requests
+var fuzzMessageID uint64 = 1 << 30
+
+// ValidateFuzz returns an error when spec is not a fuzz operation bots can run
+func ValidateFuzz(spec *models.FuzzSpec) error {
+	if spec == nil {
+		return fmt.Errorf("Missing fuzz")
+	}
+
+	switch spec.Kind {
+	case fuzzTruncated, fuzzPacketType, fuzzMessageType, fuzzMalformedBody, fuzzGarbage:
+	case fuzzArgs:
+		if spec.Requests < 0 {
+			return fmt.Errorf("Fuzz %s requests must not be negative", spec.Kind)
+		}
+		for _, mutation := range spec.Mutations {
+			if _, ok := argMutations[mutation]; !ok {
+				return fmt.Errorf("Unknown fuzz mutation: %s", mutation)
+			}
+		}
+	case fuzzOversized:
+		if spec.Size <= 0 {
+			return fmt.Errorf("Fuzz %s needs a size", spec.Kind)
+		}
+	default:
+		return fmt.Errorf("Unknown fuzz kind: %s", spec.Kind)
+	}
+	if spec.Size < 0 || spec.Size > maxPacketLength {
+		return fmt.Errorf("Fuzz size must be between 0 and %d", maxPacketLength)
+	}
+
+	switch spec.Outcome {
+	case "", fuzzDisconnect, fuzzResponse, fuzzSurvive:
+	default:
+		return fmt.Errorf("Unknown fuzz outcome: %s", spec.Outcome)
+	}
+
+	return nil
+}
+
+// runFuzz sends the malformed packet of a fuzz operation to the operation
+// uri, between the packets of the session, and checks the server reacts to
+// it as expected. The packet is written straight to the server connection,
+// so it needs client.faultInjection. Args fuzzing is run by fuzzArgs
+func (b *SequentialBot) runFuzz(ctx context.Context, op *models.Operation) error {
+	spec := op.Fuzz
+	if err := ValidateFuzz(spec); err != nil {
+		return err
+	}
+	if spec.Kind == fuzzArgs {
+		return b.fuzzArgs(ctx, op)
+	}
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+	if client.proxy == nil {
+		return fmt.Errorf("Fuzz operations need client.faultInjection")
+	}
+
+	id := uint(atomic.AddUint64(&fuzzMessageID, 1))
+	payload, err := b.fuzzPayload(client, op, id)
+	if err != nil {
+		return err
+	}
+
+	outcome := spec.Outcome
+	if outcome == "" {
+		outcome = fuzzDisconnect
+	}
+	timeout := defaultFuzzTimeout
+	if op.Timeout > 0 {
+		timeout = time.Duration(op.Timeout) * time.Millisecond
+	}
+
+	responses, done := client.proxy.awaitResponse(id)
+	defer done()
+
+	b.logger.Debugf("Sending fuzz %s packet of %d bytes to %s", spec.Kind, len(payload), op.URI)
+	if err := client.proxy.inject(payload); err != nil {
+		return fmt.Errorf("Unable to send fuzz %s packet: %s", spec.Kind, err)
+	}
+	client.stats.AddSent(len(payload))
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case data := <-responses:
+			if outcome != fuzzResponse {
+				continue
+			}
+			return b.checkFuzzResponse(client, op, id, data)
+		case <-ticker.C:
+			if !client.proxy.dropped() {
+				continue
+			}
+			b.sessions.mutex.Lock()
+			b.disconnectSession(op.Session)
+			b.sessions.mutex.Unlock()
+			if outcome != fuzzDisconnect {
+				return fmt.Errorf("Server closed the connection after fuzz %s, expected %s", spec.Kind, outcome)
+			}
+			return nil
+		case <-deadline.C:
+			if outcome != fuzzSurvive {
+				return fmt.Errorf("No %s within %v after fuzz %s", outcome, timeout, spec.Kind)
+			}
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// fuzzPayload builds the malformed packet of a fuzz operation, the request
+// ones with id
+func (b *SequentialBot) fuzzPayload(client *PClient, op *models.Operation, id uint) ([]byte, error) {
+	spec := op.Fuzz
+	switch spec.Kind {
+	case fuzzGarbage:
+		size := spec.Size
+		if size == 0 {
+			size = defaultGarbageSize
+		}
+		garbage := make([]byte, size)
+		rand.Read(garbage)
+		return garbage, nil
+	case fuzzOversized:
+		body := strings.Repeat("x", spec.Size)
+		if padding := spec.Size - len(`{"padding":""}`); padding >= 0 {
+			body = fmt.Sprintf(`{"padding":"%s"}`, strings.Repeat("x", padding))
+		}
+		return encodePacket(packetData, encodeMessage(messageRequest, id, op.URI, []byte(body))), nil
+	case fuzzMalformedBody:
+		return encodePacket(packetData, encodeMessage(messageRequest, id, op.URI, []byte{0xff, '{', 0xfe})), nil
+	}
+
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return nil, err
+	}
+	body, err := client.marshal(op.URI, args)
+	if err != nil {
+		return nil, err
+	}
+
+	switch spec.Kind {
+	case fuzzTruncated:
+		packet := encodePacket(packetData, encodeMessage(messageRequest, id, op.URI, body))
+		return packet[:packetHeaderLength+(len(packet)-packetHeaderLength)/2], nil
+	case fuzzPacketType:
+		return encodePacket(invalidPacketType, encodeMessage(messageRequest, id, op.URI, body)), nil
+	}
+
+	return encodePacket(packetData, encodeMessage(invalidMessageType, id, op.URI, body)), nil
+}
+
+// checkFuzzResponse validates and stores the response the server sent to a
+// fuzz request
+func (b *SequentialBot) checkFuzzResponse(client *PClient, op *models.Operation, id uint, data []byte) error {
+	resp, raw, err := client.unmarshalResponse(op.URI, data)
+	if err != nil {
+		return err
+	}
+	meta := newMetadata(op.URI, len(data), time.Now())
+	meta["id"] = int(id)
+	b.lastResponse, b.lastMeta = resp, meta
+
+	if op.ExpectError != nil {
+		if err := checkErrorResponse(op.ExpectError, resp); err != nil {
+			return NewExpectError(err, raw, op.ExpectError)
+		}
+	}
+
+	if err := validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+		return NewExpectError(err, raw, op.Expect)
+	}
+
+	return storeData(op.Store, b.storage, resp, meta)
+}
@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// fakePitayaServer answers requests with a 200 code, or PIT-400 when their
+// body is not json, and closes the connection on unknown packet and message
+// types like pitaya does
+func fakePitayaServer(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var r packetReader
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+
+			closing := false
+			r.feed(buf[:n], func(typ byte, body []byte) {
+				if typ != packetData || len(body) < 2 || (body[0]>>1)&0x07 != messageRequest {
+					closing = true
+					return
+				}
+
+				id, offset := uint(0), 1
+				for shift := uint(0); ; shift += 7 {
+					id |= uint(body[offset]&0x7f) << shift
+					offset++
+					if body[offset-1] < 0x80 {
+						break
+					}
+				}
+				data := body[offset+1+int(body[offset]):]
+
+				code := `{"code":200}`
+				if !json.Valid(data) {
+					code = `{"code":"PIT-400"}`
+				}
+				conn.Write(encodePacket(packetData, encodeMessage(messageResponse, id, "", []byte(code))))
+			})
+			if closing {
+				return
+			}
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+// newFuzzBot returns a bot whose main session goes through a fault injection
+// proxy to a fake pitaya server
+func newFuzzBot(t *testing.T) *SequentialBot {
+	p, err := newFaultProxy(fakePitayaServer(t), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := dialProxy(t, p)
+	go ioutil.ReadAll(conn)
+
+	for start := time.Now(); p.inject(nil) != nil; time.Sleep(time.Millisecond) {
+		if time.Since(start) > time.Second {
+			t.Fatal("Proxy did not connect")
+		}
+	}
+
+	b := newTestBot(&recordingTransport{})
+	b.sessions.clients[defaultSession] = &PClient{proxy: p}
+	return b
+}
+
+func TestFuzz(t *testing.T) {
+	tables := []struct {
+		name    string
+		fuzz    *models.FuzzSpec
+		timeout int
+		expect  models.ExpectSpec
+		err     string
+	}{
+		{"unknown packet type", &models.FuzzSpec{Kind: "packetType"}, 0, nil, ""},
+		{"unknown message type", &models.FuzzSpec{Kind: "messageType", Outcome: "disconnect"}, 0, nil, ""},
+		{"malformed body", &models.FuzzSpec{Kind: "malformedBody", Outcome: "response"}, 0,
+			models.ExpectSpec{"code": {Type: "string", Value: "PIT-400"}}, ""},
+		{"oversized", &models.FuzzSpec{Kind: "oversized", Size: 4096, Outcome: "response"}, 0,
+			models.ExpectSpec{"code": {Type: "int", Value: 200}}, ""},
+		{"truncated", &models.FuzzSpec{Kind: "truncated", Outcome: "survive"}, 50, nil, ""},
+		{"unexpected disconnect", &models.FuzzSpec{Kind: "packetType", Outcome: "survive"}, 0, nil,
+			"Server closed the connection after fuzz packetType, expected survive"},
+		{"no disconnect", &models.FuzzSpec{Kind: "truncated"}, 50, nil, "No disconnect within 50ms after fuzz truncated"},
+		{"unknown kind", &models.FuzzSpec{Kind: "bitflip"}, 0, nil, "Unknown fuzz kind: bitflip"},
+		{"oversized without size", &models.FuzzSpec{Kind: "oversized"}, 0, nil, "Fuzz oversized needs a size"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newFuzzBot(t)
+			defer b.sessions.clients[defaultSession].proxy.close()
+
+			op := &models.Operation{Type: "fuzz", URI: "room.join", Timeout: table.timeout, Fuzz: table.fuzz, Expect: table.expect}
+			err := b.runOperation(context.Background(), op)
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestFuzzNeedsFaultInjection(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	op := &models.Operation{Type: "fuzz", URI: "room.join", Fuzz: &models.FuzzSpec{Kind: "garbage"}}
+	assert.EqualError(t, b.runOperation(context.Background(), op), "Fuzz operations need client.faultInjection")
+}
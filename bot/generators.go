@@ -0,0 +1,60 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// generator produces a fresh value each time it is evaluated
+type generator func(r *rand.Rand) interface{}
+
+var (
+	generatorsMutex sync.Mutex
+	random          = rand.New(rand.NewSource(time.Now().UnixNano()))
+	generators      = map[string]generator{
+		"util.uuid":   func(r *rand.Rand) interface{} { return uuid.New().String() },
+		"random.uuid": randomUUID,
+	}
+
+	generatorExpr = regexp.MustCompile(`^\$\{([a-zA-Z]+\.[a-zA-Z0-9_.\-]+)\}$`)
+)
+
+// randomUUID builds a version 4 uuid from the seeded source
+func randomUUID(r *rand.Rand) interface{} {
+	var u uuid.UUID
+	r.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return u.String()
+}
+
+// SeedGenerators seeds the random source shared by all value generators,
+// making generated args reproducible across runs
+func SeedGenerators(seed int64) {
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+	random = rand.New(rand.NewSource(seed))
+}
+
+func registerGenerator(name string, g generator) {
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+	generators[name] = g
+}
+
+func generate(name string) (interface{}, error) {
+	generatorsMutex.Lock()
+	defer generatorsMutex.Unlock()
+
+	g, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("%s undefined", name)
+	}
+
+	return g(random), nil
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	grpcConnsMutex sync.Mutex
+	grpcConns      = map[string]*grpc.ClientConn{}
+)
+
+// rawCodec passes already encoded messages through, they are encoded and
+// decoded with the descriptor set instead of generated code
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	return *(v.(*[]byte)), nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	*(v.(*[]byte)) = append([]byte(nil), data...)
+	return nil
+}
+
+func (rawCodec) String() string {
+	return "raw"
+}
+
+// grpcConn returns the connection to address, shared by every bot
+func grpcConn(address string) (*grpc.ClientConn, error) {
+	grpcConnsMutex.Lock()
+	defer grpcConnsMutex.Unlock()
+
+	if conn, ok := grpcConns[address]; ok {
+		return conn, nil
+	}
+
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to grpc server %s: %s", address, err)
+	}
+	grpcConns[address] = conn
+	return conn, nil
+}
+
+// runGRPC calls the unary method of a grpc operation uri, written as
+// package.Service/Method. Its messages are described by the grpc.descriptors
+// descriptor set, or the operation one, and the server is the grpc.address,
+// or the operation one. Args are the request message and the response is
+// validated and stored like pitaya responses
+func (b *SequentialBot) runGRPC(ctx context.Context, op *models.Operation) error {
+	spec := op.GRPC
+	if spec == nil {
+		spec = &models.GRPCSpec{}
+	}
+
+	address, descriptors := spec.Address, spec.Descriptors
+	if address == "" && b.config != nil {
+		address = b.config.GetString("grpc.address")
+	}
+	if descriptors == "" && b.config != nil {
+		descriptors = b.config.GetString("grpc.descriptors")
+	}
+	if address == "" || descriptors == "" {
+		return fmt.Errorf("Grpc operation %s needs an address and descriptors", op.URI)
+	}
+
+	registry, err := sharedProtoRegistry(descriptors)
+	if err != nil {
+		return err
+	}
+	method, ok := registry.methods[op.URI]
+	if !ok {
+		return fmt.Errorf("Unknown grpc method %s", op.URI)
+	}
+	input, inputOK := registry.messages[method.input]
+	output, outputOK := registry.messages[method.output]
+	if !inputOK || !outputOK {
+		return fmt.Errorf("Unknown messages of grpc method %s", op.URI)
+	}
+
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+	s := &protoSerializer{registry: registry}
+	req, err := s.encodeMessage(input, args)
+	if err != nil {
+		return err
+	}
+
+	if err := b.limit(ctx, op.URI, op.Tags); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.externalTimeout(op))
+	defer cancel()
+	if traceparent := tracing.SpanFromContext(ctx).Traceparent(); traceparent != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "traceparent", traceparent)
+	}
+	for key, value := range spec.Metadata {
+		value, err := interpolate(value, b.storage)
+		if err != nil {
+			return err
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+	}
+
+	conn, err := grpcConn(address)
+	if err != nil {
+		return err
+	}
+
+	tags := metricsTags(op.URI, op.Tags)
+	var raw []byte
+	start := time.Now()
+	err = conn.Invoke(ctx, "/"+op.URI, &req, &raw, grpc.CallCustomCodec(rawCodec{}))
+	receivedAt := time.Now()
+	if err != nil {
+		reportError(b.metricsReporter, tags, metrics.GRPCError)
+		return fmt.Errorf("Grpc call to %s failed: %s", op.URI, err)
+	}
+	reportResponseTime(b.metricsReporter, tags, receivedAt.Sub(start), metrics.StatusOK)
+
+	resp, err := s.decode(output, raw)
+	if err != nil {
+		return err
+	}
+	meta := newMetadata(op.URI, len(raw), receivedAt)
+	meta["latencyMs"] = int(receivedAt.Sub(start).Nanoseconds() / 1e6)
+	b.lastResponse, b.lastMeta = resp, meta
+
+	if err := validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+		return NewExpectError(err, raw, op.Expect)
+	}
+
+	return storeData(op.Store, b.storage, resp, meta)
+}
@@ -0,0 +1,133 @@
+package bot
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// testServiceDescriptorSet describes, in proto3:
+//
+//	message GrantRequest { string player = 1; int64 amount = 2; }
+//	message GrantResponse { int64 balance = 1; }
+//	service Admin { rpc Grant(GrantRequest) returns (GrantResponse); }
+func testServiceDescriptorSet() []byte {
+	optional := descriptor.FieldDescriptorProto_LABEL_OPTIONAL
+
+	request := &descriptor.DescriptorProto{
+		Name: proto.String("GrantRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			fieldDescriptor("player", 1, optional, protoString, "", "player"),
+			fieldDescriptor("amount", 2, optional, protoInt64, "", "amount"),
+		},
+	}
+	response := &descriptor.DescriptorProto{
+		Name: proto.String("GrantResponse"),
+		Field: []*descriptor.FieldDescriptorProto{
+			fieldDescriptor("balance", 1, optional, protoInt64, "", "balance"),
+		},
+	}
+	service := &descriptor.ServiceDescriptorProto{
+		Name: proto.String("Admin"),
+		Method: []*descriptor.MethodDescriptorProto{{
+			Name:       proto.String("Grant"),
+			InputType:  proto.String(".admin.GrantRequest"),
+			OutputType: proto.String(".admin.GrantResponse"),
+		}},
+	}
+
+	return marshalDescriptorSet(&descriptor.FileDescriptorProto{
+		Name:        proto.String("admin.proto"),
+		Package:     proto.String("admin"),
+		MessageType: []*descriptor.DescriptorProto{request, response},
+		Service:     []*descriptor.ServiceDescriptorProto{service},
+		Syntax:      proto.String("proto3"),
+	})
+}
+
+func TestRunGRPC(t *testing.T) {
+	descriptors, err := ioutil.TempFile("", "admin-*.pb")
+	assert.NoError(t, err)
+	defer os.Remove(descriptors.Name())
+	descriptors.Write(testServiceDescriptorSet())
+	descriptors.Close()
+
+	registry, err := sharedProtoRegistry(descriptors.Name())
+	assert.NoError(t, err)
+	s := &protoSerializer{registry: registry}
+
+	server := grpc.NewServer(grpc.CustomCodec(rawCodec{}), grpc.UnknownServiceHandler(func(srv interface{}, stream grpc.ServerStream) error {
+		var data []byte
+		if err := stream.RecvMsg(&data); err != nil {
+			return err
+		}
+		req, err := s.decode(registry.messages["admin.GrantRequest"], data)
+		if err != nil {
+			return err
+		}
+
+		balance := req["amount"].(float64)
+		if md, ok := metadata.FromIncomingContext(stream.Context()); ok && len(md.Get("x-bonus")) > 0 {
+			balance += 100
+		}
+		resp, err := s.encodeMessage(registry.messages["admin.GrantResponse"], map[string]interface{}{"balance": balance})
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(&resp)
+	}))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	grantOp := func(uri string, spec models.GRPCSpec) *models.Operation {
+		spec.Address, spec.Descriptors = listener.Addr().String(), descriptors.Name()
+		return &models.Operation{
+			Type: "grpc", URI: uri, GRPC: &spec, Timeout: 2000,
+			Args: map[string]interface{}{
+				"player": map[string]interface{}{"type": "string", "value": "bot"},
+				"amount": map[string]interface{}{"type": "int", "value": 50},
+			},
+			Store: models.StoreSpec{"balance": {Type: "int", Value: "$response.balance"}},
+		}
+	}
+
+	tables := []struct {
+		name    string
+		op      *models.Operation
+		balance interface{}
+		err     bool
+	}{
+		{"unary call", grantOp("admin.Admin/Grant", models.GRPCSpec{}), 50, false},
+		{"metadata", grantOp("admin.Admin/Grant", models.GRPCSpec{Metadata: map[string]string{"x-bonus": "${store.player}"}}), 150, false},
+		{"unknown method", grantOp("admin.Admin/Revoke", models.GRPCSpec{}), nil, true},
+		{"missing address", &models.Operation{Type: "grpc", URI: "admin.Admin/Grant"}, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			b.storage.Set("player", "bot")
+
+			err := b.runOperation(context.Background(), table.op)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			balance, _ := b.storage.Get("balance")
+			assert.EqualValues(t, table.balance, balance)
+		})
+	}
+}
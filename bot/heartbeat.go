@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+)
+
+// heartbeatRewriter sets the heartbeat interval, in seconds, of the server
+// handshake response, the first packet the server sends. The pitaya client
+// heartbeats at the interval of that response and has no setting of its
+// own. Packets that are not a json handshake are forwarded untouched
+type heartbeatRewriter struct {
+	seconds int
+	buffer  []byte
+	done    bool
+}
+
+// rewrite returns the data to forward for data read from the server, holding
+// it back until the first packet is complete
+func (r *heartbeatRewriter) rewrite(data []byte) []byte {
+	if r.done {
+		return data
+	}
+
+	r.buffer = append(r.buffer, data...)
+	if len(r.buffer) < packetHeaderLength {
+		return nil
+	}
+	length := int(r.buffer[1])<<16 | int(r.buffer[2])<<8 | int(r.buffer[3])
+	if len(r.buffer) < packetHeaderLength+length {
+		return nil
+	}
+
+	r.done = true
+	data, r.buffer = r.buffer, nil
+	if data[0] != packetHandshake {
+		return data
+	}
+
+	var handshake map[string]interface{}
+	if err := json.Unmarshal(data[packetHeaderLength:packetHeaderLength+length], &handshake); err != nil {
+		return data
+	}
+	sys, ok := handshake["sys"].(map[string]interface{})
+	if !ok {
+		return data
+	}
+	sys["heartbeat"] = r.seconds
+
+	body, err := json.Marshal(handshake)
+	if err != nil {
+		return data
+	}
+	return append(encodePacket(packetHandshake, body), data[packetHeaderLength+length:]...)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeartbeatRewriter(t *testing.T) {
+	handshake := encodePacket(packetHandshake, []byte(`{"code":200,"sys":{"heartbeat":30}}`))
+	rewritten := encodePacket(packetHandshake, []byte(`{"code":200,"sys":{"heartbeat":5}}`))
+	data := encodePacket(packetData, []byte("body"))
+
+	tables := []struct {
+		name     string
+		chunks   [][]byte
+		expected []byte
+	}{
+		{"handshake", [][]byte{handshake}, rewritten},
+		{"split handshake", [][]byte{handshake[:2], handshake[2:10], handshake[10:]}, rewritten},
+		{"handshake and data", [][]byte{append(append([]byte{}, handshake...), data...)}, append(append([]byte{}, rewritten...), data...)},
+		{"only the first packet", [][]byte{handshake, handshake}, append(append([]byte{}, rewritten...), handshake...)},
+		{"not a handshake", [][]byte{data}, data},
+		{"not json", [][]byte{encodePacket(packetHandshake, []byte("{"))}, encodePacket(packetHandshake, []byte("{"))},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			r := &heartbeatRewriter{seconds: 5}
+			var out []byte
+			for _, chunk := range table.chunks {
+				out = append(out, r.rewrite(chunk)...)
+			}
+			assert.Equal(t, table.expected, out)
+		})
+	}
+}
+
+func TestFaultProxyHeartbeat(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write(encodePacket(packetHandshake, []byte(`{"code":200,"sys":{"heartbeat":30}}`)))
+		io.Copy(conn, conn)
+	}()
+
+	p, err := newFaultProxy(listener.Addr().String(), nil, 5)
+	assert.NoError(t, err)
+	defer p.close()
+	conn := dialProxy(t, p)
+	defer conn.Close()
+
+	expected := encodePacket(packetHandshake, []byte(`{"code":200,"sys":{"heartbeat":5}}`))
+	buf := make([]byte, len(expected))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err = io.ReadFull(conn, buf)
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(expected, buf), string(buf))
+
+	reply, err := echo(conn, "hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", reply)
+}
+
+func TestHeartbeatNeedsPlainTCP(t *testing.T) {
+	tables := []struct {
+		name string
+		opts *PClientOptions
+	}{
+		{"tls", &PClientOptions{UseTLS: true, HeartbeatSeconds: 5}},
+		{"websocket", &PClientOptions{Protocol: "ws", HeartbeatSeconds: 5}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := NewPClient("localhost:3250", table.opts)
+			assert.EqualError(t, err, "Heartbeat interval needs the tcp protocol without tls")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/tracing"
+)
+
+// runWithHooks runs the spec setup operations, then run, then the teardown
+// operations. Teardown runs even when setup or run fail, all of its
+// operations are attempted and it is not bound by ctx, so it cleans up
+// after bots that exceeded their max duration too. Bots that abandoned an
+// operation still running skip it. Resumed bots skip setup.
+// The whole run is traced as the root span of the bot trace, carried by
+// the context run and the operations get
+func (b *SequentialBot) runWithHooks(ctx context.Context, run func(context.Context) error) (err error) {
+	span := b.tracer.StartTrace("spec " + b.spec.Name)
+	span.SetAttribute("spec", b.spec.Name)
+	span.SetAttribute("bot.id", b.id)
+	defer func() { span.Finish(err) }()
+
+	ctx = tracing.ContextWithSpan(ctx, span)
+	err = b.runSetup(ctx)
+	if err == nil {
+		err = run(ctx)
+	}
+
+	if b.abandoned {
+		return fmt.Errorf("%s; teardown skipped", err)
+	}
+
+	b.handlePushes()
+	if err == nil && b.pushHandlers != nil {
+		err = b.pushHandlers.err()
+	}
+
+	if teardownErr := b.runTeardown(span); teardownErr != nil {
+		if err == nil {
+			return teardownErr
+		}
+		return fmt.Errorf("%s; %s", err, teardownErr)
+	}
+
+	return err
+}
+
+func (b *SequentialBot) runSetup(ctx context.Context) error {
+	if b.resumeFrom != nil {
+		return nil
+	}
+
+	for idx, op := range b.spec.SetupOperations {
+		if err := b.runStep(ctx, idx, op); err != nil {
+			return fmt.Errorf("Setup operation %d failed: %s", idx, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *SequentialBot) runTeardown(span *tracing.Span) error {
+	ctx := tracing.ContextWithSpan(context.Background(), span)
+	failures := make([]string, 0)
+	for idx, op := range b.spec.TeardownOperations {
+		if err := b.runStep(ctx, idx, op); err != nil {
+			b.logger.WithError(err).Warnf("Teardown operation %d failed", idx)
+			failures = append(failures, fmt.Sprintf("%d: %s", idx, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("Teardown operations failed: %s", strings.Join(failures, ", "))
+	}
+
+	return nil
+}
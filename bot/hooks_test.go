@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func hookOp(uri string) *models.Operation {
+	return &models.Operation{
+		Type:   "request",
+		URI:    uri,
+		Args:   map[string]interface{}{"step": map[string]interface{}{"type": "string", "value": uri}},
+		Expect: models.ExpectSpec{"code": {Type: "string", Value: "200"}},
+	}
+}
+
+func TestSetupAndTeardown(t *testing.T) {
+	tables := []struct {
+		name      string
+		responses []string
+		sent      []string
+		err       bool
+	}{
+		{"all pass", nil, []string{
+			`{"step":"room.create"}`, `{"step":"room.join"}`, `{"step":"room.play"}`, `{"step":"room.delete"}`,
+		}, false},
+		{"teardown after failed step", []string{`{"code": "200"}`, `{"code": "500"}`, `{"code": "200"}`}, []string{
+			`{"step":"room.create"}`, `{"step":"room.join"}`, `{"step":"room.delete"}`,
+		}, true},
+		{"teardown after failed setup", []string{`{"code": "500"}`, `{"code": "200"}`}, []string{
+			`{"step":"room.create"}`, `{"step":"room.delete"}`,
+		}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: table.responses}
+			b := newTestBot(transport)
+			b.spec = &models.Spec{
+				SetupOperations:      []*models.Operation{hookOp("room.create")},
+				SequentialOperations: []*models.Operation{hookOp("room.join"), hookOp("room.play")},
+				TeardownOperations:   []*models.Operation{hookOp("room.delete")},
+			}
+
+			err := b.Run(context.Background())
+			assert.Equal(t, table.err, err != nil)
+			assert.Equal(t, table.sent, transport.sent)
+		})
+	}
+}
+
+func TestTeardownRunsAfterDeadline(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.spec = &models.Spec{
+		SequentialOperations: []*models.Operation{hookOp("room.join")},
+		TeardownOperations:   []*models.Operation{hookOp("room.delete")},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Error(t, b.Run(ctx))
+	assert.Equal(t, []string{`{"step":"room.delete"}`}, transport.sent)
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/tracing"
+)
+
+const defaultExternalTimeout = 5 * time.Second
+
+var httpClient = &http.Client{}
+
+// runHTTP sends the http request of an http operation to its uri. Args are
+// sent as the json body and refs in the uri and headers are interpolated.
+// JSON object bodies are validated and stored like pitaya responses, other
+// bodies are exposed as $response.body, and meta.status holds the status
+func (b *SequentialBot) runHTTP(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
+
+	url, err := interpolate(op.URI, b.storage)
+	if err != nil {
+		return err
+	}
+	logger.Debug("Executing http request to: " + url)
+
+	req, err := b.newHTTPRequest(ctx, op, url)
+	if err != nil {
+		return err
+	}
+
+	if err := b.limit(ctx, op.URI, op.Tags); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.externalTimeout(op))
+	defer cancel()
+
+	resp, meta, raw, err := b.sendHTTPRequest(req.WithContext(ctx), op.Tags)
+	if err != nil {
+		return err
+	}
+	b.lastResponse, b.lastMeta = resp, meta
+
+	if err := validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+		return NewExpectError(err, raw, op.Expect)
+	}
+
+	return storeData(op.Store, b.storage, resp, meta)
+}
+
+// externalTimeout is how long http and grpc operations wait for their
+// response: their timeout, request.timeoutMs or 5s
+func (b *SequentialBot) externalTimeout(op *models.Operation) time.Duration {
+	if op.Timeout > 0 {
+		return time.Duration(op.Timeout) * time.Millisecond
+	}
+	if b.config != nil && b.config.GetInt("request.timeoutMs") > 0 {
+		return time.Duration(b.config.GetInt("request.timeoutMs")) * time.Millisecond
+	}
+
+	return defaultExternalTimeout
+}
+
+func (b *SequentialBot) newHTTPRequest(ctx context.Context, op *models.Operation, url string) (*http.Request, error) {
+	method := http.MethodGet
+	if op.HTTP != nil && op.HTTP.Method != "" {
+		method = strings.ToUpper(op.HTTP.Method)
+	}
+
+	var body io.Reader
+	if len(op.Args) > 0 {
+		args, err := buildArgs(op.Args, b.storage)
+		if err != nil {
+			return nil, err
+		}
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid http request: %s", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if traceparent := tracing.SpanFromContext(ctx).Traceparent(); traceparent != "" {
+		req.Header.Set("traceparent", traceparent)
+	}
+
+	if op.HTTP != nil {
+		for name, value := range op.HTTP.Headers {
+			value, err := interpolate(value, b.storage)
+			if err != nil {
+				return nil, err
+			}
+			req.Header.Set(name, value)
+		}
+	}
+
+	return req, nil
+}
+
+func (b *SequentialBot) sendHTTPRequest(req *http.Request, opTags map[string]string) (Response, Metadata, []byte, error) {
+	tags := metricsTags(req.URL.Path, opTags)
+
+	start := time.Now()
+	httpResp, err := httpClient.Do(req)
+	if err != nil {
+		reportError(b.metricsReporter, tags, metrics.HTTPError)
+		return nil, nil, nil, fmt.Errorf("Http request to %s failed: %s", req.URL, err)
+	}
+	defer httpResp.Body.Close()
+
+	raw, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Unable to read http response from %s: %s", req.URL, err)
+	}
+	receivedAt := time.Now()
+	reportResponseTime(b.metricsReporter, tags, receivedAt.Sub(start), strconv.Itoa(httpResp.StatusCode))
+
+	resp := make(Response)
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		resp = Response{"body": string(raw)}
+	}
+
+	meta := newMetadata(req.URL.String(), len(raw), receivedAt)
+	meta["status"] = httpResp.StatusCode
+	meta["latencyMs"] = int(receivedAt.Sub(start).Nanoseconds() / 1e6)
+
+	return resp, meta, raw, nil
+}
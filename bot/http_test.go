@@ -0,0 +1,81 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/tracing"
+)
+
+func TestRunHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			if r.Method != http.MethodPost || body["user"] != "bot" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token": "abc"}`))
+		case "/me":
+			if r.Header.Get("Authorization") != "Bearer abc" || r.Header.Get("traceparent") == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+			w.Write([]byte("plain"))
+		}
+	}))
+	defer server.Close()
+
+	status := func(code int) models.ExpectSpec {
+		return models.ExpectSpec{"meta.status": {Type: "int", Value: code}}
+	}
+
+	tables := []struct {
+		name string
+		op   *models.Operation
+		err  bool
+	}{
+		{"post json", &models.Operation{
+			Type: "http", URI: server.URL + "/login",
+			HTTP:   &models.HTTPSpec{Method: "post"},
+			Args:   map[string]interface{}{"user": map[string]interface{}{"type": "string", "value": "bot"}},
+			Expect: status(200),
+			Store:  models.StoreSpec{"token": {Type: "string", Value: "$response.token"}},
+		}, false},
+		{"interpolated header", &models.Operation{
+			Type: "http", URI: server.URL + "/me",
+			HTTP:   &models.HTTPSpec{Headers: map[string]string{"Authorization": "Bearer ${store.token}"}},
+			Expect: models.ExpectSpec{"meta.status": {Type: "int", Value: 200}, "$response.body": {Type: "string", Value: "plain"}},
+		}, false},
+		{"status expectation", &models.Operation{
+			Type: "http", URI: server.URL + "/login", Expect: status(200),
+		}, true},
+		{"unreachable", &models.Operation{
+			Type: "http", URI: "http://127.0.0.1:1/login", Timeout: 500,
+		}, true},
+	}
+
+	b := newTestBot(&recordingTransport{})
+	b.tracer = tracing.NewTracer("http://127.0.0.1:1", "bots", 1, b.logger)
+	defer b.tracer.Close()
+	ctx := tracing.ContextWithSpan(context.Background(), b.tracer.StartTrace("spec"))
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			err := b.runOperation(ctx, table.op)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	token, _ := b.storage.Get("token")
+	assert.Equal(t, "abc", token)
+}
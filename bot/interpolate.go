@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	storePrefix = "store."
+	// botIDKey is the storage key holding the bot id, ${bot.id}
+	botIDKey = "bot.id"
+)
+
+// interpolationExpr matches the ${...} references embedded in strings
+var interpolationExpr = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// embeddedRef returns whether s embeds references in a longer string
+func embeddedRef(s string) bool {
+	locs := interpolationExpr.FindAllStringIndex(s, -1)
+	if len(locs) == 0 {
+		return false
+	}
+
+	return len(locs) > 1 || locs[0][0] > 0 || locs[0][1] < len(s)
+}
+
+// resolveRef resolves the name of a ${name} reference: store.key reads the
+// storage, bot.id, params.name and vars.name the values bound to the bot,
+// name(args) is an arg function and anything else a generator
+func resolveRef(name string, store *storage) (interface{}, error) {
+	switch {
+	case strings.HasPrefix(name, storePrefix):
+		key := strings.TrimPrefix(name, storePrefix)
+		if val, ok := store.Get(key); ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("Variable %s not found", key)
+	case strings.HasPrefix(name, paramsPrefix):
+		if val, ok := store.Get(name); ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("Param %s not bound", name[len(paramsPrefix):])
+	case strings.HasPrefix(name, varsPrefix):
+		if val, ok := store.Get(name); ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("Var %s not defined", name[len(varsPrefix):])
+	case name == botIDKey:
+		if val, ok := store.Get(name); ok {
+			return val, nil
+		}
+		return nil, fmt.Errorf("Bot id not bound")
+	}
+
+	if value, ok, err := callArgFunction("$"+name, store); ok {
+		return value, err
+	}
+
+	return generate(name)
+}
+
+// interpolate replaces every ${...} reference in s with its value
+func interpolate(s string, store *storage) (string, error) {
+	var err error
+	ret := interpolationExpr.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+
+		var val interface{}
+		val, err = resolveRef(interpolationExpr.FindStringSubmatch(ref)[1], store)
+		if err != nil {
+			return ref
+		}
+		return stringify(val)
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to interpolate %s: %s", s, err)
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestInterpolateArgs(t *testing.T) {
+	tables := []struct {
+		name     string
+		value    string
+		expected interface{}
+		err      bool
+	}{
+		{"composite", "player_${store.userId}_${bot.id}", "player_u42_7", false},
+		{"whole store ref keeps type", "${store.gold}", 250, false},
+		{"whole bot id", "${bot.id}", 7, false},
+		{"param", "room-${params.room}", "room-lobby", false},
+		{"missing variable", "player_${store.missing}", nil, true},
+		{"unknown generator", "x-${foo.bar}", nil, true},
+		{"plain", "no refs", nil, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			store := &storage{}
+			store.Set("userId", "u42")
+			store.Set("gold", 250)
+			store.Set(botIDKey, 7)
+			store.Set(paramsPrefix+"room", "lobby")
+
+			value, err := tryGetValue(table.value, store)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.expected, value)
+		})
+	}
+}
+
+func TestBuildArgsInterpolation(t *testing.T) {
+	store := &storage{}
+	store.Set("userId", "u42")
+	store.Set(botIDKey, 3)
+
+	args, err := buildArgs(map[string]interface{}{
+		"name": map[string]interface{}{"type": "string", "value": "player_${store.userId}_${bot.id}"},
+	}, store)
+	assert.NoError(t, err)
+	assert.Equal(t, "player_u42_3", args["name"])
+}
+
+func TestVarsScoping(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.storage.withVars(map[string]interface{}{"region": "us", "version": "1.0"})
+	b.storage.withVars(map[string]interface{}{"region": "eu"})
+
+	seen := map[string]interface{}{}
+	RegisterFunction("testCaptureVars", func(args map[string]interface{}, store Storage) (map[string]interface{}, error) {
+		for k, v := range args {
+			seen[k] = v
+		}
+		return args, nil
+	})
+
+	args := map[string]interface{}{
+		"region":  map[string]interface{}{"type": "string", "value": "${vars.region}"},
+		"version": map[string]interface{}{"type": "string", "value": "v${vars.version}"},
+	}
+	err := b.runOperation(context.Background(), &models.Operation{Type: "function", URI: "testCaptureVars", Args: args,
+		Vars: map[string]interface{}{"version": "2.0"}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"region": "eu", "version": "v2.0"}, seen)
+
+	version, err := tryGetValue("${vars.version}", b.storage)
+	assert.NoError(t, err)
+	assert.Equal(t, "1.0", version, "operation vars are unbound after it runs")
+
+	_, err = tryGetValue("${vars.missing}", b.storage)
+	assert.Error(t, err)
+}
@@ -0,0 +1,634 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPathSelector selects children of a node
+type jsonPathSelector interface {
+	apply(node interface{}) []interface{}
+	definite() bool
+}
+
+type jsonPathStep struct {
+	recursive bool
+	selector  jsonPathSelector
+}
+
+// jsonPath is a compiled JSONPath expression
+type jsonPath struct {
+	expr  string
+	steps []jsonPathStep
+}
+
+type nameSelector struct {
+	names []string
+}
+
+func (s *nameSelector) apply(node interface{}) []interface{} {
+	ret := make([]interface{}, 0)
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		if r, isResp := node.(Response); isResp {
+			obj, ok = map[string]interface{}(r), true
+		}
+	}
+	if !ok {
+		return ret
+	}
+
+	for _, name := range s.names {
+		if v, ok := obj[name]; ok {
+			ret = append(ret, v)
+		}
+	}
+
+	return ret
+}
+
+func (s *nameSelector) definite() bool { return len(s.names) == 1 }
+
+type wildcardSelector struct{}
+
+func (s *wildcardSelector) apply(node interface{}) []interface{} {
+	return children(node)
+}
+
+func (s *wildcardSelector) definite() bool { return false }
+
+type indexSelector struct {
+	indexes []int
+}
+
+func (s *indexSelector) apply(node interface{}) []interface{} {
+	ret := make([]interface{}, 0)
+	arr, ok := node.([]interface{})
+	if !ok {
+		return ret
+	}
+
+	for _, idx := range s.indexes {
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx >= 0 && idx < len(arr) {
+			ret = append(ret, arr[idx])
+		}
+	}
+
+	return ret
+}
+
+func (s *indexSelector) definite() bool { return len(s.indexes) == 1 }
+
+type sliceSelector struct {
+	start *int
+	end   *int
+}
+
+func (s *sliceSelector) apply(node interface{}) []interface{} {
+	arr, ok := node.([]interface{})
+	if !ok {
+		return []interface{}{}
+	}
+
+	bound := func(v *int, def int) int {
+		if v == nil {
+			return def
+		}
+		i := *v
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > len(arr) {
+			return len(arr)
+		}
+		return i
+	}
+
+	start, end := bound(s.start, 0), bound(s.end, len(arr))
+	if start >= end {
+		return []interface{}{}
+	}
+
+	ret := make([]interface{}, end-start)
+	copy(ret, arr[start:end])
+	return ret
+}
+
+func (s *sliceSelector) definite() bool { return false }
+
+type filterSelector struct {
+	filter jsonPathFilter
+}
+
+func (s *filterSelector) apply(node interface{}) []interface{} {
+	ret := make([]interface{}, 0)
+	for _, child := range children(node) {
+		if s.filter.matches(child) {
+			ret = append(ret, child)
+		}
+	}
+
+	return ret
+}
+
+func (s *filterSelector) definite() bool { return false }
+
+// children returns the direct children of a node, map values are
+// returned sorted by key so results are deterministic
+func children(node interface{}) []interface{} {
+	switch n := node.(type) {
+	case []interface{}:
+		ret := make([]interface{}, len(n))
+		copy(ret, n)
+		return ret
+	case Response:
+		return children(map[string]interface{}(n))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for k := range n {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		ret := make([]interface{}, len(keys))
+		for i, k := range keys {
+			ret[i] = n[k]
+		}
+		return ret
+	}
+
+	return []interface{}{}
+}
+
+func descendants(node interface{}) []interface{} {
+	ret := []interface{}{node}
+	for _, child := range children(node) {
+		ret = append(ret, descendants(child)...)
+	}
+
+	return ret
+}
+
+func (p *jsonPath) definite() bool {
+	for _, step := range p.steps {
+		if step.recursive || !step.selector.definite() {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *jsonPath) eval(root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	for _, step := range p.steps {
+		next := make([]interface{}, 0)
+		for _, node := range nodes {
+			if step.recursive {
+				for _, d := range descendants(node) {
+					next = append(next, step.selector.apply(d)...)
+				}
+				continue
+			}
+			next = append(next, step.selector.apply(node)...)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+func isJSONPath(expr string) bool {
+	return expr == "$" || strings.HasPrefix(expr, "$.") || strings.HasPrefix(expr, "$[")
+}
+
+func jsonPathError(expr, reason string) error {
+	return fmt.Errorf("Invalid JSONPath expression %s: %s", expr, reason)
+}
+
+// compileJSONPath parses a JSONPath expression rooted at '$' (or '@' inside filters)
+func compileJSONPath(expr string) (*jsonPath, error) {
+	return compilePath(expr, expr, '$')
+}
+
+func compilePath(fullExpr, expr string, root byte) (*jsonPath, error) {
+	if len(expr) == 0 || expr[0] != root {
+		return nil, jsonPathError(fullExpr, fmt.Sprintf("must start with '%c'", root))
+	}
+
+	path := &jsonPath{expr: expr, steps: make([]jsonPathStep, 0)}
+	i := 1
+	for i < len(expr) {
+		recursive := false
+		switch {
+		case strings.HasPrefix(expr[i:], ".."):
+			recursive = true
+			i += 2
+		case expr[i] == '.':
+			i++
+		case expr[i] == '[':
+		default:
+			return nil, jsonPathError(fullExpr, fmt.Sprintf("unexpected character '%c' at %d", expr[i], i))
+		}
+
+		if i >= len(expr) {
+			return nil, jsonPathError(fullExpr, "unexpected end of expression")
+		}
+
+		if expr[i] == '[' {
+			end, err := closingBracket(expr, i)
+			if err != nil {
+				return nil, jsonPathError(fullExpr, err.Error())
+			}
+
+			selector, err := parseBracket(fullExpr, expr[i+1:end])
+			if err != nil {
+				return nil, err
+			}
+
+			path.steps = append(path.steps, jsonPathStep{recursive: recursive, selector: selector})
+			i = end + 1
+			continue
+		}
+
+		end := i
+		for end < len(expr) && expr[end] != '.' && expr[end] != '[' {
+			end++
+		}
+
+		name := expr[i:end]
+		if name == "" {
+			return nil, jsonPathError(fullExpr, fmt.Sprintf("empty member name at %d", i))
+		}
+
+		var selector jsonPathSelector = &nameSelector{names: []string{name}}
+		if name == "*" {
+			selector = &wildcardSelector{}
+		}
+
+		path.steps = append(path.steps, jsonPathStep{recursive: recursive, selector: selector})
+		i = end
+	}
+
+	return path, nil
+}
+
+// closingBracket finds the ']' matching the '[' at start, ignoring quoted content
+func closingBracket(expr string, start int) (int, error) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(expr); i++ {
+		c := expr[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return i, nil
+			}
+		}
+	}
+
+	return -1, fmt.Errorf("unclosed bracket at %d", start)
+}
+
+func parseBracket(fullExpr, content string) (jsonPathSelector, error) {
+	content = strings.TrimSpace(content)
+	switch {
+	case content == "":
+		return nil, jsonPathError(fullExpr, "empty brackets")
+	case content == "*":
+		return &wildcardSelector{}, nil
+	case strings.HasPrefix(content, "?"):
+		inner := strings.TrimSpace(content[1:])
+		if !strings.HasPrefix(inner, "(") || !strings.HasSuffix(inner, ")") {
+			return nil, jsonPathError(fullExpr, "filter must be enclosed in '?(...)'")
+		}
+
+		filter, err := parseFilter(fullExpr, inner[1:len(inner)-1])
+		if err != nil {
+			return nil, err
+		}
+		return &filterSelector{filter: filter}, nil
+	case content[0] == '\'' || content[0] == '"':
+		names := make([]string, 0)
+		for _, part := range splitOutsideQuotes(content, ",") {
+			name, ok := unquote(strings.TrimSpace(part))
+			if !ok {
+				return nil, jsonPathError(fullExpr, fmt.Sprintf("malformed member name %s", part))
+			}
+			names = append(names, name)
+		}
+		return &nameSelector{names: names}, nil
+	case strings.Contains(content, ":"):
+		parts := strings.Split(content, ":")
+		if len(parts) != 2 {
+			return nil, jsonPathError(fullExpr, fmt.Sprintf("unsupported slice [%s]", content))
+		}
+
+		selector := &sliceSelector{}
+		for i, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			v, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, jsonPathError(fullExpr, fmt.Sprintf("malformed slice bound %s", part))
+			}
+			if i == 0 {
+				selector.start = &v
+			} else {
+				selector.end = &v
+			}
+		}
+		return selector, nil
+	}
+
+	indexes := make([]int, 0)
+	for _, part := range strings.Split(content, ",") {
+		idx, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, jsonPathError(fullExpr, fmt.Sprintf("malformed index %s", part))
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return &indexSelector{indexes: indexes}, nil
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) < 2 {
+		return "", false
+	}
+	if (s[0] == '\'' && s[len(s)-1] == '\'') || (s[0] == '"' && s[len(s)-1] == '"') {
+		return s[1 : len(s)-1], true
+	}
+
+	return "", false
+}
+
+func splitOutsideQuotes(s, sep string) []string {
+	ret := make([]string, 0)
+	var quote byte
+	depth := 0
+	last := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+
+		switch c {
+		case '\'', '"':
+			quote = c
+			continue
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		}
+
+		if depth == 0 && strings.HasPrefix(s[i:], sep) {
+			ret = append(ret, s[last:i])
+			i += len(sep) - 1
+			last = i + 1
+		}
+	}
+
+	return append(ret, s[last:])
+}
+
+// jsonPathFilter is a boolean expression evaluated against a candidate node
+type jsonPathFilter interface {
+	matches(node interface{}) bool
+}
+
+type orFilter []jsonPathFilter
+
+func (f orFilter) matches(node interface{}) bool {
+	for _, sub := range f {
+		if sub.matches(node) {
+			return true
+		}
+	}
+	return false
+}
+
+type andFilter []jsonPathFilter
+
+func (f andFilter) matches(node interface{}) bool {
+	for _, sub := range f {
+		if !sub.matches(node) {
+			return false
+		}
+	}
+	return true
+}
+
+type filterOperand struct {
+	path    *jsonPath
+	literal interface{}
+}
+
+func (o *filterOperand) resolve(node interface{}) (interface{}, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+
+	values := o.path.eval(node)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+type comparisonFilter struct {
+	lhs *filterOperand
+	op  string
+	rhs *filterOperand
+}
+
+var filterOperators = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (f *comparisonFilter) matches(node interface{}) bool {
+	lhs, ok := f.lhs.resolve(node)
+	if !ok {
+		return false
+	}
+	if f.op == "" {
+		return true
+	}
+
+	rhs, ok := f.rhs.resolve(node)
+	if !ok {
+		return false
+	}
+
+	lnum, lok := lhs.(float64)
+	rnum, rok := rhs.(float64)
+	if lok && rok {
+		switch f.op {
+		case "==":
+			return lnum == rnum
+		case "!=":
+			return lnum != rnum
+		case "<":
+			return lnum < rnum
+		case "<=":
+			return lnum <= rnum
+		case ">":
+			return lnum > rnum
+		case ">=":
+			return lnum >= rnum
+		}
+	}
+
+	lstr, lok := lhs.(string)
+	rstr, rok := rhs.(string)
+	if lok && rok {
+		switch f.op {
+		case "<":
+			return lstr < rstr
+		case "<=":
+			return lstr <= rstr
+		case ">":
+			return lstr > rstr
+		case ">=":
+			return lstr >= rstr
+		}
+	}
+
+	switch f.op {
+	case "==":
+		return reflect.DeepEqual(lhs, rhs)
+	case "!=":
+		return !reflect.DeepEqual(lhs, rhs)
+	}
+
+	return false
+}
+
+func parseFilter(fullExpr, content string) (jsonPathFilter, error) {
+	orParts := splitOutsideQuotes(content, "||")
+	or := make(orFilter, 0, len(orParts))
+	for _, orPart := range orParts {
+		andParts := splitOutsideQuotes(orPart, "&&")
+		and := make(andFilter, 0, len(andParts))
+		for _, andPart := range andParts {
+			cmp, err := parseComparison(fullExpr, strings.TrimSpace(andPart))
+			if err != nil {
+				return nil, err
+			}
+			and = append(and, cmp)
+		}
+		or = append(or, and)
+	}
+
+	return or, nil
+}
+
+func parseComparison(fullExpr, content string) (jsonPathFilter, error) {
+	for _, op := range filterOperators {
+		parts := splitOutsideQuotes(content, op)
+		if len(parts) == 1 {
+			continue
+		}
+		if len(parts) != 2 {
+			return nil, jsonPathError(fullExpr, fmt.Sprintf("malformed filter %s", content))
+		}
+
+		lhs, err := parseOperand(fullExpr, strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := parseOperand(fullExpr, strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		return &comparisonFilter{lhs: lhs, op: op, rhs: rhs}, nil
+	}
+
+	lhs, err := parseOperand(fullExpr, content)
+	if err != nil {
+		return nil, err
+	}
+	if lhs.path == nil {
+		return nil, jsonPathError(fullExpr, fmt.Sprintf("filter %s must reference '@'", content))
+	}
+
+	return &comparisonFilter{lhs: lhs}, nil
+}
+
+func parseOperand(fullExpr, s string) (*filterOperand, error) {
+	switch {
+	case s == "":
+		return nil, jsonPathError(fullExpr, "empty filter operand")
+	case s[0] == '@':
+		path, err := compilePath(fullExpr, s, '@')
+		if err != nil {
+			return nil, err
+		}
+		return &filterOperand{path: path}, nil
+	case s == "true":
+		return &filterOperand{literal: true}, nil
+	case s == "false":
+		return &filterOperand{literal: false}, nil
+	case s == "null":
+		return &filterOperand{literal: nil}, nil
+	}
+
+	if str, ok := unquote(s); ok {
+		return &filterOperand{literal: str}, nil
+	}
+
+	num, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, jsonPathError(fullExpr, fmt.Sprintf("malformed filter operand %s", s))
+	}
+
+	return &filterOperand{literal: num}, nil
+}
+
+func extractJSONPathValue(src interface{}, expr Expr, exprType string) (interface{}, error) {
+	path, err := compileJSONPath(string(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	matches := path.eval(src)
+	if exprType == "array" && !path.definite() {
+		return matches, nil
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("JSONPath expression %s matched no values", expr)
+	case 1:
+		return assertType(matches[0], exprType)
+	default:
+		return nil, fmt.Errorf("JSONPath expression %s matched %d values, expected 1", expr, len(matches))
+	}
+}
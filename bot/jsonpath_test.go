@@ -0,0 +1,89 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+const jsonPathDocument = `{
+	"code": "200",
+	"player": {"name": "john", "level": 7},
+	"items": [
+		{"id": "i1", "rarity": "common", "price": 10},
+		{"id": "i2", "rarity": "legendary", "price": 500},
+		{"id": "i3", "rarity": "epic", "price": 120}
+	]
+}`
+
+var jsonPathTable = map[string]struct {
+	expr   string
+	typ    string
+	result interface{}
+	hasErr bool
+}{
+	"success_root_member":  {"$.code", "string", "200", false},
+	"success_nested":       {"$.player.name", "string", "john", false},
+	"success_bracket_name": {"$['player']['level']", "int", 7, false},
+	"success_index":        {"$.items[1].id", "string", "i2", false},
+	"success_negative_idx": {"$.items[-1].id", "string", "i3", false},
+	"success_filter_eq":    {"$.items[?(@.rarity=='legendary')].id", "string", "i2", false},
+	"success_filter_num":   {"$.items[?(@.price > 100 && @.rarity != 'epic')].id", "string", "i2", false},
+	"success_wildcard":     {"$.items[*].id", "array", []interface{}{"i1", "i2", "i3"}, false},
+	"success_slice":        {"$.items[0:2].id", "array", []interface{}{"i1", "i2"}, false},
+	"success_recursive":    {"$..level", "int", 7, false},
+	"success_filter_or":    {"$.items[?(@.price < 50 || @.rarity == 'epic')].id", "array", []interface{}{"i1", "i3"}, false},
+	"err_multiple_matches": {"$.items[*].id", "string", nil, true},
+	"err_no_match":         {"$.items[?(@.rarity=='mythic')].id", "string", nil, true},
+	"err_unclosed":         {"$.items[0", "string", nil, true},
+	"err_bad_filter":       {"$.items[?@.rarity]", "string", nil, true},
+	"err_wrong_type":       {"$.player.name", "int", nil, true},
+}
+
+func TestExtractJSONPathValue(t *testing.T) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonPathDocument), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, table := range jsonPathTable {
+		t.Run(name, func(t *testing.T) {
+			val, err := extractValue(doc, Expr(table.expr), table.typ)
+			assert.Equal(t, table.result, val)
+			assert.Equal(t, table.hasErr, err != nil)
+		})
+	}
+}
+
+func TestInvalidJSONPathMentionsExpression(t *testing.T) {
+	_, err := compileJSONPath("$.items[0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "$.items[0")
+}
+
+func TestJSONPathInRequestOperation(t *testing.T) {
+	transport := &recordingTransport{responses: []string{
+		`{"items": [{"id": "i1", "type": "shield"}, {"id": "i2", "type": "sword", "stats": {"damage": 12}}]}`,
+	}}
+	b := newTestBot(transport)
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type: "request",
+		URI:  "inventory.list",
+		Expect: models.ExpectSpec{
+			"$.items[?(@.type=='sword')].stats.damage": {Type: "int", Value: 12},
+			"$.items[*].id": {Type: "array", Value: []interface{}{"i1", "i2"}},
+		},
+		Store: models.StoreSpec{
+			"swordId": {Type: "string", Value: "$.items[?(@.type=='sword')].id"},
+		},
+	})
+	assert.NoError(t, err)
+
+	swordID, ok := b.storage.Get("swordId")
+	assert.True(t, ok)
+	assert.Equal(t, "i2", swordID)
+}
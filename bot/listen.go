@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// listenToPushes waits for pushes on every Listen route, all sharing the
+// operation timeout. In any mode the first push on any route completes the
+// operation, in all mode every route must deliver one in any order and in
+// ordered mode they must also have arrived in the listed order, buffered
+// pushes included. Each push is validated and stored by its route spec
+func (b *SequentialBot) listenToPushes(ctx context.Context, op *models.Operation) error {
+	spec := op.Listen
+	if len(spec.Routes) == 0 {
+		return fmt.Errorf("Listen operation has no routes")
+	}
+
+	mode := spec.Mode
+	if mode == "" {
+		mode = "all"
+	}
+	if mode != "any" && mode != "all" && mode != "ordered" {
+		return fmt.Errorf("Unknown listen mode: %s", mode)
+	}
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]int, len(spec.Routes))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	received := make([]time.Time, len(spec.Routes))
+	deadline := time.After(time.Duration(op.Timeout) * time.Millisecond)
+	for len(pending) > 0 {
+		routes := make([]string, len(pending))
+		for i, idx := range pending {
+			routes[i] = spec.Routes[idx].Route
+		}
+
+		chosen, push, err := client.ReceiveAnyPush(ctx, routes, deadline)
+		if err != nil {
+			return err
+		}
+
+		idx := pending[chosen]
+		received[idx] = push.ReceivedAt
+
+		if err := b.handleListenedPush(spec.Routes[idx], push); err != nil {
+			return err
+		}
+
+		if mode == "any" {
+			return nil
+		}
+		pending = append(pending[:chosen], pending[chosen+1:]...)
+	}
+
+	if mode == "ordered" {
+		for i := 1; i < len(received); i++ {
+			if received[i].Before(received[i-1]) {
+				return fmt.Errorf("Push on route %s arrived before the one on %s", spec.Routes[i].Route, spec.Routes[i-1].Route)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (b *SequentialBot) handleListenedPush(route *models.ListenRoute, push *Push) error {
+	resp, err := push.decode()
+	if err != nil {
+		return err
+	}
+	meta := pushMetadata(route.Route, push)
+	b.lastResponse, b.lastMeta = resp, meta
+
+	if err := validateExpectations(route.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+		return fmt.Errorf("Push on route %s: %s", route.Route, err)
+	}
+
+	return storeData(route.Store, b.storage, resp, meta)
+}
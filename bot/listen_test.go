@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestListenMultipleRoutes(t *testing.T) {
+	routes := []*models.ListenRoute{
+		{Route: "match.found", Store: models.StoreSpec{"matchId": {Type: "string", Value: "id"}}},
+		{Route: "match.start", Expect: models.ExpectSpec{"status": {Type: "string", Value: "started"}}},
+	}
+
+	tables := []struct {
+		name   string
+		mode   string
+		pushes []string
+		err    bool
+	}{
+		{"all in order", "all", []string{"match.found", "match.start"}, false},
+		{"all out of order", "all", []string{"match.start", "match.found"}, false},
+		{"ordered", "ordered", []string{"match.found", "match.start"}, false},
+		{"ordered out of order", "ordered", []string{"match.start", "match.found"}, true},
+		{"any", "any", []string{"match.start"}, false},
+		{"all missing one", "all", []string{"match.found"}, true},
+	}
+
+	payloads := map[string]string{
+		"match.found": `{"id": "m1"}`,
+		"match.start": `{"status": "started"}`,
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+
+			go func() {
+				for _, route := range table.pushes {
+					transport.handler(MsgPushType, 0, route, []byte(payloads[route]))
+				}
+			}()
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:    "listen",
+				Timeout: 100,
+				Listen:  &models.ListenSpec{Mode: table.mode, Routes: routes},
+			})
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if table.mode != "any" {
+				matchID, _ := b.storage.Get("matchId")
+				assert.Equal(t, "m1", matchID)
+			}
+		})
+	}
+}
+
+func TestListenSilent(t *testing.T) {
+	tables := []struct {
+		name   string
+		expect models.ExpectSpec
+		route  string
+		err    bool
+	}{
+		{"no push", nil, "", false},
+		{"push in the window", nil, "room.left", true},
+		{"push on another route", nil, "room.joined", false},
+		{"matching push", models.ExpectSpec{"player": {Type: "string", Value: "p1"}}, "room.left", true},
+		{"push not matching", models.ExpectSpec{"player": {Type: "string", Value: "p2"}}, "room.left", false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+			// pushes nobody listens to must not block their sender
+			b.sessions.clients[defaultSession].pushBufferSize = 1
+
+			if table.route != "" {
+				sent := make(chan struct{})
+				defer func() { <-sent }()
+				go func() {
+					defer close(sent)
+					time.Sleep(10 * time.Millisecond)
+					transport.handler(MsgPushType, 0, table.route, []byte(`{"player": "p1"}`))
+				}()
+			}
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:    "listenSilent",
+				URI:     "room.left",
+				Timeout: 50,
+				Expect:  table.expect,
+			})
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const maskedValue = "***"
+
+var discardLogger = func() *logrus.Logger {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	return l
+}()
+
+// logSampler decides which operations log their full detail. Only a
+// log.sampleRate fraction of them do, metrics are still reported for all.
+// Values of the log.maskKeys keys are masked in the logged args and responses
+type logSampler struct {
+	rate     float64
+	maskKeys map[string]bool
+	mutex    sync.Mutex
+	random   *rand.Rand
+}
+
+func newLogSampler(config *viper.Viper) *logSampler {
+	rate := 1.0
+	if config.IsSet("log.sampleRate") {
+		rate = config.GetFloat64("log.sampleRate")
+	}
+
+	maskKeys := make(map[string]bool)
+	for _, key := range config.GetStringSlice("log.maskKeys") {
+		maskKeys[key] = true
+	}
+
+	return &logSampler{
+		rate:     rate,
+		maskKeys: maskKeys,
+		random:   rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// logger returns logger when the operation is sampled, a discarding logger
+// otherwise
+func (s *logSampler) logger(logger logrus.FieldLogger) logrus.FieldLogger {
+	if s == nil || s.rate >= 1 {
+		return logger
+	}
+
+	s.mutex.Lock()
+	sampled := s.random.Float64() < s.rate
+	s.mutex.Unlock()
+
+	if sampled {
+		return logger
+	}
+
+	return discardLogger
+}
+
+// debugging returns whether logger, as returned by logger, writes debug
+// entries. Values are only masked for the entries it writes
+func debugging(logger logrus.FieldLogger) bool {
+	switch l := logger.(type) {
+	case *logrus.Logger:
+		return l != discardLogger && l.Level >= logrus.DebugLevel
+	case *logrus.Entry:
+		return l.Logger.Level >= logrus.DebugLevel
+	}
+
+	return true
+}
+
+// mask returns a copy of value with the mask keys values replaced
+func (s *logSampler) mask(value interface{}) interface{} {
+	if s == nil || len(s.maskKeys) == 0 {
+		return value
+	}
+
+	switch val := value.(type) {
+	case Response:
+		return s.mask(map[string]interface{}(val))
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if s.maskKeys[k] {
+				ret[k] = maskedValue
+				continue
+			}
+			ret[k] = s.mask(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, item := range val {
+			ret[i] = s.mask(item)
+		}
+		return ret
+	}
+
+	return value
+}
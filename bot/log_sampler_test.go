@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSamplerMask(t *testing.T) {
+	config := viper.New()
+	config.Set("log.maskKeys", []string{"token", "password"})
+	sampler := newLogSampler(config)
+
+	value := Response{
+		"user":  map[string]interface{}{"name": "bob", "password": "secret"},
+		"token": "abc",
+		"items": []interface{}{map[string]interface{}{"token": "def"}},
+	}
+
+	masked := sampler.mask(value)
+	assert.Equal(t, map[string]interface{}{
+		"user":  map[string]interface{}{"name": "bob", "password": maskedValue},
+		"token": maskedValue,
+		"items": []interface{}{map[string]interface{}{"token": maskedValue}},
+	}, masked)
+	assert.Equal(t, "abc", value["token"])
+}
+
+func TestDebugging(t *testing.T) {
+	debug := logrus.New()
+	debug.Level = logrus.DebugLevel
+	info := logrus.New()
+	info.Level = logrus.InfoLevel
+
+	assert.True(t, debugging(debug))
+	assert.True(t, debugging(debug.WithField("bot", 1)))
+	assert.False(t, debugging(info))
+	assert.False(t, debugging(info.WithField("bot", 1)))
+
+	sampler := &logSampler{rate: 0, random: rand.New(rand.NewSource(1))}
+	assert.False(t, debugging(sampler.logger(debug)), "dropped samples are not logged")
+}
+
+func TestLogSamplerRate(t *testing.T) {
+	tables := []struct {
+		name string
+		rate float64
+	}{
+		{"sample all", 1},
+		{"sample none", 0},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			config := viper.New()
+			config.Set("log.sampleRate", table.rate)
+			sampler := newLogSampler(config)
+			logger := discardLogger.WithField("bot", 1)
+
+			sampled := sampler.logger(logger) == logger
+			assert.Equal(t, table.rate == 1, sampled)
+		})
+	}
+}
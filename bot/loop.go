@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// runLoop runs the loop operations up to Count times, waiting IntervalMs
+// between iterations. With an Until condition, checked against the storage
+// after each iteration, the loop stops as soon as it holds and fails if it
+// never does
+func (b *SequentialBot) runLoop(ctx context.Context, op *models.Operation) error {
+	loop := op.Loop
+	if loop == nil {
+		return fmt.Errorf("Missing loop spec")
+	}
+	if loop.Count <= 0 {
+		return fmt.Errorf("Loop count must be positive")
+	}
+
+	for iteration := 0; iteration < loop.Count; iteration++ {
+		if iteration > 0 && loop.IntervalMs > 0 {
+			select {
+			case <-time.After(time.Duration(loop.IntervalMs) * time.Millisecond):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		for idx, loopOp := range loop.Operations {
+			if err := b.runOperation(ctx, loopOp); err != nil {
+				if _, ok := err.(*jumpError); ok {
+					return err
+				}
+				return fmt.Errorf("Loop iteration %d operation %d (%s %s) failed: %s", iteration, idx, loopOp.Type, loopOp.URI, err)
+			}
+		}
+
+		if len(loop.Until) > 0 && validateExpectations(loop.Until, Response(*b.storage), nil, b.storage, b.strictRefs) == nil {
+			b.logger.Debugf("Loop condition met after %d iterations", iteration+1)
+			return nil
+		}
+	}
+
+	if len(loop.Until) > 0 {
+		return fmt.Errorf("Loop condition not met after %d iterations", loop.Count)
+	}
+
+	return nil
+}
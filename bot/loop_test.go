@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestLoopOperation(t *testing.T) {
+	poll := &models.Operation{
+		Type:  "request",
+		URI:   "matchmaking.poll",
+		Store: models.StoreSpec{"status": {Type: "string", Value: "status"}},
+	}
+	ready := models.ExpectSpec{"status": {Type: "string", Value: "ready"}}
+
+	tables := []struct {
+		name  string
+		loop  *models.LoopSpec
+		sends int
+		err   bool
+	}{
+		{"until met", &models.LoopSpec{Count: 5, IntervalMs: 1, Operations: []*models.Operation{poll}, Until: ready}, 3, false},
+		{"until never met", &models.LoopSpec{Count: 2, Operations: []*models.Operation{poll}, Until: ready}, 2, true},
+		{"plain repeat", &models.LoopSpec{Count: 4, Operations: []*models.Operation{poll}}, 4, false},
+		{"no count", &models.LoopSpec{Operations: []*models.Operation{poll}}, 0, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: []string{`{"status": "waiting"}`, `{"status": "waiting"}`, `{"status": "ready"}`}}
+			b := newTestBot(transport)
+
+			err := b.runOperation(context.Background(), &models.Operation{Type: "loop", Loop: table.loop})
+			assert.Len(t, transport.sent, table.sends)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// maxCallDepth bounds nested macro calls, catching recursive macros
+const maxCallDepth = 16
+
+// runCall runs the macro named by the operation uri, its args are bound as
+// ${params.<name>} while the macro runs
+func (b *SequentialBot) runCall(ctx context.Context, op *models.Operation) error {
+	macro, ok := b.spec.Macros[op.URI]
+	if !ok {
+		return fmt.Errorf("Unknown macro: %s", op.URI)
+	}
+
+	if b.callDepth >= maxCallDepth {
+		return fmt.Errorf("Macro %s exceeded max call depth %d", op.URI, maxCallDepth)
+	}
+
+	params, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	b.logger.Debugf("Calling macro %s", op.URI)
+	restore := b.storage.withParams(params)
+	b.callDepth++
+	defer func() {
+		b.callDepth--
+		restore()
+	}()
+
+	for idx, macroOp := range macro {
+		if err := b.runOperation(ctx, macroOp); err != nil {
+			if _, ok := err.(*jumpError); ok {
+				return err
+			}
+			return fmt.Errorf("Macro %s operation %d (%s %s) failed: %s", op.URI, idx, macroOp.Type, macroOp.URI, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package bot
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestRunCall(t *testing.T) {
+	buy := &models.Operation{
+		Type: "request",
+		URI:  "shop.buy",
+		Args: map[string]interface{}{
+			"itemId": map[string]interface{}{"type": "string", "value": "${params.itemId}"},
+		},
+	}
+
+	tables := []struct {
+		name   string
+		macros map[string][]*models.Operation
+		call   *models.Operation
+		sent   []string
+		err    string
+	}{
+		{
+			name:   "binds params",
+			macros: map[string][]*models.Operation{"buyItem": {buy}},
+			call: &models.Operation{Type: "call", URI: "buyItem", Args: map[string]interface{}{
+				"itemId": map[string]interface{}{"type": "string", "value": "sword"},
+			}},
+			sent: []string{`{"itemId":"sword"}`},
+		},
+		{
+			name: "nested calls",
+			macros: map[string][]*models.Operation{
+				"buyItem": {buy},
+				"buyTwo": {
+					{Type: "call", URI: "buyItem", Args: map[string]interface{}{
+						"itemId": map[string]interface{}{"type": "string", "value": "${params.first}"},
+					}},
+					{Type: "call", URI: "buyItem", Args: map[string]interface{}{
+						"itemId": map[string]interface{}{"type": "string", "value": "shield"},
+					}},
+				},
+			},
+			call: &models.Operation{Type: "call", URI: "buyTwo", Args: map[string]interface{}{
+				"first": map[string]interface{}{"type": "string", "value": "sword"},
+			}},
+			sent: []string{`{"itemId":"sword"}`, `{"itemId":"shield"}`},
+		},
+		{
+			name:   "unbound param",
+			macros: map[string][]*models.Operation{"buyItem": {buy}},
+			call:   &models.Operation{Type: "call", URI: "buyItem", Args: map[string]interface{}{}},
+			err:    "Param itemId not bound",
+		},
+		{
+			name:   "recursion",
+			macros: map[string][]*models.Operation{"loop": {{Type: "call", URI: "loop", Args: map[string]interface{}{}}}},
+			call:   &models.Operation{Type: "call", URI: "loop", Args: map[string]interface{}{}},
+			err:    "exceeded max call depth",
+		},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+			b.spec = &models.Spec{Macros: table.macros}
+
+			err := b.runOperation(context.Background(), table.call)
+			if table.err != "" {
+				assert.Error(t, err)
+				assert.True(t, strings.Contains(err.Error(), table.err), err.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.sent, transport.sent)
+			assert.Empty(t, *b.storage)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// mockPush is a push replayed by the mock transport, Delay is in milliseconds
+type mockPush struct {
+	Route string      `json:"route"`
+	Data  interface{} `json:"data"`
+	Delay int         `json:"delay"`
+}
+
+// mockFixture is a recorded response for a route, matched against the
+// request args when they are present
+type mockFixture struct {
+	Route    string                 `json:"route"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	Response interface{}            `json:"response"`
+	Pushes   []*mockPush            `json:"pushes,omitempty"`
+}
+
+// mockFixtures holds the request fixtures and the pushes sent as soon as the
+// client starts listening
+type mockFixtures struct {
+	Requests []*mockFixture `json:"requests"`
+	Pushes   []*mockPush    `json:"pushes,omitempty"`
+}
+
+var (
+	mockFixturesMutex sync.Mutex
+	mockFixturesCache = map[string]*mockFixtures{}
+)
+
+// loadMockFixtures reads the fixtures file once, sharing it between bots
+func loadMockFixtures(path string) (*mockFixtures, error) {
+	mockFixturesMutex.Lock()
+	defer mockFixturesMutex.Unlock()
+	if fixtures, ok := mockFixturesCache[path]; ok {
+		return fixtures, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read mock fixtures: %s", err)
+	}
+
+	var fixtures mockFixtures
+	if err := json.Unmarshal(raw, &fixtures); err != nil {
+		return nil, fmt.Errorf("Malformed mock fixtures %s: %s", path, err)
+	}
+
+	mockFixturesCache[path] = &fixtures
+	return &fixtures, nil
+}
+
+// mockTransport replays canned responses and pushes, so specs can run
+// without a live server
+type mockTransport struct {
+	fixtures  *mockFixtures
+	mutex     sync.Mutex
+	nextID    uint
+	connected bool
+	handler   messageHandler
+}
+
+func newMockTransport(fixtures *mockFixtures) *mockTransport {
+	return &mockTransport{
+		fixtures:  fixtures,
+		connected: true,
+	}
+}
+
+func (t *mockTransport) findFixture(route string, data []byte) (*mockFixture, error) {
+	var args map[string]interface{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &args); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, fixture := range t.fixtures.Requests {
+		if fixture.Route != route {
+			continue
+		}
+		if fixture.Args == nil || reflect.DeepEqual(normalizeValue(fixture.Args), normalizeValue(args)) {
+			return fixture, nil
+		}
+	}
+
+	return nil, fmt.Errorf("No mock fixture for route %s with args %s", route, string(data))
+}
+
+func (t *mockTransport) deliver(msgType byte, id uint, route string, payload interface{}, delay int) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	handler := t.handler
+	t.mutex.Unlock()
+	if handler == nil {
+		return fmt.Errorf("Mock transport is not listening")
+	}
+
+	time.AfterFunc(time.Duration(delay)*time.Millisecond, func() {
+		if t.Connected() {
+			handler(msgType, id, route, data)
+		}
+	})
+
+	return nil
+}
+
+func (t *mockTransport) SendRequest(route string, data []byte) (uint, error) {
+	fixture, err := t.findFixture(route, data)
+	if err != nil {
+		return 0, err
+	}
+
+	t.mutex.Lock()
+	t.nextID++
+	id := t.nextID
+	t.mutex.Unlock()
+
+	if err := t.deliver(MsgResponseType, id, route, fixture.Response, 0); err != nil {
+		return 0, err
+	}
+
+	for _, push := range fixture.Pushes {
+		if err := t.deliver(MsgPushType, 0, push.Route, push.Data, push.Delay); err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+func (t *mockTransport) SendNotify(route string, data []byte) error {
+	return nil
+}
+
+func (t *mockTransport) Listen(handler messageHandler) {
+	t.mutex.Lock()
+	t.handler = handler
+	t.mutex.Unlock()
+
+	for _, push := range t.fixtures.Pushes {
+		t.deliver(MsgPushType, 0, push.Route, push.Data, push.Delay)
+	}
+}
+
+func (t *mockTransport) Connected() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.connected
+}
+
+func (t *mockTransport) Disconnect() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.connected = false
+}
@@ -0,0 +1,55 @@
+package bot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockFixturesJSON = `{
+	"requests": [
+		{"route": "room.join", "args": {"roomId": "r1"}, "response": {"code": "200"},
+		 "pushes": [{"route": "room.joined", "data": {"roomId": "r1"}, "delay": 10}]},
+		{"route": "room.join", "response": {"code": "404"}}
+	]
+}`
+
+func newMockPClient(t *testing.T) *PClient {
+	f, err := ioutil.TempFile("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.WriteString(mockFixturesJSON)
+
+	pclient, err := NewPClient("", &PClientOptions{Transport: "mock", FixturesPath: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(f.Name())
+	pclient.StartListening()
+	return pclient
+}
+
+func TestMockTransportReplaysResponses(t *testing.T) {
+	pclient := newMockPClient(t)
+	defer pclient.Disconnect()
+
+	resp, _, _, err := pclient.Request(context.Background(), "room.join", []byte(`{"roomId": "r1"}`), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "200", resp["code"])
+
+	push, _, err := pclient.ReceivePush(context.Background(), "room.joined", 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, "r1", push["roomId"])
+
+	resp, _, _, err = pclient.Request(context.Background(), "room.join", []byte(`{"roomId": "other"}`), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "404", resp["code"])
+
+	_, _, _, err = pclient.Request(context.Background(), "room.leave", []byte(`{}`), 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// mss is the payload of a TCP segment, lost packets are drawn per segment
+const mss = 1460
+
+// minRetransmitTimeout is the lowest TCP retransmission timeout of linux
+const minRetransmitTimeout = 200 * time.Millisecond
+
+// networkProfiles are the network conditions of common mobile and wifi
+// connections
+var networkProfiles = map[string]models.NetworkSpec{
+	"3g":       {LatencyMs: 100, JitterMs: 50, BandwidthKbps: 750, LossRate: 0.01},
+	"4g":       {LatencyMs: 50, JitterMs: 20, BandwidthKbps: 4000, LossRate: 0.005},
+	"poorWifi": {LatencyMs: 150, JitterMs: 100, BandwidthKbps: 1000, LossRate: 0.05},
+}
+
+// withNetwork overrides the network conditions set by the spec
+func (o *PClientOptions) withNetwork(spec *models.NetworkSpec) *PClientOptions {
+	if spec == nil {
+		return o
+	}
+
+	network := &models.NetworkSpec{}
+	if o.Network != nil {
+		*network = *o.Network
+	}
+
+	if spec.Profile != "" {
+		network.Profile = spec.Profile
+	}
+	if spec.LatencyMs != 0 {
+		network.LatencyMs = spec.LatencyMs
+	}
+	if spec.JitterMs != 0 {
+		network.JitterMs = spec.JitterMs
+	}
+	if spec.BandwidthKbps != 0 {
+		network.BandwidthKbps = spec.BandwidthKbps
+	}
+	if spec.LossRate != 0 {
+		network.LossRate = spec.LossRate
+	}
+
+	o.Network = network
+	return o
+}
+
+// networkConditions resolves the profile of network, its own fields
+// overriding the profile ones. It is nil when the network is not shaped
+func networkConditions(network *models.NetworkSpec) (*models.NetworkSpec, error) {
+	if network == nil {
+		return nil, nil
+	}
+
+	conditions := models.NetworkSpec{}
+	if network.Profile != "" {
+		profile, ok := networkProfiles[network.Profile]
+		if !ok {
+			return nil, fmt.Errorf("Unknown network profile: %s", network.Profile)
+		}
+		conditions = profile
+	}
+	if network.LatencyMs != 0 {
+		conditions.LatencyMs = network.LatencyMs
+	}
+	if network.JitterMs != 0 {
+		conditions.JitterMs = network.JitterMs
+	}
+	if network.BandwidthKbps != 0 {
+		conditions.BandwidthKbps = network.BandwidthKbps
+	}
+	if network.LossRate != 0 {
+		conditions.LossRate = network.LossRate
+	}
+
+	if conditions.LatencyMs < 0 || conditions.JitterMs < 0 || conditions.BandwidthKbps < 0 {
+		return nil, fmt.Errorf("Malformed network conditions: latencyMs, jitterMs and bandwidthKbps must not be negative")
+	}
+	if conditions.LossRate < 0 || conditions.LossRate >= 1 {
+		return nil, fmt.Errorf("Malformed network conditions: lossRate must be in [0, 1)")
+	}
+	if conditions == (models.NetworkSpec{}) {
+		return nil, nil
+	}
+
+	return &conditions, nil
+}
+
+// shaper delays the data sent one way through a connection as the network
+// conditions would: it is sent at the bandwidth, arrives after the latency
+// plus or minus the jitter, never before the data sent earlier, and lost
+// packets wait a retransmission timeout. A nil shaper delays nothing
+type shaper struct {
+	latency        time.Duration
+	jitter         time.Duration
+	bytesPerSecond float64
+	lossRate       float64
+	random         *rand.Rand
+
+	linkFree time.Time
+	last     time.Time
+}
+
+func newShaper(conditions *models.NetworkSpec, seed int64) *shaper {
+	if conditions == nil {
+		return nil
+	}
+
+	return &shaper{
+		latency:        time.Duration(conditions.LatencyMs) * time.Millisecond,
+		jitter:         time.Duration(conditions.JitterMs) * time.Millisecond,
+		bytesPerSecond: float64(conditions.BandwidthKbps) * 1000 / 8,
+		lossRate:       conditions.LossRate,
+		random:         rand.New(rand.NewSource(seed)),
+	}
+}
+
+// deliverAt returns when n bytes read at at reach the other side
+func (s *shaper) deliverAt(at time.Time, n int) time.Time {
+	if s == nil {
+		return at
+	}
+
+	sent := at
+	if s.linkFree.After(sent) {
+		sent = s.linkFree
+	}
+	if s.bytesPerSecond > 0 {
+		sent = sent.Add(time.Duration(float64(n) / s.bytesPerSecond * float64(time.Second)))
+	}
+	s.linkFree = sent
+
+	delay := s.latency
+	if s.jitter > 0 {
+		delay += time.Duration(s.random.Int63n(int64(2*s.jitter)+1)) - s.jitter
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	if s.lost(n) {
+		delay += minRetransmitTimeout + 2*s.latency
+	}
+
+	deliver := sent.Add(delay)
+	if deliver.Before(s.last) {
+		deliver = s.last
+	}
+	s.last = deliver
+	return deliver
+}
+
+// lost returns whether any of the packets carrying n bytes is lost
+func (s *shaper) lost(n int) bool {
+	if s.lossRate <= 0 {
+		return false
+	}
+
+	packets := math.Ceil(float64(n) / mss)
+	return s.random.Float64() < 1-math.Pow(1-s.lossRate, packets)
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestNetworkConditions(t *testing.T) {
+	tables := []struct {
+		name       string
+		network    *models.NetworkSpec
+		conditions *models.NetworkSpec
+		err        bool
+	}{
+		{"not shaped", nil, nil, false},
+		{"all zero", &models.NetworkSpec{}, nil, false},
+		{"profile", &models.NetworkSpec{Profile: "3g"}, &models.NetworkSpec{LatencyMs: 100, JitterMs: 50, BandwidthKbps: 750, LossRate: 0.01}, false},
+		{"profile override", &models.NetworkSpec{Profile: "4g", LatencyMs: 80}, &models.NetworkSpec{LatencyMs: 80, JitterMs: 20, BandwidthKbps: 4000, LossRate: 0.005}, false},
+		{"without profile", &models.NetworkSpec{LatencyMs: 30}, &models.NetworkSpec{LatencyMs: 30}, false},
+		{"unknown profile", &models.NetworkSpec{Profile: "5g"}, nil, true},
+		{"negative latency", &models.NetworkSpec{LatencyMs: -1}, nil, true},
+		{"certain loss", &models.NetworkSpec{LossRate: 1}, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			conditions, err := networkConditions(table.network)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, table.conditions, conditions)
+		})
+	}
+}
+
+func TestPClientOptionsNetwork(t *testing.T) {
+	config := viper.New()
+	assert.Nil(t, NewPClientOptions(config).Network)
+
+	config.Set("client.network", map[string]interface{}{"profile": "4g", "latencyMs": 80, "lossRate": 0.02})
+	assert.Equal(t, &models.NetworkSpec{Profile: "4g", LatencyMs: 80, LossRate: 0.02}, NewPClientOptions(config).Network)
+}
+
+func TestWithNetwork(t *testing.T) {
+	opts := &PClientOptions{Network: &models.NetworkSpec{Profile: "3g", LatencyMs: 10}}
+	opts.withNetwork(&models.NetworkSpec{Profile: "poorWifi", LossRate: 0.1})
+	assert.Equal(t, &models.NetworkSpec{Profile: "poorWifi", LatencyMs: 10, LossRate: 0.1}, opts.Network)
+}
+
+func TestShaperDeliverAt(t *testing.T) {
+	start := time.Unix(0, 0)
+	ms := func(n int) time.Time { return start.Add(time.Duration(n) * time.Millisecond) }
+
+	tables := []struct {
+		name       string
+		conditions *models.NetworkSpec
+		reads      []time.Time
+		sizes      []int
+		delivered  []time.Time
+	}{
+		{"not shaped", nil, []time.Time{ms(0)}, []int{100}, []time.Time{ms(0)}},
+		{"latency", &models.NetworkSpec{LatencyMs: 50}, []time.Time{ms(0), ms(10)}, []int{100, 100}, []time.Time{ms(50), ms(60)}},
+		// 8kbps sends a byte per millisecond
+		{"bandwidth queues", &models.NetworkSpec{BandwidthKbps: 8}, []time.Time{ms(0), ms(0), ms(500)}, []int{100, 100, 10}, []time.Time{ms(100), ms(200), ms(510)}},
+		{"lost packets wait a retransmission", &models.NetworkSpec{LatencyMs: 10, LossRate: 0.999999}, []time.Time{ms(0)}, []int{100}, []time.Time{ms(230)}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			s := newShaper(table.conditions, 1)
+			for i, at := range table.reads {
+				assert.Equal(t, table.delivered[i], s.deliverAt(at, table.sizes[i]))
+			}
+		})
+	}
+}
+
+func TestShaperJitterKeepsOrder(t *testing.T) {
+	s := newShaper(&models.NetworkSpec{LatencyMs: 50, JitterMs: 40}, 1)
+	start := time.Now()
+	last := start
+	for i := 0; i < 100; i++ {
+		at := s.deliverAt(start.Add(time.Duration(i)*time.Millisecond), 10)
+		assert.False(t, at.Before(last))
+		assert.True(t, at.Sub(start) >= 10*time.Millisecond)
+		last = at
+	}
+}
+
+func TestFaultProxyLatency(t *testing.T) {
+	upstream, _ := echoServer(t)
+	p, err := newFaultProxy(upstream, &models.NetworkSpec{LatencyMs: 40}, 0)
+	assert.NoError(t, err)
+	defer p.close()
+	conn := dialProxy(t, p)
+
+	start := time.Now()
+	reply, err := echo(conn, "slow")
+	assert.NoError(t, err)
+	assert.Equal(t, "slow", reply)
+	assert.True(t, time.Since(start) >= 80*time.Millisecond)
+}
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// onError actions
+const (
+	OnErrorAbort    = "abort"
+	OnErrorContinue = "continue"
+	OnErrorRetry    = "retry"
+	OnErrorGoto     = "goto"
+)
+
+// defaultOnErrorAttempts is how many times retry runs an operation in total
+// and defaultMaxJumps how many jumps a bot may take, unless bot.maxJumps
+// says otherwise
+const (
+	defaultOnErrorAttempts = 3
+	defaultMaxJumps        = 1000
+)
+
+var onErrorExpr = regexp.MustCompile(`^(abort|continue|retry|goto)(?:\((.*)\))?$`)
+
+// OnErrorPolicy is a parsed onError policy: its action, the label goto
+// carries on from and how many times retry runs the operation
+type OnErrorPolicy struct {
+	Action   string
+	Label    string
+	Attempts int
+}
+
+// ParseOnError parses an onError policy, abort when it is empty
+func ParseOnError(policy string) (*OnErrorPolicy, error) {
+	if policy == "" {
+		return &OnErrorPolicy{Action: OnErrorAbort}, nil
+	}
+
+	m := onErrorExpr.FindStringSubmatch(policy)
+	if m == nil {
+		return nil, fmt.Errorf("Invalid onError policy %q", policy)
+	}
+
+	p := &OnErrorPolicy{Action: m[1]}
+	hasArg := len(policy) > len(m[1])
+	switch p.Action {
+	case OnErrorRetry:
+		p.Attempts = defaultOnErrorAttempts
+		if hasArg {
+			attempts, err := strconv.Atoi(m[2])
+			if err != nil || attempts < 1 {
+				return nil, fmt.Errorf("Invalid onError policy %q: attempts must be a positive int", policy)
+			}
+			p.Attempts = attempts
+		}
+	case OnErrorGoto:
+		if m[2] == "" {
+			return nil, fmt.Errorf("Invalid onError policy %q: missing label", policy)
+		}
+		p.Label = m[2]
+	default:
+		if hasArg {
+			return nil, fmt.Errorf("Invalid onError policy %q", policy)
+		}
+	}
+
+	return p, nil
+}
+
+// jumpError is returned by goto functions, the sequential operations carry
+// on from the one labeled label. It only fails the operations that can not
+// jump, those outside of the sequential ones
+type jumpError struct {
+	label string
+}
+
+func (e *jumpError) Error() string {
+	return fmt.Sprintf("goto %s outside of the sequential operations", e.label)
+}
+
+// gotoLabel returns the jump to the operation labeled by the label arg
+func (b *SequentialBot) gotoLabel(op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	label, _ := args["label"].(string)
+	if label == "" {
+		return fmt.Errorf("goto needs a label")
+	}
+	return &jumpError{label: label}
+}
+
+// runAt runs the sequential operation at pos of order. It returns the
+// position to carry on from, after the operation or where a goto jumped to
+// once its onError policy was applied, or the error aborting the spec
+func (b *SequentialBot) runAt(ctx context.Context, order []int, pos int) (int, error) {
+	idx := order[pos]
+	err := b.runStep(ctx, idx, b.spec.SequentialOperations[idx])
+	if jump, ok := err.(*jumpError); ok {
+		return b.jump(order, jump.label)
+	}
+	if err != nil {
+		return b.onError(ctx, order, pos, err)
+	}
+	return pos + 1, nil
+}
+
+// onError applies the onError policy of the operation at pos, or the spec
+// one, to the error it failed with. It returns the position to carry on
+// from or the error aborting the spec. Operations are never retried nor
+// skipped once ctx is done
+func (b *SequentialBot) onError(ctx context.Context, order []int, pos int, err error) (int, error) {
+	op := b.spec.SequentialOperations[order[pos]]
+	policy := op.OnError
+	if policy == "" {
+		policy = b.spec.OnError
+	}
+	p, perr := ParseOnError(policy)
+	if perr != nil {
+		return 0, perr
+	}
+	if ctx.Err() != nil {
+		return 0, err
+	}
+
+	switch p.Action {
+	case OnErrorRetry:
+		for attempt := 2; attempt <= p.Attempts; attempt++ {
+			if op.Retry != nil {
+				select {
+				case <-time.After(backoffDelay(op.Retry.Backoff, attempt-1)):
+				case <-ctx.Done():
+					return 0, err
+				}
+			}
+			b.logger.Debugf("Operation %d failed, running it again, attempt %d of %d: %s", order[pos], attempt, p.Attempts, err)
+			err = b.runStep(ctx, order[pos], op)
+			if jump, ok := err.(*jumpError); ok {
+				return b.jump(order, jump.label)
+			}
+			if err == nil {
+				return pos + 1, nil
+			}
+		}
+		return 0, err
+	case OnErrorContinue:
+		b.continueAfter(err)
+		return pos + 1, nil
+	case OnErrorGoto:
+		b.continueAfter(err)
+		return b.jump(order, p.Label)
+	}
+
+	return 0, err
+}
+
+// continueAfter records a failure the spec carries on after
+func (b *SequentialBot) continueAfter(err error) {
+	b.logger.WithError(err).Warn("Operation failed, continuing")
+	if b.continued == 0 {
+		b.firstContinued = err
+	}
+	b.continued++
+}
+
+// continuedError fails the bot when operations failed along the way
+func (b *SequentialBot) continuedError() error {
+	if b.continued == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d operations failed and the spec continued, first: %s", b.continued, b.firstContinued)
+}
+
+// jump returns the position of the operation labeled label in order,
+// failing once the bot took more than bot.maxJumps jumps, by goto functions
+// or onError policies
+func (b *SequentialBot) jump(order []int, label string) (int, error) {
+	maxJumps := defaultMaxJumps
+	if b.config != nil && b.config.IsSet("bot.maxJumps") {
+		maxJumps = b.config.GetInt("bot.maxJumps")
+	}
+	b.jumps++
+	if b.jumps > maxJumps {
+		return 0, fmt.Errorf("Exceeded %d jumps, the last one to label %s", maxJumps, label)
+	}
+
+	for pos, idx := range order {
+		if b.spec.SequentialOperations[idx].Label == label {
+			return pos, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown label %s", label)
+}
@@ -0,0 +1,146 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestParseOnError(t *testing.T) {
+	tables := []struct {
+		policy string
+		parsed *OnErrorPolicy
+		err    string
+	}{
+		{"", &OnErrorPolicy{Action: OnErrorAbort}, ""},
+		{"continue", &OnErrorPolicy{Action: OnErrorContinue}, ""},
+		{"retry", &OnErrorPolicy{Action: OnErrorRetry, Attempts: 3}, ""},
+		{"retry(5)", &OnErrorPolicy{Action: OnErrorRetry, Attempts: 5}, ""},
+		{"goto(enqueue)", &OnErrorPolicy{Action: OnErrorGoto, Label: "enqueue"}, ""},
+		{"goto", nil, `Invalid onError policy "goto": missing label`},
+		{"retry(x)", nil, `Invalid onError policy "retry(x)": attempts must be a positive int`},
+		{"continue(1)", nil, `Invalid onError policy "continue(1)"`},
+		{"ignore", nil, `Invalid onError policy "ignore"`},
+	}
+
+	for _, table := range tables {
+		t.Run(table.policy, func(t *testing.T) {
+			parsed, err := ParseOnError(table.policy)
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, table.parsed, parsed)
+		})
+	}
+}
+
+func TestOnError(t *testing.T) {
+	expectOK := models.ExpectSpec{"code": {Type: "string", Value: "200"}}
+	request := func(label, onError string) *models.Operation {
+		return &models.Operation{Type: "request", URI: "room.join", Expect: expectOK, Label: label, OnError: onError}
+	}
+
+	tables := []struct {
+		name      string
+		specError string
+		ops       []*models.Operation
+		responses []string
+		sent      int
+		err       string
+	}{
+		{"abort by default", "", []*models.Operation{request("", ""), request("", "")},
+			[]string{`{"code": "500"}`}, 1, "200 != 500"},
+		{"continue", "", []*models.Operation{request("", "continue"), request("", "continue")},
+			[]string{`{"code": "500"}`}, 2, "2 operations failed and the spec continued, first: "},
+		{"spec continue", "continue", []*models.Operation{request("", ""), request("", "abort")},
+			[]string{`{"code": "500"}`}, 2, "200 != 500"},
+		{"retry until it passes", "", []*models.Operation{request("", "retry(3)")},
+			[]string{`{"code": "500"}`, `{"code": "500"}`, `{"code": "200"}`}, 3, ""},
+		{"retry gives up", "", []*models.Operation{request("", "retry(2)"), request("", "")},
+			[]string{`{"code": "500"}`}, 2, "200 != 500"},
+		{"goto", "", []*models.Operation{request("start", ""), request("", "goto(start)")},
+			[]string{`{"code": "200"}`, `{"code": "500"}`, `{"code": "200"}`, `{"code": "200"}`}, 4,
+			"1 operations failed and the spec continued, first: "},
+		{"goto max jumps", "", []*models.Operation{request("start", ""), request("", "goto(start)")},
+			[]string{`{"code": "200"}`, `{"code": "500"}`}, 6, "Exceeded 2 jumps, the last one to label start"},
+		{"goto unknown label", "", []*models.Operation{request("", "goto(end)")},
+			[]string{`{"code": "500"}`}, 1, "Unknown label end"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: table.responses}
+			b := newTestBot(transport)
+			b.config = viper.New()
+			b.config.Set("bot.maxJumps", 2)
+			b.spec = &models.Spec{SequentialOperations: table.ops, OnError: table.specError}
+
+			err := b.Run(context.Background())
+			if table.err != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, transport.sent, table.sent)
+		})
+	}
+}
+
+func gotoOp(label string) *models.Operation {
+	return &models.Operation{
+		Type: "function",
+		URI:  "goto",
+		Args: map[string]interface{}{"label": map[string]interface{}{"type": "string", "value": label}},
+	}
+}
+
+func TestGoto(t *testing.T) {
+	RegisterFunction("testCount", func(args map[string]interface{}, store Storage) (map[string]interface{}, error) {
+		n, _ := store.Get("n")
+		count, _ := n.(int)
+		store.Set("n", count+1)
+		return nil, nil
+	})
+
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.spec = &models.Spec{SequentialOperations: []*models.Operation{
+		{Type: "function", URI: "testCount", Label: "enqueue"},
+		{Type: "condition", Condition: &models.ConditionSpec{
+			If:   models.ExpectSpec{"n": {Type: "int", Value: 3}},
+			Else: []*models.Operation{gotoOp("enqueue")},
+		}},
+		{Type: "request", URI: "room.join"},
+	}}
+
+	assert.NoError(t, b.Run(context.Background()))
+	n, _ := b.storage.Get("n")
+	assert.Equal(t, 3, n)
+	assert.Len(t, transport.sent, 1)
+	assert.Equal(t, 2, b.jumps)
+}
+
+func TestGotoMaxJumps(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.config = viper.New()
+	b.config.Set("bot.maxJumps", 2)
+	b.spec = &models.Spec{SequentialOperations: []*models.Operation{
+		{Type: "request", URI: "room.join", Label: "start"},
+		gotoOp("start"),
+	}}
+
+	assert.EqualError(t, b.Run(context.Background()), "Exceeded 2 jumps, the last one to label start")
+	assert.Len(t, transport.sent, 3)
+}
+
+func TestGotoOutsideSequentialOperations(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	assert.EqualError(t, b.runOperation(context.Background(), gotoOp("start")), "goto start outside of the sequential operations")
+}
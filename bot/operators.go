@@ -0,0 +1,221 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// expectOperators are the operators an expected value may use instead of a
+// literal, e.g. {"$gt": 100}. All operators of a value must hold
+var expectOperators = map[string]bool{
+	"$gt":     true,
+	"$gte":    true,
+	"$lt":     true,
+	"$lte":    true,
+	"$ne":     true,
+	"$in":     true,
+	"$regex":  true,
+	"$exists": true,
+	"$length": true,
+}
+
+// operatorExpr returns the operators of an expected value made only of
+// known operators
+func operatorExpr(value interface{}) (map[string]interface{}, bool) {
+	ops, ok := value.(map[string]interface{})
+	if !ok || len(ops) == 0 {
+		return nil, false
+	}
+
+	for k := range ops {
+		if !expectOperators[k] {
+			return nil, false
+		}
+	}
+
+	return ops, true
+}
+
+func sortedOperators(ops map[string]interface{}) []string {
+	names := make([]string, 0, len(ops))
+	for name := range ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// validateOperators checks the value at propertyExpr against every operator
+func validateOperators(propertyExpr string, spec models.ExpectSpecEntry, ops map[string]interface{}, resp Response, meta Metadata, store *storage, strict bool) error {
+	gotValue, extractErr := extractResponseValue(resp, meta, propertyExpr, spec.Type)
+
+	if exists, ok := ops["$exists"]; ok {
+		shouldExist, ok := exists.(bool)
+		if !ok {
+			return fmt.Errorf("Malformed $exists operand for %s: %v", propertyExpr, exists)
+		}
+		if !shouldExist {
+			if extractErr == nil {
+				return fmt.Errorf("%s exists, expected it not to", propertyExpr)
+			}
+			return nil
+		}
+	}
+
+	if extractErr != nil {
+		return extractErr
+	}
+
+	for _, name := range sortedOperators(ops) {
+		if name == "$exists" {
+			continue
+		}
+
+		operand, err := resolveOperand(ops[name], store, strict)
+		if err != nil {
+			return err
+		}
+
+		if err := applyOperator(name, operand, spec.Type, gotValue, store, strict); err != nil {
+			return fmt.Errorf("%s: %s", propertyExpr, err)
+		}
+	}
+
+	return nil
+}
+
+// resolveOperand replaces $store and generator references in operands
+func resolveOperand(operand interface{}, store *storage, strict bool) (interface{}, error) {
+	value, err := tryGetValue(operand, store)
+	if err != nil {
+		return nil, err
+	}
+	if value != nil {
+		return value, nil
+	}
+
+	if ref := unresolvedRef(operand); strict && ref != "" {
+		return nil, fmt.Errorf("Unresolved reference %s in expected value", ref)
+	}
+
+	return operand, nil
+}
+
+func applyOperator(name string, operand interface{}, typ string, got interface{}, store *storage, strict bool) error {
+	switch name {
+	case "$gt", "$gte", "$lt", "$lte":
+		return compareNumbers(name, operand, got)
+	case "$ne":
+		expected, err := assertType(operand, typ)
+		if err != nil {
+			return err
+		}
+		if equals(expected, got) {
+			return fmt.Errorf("%v == %v", got, expected)
+		}
+	case "$in":
+		candidates, ok := operand.([]interface{})
+		if !ok {
+			return fmt.Errorf("Malformed $in operand: %v", operand)
+		}
+		for _, candidate := range candidates {
+			expected, err := assertType(candidate, typ)
+			if err == nil && equals(expected, got) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v not in %v", got, candidates)
+	case "$regex":
+		pattern, ok := operand.(string)
+		if !ok {
+			return fmt.Errorf("Malformed $regex operand: %v", operand)
+		}
+		str, ok := got.(string)
+		if !ok {
+			return fmt.Errorf("$regex needs a string, got %v", got)
+		}
+		matched, err := regexp.MatchString(pattern, str)
+		if err != nil {
+			return fmt.Errorf("Malformed $regex operand %s: %s", pattern, err)
+		}
+		if !matched {
+			return fmt.Errorf("%q does not match %s", str, pattern)
+		}
+	case "$length":
+		return checkLength(operand, got, store, strict)
+	}
+
+	return nil
+}
+
+// checkLength checks the length of strings, arrays and objects, operand
+// is either the exact length or an operator expression, e.g. {"$gte": 1}
+func checkLength(operand interface{}, got interface{}, store *storage, strict bool) error {
+	var length int
+	switch val := got.(type) {
+	case string:
+		length = len(val)
+	case []interface{}:
+		length = len(val)
+	case map[string]interface{}:
+		length = len(val)
+	default:
+		return fmt.Errorf("$length needs a string, an array or an object, got %v", got)
+	}
+
+	if ops, ok := operatorExpr(operand); ok {
+		for _, name := range sortedOperators(ops) {
+			nested, err := resolveOperand(ops[name], store, strict)
+			if err != nil {
+				return err
+			}
+			if err := applyOperator(name, nested, "int", length, store, strict); err != nil {
+				return fmt.Errorf("length %s", err)
+			}
+		}
+		return nil
+	}
+
+	expected, err := assertType(operand, "int")
+	if err != nil {
+		return fmt.Errorf("Malformed $length operand: %v", operand)
+	}
+	if expected != length {
+		return fmt.Errorf("length %d != %d", length, expected)
+	}
+
+	return nil
+}
+
+func compareNumbers(name string, operand interface{}, got interface{}) error {
+	expected, err := toFloat(operand)
+	if err != nil {
+		return fmt.Errorf("Malformed %s operand: %v", name, operand)
+	}
+	value, err := toFloat(got)
+	if err != nil {
+		return fmt.Errorf("%s needs a number, got %v", name, got)
+	}
+
+	var holds bool
+	switch name {
+	case "$gt":
+		holds = value > expected
+	case "$gte":
+		holds = value >= expected
+	case "$lt":
+		holds = value < expected
+	case "$lte":
+		holds = value <= expected
+	}
+
+	if !holds {
+		return fmt.Errorf("expected %v %s %v", got, name, operand)
+	}
+
+	return nil
+}
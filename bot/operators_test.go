@@ -0,0 +1,69 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestExpectOperators(t *testing.T) {
+	resp := Response{
+		"gold":   float64(150),
+		"roomId": "1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+		"status": "playing",
+		"items":  []interface{}{"sword", "shield"},
+	}
+
+	tables := []struct {
+		name   string
+		expect string
+		err    bool
+	}{
+		{"gt", `{"gold": {"type": "int", "value": {"$gt": 100}}}`, false},
+		{"gt fails", `{"gold": {"type": "int", "value": {"$gt": 150}}}`, true},
+		{"range", `{"gold": {"type": "int", "value": {"$gte": 150, "$lt": 200}}}`, false},
+		{"lte fails", `{"gold": {"type": "int", "value": {"$lte": 149}}}`, true},
+		{"ne", `{"status": {"type": "string", "value": {"$ne": "finished"}}}`, false},
+		{"ne fails", `{"status": {"type": "string", "value": {"$ne": "playing"}}}`, true},
+		{"in", `{"status": {"type": "string", "value": {"$in": ["waiting", "playing"]}}}`, false},
+		{"in ints", `{"gold": {"type": "int", "value": {"$in": [100, 150]}}}`, false},
+		{"in fails", `{"status": {"type": "string", "value": {"$in": ["finished"]}}}`, true},
+		{"regex", `{"roomId": {"type": "string", "value": {"$regex": "^[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}$"}}}`, false},
+		{"regex fails", `{"status": {"type": "string", "value": {"$regex": "^[0-9]+$"}}}`, true},
+		{"exists", `{"status": {"type": "string", "value": {"$exists": true}}}`, false},
+		{"missing", `{"score": {"type": "int", "value": {"$exists": false}}}`, false},
+		{"exists fails", `{"score": {"type": "int", "value": {"$exists": true}}}`, true},
+		{"length", `{"items": {"type": "array", "value": {"$length": 2}}}`, false},
+		{"length operator", `{"items": {"type": "array", "value": {"$length": {"$gte": 1}}}}`, false},
+		{"length fails", `{"items": {"type": "array", "value": {"$length": {"$gt": 2}}}}`, true},
+		{"stored operand", `{"gold": {"type": "int", "value": {"$gt": "$store.minGold"}}}`, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			var expect models.ExpectSpec
+			assert.NoError(t, json.Unmarshal([]byte(table.expect), &expect))
+
+			store := &storage{}
+			store.Set("minGold", 100)
+
+			err := validateExpectations(expect, resp, nil, store, true)
+			if table.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestOperatorExprLeavesObjectsAlone(t *testing.T) {
+	_, ok := operatorExpr(map[string]interface{}{"$gt": 1, "name": "a"})
+	assert.False(t, ok)
+
+	ops, ok := operatorExpr(map[string]interface{}{"$gt": 1})
+	assert.True(t, ok)
+	assert.Len(t, ops, 1)
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// pacing waits the think time between operations, the operation postDelay
+// or, when it has none, the target rps controller pacing falling back to
+// bot.pacingMs
+type pacing struct {
+	defaultDelay time.Duration
+	throughput   *state.Throughput
+	mutex        sync.Mutex
+	random       *rand.Rand
+}
+
+func newPacing(config *viper.Viper, seed int64, throughput *state.Throughput) *pacing {
+	return &pacing{
+		defaultDelay: time.Duration(config.GetInt("bot.pacingMs")) * time.Millisecond,
+		throughput:   throughput,
+		random:       rand.New(rand.NewSource(seed)),
+	}
+}
+
+// delay returns how long to wait for spec
+func (p *pacing) delay(spec *models.DelaySpec) time.Duration {
+	if spec == nil {
+		if p.throughput != nil {
+			if delay, ok := p.throughput.Pacing(); ok {
+				return delay
+			}
+		}
+		return p.defaultDelay
+	}
+
+	if spec.MaxMs <= 0 {
+		return time.Duration(spec.Ms) * time.Millisecond
+	}
+
+	ms := spec.MinMs
+	if spec.MaxMs > spec.MinMs {
+		p.mutex.Lock()
+		ms += p.random.Intn(spec.MaxMs - spec.MinMs + 1)
+		p.mutex.Unlock()
+	}
+
+	return time.Duration(ms) * time.Millisecond
+}
+
+// wait sleeps for the spec delay or until ctx is done
+func (p *pacing) wait(ctx context.Context, spec *models.DelaySpec) {
+	if p == nil {
+		return
+	}
+
+	delay := p.delay(spec)
+	if delay <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestPacingDelay(t *testing.T) {
+	config := viper.New()
+	config.Set("bot.pacingMs", 250)
+	p := newPacing(config, 42, nil)
+
+	assert.Equal(t, 250*time.Millisecond, p.delay(nil))
+	assert.Equal(t, 100*time.Millisecond, p.delay(&models.DelaySpec{Ms: 100}))
+	assert.Equal(t, time.Duration(0), p.delay(&models.DelaySpec{}))
+
+	for i := 0; i < 100; i++ {
+		delay := p.delay(&models.DelaySpec{MinMs: 10, MaxMs: 20})
+		assert.True(t, delay >= 10*time.Millisecond && delay <= 20*time.Millisecond, "delay %v out of range", delay)
+	}
+}
+
+func TestPacingFollowsController(t *testing.T) {
+	config := viper.New()
+	config.Set("bot.pacingMs", 250)
+	throughput := state.NewThroughput()
+	p := newPacing(config, 42, throughput)
+
+	assert.Equal(t, 250*time.Millisecond, p.delay(nil))
+	throughput.SetPacing(40 * time.Millisecond)
+	assert.Equal(t, 40*time.Millisecond, p.delay(nil))
+	assert.Equal(t, 100*time.Millisecond, p.delay(&models.DelaySpec{Ms: 100}))
+}
+
+func TestPacingWaitStopsWhenDone(t *testing.T) {
+	p := newPacing(viper.New(), 42, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	p.wait(ctx, &models.DelaySpec{Ms: 10000})
+	assert.True(t, time.Since(start) < time.Second)
+}
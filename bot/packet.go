@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+// Pitaya packet types, the first byte of the packet header. Handshakes are
+// answered by the server with its heartbeat interval, messages are data
+// packets. Handshake acks, heartbeats and kicks are the other ones
+const (
+	packetHandshake byte = 0x01
+	packetData      byte = 0x04
+)
+
+// Pitaya message types, bits 1 to 3 of the message flag. Notifies and
+// pushes are the other ones
+const (
+	messageRequest  byte = 0x00
+	messageResponse byte = 0x02
+)
+
+const (
+	packetHeaderLength = 4
+	maxPacketLength    = 1<<24 - 1
+	messageGzipFlag    = 0x10
+)
+
+// encodePacket frames body as a pitaya packet of typ, its header holding the
+// body length in 3 big endian bytes
+func encodePacket(typ byte, body []byte) []byte {
+	packet := make([]byte, packetHeaderLength, packetHeaderLength+len(body))
+	packet[0] = typ
+	packet[1] = byte(len(body) >> 16)
+	packet[2] = byte(len(body) >> 8)
+	packet[3] = byte(len(body))
+	return append(packet, body...)
+}
+
+// encodeMessage encodes a pitaya message of typ, the id being only written
+// for requests and responses and the route, uncompressed, for the routable
+// ones
+func encodeMessage(typ byte, id uint, route string, data []byte) []byte {
+	msg := []byte{typ << 1}
+	if typ == messageRequest || typ == messageResponse {
+		for id >= 0x80 {
+			msg = append(msg, byte(id&0x7f)|0x80)
+			id >>= 7
+		}
+		msg = append(msg, byte(id))
+	}
+	if typ != messageResponse {
+		msg = append(msg, byte(len(route)))
+		msg = append(msg, route...)
+	}
+
+	return append(msg, data...)
+}
+
+// decodeResponseMessage returns the id and data of msg when it is a
+// response
+func decodeResponseMessage(msg []byte) (uint, []byte, bool, error) {
+	if len(msg) == 0 {
+		return 0, nil, false, fmt.Errorf("Empty message")
+	}
+
+	flag := msg[0]
+	if (flag>>1)&0x07 != messageResponse {
+		return 0, nil, false, nil
+	}
+
+	var id uint
+	offset := 1
+	for shift := uint(0); ; shift += 7 {
+		if offset >= len(msg) {
+			return 0, nil, false, fmt.Errorf("Truncated message id")
+		}
+		b := msg[offset]
+		offset++
+		id |= uint(b&0x7f) << shift
+		if b < 0x80 {
+			break
+		}
+	}
+
+	data := msg[offset:]
+	if flag&messageGzipFlag != 0 {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return 0, nil, false, err
+		}
+		if data, err = ioutil.ReadAll(reader); err != nil {
+			return 0, nil, false, err
+		}
+	}
+
+	return id, data, true, nil
+}
+
+// packetReader splits a stream of pitaya packets, fed in arbitrary chunks,
+// into packets
+type packetReader struct {
+	buffer []byte
+}
+
+// feed adds data to the stream, calling packet for every packet completed
+func (r *packetReader) feed(data []byte, packet func(typ byte, body []byte)) {
+	r.buffer = append(r.buffer, data...)
+	offset := 0
+	for len(r.buffer)-offset >= packetHeaderLength {
+		header := r.buffer[offset:]
+		length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+		if len(header) < packetHeaderLength+length {
+			break
+		}
+
+		packet(header[0], header[packetHeaderLength:packetHeaderLength+length])
+		offset += packetHeaderLength + length
+	}
+	r.buffer = append([]byte(nil), r.buffer[offset:]...)
+}
@@ -0,0 +1,84 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeMessage(t *testing.T) {
+	tables := []struct {
+		name    string
+		typ     byte
+		id      uint
+		route   string
+		encoded []byte
+	}{
+		{"request", messageRequest, 1, "a.b", []byte{0x00, 0x01, 0x03, 'a', '.', 'b', '{', '}'}},
+		{"multi byte id", messageRequest, 300, "a", []byte{0x00, 0xac, 0x02, 0x01, 'a', '{', '}'}},
+		{"response", messageResponse, 5, "", []byte{0x04, 0x05, '{', '}'}},
+		{"notify", 0x01, 5, "a", []byte{0x02, 0x01, 'a', '{', '}'}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.Equal(t, table.encoded, encodeMessage(table.typ, table.id, table.route, []byte("{}")))
+		})
+	}
+}
+
+func TestDecodeResponseMessage(t *testing.T) {
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write([]byte(`{"code":200}`))
+	w.Close()
+
+	tables := []struct {
+		name     string
+		msg      []byte
+		id       uint
+		data     string
+		response bool
+		err      string
+	}{
+		{"response", encodeMessage(messageResponse, 300, "", []byte(`{"code":200}`)), 300, `{"code":200}`, true, ""},
+		{"gzipped", append([]byte{messageResponse<<1 | messageGzipFlag, 0x07}, compressed.Bytes()...), 7, `{"code":200}`, true, ""},
+		{"request", encodeMessage(messageRequest, 1, "a.b", nil), 0, "", false, ""},
+		{"truncated id", []byte{messageResponse << 1, 0x80}, 0, "", false, "Truncated message id"},
+		{"empty", []byte{}, 0, "", false, "Empty message"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			id, data, ok, err := decodeResponseMessage(table.msg)
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, table.response, ok)
+			assert.Equal(t, table.id, id)
+			if table.response {
+				assert.Equal(t, table.data, string(data))
+			}
+		})
+	}
+}
+
+func TestPacketReader(t *testing.T) {
+	stream := append(encodePacket(packetData, []byte("first")), encodePacket(0x03, nil)...)
+	stream = append(stream, encodePacket(packetData, []byte("second"))...)
+
+	var r packetReader
+	var bodies []string
+	for _, b := range stream {
+		r.feed([]byte{b}, func(typ byte, body []byte) {
+			bodies = append(bodies, string([]byte{typ + '0'})+string(body))
+		})
+	}
+
+	assert.Equal(t, []string{"4first", "3", "4second"}, bodies)
+	assert.Empty(t, r.buffer)
+}
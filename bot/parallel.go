@@ -0,0 +1,59 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// runParallel runs the parallelOperations concurrently and waits for all of
+// them. Each one works on a copy of the storage, what they store is merged
+// back in operation order once all are done, so later ones win conflicts
+func (b *SequentialBot) runParallel(ctx context.Context, op *models.Operation) error {
+	if len(op.ParallelOperations) == 0 {
+		return fmt.Errorf("Missing parallelOperations")
+	}
+
+	branches := make([]*SequentialBot, len(op.ParallelOperations))
+	errs := make([]error, len(op.ParallelOperations))
+
+	var wg sync.WaitGroup
+	for idx, parallelOp := range op.ParallelOperations {
+		branch := *b
+		branch.storage = b.storage.copy()
+		branches[idx] = &branch
+
+		wg.Add(1)
+		go func(idx int, parallelOp *models.Operation) {
+			defer wg.Done()
+			errs[idx] = branches[idx].runOperation(ctx, parallelOp)
+		}(idx, parallelOp)
+	}
+	wg.Wait()
+
+	failures := make([]string, 0)
+	for idx, err := range errs {
+		if err != nil {
+			parallelOp := op.ParallelOperations[idx]
+			failures = append(failures, fmt.Sprintf("operation %d (%s %s): %s", idx, parallelOp.Type, parallelOp.URI, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("Parallel operations failed: %s", strings.Join(failures, "; "))
+	}
+
+	before := b.storage.copy()
+	for _, branch := range branches {
+		for k, v := range *branch.storage {
+			if old, ok := (*before)[k]; !ok || !reflect.DeepEqual(old, v) {
+				b.storage.Set(k, v)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestParallelOperations(t *testing.T) {
+	chat := &models.Operation{Type: "request", URI: "chat.send", Store: models.StoreSpec{"chatCode": {Type: "string", Value: "code"}}}
+	poll := &models.Operation{Type: "request", URI: "matchmaking.poll", Store: models.StoreSpec{"pollCode": {Type: "string", Value: "code"}}}
+	failing := &models.Operation{Type: "request", URI: "shop.buy", Expect: models.ExpectSpec{"code": {Type: "string", Value: "500"}}}
+
+	tables := []struct {
+		name string
+		ops  []*models.Operation
+		err  bool
+	}{
+		{"all succeed", []*models.Operation{chat, poll}, false},
+		{"one fails", []*models.Operation{chat, failing}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+
+			err := b.runOperation(context.Background(), &models.Operation{Type: "parallel", ParallelOperations: table.ops})
+			assert.Len(t, transport.sent, len(table.ops))
+			if table.err {
+				assert.Error(t, err)
+				_, stored := b.storage.Get("chatCode")
+				assert.False(t, stored)
+				return
+			}
+
+			assert.NoError(t, err)
+			for _, key := range []string{"chatCode", "pollCode"} {
+				value, _ := b.storage.Get(key)
+				assert.Equal(t, "200", value)
+			}
+		})
+	}
+}
@@ -1,78 +1,389 @@
 package bot
 
 import (
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"net"
+	"reflect"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/sirupsen/logrus"
-	"github.com/topfreegames/pitaya/client"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya/session"
 )
 
+const defaultRequestTimeout = 5 * time.Second
+
+// TimeoutError is returned by requests whose response did not arrive in time
+type TimeoutError struct {
+	Route   string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("Timeout waiting %v for response on route %s", e.Timeout, e.Route)
+}
+
 // FIXME - constants from internal pitaya package
 const (
 	MsgResponseType byte = 0x02
 	MsgPushType     byte = 0x03
 )
 
+// Push is a server push along with the moment it was received. Err is set,
+// and Data dropped, when the push is above the max response size
+type Push struct {
+	Data       []byte
+	ReceivedAt time.Time
+	Err        error
+
+	route      string
+	serializer serializer
+}
+
+func (p *Push) decode() (Response, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+
+	if p.serializer != nil {
+		return p.serializer.unmarshalPush(p.route, p.Data)
+	}
+
+	return decodeResponse(p.Data)
+}
+
+// PClientOptions configures how PClient connects to the server.
+// TLSServerName is sent as SNI and used to validate the server certificate
+// instead of the dialed host, validation is skipped altogether when
+// TLSInsecureSkipVerify is set. Responses and pushes larger than
+// MaxResponseBytes are rejected without being decoded, 0 means no limit.
+// RequestTimeout is how long requests wait for their response by default.
+// Protocol is tcp, ws or wss, websockets connect to WSPath and wss always
+// uses tls. WSHeaders are extra websocket handshake headers. Serializer is
+// json or protobuf, the latter encoding the ProtoRoutes messages described
+// by the Descriptors set, or by the server DocsRoute and ProtosRoute when
+// there is no set. Handshake is the client data sent in the pitaya
+// handshake, the pitaya default when nil. Up to PushBufferSize pushes are
+// kept per route until an operation consumes them, the oldest being dropped
+// when it is full, and those older than PushTTL are discarded.
+// FaultInjection connects through a local proxy that simulateDisconnect
+// functions can reset or blackhole and fuzz operations send malformed
+// packets through. Network shapes the traffic through that
+// same proxy, as a slow or lossy network would, when set. HeartbeatSeconds,
+// when positive, replaces the heartbeat interval the server sends in its
+// handshake response, also through that proxy
+type PClientOptions struct {
+	Serializer            string
+	Descriptors           string
+	DocsRoute             string
+	ProtosRoute           string
+	ProtoRoutes           []ProtoRoute
+	Protocol              string
+	WSPath                string
+	WSHeaders             map[string]string
+	UseTLS                bool
+	TLSServerName         string
+	TLSInsecureSkipVerify bool
+	Transport             string
+	FixturesPath          string
+	MaxResponseBytes      int
+	RequestTimeout        time.Duration
+	Handshake             *session.HandshakeData
+	PushBufferSize        int
+	PushTTL               time.Duration
+	FaultInjection        bool
+	Network               *models.NetworkSpec
+	HeartbeatSeconds      int
+}
+
+// NewPClientOptions reads the client options from the server config.
+// server.tlsInsecureSkipVerify defaults to true, as certificates were never
+// validated before it existed
+func NewPClientOptions(config *viper.Viper) *PClientOptions {
+	skipVerify := true
+	if config.IsSet("server.tlsInsecureSkipVerify") {
+		skipVerify = config.GetBool("server.tlsInsecureSkipVerify")
+	}
+
+	var routes []ProtoRoute
+	config.UnmarshalKey("serializer.routes", &routes)
+
+	var network *models.NetworkSpec
+	if config.IsSet("client.network") {
+		network = &models.NetworkSpec{}
+		config.UnmarshalKey("client.network", network)
+	}
+
+	return &PClientOptions{
+		Serializer:            config.GetString("serializer.type"),
+		Descriptors:           config.GetString("serializer.descriptors"),
+		DocsRoute:             config.GetString("serializer.docsRoute"),
+		ProtosRoute:           config.GetString("serializer.protosRoute"),
+		ProtoRoutes:           routes,
+		Protocol:              config.GetString("server.protocol"),
+		WSPath:                config.GetString("server.wsPath"),
+		WSHeaders:             config.GetStringMapString("server.wsHeaders"),
+		UseTLS:                config.GetBool("server.tls"),
+		TLSServerName:         config.GetString("server.tlsServerName"),
+		TLSInsecureSkipVerify: skipVerify,
+		Transport:             config.GetString("server.transport"),
+		FixturesPath:          config.GetString("server.fixtures"),
+		MaxResponseBytes:      config.GetInt("client.maxResponseBytes"),
+		RequestTimeout:        time.Duration(config.GetInt("request.timeoutMs")) * time.Millisecond,
+		Handshake:             handshakeFromConfig(config),
+		PushBufferSize:        config.GetInt("client.pushBuffer.size"),
+		PushTTL:               config.GetDuration("client.pushBuffer.ttl"),
+		FaultInjection:        config.GetBool("client.faultInjection"),
+		Network:               network,
+		HeartbeatSeconds:      config.GetInt("client.heartbeatSeconds"),
+	}
+}
+
+// handshakeFromConfig reads client.handshake, nil when it is not set
+func handshakeFromConfig(config *viper.Viper) *session.HandshakeData {
+	if !config.IsSet("client.handshake") {
+		return nil
+	}
+
+	return &session.HandshakeData{
+		Sys: session.HandshakeClientData{
+			Platform:    config.GetString("client.handshake.platform"),
+			LibVersion:  config.GetString("client.handshake.libVersion"),
+			BuildNumber: config.GetString("client.handshake.buildNumber"),
+			Version:     config.GetString("client.handshake.version"),
+		},
+		User: config.GetStringMap("client.handshake.user"),
+	}
+}
+
+// withHandshake overrides the handshake fields set by the spec
+func (o *PClientOptions) withHandshake(spec *models.HandshakeSpec) *PClientOptions {
+	if spec == nil {
+		return o
+	}
+
+	handshake := &session.HandshakeData{}
+	if o.Handshake != nil {
+		*handshake = *o.Handshake
+	}
+
+	if spec.Platform != "" {
+		handshake.Sys.Platform = spec.Platform
+	}
+	if spec.LibVersion != "" {
+		handshake.Sys.LibVersion = spec.LibVersion
+	}
+	if spec.BuildNumber != "" {
+		handshake.Sys.BuildNumber = spec.BuildNumber
+	}
+	if spec.Version != "" {
+		handshake.Sys.Version = spec.Version
+	}
+	if spec.User != nil {
+		handshake.User = spec.User
+	}
+
+	opts := *o
+	opts.Handshake = handshake
+	return &opts
+}
+
+// tlsConfig returns the tls config to connect with, nil when not using tls
+func (o *PClientOptions) tlsConfig() (*tls.Config, error) {
+	if !o.UseTLS && o.Protocol != "wss" {
+		if o.TLSServerName != "" {
+			return nil, fmt.Errorf("TLS server name %s set but tls is disabled", o.TLSServerName)
+		}
+		return nil, nil
+	}
+
+	return &tls.Config{
+		ServerName:         o.TLSServerName,
+		InsecureSkipVerify: o.TLSInsecureSkipVerify,
+	}, nil
+}
+
 // PClient is a wrapper arund pitaya/client.
 // The ideia is to be able to separeta request/responses
 // from server pushes
 type PClient struct {
-	client         *client.Client
+	client         transport
 	responsesMutex sync.Mutex
 	responses      map[uint]chan []byte
+	// held for reading while a request is sent and its response channel
+	// created, so the listener can tell early responses from late ones
+	sendMutex sync.RWMutex
 
 	pushesMutex sync.Mutex
-	pushes      map[string]chan []byte
+	pushes      map[string]chan *Push
+
+	stats            *metrics.ConnectionStats
+	maxResponseBytes int
+	serializer       serializer
+	requestTimeout   time.Duration
+	done             chan struct{}
+	pushBufferSize   int
+	pushTTL          time.Duration
+	metricsReporter  []metrics.Reporter
+	proxy            *faultProxy
+
+	markersMutex sync.Mutex
+	markers      map[string]time.Time
 }
 
 // NewPClient is the PCLient constructor
-func NewPClient(host string, useTLS bool) (*PClient, error) {
-	pclient := client.New(logrus.InfoLevel)
-	if useTLS {
-		if err := pclient.ConnectToTLS(host, true); err != nil {
-			fmt.Println("Error connecting to server")
-			fmt.Println(err)
+func NewPClient(host string, opts *PClientOptions) (*PClient, error) {
+	var t transport
+	var proxy *faultProxy
+	switch opts.Transport {
+	case "", "tcp":
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
 			return nil, err
 		}
-	} else {
-		if err := pclient.ConnectTo(host); err != nil {
-			fmt.Println("Error connecting to server")
-			fmt.Println(err)
+
+		network, err := networkConditions(opts.Network)
+		if err != nil {
 			return nil, err
 		}
+
+		// the proxy reads the handshake of plain tcp connections only
+		if opts.HeartbeatSeconds > 0 && (tlsConfig != nil || (opts.Protocol != "" && opts.Protocol != "tcp")) {
+			return nil, fmt.Errorf("Heartbeat interval needs the tcp protocol without tls")
+		}
+
+		dialed := host
+		if opts.FaultInjection || network != nil || opts.HeartbeatSeconds > 0 {
+			if proxy, err = newFaultProxy(host, network, opts.HeartbeatSeconds); err != nil {
+				return nil, err
+			}
+			dialed = proxy.addr()
+			// certificates are still validated against the server host
+			if tlsConfig != nil && tlsConfig.ServerName == "" {
+				tlsConfig = tlsConfig.Clone()
+				tlsConfig.ServerName, _, _ = net.SplitHostPort(host)
+			}
+		}
+
+		pt, err := newPitayaTransport(dialed, opts, tlsConfig)
+		if err != nil {
+			if proxy != nil {
+				proxy.close()
+			}
+			return nil, err
+		}
+		t = pt
+	case "mock":
+		fixtures, err := loadMockFixtures(opts.FixturesPath)
+		if err != nil {
+			return nil, err
+		}
+		t = newMockTransport(fixtures)
+	default:
+		return nil, fmt.Errorf("Unknown transport: %s", opts.Transport)
+	}
+
+	s, err := opts.serializer(host)
+	if err != nil {
+		return nil, err
 	}
 
 	return &PClient{
-		client:    pclient,
-		responses: make(map[uint]chan []byte),
-		pushes:    make(map[string]chan []byte),
+		client:           t,
+		responses:        make(map[uint]chan []byte),
+		pushes:           make(map[string]chan *Push),
+		maxResponseBytes: opts.MaxResponseBytes,
+		serializer:       s,
+		requestTimeout:   opts.RequestTimeout,
+		done:             make(chan struct{}),
+		pushBufferSize:   opts.PushBufferSize,
+		pushTTL:          opts.PushTTL,
+		proxy:            proxy,
 	}, nil
 }
 
+func (o *PClientOptions) serializer(host string) (serializer, error) {
+	switch o.Serializer {
+	case "", "json":
+		return jsonSerializer{}, nil
+	case "protobuf":
+		if o.Descriptors == "" && o.DocsRoute != "" {
+			registry, err := sharedServerProtoRegistry(host, o)
+			if err != nil {
+				return nil, err
+			}
+			return registry.serializer(o.ProtoRoutes)
+		}
+		return newProtoSerializer(o.Descriptors, o.ProtoRoutes)
+	}
+
+	return nil, fmt.Errorf("Unknown serializer: %s", o.Serializer)
+}
+
+// marshal encodes request and notify args with the client serializer
+func (c *PClient) marshal(route string, args map[string]interface{}) ([]byte, error) {
+	if c.serializer == nil {
+		return jsonSerializer{}.marshal(route, args)
+	}
+
+	return c.serializer.marshal(route, args)
+}
+
 // Disconnect disconnects the client
 func (c *PClient) Disconnect() {
 	c.client.Disconnect()
 	c.client = nil
+	if c.proxy != nil {
+		c.proxy.close()
+	}
+	if c.done != nil {
+		close(c.done)
+	}
 }
 
 // Connected returns if the given client is connected or not
 func (c *PClient) Connected() bool {
-	return c.client != nil && c.client.Connected
+	return c.client != nil && c.client.Connected()
 }
 
 func (c *PClient) getResponseChannelForID(id uint) chan []byte {
 	c.responsesMutex.Lock()
 	defer c.responsesMutex.Unlock()
 	if _, ok := c.responses[id]; !ok {
-		c.responses[id] = make(chan []byte)
+		// buffered so the listener never blocks on responses whose request
+		// gives up waiting
+		c.responses[id] = make(chan []byte, 1)
 	}
 
 	return c.responses[id]
 }
 
+// responseChannel returns the channel of the request waiting for the
+// response id, or false when no request waits for it. Responses arriving
+// while requests are sent wait for their channels to be created
+func (c *PClient) responseChannel(id uint) (chan []byte, bool) {
+	lookup := func() (chan []byte, bool) {
+		c.responsesMutex.Lock()
+		defer c.responsesMutex.Unlock()
+		ch, ok := c.responses[id]
+		return ch, ok
+	}
+
+	if ch, ok := lookup(); ok {
+		return ch, true
+	}
+
+	c.sendMutex.Lock()
+	c.sendMutex.Unlock()
+	return lookup()
+}
+
 func (c *PClient) removeResponseChannelForID(id uint) {
 	c.responsesMutex.Lock()
 	defer c.responsesMutex.Unlock()
@@ -80,81 +391,298 @@ func (c *PClient) removeResponseChannelForID(id uint) {
 	delete(c.responses, id)
 }
 
-func (c *PClient) getPushChannelForRoute(route string) chan []byte {
+func (c *PClient) getPushChannelForRoute(route string) chan *Push {
 	c.pushesMutex.Lock()
 	defer c.pushesMutex.Unlock()
 	if _, ok := c.pushes[route]; !ok {
-		c.pushes[route] = make(chan []byte)
+		c.pushes[route] = make(chan *Push, c.pushBufferSize)
 	}
 
 	return c.pushes[route]
 }
 
-// Request ...
-func (c *PClient) Request(route string, data []byte) (Response, []byte, error) {
-	messageID, err := c.client.SendRequest(route, data)
+// Request sends a request and waits timeout for its response, the client
+// request timeout is used when it is not positive. It gives up waiting once
+// ctx is done
+func (c *PClient) Request(ctx context.Context, route string, data []byte, timeout time.Duration) (Response, Metadata, []byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	sentAt := time.Now()
+	responseData, messageID, err := c.roundTrip(ctx, route, data, timeout)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	receivedAt := time.Now()
+	if err := c.checkSize("Response", route, responseData); err != nil {
+		return nil, nil, nil, err
 	}
 
+	ret, raw, err := c.unmarshalResponse(route, responseData)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	meta := newMetadata(route, len(responseData), receivedAt)
+	meta["id"] = int(messageID)
+	meta["latencyMs"] = int(receivedAt.Sub(sentAt).Nanoseconds() / 1e6)
+	return ret, meta, raw, nil
+}
+
+// roundTrip sends the request and waits for its response, as received
+func (c *PClient) roundTrip(ctx context.Context, route string, data []byte, timeout time.Duration) ([]byte, uint, error) {
+	c.sendMutex.RLock()
+	messageID, err := c.client.SendRequest(route, data)
+	if err != nil {
+		c.sendMutex.RUnlock()
+		return nil, 0, err
+	}
 	ch := c.getResponseChannelForID(messageID)
+	c.sendMutex.RUnlock()
+	defer c.removeResponseChannelForID(messageID)
+	c.stats.AddSent(len(data))
+
+	if timeout <= 0 {
+		timeout = c.requestTimeout
+	}
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
 
 	select {
 	case responseData := <-ch:
-		ret := make(Response)
-		if err := json.Unmarshal(responseData, &ret); err != nil {
-			err = fmt.Errorf("Error unmarshaling response: %s", err)
-			return nil, nil, err
-		}
+		return responseData, messageID, nil
+	case <-time.After(timeout):
+		return nil, 0, &TimeoutError{Route: route, Timeout: timeout}
+	case <-ctx.Done():
+		return nil, 0, ctx.Err()
+	}
+}
+
+// checkSize rejects messages above the max response size
+func (c *PClient) checkSize(kind, route string, data []byte) error {
+	if c.maxResponseBytes > 0 && len(data) > c.maxResponseBytes {
+		return fmt.Errorf("%s on route %s has %d bytes, above client.maxResponseBytes %d", kind, route, len(data), c.maxResponseBytes)
+	}
+
+	return nil
+}
+
+// unmarshalResponse decodes the response, also returning it in a readable
+// form for error messages: as received for json, reencoded as json otherwise
+func (c *PClient) unmarshalResponse(route string, data []byte) (Response, []byte, error) {
+	if c.serializer == nil {
+		ret, err := decodeResponse(data)
+		return ret, data, err
+	}
 
-		return ret, responseData, nil
-	case <-time.After(5 * time.Second): // TODO - pass timeout as config
-		return nil, nil, fmt.Errorf("Timeout waiting for response on route %s", route)
+	ret, err := c.serializer.unmarshalResponse(route, data)
+	if err != nil || !c.serializer.binary() {
+		return ret, data, err
 	}
 
-	return nil, nil, nil
+	raw, err := json.Marshal(ret)
+	return ret, raw, err
 }
 
-// Notify sends a notify to the server
-func (c *PClient) Notify(route string, data []byte) error {
+func decodeResponse(data []byte) (Response, error) {
+	ret := make(Response)
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("Error unmarshaling response: %s", err)
+	}
+
+	return ret, nil
+}
+
+// Notify sends a notify to the server, unless ctx is done
+func (c *PClient) Notify(ctx context.Context, route string, data []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	err := c.client.SendNotify(route, data)
+	if err == nil {
+		c.stats.AddSent(len(data))
+	}
 	return err
 }
 
-// ReceivePush ...
-func (c *PClient) ReceivePush(route string, timeout int) (Response, error) {
+// ReceivePush waits timeout milliseconds for a push on route, or until ctx
+// is done
+func (c *PClient) ReceivePush(ctx context.Context, route string, timeout int) (Response, Metadata, error) {
 	ch := c.getPushChannelForRoute(route)
+	deadline := time.After(time.Duration(timeout) * time.Millisecond)
 
-	select {
-	case data := <-ch:
-		ret := make(Response)
-		if err := json.Unmarshal(data, &ret); err != nil {
-			err = fmt.Errorf("Error unmarshaling response: %s", err)
-			return nil, err
+	for {
+		select {
+		case push := <-ch:
+			if !c.fresh(push) {
+				continue
+			}
+			resp, err := push.decode()
+			if err != nil {
+				return nil, nil, err
+			}
+			return resp, pushMetadata(route, push), nil
+		case <-deadline:
+			return nil, nil, fmt.Errorf("Timeout waiting for push on route %s", route)
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
 		}
+	}
+}
 
-		return ret, nil
-	case <-time.After(time.Duration(timeout) * time.Millisecond):
-		return nil, fmt.Errorf("Timeout waiting for push on route %s", route)
+// fresh returns whether the push is within the push TTL
+func (c *PClient) fresh(push *Push) bool {
+	return c.pushTTL <= 0 || time.Since(push.ReceivedAt) <= c.pushTTL
+}
+
+// deliverPush hands the push to its route channel. Buffered channels never
+// block the listener, their oldest push is dropped to make room
+func (c *PClient) deliverPush(route string, push *Push) {
+	ch := c.getPushChannelForRoute(route)
+	if c.pushBufferSize <= 0 {
+		ch <- push
+		return
+	}
+
+	for {
+		select {
+		case ch <- push:
+			return
+		default:
+		}
+
+		select {
+		case <-ch:
+		default:
+		}
 	}
 }
 
-// StartListening ...
-func (c *PClient) StartListening() {
+// ReceiveAnyPush waits for the first push on any of routes until deadline,
+// or until ctx is done, returning the index of its route
+func (c *PClient) ReceiveAnyPush(ctx context.Context, routes []string, deadline <-chan time.Time) (int, *Push, error) {
+	cases := make([]reflect.SelectCase, len(routes)+2)
+	for i, route := range routes {
+		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.getPushChannelForRoute(route))}
+	}
+	cases[len(routes)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(deadline)}
+	cases[len(routes)+1] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+
+	for {
+		chosen, value, _ := reflect.Select(cases)
+		if chosen == len(routes) {
+			return -1, nil, fmt.Errorf("Timeout waiting for push on routes %s", strings.Join(routes, ", "))
+		}
+		if chosen == len(routes)+1 {
+			return -1, nil, ctx.Err()
+		}
+
+		if push := value.Interface().(*Push); c.fresh(push) {
+			return chosen, push, nil
+		}
+	}
+}
+
+// Consume hands every push received on route to sink, in the background,
+// until the client disconnects
+func (c *PClient) Consume(route string, sink func(*Push)) {
+	ch := c.getPushChannelForRoute(route)
 	go func() {
-		for m := range c.client.IncomingMsgChan {
-			t := byte(m.Type)
-			switch t {
-			case MsgResponseType:
-				ch := c.getResponseChannelForID(m.ID)
-				ch <- m.Data
-				c.removeResponseChannelForID(m.ID)
-			case MsgPushType:
-				ch := c.getPushChannelForRoute(m.Route)
-				ch <- m.Data
-			default:
-				panic("Unknown message type")
+		for {
+			select {
+			case push := <-ch:
+				if c.fresh(push) {
+					sink(push)
+				}
+			case <-c.done:
+				return
 			}
 		}
 	}()
 }
+
+// ReceivePushes collects every push received on route during the given
+// window, it fails if ctx is done before the window ends
+func (c *PClient) ReceivePushes(ctx context.Context, route string, window time.Duration) ([]*Push, error) {
+	ch := c.getPushChannelForRoute(route)
+	pushes := make([]*Push, 0)
+	deadline := time.After(window)
+
+	for {
+		select {
+		case push := <-ch:
+			if c.fresh(push) {
+				pushes = append(pushes, push)
+			}
+		case <-deadline:
+			return pushes, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// bufferedPushes takes the fresh pushes buffered on route, without waiting
+// for more
+func (c *PClient) bufferedPushes(route string) []*Push {
+	ch := c.getPushChannelForRoute(route)
+	pushes := make([]*Push, 0)
+	for {
+		select {
+		case push := <-ch:
+			if c.fresh(push) {
+				pushes = append(pushes, push)
+			}
+		default:
+			return pushes
+		}
+	}
+}
+
+// MarkPushes records the moment pushes are counted from by the assertPushes
+// operations since the marker name
+func (c *PClient) MarkPushes(name string) {
+	c.markersMutex.Lock()
+	defer c.markersMutex.Unlock()
+	if c.markers == nil {
+		c.markers = map[string]time.Time{}
+	}
+	c.markers[name] = time.Now()
+}
+
+func (c *PClient) pushMarker(name string) (time.Time, bool) {
+	c.markersMutex.Lock()
+	defer c.markersMutex.Unlock()
+	at, ok := c.markers[name]
+	return at, ok
+}
+
+// StartListening ...
+func (c *PClient) StartListening() {
+	c.client.Listen(func(msgType byte, id uint, route string, data []byte) {
+		c.stats.AddReceived(len(data))
+		switch msgType {
+		case MsgResponseType:
+			// responses of requests that gave up waiting are dropped
+			if ch, ok := c.responseChannel(id); ok {
+				ch <- data
+			}
+		case MsgPushType:
+			for _, mr := range c.metricsReporter {
+				mr.ReportCount(metrics.PushCount, metricsTags(route, nil), 1)
+			}
+			push := &Push{Data: data, ReceivedAt: time.Now(), route: route, serializer: c.serializer}
+			if err := c.checkSize("Push", route, data); err != nil {
+				push.Data = nil
+				push.Err = err
+			}
+			c.deliverPush(route, push)
+		default:
+			panic("Unknown message type")
+		}
+	})
+}
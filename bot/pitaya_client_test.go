@@ -0,0 +1,173 @@
+package bot
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestPClientOptionsTLSConfig(t *testing.T) {
+	tables := []struct {
+		name     string
+		settings map[string]interface{}
+		expected *tls.Config
+		err      bool
+	}{
+		{"no tls", map[string]interface{}{}, nil, false},
+		{"tls skips verify by default", map[string]interface{}{"server.tls": true}, &tls.Config{InsecureSkipVerify: true}, false},
+		{"server name with verification", map[string]interface{}{
+			"server.tls":                   true,
+			"server.tlsServerName":         "game.example.com",
+			"server.tlsInsecureSkipVerify": false,
+		}, &tls.Config{ServerName: "game.example.com"}, false},
+		{"server name without tls", map[string]interface{}{"server.tlsServerName": "game.example.com"}, nil, true},
+		{"wss always uses tls", map[string]interface{}{"server.protocol": "wss"}, &tls.Config{InsecureSkipVerify: true}, false},
+		{"ws follows tls option", map[string]interface{}{"server.protocol": "ws"}, nil, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			config := viper.New()
+			for k, v := range table.settings {
+				config.Set(k, v)
+			}
+
+			tlsConfig, err := NewPClientOptions(config).tlsConfig()
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.expected, tlsConfig)
+		})
+	}
+}
+
+func TestNewPitayaTransportInvalidProtocol(t *testing.T) {
+	tables := []struct {
+		name string
+		opts *PClientOptions
+	}{
+		{"ws headers over tcp", &PClientOptions{Protocol: "tcp", WSHeaders: map[string]string{"X-Route": "a"}}},
+		{"unknown", &PClientOptions{Protocol: "quic"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := newPitayaTransport("localhost:3250", table.opts, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNewPClientUnsupportedTransport(t *testing.T) {
+	tables := []struct {
+		transport string
+		expected  string
+	}{
+		{"longpolling", "Unknown transport: longpolling"},
+		{"quic", "Unknown transport: quic"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.transport, func(t *testing.T) {
+			_, err := NewPClient("localhost:3250", &PClientOptions{Transport: table.transport})
+			assert.EqualError(t, err, table.expected)
+		})
+	}
+}
+
+func TestHandshakeOptions(t *testing.T) {
+	config := viper.New()
+	assert.Nil(t, NewPClientOptions(config).Handshake)
+
+	config.Set("client.handshake", map[string]interface{}{
+		"platform": "android",
+		"version":  "2.1.0",
+		"user":     map[string]interface{}{"region": "eu"},
+	})
+	opts := NewPClientOptions(config)
+	assert.Equal(t, "android", opts.Handshake.Sys.Platform)
+	assert.Equal(t, "2.1.0", opts.Handshake.Sys.Version)
+	assert.Equal(t, map[string]interface{}{"region": "eu"}, opts.Handshake.User)
+
+	overridden := opts.withHandshake(&models.HandshakeSpec{Version: "1.0.0", BuildNumber: "7"})
+	assert.Equal(t, "android", overridden.Handshake.Sys.Platform)
+	assert.Equal(t, "1.0.0", overridden.Handshake.Sys.Version)
+	assert.Equal(t, "7", overridden.Handshake.Sys.BuildNumber)
+	assert.Equal(t, "2.1.0", opts.Handshake.Sys.Version)
+
+	assert.Equal(t, opts, opts.withHandshake(nil))
+}
+
+func TestResponses(t *testing.T) {
+	transport := &recordingTransport{}
+	c := newTestBot(transport).sessions.clients[defaultSession]
+
+	// responses may arrive before the request channel is created
+	for i := 0; i < 100; i++ {
+		_, _, _, err := c.Request(context.Background(), "room.join", []byte(`{}`), time.Second)
+		assert.NoError(t, err)
+	}
+
+	// late responses are dropped instead of waiting for a request forever
+	transport.silent = true
+	_, _, _, err := c.Request(context.Background(), "room.join", []byte(`{}`), 10*time.Millisecond)
+	assert.IsType(t, &TimeoutError{}, err)
+	transport.handler(MsgResponseType, 101, "room.join", []byte(`{"code": "200"}`))
+	assert.Empty(t, c.responses)
+}
+
+func TestPushBuffer(t *testing.T) {
+	push := func(n int, age time.Duration) *Push {
+		return &Push{Data: []byte(fmt.Sprintf(`{"n":%d}`, n)), ReceivedAt: time.Now().Add(-age)}
+	}
+
+	tables := []struct {
+		name   string
+		size   int
+		ttl    time.Duration
+		pushes []*Push
+		n      float64
+	}{
+		{"buffered before receive", 2, 0, []*Push{push(1, 0)}, 1},
+		{"oldest dropped when full", 2, 0, []*Push{push(1, 0), push(2, 0), push(3, 0)}, 2},
+		{"expired discarded", 2, time.Minute, []*Push{push(1, time.Hour), push(2, 0)}, 2},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			c := &PClient{
+				pushes:         make(map[string]chan *Push),
+				pushBufferSize: table.size,
+				pushTTL:        table.ttl,
+			}
+			for _, p := range table.pushes {
+				c.deliverPush("connector.push", p)
+			}
+
+			resp, _, err := c.ReceivePush(context.Background(), "connector.push", 50)
+			assert.NoError(t, err)
+			assert.Equal(t, table.n, resp["n"])
+		})
+	}
+
+	c := &PClient{pushes: make(map[string]chan *Push), pushBufferSize: 1, pushTTL: time.Minute}
+	c.deliverPush("connector.push", push(1, time.Hour))
+	_, _, err := c.ReceivePush(context.Background(), "connector.push", 50)
+	assert.Error(t, err)
+
+	config := viper.New()
+	config.Set("client.pushBuffer.size", 10)
+	config.Set("client.pushBuffer.ttl", "30s")
+	opts := NewPClientOptions(config)
+	assert.Equal(t, 10, opts.PushBufferSize)
+	assert.Equal(t, 30*time.Second, opts.PushTTL)
+}
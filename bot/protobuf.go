@@ -0,0 +1,582 @@
+package bot
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// serializer encodes request args and decodes responses and pushes
+type serializer interface {
+	marshal(route string, args map[string]interface{}) ([]byte, error)
+	unmarshalResponse(route string, data []byte) (Response, error)
+	unmarshalPush(route string, data []byte) (Response, error)
+	binary() bool
+}
+
+type jsonSerializer struct{}
+
+func (jsonSerializer) marshal(route string, args map[string]interface{}) ([]byte, error) {
+	return json.Marshal(args)
+}
+
+func (jsonSerializer) unmarshalResponse(route string, data []byte) (Response, error) {
+	return decodeResponse(data)
+}
+
+func (jsonSerializer) unmarshalPush(route string, data []byte) (Response, error) {
+	return decodeResponse(data)
+}
+
+func (jsonSerializer) binary() bool {
+	return false
+}
+
+// ProtoRoute maps a route to the protobuf messages of its requests and
+// responses, or of its pushes
+type ProtoRoute struct {
+	Route    string `mapstructure:"route"`
+	Request  string `mapstructure:"request"`
+	Response string `mapstructure:"response"`
+	Push     string `mapstructure:"push"`
+}
+
+// protoSerializer encodes and decodes the messages of each route as
+// described by a descriptor set, like the pitaya protobuf serializer.
+// Decoded messages are keyed by proto field name and hold every field, as
+// the json serializer would. Args may use field or json names, bytes are
+// base64 strings and enums are names or numbers
+type protoSerializer struct {
+	registry  *protoRegistry
+	requests  map[string]*protoMessageType
+	responses map[string]*protoMessageType
+	pushes    map[string]*protoMessageType
+}
+
+var (
+	protoRegistriesMutex sync.Mutex
+	protoRegistries      = map[string]*protoRegistry{}
+)
+
+// newProtoSerializer returns the serializer of the routes, the descriptor
+// set is loaded once and shared by every bot
+func newProtoSerializer(descriptors string, routes []ProtoRoute) (*protoSerializer, error) {
+	if descriptors == "" {
+		return nil, fmt.Errorf("Protobuf serializer needs serializer.descriptors")
+	}
+
+	registry, err := sharedProtoRegistry(descriptors)
+	if err != nil {
+		return nil, err
+	}
+
+	return registry.serializer(routes)
+}
+
+// sharedProtoRegistry loads the descriptor set once for every bot
+func sharedProtoRegistry(descriptors string) (*protoRegistry, error) {
+	protoRegistriesMutex.Lock()
+	defer protoRegistriesMutex.Unlock()
+
+	registry, ok := protoRegistries[descriptors]
+	if !ok {
+		var err error
+		if registry, err = loadDescriptorSet(descriptors); err != nil {
+			return nil, err
+		}
+		protoRegistries[descriptors] = registry
+	}
+
+	return registry, nil
+}
+
+// serializer returns the serializer of the routes the registry documents,
+// if any, and of routes, which override them
+func (p *protoRegistry) serializer(routes []ProtoRoute) (*protoSerializer, error) {
+	s := &protoSerializer{
+		registry:  p,
+		requests:  map[string]*protoMessageType{},
+		responses: map[string]*protoMessageType{},
+		pushes:    map[string]*protoMessageType{},
+	}
+
+	for _, route := range append(append([]ProtoRoute{}, p.routes...), routes...) {
+		mappings := []struct {
+			name     string
+			messages map[string]*protoMessageType
+		}{
+			{route.Request, s.requests},
+			{route.Response, s.responses},
+			{route.Push, s.pushes},
+		}
+		for _, mapping := range mappings {
+			if mapping.name == "" {
+				continue
+			}
+			msg, ok := p.messages[mapping.name]
+			if !ok {
+				return nil, fmt.Errorf("Unknown protobuf message %s for route %s", mapping.name, route.Route)
+			}
+			mapping.messages[route.Route] = msg
+		}
+	}
+
+	return s, nil
+}
+
+func (s *protoSerializer) marshal(route string, args map[string]interface{}) ([]byte, error) {
+	msg, ok := s.requests[route]
+	if !ok {
+		return nil, fmt.Errorf("No protobuf request message for route %s", route)
+	}
+
+	return s.encodeMessage(msg, args)
+}
+
+func (s *protoSerializer) unmarshalResponse(route string, data []byte) (Response, error) {
+	msg, ok := s.responses[route]
+	if !ok {
+		return nil, fmt.Errorf("No protobuf response message for route %s", route)
+	}
+
+	return s.decode(msg, data)
+}
+
+func (s *protoSerializer) unmarshalPush(route string, data []byte) (Response, error) {
+	msg, ok := s.pushes[route]
+	if !ok {
+		return nil, fmt.Errorf("No protobuf push message for route %s", route)
+	}
+
+	return s.decode(msg, data)
+}
+
+func (s *protoSerializer) binary() bool {
+	return true
+}
+
+func (s *protoSerializer) decode(msg *protoMessageType, data []byte) (Response, error) {
+	ret, err := s.decodeMessage(msg, data)
+	if err != nil {
+		return nil, fmt.Errorf("Error unmarshaling %s: %s", msg.name, err)
+	}
+
+	return Response(ret), nil
+}
+
+func (s *protoSerializer) encodeMessage(msg *protoMessageType, value map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(value))
+	for k := range value {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b := proto.NewBuffer(nil)
+	for _, k := range keys {
+		field, ok := msg.byName[k]
+		if !ok {
+			return nil, fmt.Errorf("Unknown field %s in %s", k, msg.name)
+		}
+
+		v := value[k]
+		if v == nil {
+			continue
+		}
+
+		var err error
+		if field.repeated {
+			err = s.encodeRepeated(b, field, v)
+		} else {
+			err = s.encodeValue(b, field, v)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", msg.name, k, err)
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func encodeTag(b *proto.Buffer, field *protoField, wireType int) {
+	b.EncodeVarint(uint64(field.number)<<3 | uint64(wireType))
+}
+
+func (s *protoSerializer) encodeRepeated(b *proto.Buffer, field *protoField, v interface{}) error {
+	if entry := s.mapEntry(field); entry != nil {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected an object, got %v", v)
+		}
+
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			data, err := s.encodeMessage(entry, map[string]interface{}{"key": k, "value": m[k]})
+			if err != nil {
+				return err
+			}
+			encodeTag(b, field, proto.WireBytes)
+			b.EncodeRawBytes(data)
+		}
+		return nil
+	}
+
+	items, ok := v.([]interface{})
+	if !ok {
+		return fmt.Errorf("expected an array, got %v", v)
+	}
+
+	if !field.packed {
+		for _, item := range items {
+			if err := s.encodeValue(b, field, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	packed := proto.NewBuffer(nil)
+	for _, item := range items {
+		if err := s.encodeScalar(packed, field, item); err != nil {
+			return err
+		}
+	}
+	encodeTag(b, field, proto.WireBytes)
+	return b.EncodeRawBytes(packed.Bytes())
+}
+
+func (s *protoSerializer) encodeValue(b *proto.Buffer, field *protoField, v interface{}) error {
+	switch field.typ {
+	case protoMessage:
+		msg, ok := s.registry.messages[field.typeName]
+		if !ok {
+			return fmt.Errorf("unknown message %s", field.typeName)
+		}
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected an object, got %v", v)
+		}
+		data, err := s.encodeMessage(msg, m)
+		if err != nil {
+			return err
+		}
+		encodeTag(b, field, proto.WireBytes)
+		return b.EncodeRawBytes(data)
+	case protoString:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a string, got %v", v)
+		}
+		encodeTag(b, field, proto.WireBytes)
+		return b.EncodeStringBytes(str)
+	case protoBytes:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("expected a base64 string, got %v", v)
+		}
+		data, err := base64.StdEncoding.DecodeString(str)
+		if err != nil {
+			return err
+		}
+		encodeTag(b, field, proto.WireBytes)
+		return b.EncodeRawBytes(data)
+	case protoGroup:
+		return fmt.Errorf("groups are not supported")
+	}
+
+	encodeTag(b, field, scalarWireType(field.typ))
+	return s.encodeScalar(b, field, v)
+}
+
+func (s *protoSerializer) encodeScalar(b *proto.Buffer, field *protoField, v interface{}) error {
+	switch field.typ {
+	case protoDouble:
+		f, err := toFloat(v)
+		if err != nil {
+			return err
+		}
+		return b.EncodeFixed64(math.Float64bits(f))
+	case protoFloat:
+		f, err := toFloat(v)
+		if err != nil {
+			return err
+		}
+		return b.EncodeFixed32(uint64(math.Float32bits(float32(f))))
+	case protoBool:
+		val, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("expected a bool, got %v", v)
+		}
+		if val {
+			return b.EncodeVarint(1)
+		}
+		return b.EncodeVarint(0)
+	case protoEnum:
+		if name, ok := v.(string); ok {
+			enum, ok := s.registry.enums[field.typeName]
+			if !ok {
+				return fmt.Errorf("unknown enum %s", field.typeName)
+			}
+			number, ok := enum.numbers[name]
+			if !ok {
+				return fmt.Errorf("unknown %s value %s", field.typeName, name)
+			}
+			return b.EncodeVarint(uint64(int64(number)))
+		}
+	}
+
+	i, err := toInt(v)
+	if err != nil {
+		return err
+	}
+
+	switch field.typ {
+	case protoSint32:
+		return b.EncodeZigzag32(uint64(i))
+	case protoSint64:
+		return b.EncodeZigzag64(uint64(i))
+	case protoFixed32, protoSfixed32:
+		return b.EncodeFixed32(uint64(uint32(i)))
+	case protoFixed64, protoSfixed64:
+		return b.EncodeFixed64(uint64(i))
+	}
+
+	return b.EncodeVarint(uint64(i))
+}
+
+func (s *protoSerializer) mapEntry(field *protoField) *protoMessageType {
+	if field.typ != protoMessage {
+		return nil
+	}
+
+	if msg, ok := s.registry.messages[field.typeName]; ok && msg.mapEntry {
+		return msg
+	}
+
+	return nil
+}
+
+func (s *protoSerializer) decodeMessage(msg *protoMessageType, data []byte) (map[string]interface{}, error) {
+	ret := make(map[string]interface{}, len(msg.fields))
+	for _, field := range msg.fields {
+		if def := s.defaultValue(field); def != nil {
+			ret[field.name] = def
+		}
+	}
+
+	r := &protoReader{data: data}
+	for !r.done() {
+		number, wireType, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+
+		field, ok := msg.byNumber[number]
+		if !ok {
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := s.decodeField(ret, field, wireType, r); err != nil {
+			return nil, fmt.Errorf("%s.%s: %s", msg.name, field.name, err)
+		}
+	}
+
+	return ret, nil
+}
+
+func (s *protoSerializer) decodeField(ret map[string]interface{}, field *protoField, wireType int, r *protoReader) error {
+	switch field.typ {
+	case protoMessage, protoString, protoBytes:
+		b, err := r.bytes()
+		if err != nil {
+			return err
+		}
+
+		if entry := s.mapEntry(field); entry != nil {
+			kv, err := s.decodeMessage(entry, b)
+			if err != nil {
+				return err
+			}
+			ret[field.name].(map[string]interface{})[fmt.Sprintf("%v", kv["key"])] = kv["value"]
+			return nil
+		}
+
+		var value interface{}
+		switch field.typ {
+		case protoMessage:
+			msg, ok := s.registry.messages[field.typeName]
+			if !ok {
+				return fmt.Errorf("unknown message %s", field.typeName)
+			}
+			if value, err = s.decodeMessage(msg, b); err != nil {
+				return err
+			}
+		case protoString:
+			value = string(b)
+		default:
+			value = base64.StdEncoding.EncodeToString(b)
+		}
+		setField(ret, field, value)
+		return nil
+	case protoGroup:
+		return fmt.Errorf("groups are not supported")
+	}
+
+	if wireType == proto.WireBytes {
+		b, err := r.bytes()
+		if err != nil {
+			return err
+		}
+		packed := &protoReader{data: b}
+		for !packed.done() {
+			value, err := s.decodeScalar(field, packed)
+			if err != nil {
+				return err
+			}
+			setField(ret, field, value)
+		}
+		return nil
+	}
+
+	value, err := s.decodeScalar(field, r)
+	if err != nil {
+		return err
+	}
+	setField(ret, field, value)
+	return nil
+}
+
+func setField(ret map[string]interface{}, field *protoField, value interface{}) {
+	if field.repeated {
+		ret[field.name] = append(ret[field.name].([]interface{}), value)
+		return
+	}
+
+	ret[field.name] = value
+}
+
+func (s *protoSerializer) decodeScalar(field *protoField, r *protoReader) (interface{}, error) {
+	var (
+		v   uint64
+		err error
+	)
+	switch scalarWireType(field.typ) {
+	case proto.WireFixed64:
+		v, err = r.fixed(8)
+	case proto.WireFixed32:
+		v, err = r.fixed(4)
+	default:
+		v, err = r.varint()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch field.typ {
+	case protoDouble:
+		return math.Float64frombits(v), nil
+	case protoFloat:
+		return float64(math.Float32frombits(uint32(v))), nil
+	case protoBool:
+		return v != 0, nil
+	case protoEnum:
+		if enum, ok := s.registry.enums[field.typeName]; ok {
+			if name, ok := enum.names[int32(v)]; ok {
+				return name, nil
+			}
+		}
+		return float64(int32(v)), nil
+	case protoInt32, protoSfixed32:
+		return float64(int32(v)), nil
+	case protoUint32, protoFixed32:
+		return float64(uint32(v)), nil
+	case protoSint32, protoSint64:
+		return float64(int64(v>>1) ^ -int64(v&1)), nil
+	case protoUint64, protoFixed64:
+		return float64(v), nil
+	}
+
+	return float64(int64(v)), nil
+}
+
+// defaultValue is the value of fields missing from the wire, nil for
+// singular messages
+func (s *protoSerializer) defaultValue(field *protoField) interface{} {
+	if s.mapEntry(field) != nil {
+		return map[string]interface{}{}
+	}
+	if field.repeated {
+		return []interface{}{}
+	}
+
+	switch field.typ {
+	case protoMessage, protoGroup:
+		return nil
+	case protoString, protoBytes:
+		return ""
+	case protoBool:
+		return false
+	case protoEnum:
+		if enum, ok := s.registry.enums[field.typeName]; ok {
+			if name, ok := enum.names[0]; ok {
+				return name
+			}
+		}
+	}
+
+	return float64(0)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case float32:
+		return float64(val), nil
+	case int:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case json.Number:
+		return val.Float64()
+	case string:
+		return strconv.ParseFloat(val, 64)
+	}
+
+	return 0, fmt.Errorf("expected a number, got %v", v)
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch val := v.(type) {
+	case int:
+		return int64(val), nil
+	case int32:
+		return int64(val), nil
+	case int64:
+		return val, nil
+	case float64:
+		if val != math.Trunc(val) {
+			return 0, fmt.Errorf("expected an integer, got %v", v)
+		}
+		return int64(val), nil
+	case json.Number:
+		return val.Int64()
+	case string:
+		return strconv.ParseInt(val, 10, 64)
+	}
+
+	return 0, fmt.Errorf("expected an integer, got %v", v)
+}
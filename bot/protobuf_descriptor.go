@@ -0,0 +1,301 @@
+package bot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// protobuf field types
+const (
+	protoDouble   = descriptor.FieldDescriptorProto_TYPE_DOUBLE
+	protoFloat    = descriptor.FieldDescriptorProto_TYPE_FLOAT
+	protoInt64    = descriptor.FieldDescriptorProto_TYPE_INT64
+	protoUint64   = descriptor.FieldDescriptorProto_TYPE_UINT64
+	protoInt32    = descriptor.FieldDescriptorProto_TYPE_INT32
+	protoFixed64  = descriptor.FieldDescriptorProto_TYPE_FIXED64
+	protoFixed32  = descriptor.FieldDescriptorProto_TYPE_FIXED32
+	protoBool     = descriptor.FieldDescriptorProto_TYPE_BOOL
+	protoString   = descriptor.FieldDescriptorProto_TYPE_STRING
+	protoGroup    = descriptor.FieldDescriptorProto_TYPE_GROUP
+	protoMessage  = descriptor.FieldDescriptorProto_TYPE_MESSAGE
+	protoBytes    = descriptor.FieldDescriptorProto_TYPE_BYTES
+	protoUint32   = descriptor.FieldDescriptorProto_TYPE_UINT32
+	protoEnum     = descriptor.FieldDescriptorProto_TYPE_ENUM
+	protoSfixed32 = descriptor.FieldDescriptorProto_TYPE_SFIXED32
+	protoSfixed64 = descriptor.FieldDescriptorProto_TYPE_SFIXED64
+	protoSint32   = descriptor.FieldDescriptorProto_TYPE_SINT32
+	protoSint64   = descriptor.FieldDescriptorProto_TYPE_SINT64
+)
+
+var errTruncated = errors.New("truncated protobuf message")
+
+// protoReader walks the fields of a protobuf message. golang/protobuf only
+// decodes generated messages, so the messages of a descriptor set are read
+// field by field
+type protoReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *protoReader) done() bool {
+	return r.pos >= len(r.data)
+}
+
+func (r *protoReader) varint() (uint64, error) {
+	v, n := proto.DecodeVarint(r.data[r.pos:])
+	if n == 0 {
+		return 0, errTruncated
+	}
+	r.pos += n
+	return v, nil
+}
+
+func (r *protoReader) fixed(size int) (uint64, error) {
+	if r.pos+size > len(r.data) {
+		return 0, errTruncated
+	}
+
+	var v uint64
+	if size == 4 {
+		v = uint64(binary.LittleEndian.Uint32(r.data[r.pos:]))
+	} else {
+		v = binary.LittleEndian.Uint64(r.data[r.pos:])
+	}
+	r.pos += size
+	return v, nil
+}
+
+func (r *protoReader) bytes() ([]byte, error) {
+	n, err := r.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.data)-r.pos) < n {
+		return nil, errTruncated
+	}
+
+	b := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// next reads the next field tag
+func (r *protoReader) next() (int, int, error) {
+	tag, err := r.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+func (r *protoReader) skip(wireType int) error {
+	var err error
+	switch wireType {
+	case proto.WireVarint:
+		_, err = r.varint()
+	case proto.WireFixed64:
+		_, err = r.fixed(8)
+	case proto.WireBytes:
+		_, err = r.bytes()
+	case proto.WireFixed32:
+		_, err = r.fixed(4)
+	default:
+		err = fmt.Errorf("unsupported protobuf wire type %d", wireType)
+	}
+
+	return err
+}
+
+// protoField is what the codec needs from a FieldDescriptorProto
+type protoField struct {
+	name     string
+	jsonName string
+	number   int
+	repeated bool
+	typ      descriptor.FieldDescriptorProto_Type
+	typeName string
+	packed   bool
+}
+
+// protoMessageType is what the codec needs from a DescriptorProto
+type protoMessageType struct {
+	name     string
+	fields   []*protoField
+	byNumber map[int]*protoField
+	byName   map[string]*protoField
+	mapEntry bool
+}
+
+// protoEnumType maps enum value names to numbers and back
+type protoEnumType struct {
+	numbers map[string]int32
+	names   map[int32]string
+}
+
+// protoMethod holds the message types of a service method
+type protoMethod struct {
+	input  string
+	output string
+}
+
+// protoRegistry holds the message and enum types of a descriptor set, by
+// fully qualified name without the leading dot, and its service methods,
+// by package.Service/Method. Registries fetched from a server also hold the
+// routes its docs map to messages
+type protoRegistry struct {
+	messages map[string]*protoMessageType
+	enums    map[string]*protoEnumType
+	methods  map[string]*protoMethod
+	routes   []ProtoRoute
+}
+
+// loadDescriptorSet reads a FileDescriptorSet, as written by protoc
+// --include_imports --descriptor_set_out
+func loadDescriptorSet(path string) (*protoRegistry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read descriptor set: %s", err)
+	}
+
+	registry, err := parseDescriptorSet(data)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed descriptor set %s: %s", path, err)
+	}
+
+	return registry, nil
+}
+
+func parseDescriptorSet(data []byte) (*protoRegistry, error) {
+	set := &descriptor.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, err
+	}
+
+	registry := newProtoRegistry()
+	for _, file := range set.File {
+		registry.addFile(file)
+	}
+
+	return registry, nil
+}
+
+func newProtoRegistry() *protoRegistry {
+	return &protoRegistry{
+		messages: map[string]*protoMessageType{},
+		enums:    map[string]*protoEnumType{},
+		methods:  map[string]*protoMethod{},
+	}
+}
+
+func (p *protoRegistry) addFile(file *descriptor.FileDescriptorProto) {
+	prefix := ""
+	if file.GetPackage() != "" {
+		prefix = file.GetPackage() + "."
+	}
+	proto3 := file.GetSyntax() == "proto3"
+
+	for _, m := range file.MessageType {
+		p.addMessage(prefix, m, proto3)
+	}
+	for _, e := range file.EnumType {
+		p.addEnum(prefix, e)
+	}
+	for _, s := range file.Service {
+		for _, m := range s.Method {
+			p.methods[prefix+s.GetName()+"/"+m.GetName()] = &protoMethod{
+				input:  strings.TrimPrefix(m.GetInputType(), "."),
+				output: strings.TrimPrefix(m.GetOutputType(), "."),
+			}
+		}
+	}
+}
+
+func (p *protoRegistry) addMessage(prefix string, m *descriptor.DescriptorProto, proto3 bool) {
+	msg := &protoMessageType{
+		name:     prefix + m.GetName(),
+		byNumber: map[int]*protoField{},
+		byName:   map[string]*protoField{},
+		mapEntry: m.GetOptions().GetMapEntry(),
+	}
+
+	for _, f := range m.Field {
+		field := newProtoField(f, proto3)
+		msg.fields = append(msg.fields, field)
+		msg.byNumber[field.number] = field
+		msg.byName[field.name] = field
+		if field.jsonName != "" {
+			msg.byName[field.jsonName] = field
+		}
+	}
+	p.messages[msg.name] = msg
+
+	for _, n := range m.NestedType {
+		p.addMessage(msg.name+".", n, proto3)
+	}
+	for _, e := range m.EnumType {
+		p.addEnum(msg.name+".", e)
+	}
+}
+
+// newProtoField returns the field, repeated scalars being packed unless
+// told otherwise in proto3 files
+func newProtoField(f *descriptor.FieldDescriptorProto, proto3 bool) *protoField {
+	field := &protoField{
+		name:     f.GetName(),
+		jsonName: f.GetJsonName(),
+		number:   int(f.GetNumber()),
+		repeated: f.GetLabel() == descriptor.FieldDescriptorProto_LABEL_REPEATED,
+		typ:      f.GetType(),
+		typeName: strings.TrimPrefix(f.GetTypeName(), "."),
+	}
+
+	if f.GetOptions() != nil && f.GetOptions().Packed != nil {
+		field.packed = f.GetOptions().GetPacked()
+	} else {
+		field.packed = proto3 && field.repeated && isPackable(field.typ)
+	}
+
+	return field
+}
+
+func (p *protoRegistry) addEnum(prefix string, e *descriptor.EnumDescriptorProto) {
+	enum := &protoEnumType{numbers: map[string]int32{}, names: map[int32]string{}}
+	for _, value := range e.Value {
+		enum.numbers[value.GetName()] = value.GetNumber()
+		if _, ok := enum.names[value.GetNumber()]; !ok {
+			enum.names[value.GetNumber()] = value.GetName()
+		}
+	}
+
+	p.enums[prefix+e.GetName()] = enum
+}
+
+func isPackable(typ descriptor.FieldDescriptorProto_Type) bool {
+	switch typ {
+	case protoString, protoBytes, protoMessage, protoGroup:
+		return false
+	}
+
+	return true
+}
+
+// scalarWireType returns the wire type scalar values of typ are encoded with
+func scalarWireType(typ descriptor.FieldDescriptorProto_Type) int {
+	switch typ {
+	case protoDouble, protoFixed64, protoSfixed64:
+		return proto.WireFixed64
+	case protoFloat, protoFixed32, protoSfixed32:
+		return proto.WireFixed32
+	case protoString, protoBytes, protoMessage:
+		return proto.WireBytes
+	}
+
+	return proto.WireVarint
+}
@@ -0,0 +1,201 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// sharedServerProtoRegistry fetches the protobuf messages the server
+// documents once for every bot, through a connection of its own
+func sharedServerProtoRegistry(host string, opts *PClientOptions) (*protoRegistry, error) {
+	if opts.ProtosRoute == "" {
+		return nil, fmt.Errorf("Protobuf serializer docsRoute needs serializer.protosRoute")
+	}
+
+	protoRegistriesMutex.Lock()
+	defer protoRegistriesMutex.Unlock()
+
+	key := fmt.Sprintf("%s %s %s", host, opts.DocsRoute, opts.ProtosRoute)
+	if registry, ok := protoRegistries[key]; ok {
+		return registry, nil
+	}
+
+	docsOpts := *opts
+	docsOpts.Serializer = "json"
+	docsOpts.FaultInjection = false
+	docsOpts.Network = nil
+	client, err := NewPClient(host, &docsOpts)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to fetch the server protos: %s", err)
+	}
+	client.StartListening()
+	defer client.Disconnect()
+
+	registry, err := fetchServerProtos(client, opts.DocsRoute, opts.ProtosRoute)
+	if err != nil {
+		return nil, err
+	}
+	protoRegistries[key] = registry
+
+	return registry, nil
+}
+
+// fetchServerProtos builds a registry from the server docs, as pitaya
+// documents its handlers with their pointer type names, and from the file
+// descriptors of those types, as its protos route returns them gzipped
+func fetchServerProtos(client *PClient, docsRoute, protosRoute string) (*protoRegistry, error) {
+	ctx := context.Background()
+	data, _, err := client.roundTrip(ctx, docsRoute, nil, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch the server docs from %s: %s", docsRoute, err)
+	}
+
+	// protos.Doc, the json docs being its field 1
+	docs, err := repeatedBytesField(data, 1)
+	if err != nil || len(docs) != 1 {
+		return nil, fmt.Errorf("Malformed server docs from %s", docsRoute)
+	}
+	routes, names, err := parseServerDocs(docs[0])
+	if err != nil {
+		return nil, fmt.Errorf("Malformed server docs from %s: %s", docsRoute, err)
+	}
+
+	// protos.ProtoNames, the names being its repeated field 1
+	b := proto.NewBuffer(nil)
+	nameField := &protoField{number: 1}
+	for _, name := range names {
+		encodeTag(b, nameField, proto.WireBytes)
+		b.EncodeStringBytes(name)
+	}
+	if data, _, err = client.roundTrip(ctx, protosRoute, b.Bytes(), 0); err != nil {
+		return nil, fmt.Errorf("Unable to fetch the server protos from %s: %s", protosRoute, err)
+	}
+
+	// protos.ProtoDescriptors, the descriptors being its repeated field 1
+	descriptors, err := repeatedBytesField(data, 1)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed server protos from %s: %s", protosRoute, err)
+	}
+
+	registry := newProtoRegistry()
+	for _, desc := range descriptors {
+		file, err := unmarshalFileDescriptor(desc)
+		if err != nil {
+			return nil, fmt.Errorf("Malformed server protos from %s: %s", protosRoute, err)
+		}
+		registry.addFile(file)
+	}
+	registry.routes = routes
+
+	return registry, nil
+}
+
+// repeatedBytesField returns the values of the bytes or string field
+// number of a message
+func repeatedBytesField(data []byte, number int) ([][]byte, error) {
+	r := &protoReader{data: data}
+	values := make([][]byte, 0)
+	for !r.done() {
+		n, wireType, err := r.next()
+		if err != nil {
+			return nil, err
+		}
+		if n != number || wireType != proto.WireBytes {
+			if err := r.skip(wireType); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		value, err := r.bytes()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// parseServerDocs returns the routes of the documented handlers with their
+// request and response messages, and the names of those messages. Handler
+// inputs and outputs are maps keyed by *package.Message for protobuf
+// messages, outputs being listed along with their error
+func parseServerDocs(data []byte) ([]ProtoRoute, []string, error) {
+	var docs struct {
+		Handlers map[string]struct {
+			Input  interface{}   `json:"input"`
+			Output []interface{} `json:"output"`
+		} `json:"handlers"`
+	}
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return nil, nil, err
+	}
+
+	routes := make([]ProtoRoute, 0, len(docs.Handlers))
+	seen := map[string]bool{}
+	names := make([]string, 0)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for route, handler := range docs.Handlers {
+		r := ProtoRoute{Route: route, Request: docsMessageName(handler.Input)}
+		if len(handler.Output) > 0 {
+			r.Response = docsMessageName(handler.Output[0])
+		}
+		if r.Request == "" && r.Response == "" {
+			continue
+		}
+		add(r.Request)
+		add(r.Response)
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool { return routes[i].Route < routes[j].Route })
+	sort.Strings(names)
+
+	return routes, names, nil
+}
+
+// docsMessageName returns the message name of a documented type, empty when
+// it is not a protobuf message
+func docsMessageName(typ interface{}) string {
+	fields, ok := typ.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	for key := range fields {
+		if strings.HasPrefix(key, "*") {
+			return key[1:]
+		}
+	}
+	return ""
+}
+
+// unmarshalFileDescriptor decodes a FileDescriptorProto, gzipped as
+// golang/protobuf registers them or not
+func unmarshalFileDescriptor(data []byte) (*descriptor.FileDescriptorProto, error) {
+	if zr, err := gzip.NewReader(bytes.NewReader(data)); err == nil {
+		if data, err = ioutil.ReadAll(zr); err != nil {
+			return nil, err
+		}
+	}
+
+	file := &descriptor.FileDescriptorProto{}
+	if err := proto.Unmarshal(data, file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -0,0 +1,120 @@
+package bot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+)
+
+// bytesFieldMessage encodes values as the repeated bytes field 1 of a
+// message, like protos.Doc and protos.ProtoDescriptors
+func bytesFieldMessage(values ...[]byte) string {
+	b := proto.NewBuffer(nil)
+	for _, value := range values {
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeRawBytes(value)
+	}
+	return string(b.Bytes())
+}
+
+func gzippedTestFileDescriptor(t *testing.T) []byte {
+	set := &descriptor.FileDescriptorSet{}
+	assert.NoError(t, proto.Unmarshal(testDescriptorSet(), set))
+	data, err := proto.Marshal(set.File[0])
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+func newTestDocsClient(transport *recordingTransport) *PClient {
+	client := &PClient{
+		client:    transport,
+		responses: make(map[uint]chan []byte),
+		pushes:    make(map[string]chan *Push),
+	}
+	client.StartListening()
+	return client
+}
+
+func TestFetchServerProtos(t *testing.T) {
+	docs := `{"handlers": {
+		"room.room.join": {"input": {"*room.JoinRequest": {"roomId": "string"}}, "output": [{"*room.Player": {"name": "string"}}, "error"]},
+		"room.room.ping": {"input": null, "output": [{"pong": "string"}, "error"]}
+	}}`
+	transport := &recordingTransport{responses: []string{
+		bytesFieldMessage([]byte(docs)),
+		bytesFieldMessage(gzippedTestFileDescriptor(t)),
+	}}
+
+	registry, err := fetchServerProtos(newTestDocsClient(transport), "connector.docsHandler.docs", "connector.docsHandler.protos")
+	assert.NoError(t, err)
+	assert.Equal(t, []ProtoRoute{{Route: "room.room.join", Request: "room.JoinRequest", Response: "room.Player"}}, registry.routes)
+
+	names, err := repeatedBytesField([]byte(transport.sent[1]), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("room.JoinRequest"), []byte("room.Player")}, names)
+
+	s, err := registry.serializer([]ProtoRoute{{Route: "onPlayer", Push: "room.Player"}})
+	assert.NoError(t, err)
+	data, err := s.marshal("room.room.join", map[string]interface{}{"roomId": "lobby"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0a, 0x05, 'l', 'o', 'b', 'b', 'y'}, data)
+
+	player := []byte{0x0a, 0x06, 'k', 'n', 'i', 'g', 'h', 't'}
+	resp, err := s.unmarshalResponse("room.room.join", player)
+	assert.NoError(t, err)
+	assert.Equal(t, "knight", resp["name"])
+	push, err := s.unmarshalPush("onPlayer", player)
+	assert.NoError(t, err)
+	assert.Equal(t, "knight", push["name"])
+}
+
+func TestFetchServerProtosErrors(t *testing.T) {
+	tables := []struct {
+		name      string
+		responses []string
+		expected  string
+	}{
+		{"no docs", []string{"\x10\x01"}, "Malformed server docs from docs"},
+		{"docs not json", []string{bytesFieldMessage([]byte("{"))}, "Malformed server docs from docs: unexpected end of JSON input"},
+		{"malformed protos", []string{bytesFieldMessage([]byte(`{"handlers": {}}`)), bytesFieldMessage([]byte{0xff})}, "Malformed server protos from protos"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: table.responses}
+			_, err := fetchServerProtos(newTestDocsClient(transport), "docs", "protos")
+			assert.Contains(t, err.Error(), table.expected)
+		})
+	}
+}
+
+func TestSharedServerProtoRegistry(t *testing.T) {
+	opts := &PClientOptions{DocsRoute: "docs"}
+	_, err := sharedServerProtoRegistry("localhost:1", opts)
+	assert.EqualError(t, err, "Protobuf serializer docsRoute needs serializer.protosRoute")
+
+	// fetched registries are shared, without connecting again
+	opts.ProtosRoute = "protos"
+	registry := newProtoRegistry()
+	protoRegistriesMutex.Lock()
+	protoRegistries["localhost:1 docs protos"] = registry
+	protoRegistriesMutex.Unlock()
+	defer func() {
+		protoRegistriesMutex.Lock()
+		delete(protoRegistries, "localhost:1 docs protos")
+		protoRegistriesMutex.Unlock()
+	}()
+
+	shared, err := sharedServerProtoRegistry("localhost:1", opts)
+	assert.NoError(t, err)
+	assert.True(t, shared == registry)
+}
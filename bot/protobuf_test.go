@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldDescriptor(name string, number int32, label descriptor.FieldDescriptorProto_Label, typ descriptor.FieldDescriptorProto_Type, typeName, jsonName string) *descriptor.FieldDescriptorProto {
+	field := &descriptor.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Label:    label.Enum(),
+		Type:     typ.Enum(),
+		JsonName: proto.String(jsonName),
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+func marshalDescriptorSet(files ...*descriptor.FileDescriptorProto) []byte {
+	data, err := proto.Marshal(&descriptor.FileDescriptorSet{File: files})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// testDescriptorSet describes, in proto3:
+//
+//	enum Role { MEMBER = 0; ADMIN = 1; }
+//	message Player { string name = 1; bool vip = 2; repeated string tags = 3; }
+//	message JoinRequest {
+//	  string room_id = 1; repeated int32 seats = 2; Role role = 3;
+//	  map<string, int64> scores = 4; Player player = 5; sint32 delta = 6;
+//	  bytes token = 7; double ratio = 8;
+//	}
+func testDescriptorSet() []byte {
+	optional, repeated := descriptor.FieldDescriptorProto_LABEL_OPTIONAL, descriptor.FieldDescriptorProto_LABEL_REPEATED
+
+	role := &descriptor.EnumDescriptorProto{
+		Name: proto.String("Role"),
+		Value: []*descriptor.EnumValueDescriptorProto{
+			{Name: proto.String("MEMBER"), Number: proto.Int32(0)},
+			{Name: proto.String("ADMIN"), Number: proto.Int32(1)},
+		},
+	}
+	player := &descriptor.DescriptorProto{
+		Name: proto.String("Player"),
+		Field: []*descriptor.FieldDescriptorProto{
+			fieldDescriptor("name", 1, optional, protoString, "", "name"),
+			fieldDescriptor("vip", 2, optional, protoBool, "", "vip"),
+			fieldDescriptor("tags", 3, repeated, protoString, "", "tags"),
+		},
+	}
+	scoresEntry := &descriptor.DescriptorProto{
+		Name: proto.String("ScoresEntry"),
+		Field: []*descriptor.FieldDescriptorProto{
+			fieldDescriptor("key", 1, optional, protoString, "", "key"),
+			fieldDescriptor("value", 2, optional, protoInt64, "", "value"),
+		},
+		Options: &descriptor.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+	join := &descriptor.DescriptorProto{
+		Name: proto.String("JoinRequest"),
+		Field: []*descriptor.FieldDescriptorProto{
+			fieldDescriptor("room_id", 1, optional, protoString, "", "roomId"),
+			fieldDescriptor("seats", 2, repeated, protoInt32, "", "seats"),
+			fieldDescriptor("role", 3, optional, protoEnum, ".room.Role", "role"),
+			fieldDescriptor("scores", 4, repeated, protoMessage, ".room.JoinRequest.ScoresEntry", "scores"),
+			fieldDescriptor("player", 5, optional, protoMessage, ".room.Player", "player"),
+			fieldDescriptor("delta", 6, optional, protoSint32, "", "delta"),
+			fieldDescriptor("token", 7, optional, protoBytes, "", "token"),
+			fieldDescriptor("ratio", 8, optional, protoDouble, "", "ratio"),
+		},
+		NestedType: []*descriptor.DescriptorProto{scoresEntry},
+	}
+
+	return marshalDescriptorSet(&descriptor.FileDescriptorProto{
+		Name:        proto.String("room.proto"),
+		Package:     proto.String("room"),
+		MessageType: []*descriptor.DescriptorProto{player, join},
+		EnumType:    []*descriptor.EnumDescriptorProto{role},
+		Syntax:      proto.String("proto3"),
+	})
+}
+
+func newTestProtoSerializer(t *testing.T) *protoSerializer {
+	registry, err := parseDescriptorSet(testDescriptorSet())
+	assert.NoError(t, err)
+
+	s, err := registry.serializer([]ProtoRoute{
+		{Route: "room.room.join", Request: "room.JoinRequest", Response: "room.JoinRequest"},
+		{Route: "onPlayer", Push: "room.Player"},
+	})
+	assert.NoError(t, err)
+	return s
+}
+
+func TestProtoSerializerRoundTrip(t *testing.T) {
+	s := newTestProtoSerializer(t)
+
+	args := map[string]interface{}{
+		"roomId": "lobby",
+		"seats":  []interface{}{float64(1), float64(2)},
+		"role":   "ADMIN",
+		"scores": map[string]interface{}{"ann": float64(10), "bob": 3},
+		"player": map[string]interface{}{"name": "knight", "tags": []interface{}{"a", "b"}},
+		"delta":  -3,
+		"token":  "AQI=",
+		"ratio":  0.5,
+	}
+
+	data, err := s.marshal("room.room.join", args)
+	assert.NoError(t, err)
+
+	resp, err := s.unmarshalResponse("room.room.join", data)
+	assert.NoError(t, err)
+	assert.Equal(t, Response{
+		"room_id": "lobby",
+		"seats":   []interface{}{float64(1), float64(2)},
+		"role":    "ADMIN",
+		"scores":  map[string]interface{}{"ann": float64(10), "bob": float64(3)},
+		"player":  map[string]interface{}{"name": "knight", "vip": false, "tags": []interface{}{"a", "b"}},
+		"delta":   float64(-3),
+		"token":   "AQI=",
+		"ratio":   0.5,
+	}, resp)
+}
+
+func TestProtoSerializerWire(t *testing.T) {
+	s := newTestProtoSerializer(t)
+
+	data, err := s.marshal("room.room.join", map[string]interface{}{"seats": []interface{}{1, 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x12, 0x02, 0x01, 0x02}, data, "proto3 repeated scalars are packed")
+
+	resp, err := s.unmarshalResponse("room.room.join", []byte{0x10, 0x01, 0x10, 0x02, 0x18, 0x05})
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{float64(1), float64(2)}, resp["seats"], "unpacked input is accepted")
+	assert.Equal(t, float64(5), resp["role"], "unknown enum values are kept as numbers")
+	assert.Equal(t, "", resp["room_id"])
+	_, hasPlayer := resp["player"]
+	assert.False(t, hasPlayer)
+
+	push, err := s.unmarshalPush("onPlayer", []byte{0x0a, 0x06, 'k', 'n', 'i', 'g', 'h', 't', 0x10, 0x01})
+	assert.NoError(t, err)
+	assert.Equal(t, Response{"name": "knight", "vip": true, "tags": []interface{}{}}, push)
+}
+
+func TestProtoSerializerErrors(t *testing.T) {
+	s := newTestProtoSerializer(t)
+
+	tables := []struct {
+		name  string
+		route string
+		args  map[string]interface{}
+	}{
+		{"unknown route", "room.room.leave", map[string]interface{}{}},
+		{"unknown field", "room.room.join", map[string]interface{}{"floor": 1}},
+		{"unknown enum value", "room.room.join", map[string]interface{}{"role": "OWNER"}},
+		{"wrong type", "room.room.join", map[string]interface{}{"roomId": 1}},
+		{"fractional integer", "room.room.join", map[string]interface{}{"delta": 1.5}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := s.marshal(table.route, table.args)
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := s.unmarshalResponse("room.room.join", []byte{0x0a, 0x05, 'a'})
+	assert.Error(t, err)
+
+	registry, _ := parseDescriptorSet(testDescriptorSet())
+	_, err = registry.serializer([]ProtoRoute{{Route: "x", Request: "room.Missing"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,98 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// pushHandlers consumes the spec pushHandlers routes in the background.
+// Pushes are queued as they arrive and handled by the bot itself before
+// each operation, so they never touch its storage concurrently
+type pushHandlers struct {
+	mutex    sync.Mutex
+	queue    []*queuedPush
+	failures []string
+}
+
+type queuedPush struct {
+	handler *models.PushHandlerSpec
+	push    *Push
+}
+
+// register starts consuming the handlers routes of a new connection
+func (h *pushHandlers) register(client *PClient, specs []*models.PushHandlerSpec) {
+	for _, spec := range specs {
+		spec := spec
+		client.Consume(spec.Route, func(push *Push) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+			h.queue = append(h.queue, &queuedPush{handler: spec, push: push})
+		})
+	}
+}
+
+func (h *pushHandlers) take() []*queuedPush {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	queue := h.queue
+	h.queue = nil
+	return queue
+}
+
+func (h *pushHandlers) fail(format string, args ...interface{}) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.failures = append(h.failures, fmt.Sprintf(format, args...))
+}
+
+// err returns the validation failures of the handled pushes
+func (h *pushHandlers) err() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("Push handlers failed: %s", strings.Join(h.failures, "; "))
+}
+
+// handlePushes handles the pushes queued since the last call: validating
+// them, storing their fields and incrementing their counters
+func (b *SequentialBot) handlePushes() {
+	if b.pushHandlers == nil {
+		return
+	}
+
+	for _, queued := range b.pushHandlers.take() {
+		spec := queued.handler
+		resp, err := queued.push.decode()
+		if err != nil {
+			b.pushHandlers.fail("%s: %s", spec.Route, err)
+			continue
+		}
+		meta := pushMetadata(spec.Route, queued.push)
+
+		if err := validateExpectations(spec.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+			b.pushHandlers.fail("%s: %s", spec.Route, err)
+			reportError(b.metricsReporter, metricsTags(spec.Route, nil), metrics.ExpectationError)
+			continue
+		}
+
+		if err := storeData(spec.Store, b.storage, resp, meta); err != nil {
+			b.pushHandlers.fail("%s: %s", spec.Route, err)
+			continue
+		}
+
+		if spec.Count != "" {
+			count, _ := b.storage.Get(spec.Count)
+			n, _ := count.(int)
+			b.storage.Set(spec.Count, n+1)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestPushHandlers(t *testing.T) {
+	tables := []struct {
+		name   string
+		pushes []string
+		err    bool
+	}{
+		{"valid pushes", []string{`{"gold": 10}`, `{"gold": 30}`}, false},
+		{"invalid push", []string{`{"gold": 10}`, `{"gold": -5}`}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+			b.spec = &models.Spec{
+				SequentialOperations: []*models.Operation{{Type: "request", URI: "shop.list"}},
+				PushHandlers: []*models.PushHandlerSpec{{
+					Route:  "wallet.changed",
+					Expect: models.ExpectSpec{"gold": {Type: "int", Value: map[string]interface{}{"$gte": 0}}},
+					Store:  models.StoreSpec{"gold": {Type: "int", Value: "gold"}},
+					Count:  "walletChanges",
+				}},
+			}
+			b.pushHandlers = &pushHandlers{}
+			b.pushHandlers.register(b.sessions.clients[defaultSession], b.spec.PushHandlers)
+
+			for _, push := range table.pushes {
+				transport.handler(MsgPushType, 0, "wallet.changed", []byte(push))
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			err := b.Run(context.Background())
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			gold, _ := b.storage.Get("gold")
+			assert.Equal(t, 30, gold)
+			count, _ := b.storage.Get("walletChanges")
+			assert.Equal(t, 2, count)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+const defaultOperationWeight = 1.0
+
+// RandomBot runs operations picked at random from the spec random set,
+// proportionally to their weight, among those whose preconditions hold
+type RandomBot struct {
+	*SequentialBot
+	random *rand.Rand
+}
+
+// NewRandomBot returns a new random bot, seeded like shuffled specs
+func NewRandomBot(app *state.App, config *viper.Viper, spec *models.Spec, id int, logger logrus.FieldLogger) (Bot, error) {
+	if spec.Random == nil || len(spec.Random.Operations) == 0 {
+		return nil, fmt.Errorf("Missing random operations")
+	}
+	if spec.Random.Steps < 0 {
+		return nil, fmt.Errorf("Random steps must not be negative")
+	}
+	if spec.Random.Steps == 0 && spec.MaxDuration <= 0 {
+		return nil, fmt.Errorf("Random bot without steps limit needs maxDuration")
+	}
+	for idx, op := range spec.Random.Operations {
+		if op.Weight < 0 {
+			return nil, fmt.Errorf("Random operation %d has a negative weight", idx)
+		}
+	}
+
+	seq, err := NewSequentialBot(app, config, spec, id, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	b := seq.(*SequentialBot)
+	return &RandomBot{
+		SequentialBot: b,
+		random:        rand.New(rand.NewSource(b.shuffleSeed)),
+	}, nil
+}
+
+// Run runs Random.Steps operations, or runs them until ctx is done when it
+// is 0, in which case being interrupted does not fail the run
+func (b *RandomBot) Run(ctx context.Context) error {
+	defer b.Disconnect()
+
+	return b.runWithHooks(ctx, b.runRandom)
+}
+
+func (b *RandomBot) runRandom(ctx context.Context) error {
+	steps := b.spec.Random.Steps
+	step := 0
+	for ; steps == 0 || step < steps; step++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		idx, err := b.pick()
+		if err != nil {
+			return fmt.Errorf("Step %d: %s", step, err)
+		}
+
+		if err := b.runStep(ctx, idx, b.spec.Random.Operations[idx]); err != nil {
+			if steps == 0 && ctx.Err() != nil && !b.abandoned {
+				break
+			}
+			return fmt.Errorf("Step %d: %s", step, err)
+		}
+	}
+	b.logger.Infof("Finished %d random steps", step)
+
+	if report := b.correlations.report(); report != "" {
+		return fmt.Errorf("Unmatched correlations: %s", report)
+	}
+
+	return nil
+}
+
+// pick returns the index of the next operation among the ones whose
+// preconditions hold
+func (b *RandomBot) pick() (int, error) {
+	ops := b.spec.Random.Operations
+	eligible := make([]int, 0, len(ops))
+	total := 0.0
+	for idx, op := range ops {
+		if validateExpectations(op.Preconditions, Response(*b.storage), nil, b.storage, b.strictRefs) != nil {
+			continue
+		}
+		eligible = append(eligible, idx)
+		total += operationWeight(op)
+	}
+
+	if len(eligible) == 0 || total <= 0 {
+		return 0, fmt.Errorf("No random operation has its preconditions met")
+	}
+
+	r := b.random.Float64() * total
+	for _, idx := range eligible {
+		r -= operationWeight(ops[idx])
+		if r < 0 {
+			return idx, nil
+		}
+	}
+
+	return eligible[len(eligible)-1], nil
+}
+
+func operationWeight(op *models.Operation) float64 {
+	if op.Weight == 0 {
+		return defaultOperationWeight
+	}
+
+	return op.Weight
+}
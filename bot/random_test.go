@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func newTestRandomBot(transport *recordingTransport, random *models.RandomSpec) *RandomBot {
+	b := newTestBot(transport)
+	b.spec = &models.Spec{Random: random}
+	return &RandomBot{SequentialBot: b, random: rand.New(rand.NewSource(1))}
+}
+
+func TestRandomBotPickWeights(t *testing.T) {
+	b := newTestRandomBot(&recordingTransport{}, &models.RandomSpec{
+		Operations: []*models.Operation{
+			{Type: "request", URI: "shop.browse", Weight: 3},
+			{Type: "request", URI: "shop.buy"},
+		},
+	})
+
+	counts := make([]int, 2)
+	for i := 0; i < 1000; i++ {
+		idx, err := b.pick()
+		assert.NoError(t, err)
+		counts[idx]++
+	}
+
+	assert.InDelta(t, 750, counts[0], 60)
+	assert.InDelta(t, 250, counts[1], 60)
+}
+
+func TestRandomBotPreconditions(t *testing.T) {
+	b := newTestRandomBot(&recordingTransport{}, &models.RandomSpec{
+		Operations: []*models.Operation{
+			{Type: "request", URI: "shop.sell", Preconditions: models.ExpectSpec{"item": {Type: "string", Value: "sword"}}},
+		},
+	})
+
+	_, err := b.pick()
+	assert.EqualError(t, err, "No random operation has its preconditions met")
+
+	b.storage.Set("item", "shield")
+	_, err = b.pick()
+	assert.Error(t, err)
+
+	b.storage.Set("item", "sword")
+	idx, err := b.pick()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, idx)
+}
+
+func TestRandomBotRun(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestRandomBot(transport, &models.RandomSpec{
+		Steps: 5,
+		Operations: []*models.Operation{
+			{Type: "request", URI: "shop.browse"},
+		},
+	})
+
+	assert.NoError(t, b.Run(context.Background()))
+	assert.Len(t, transport.sent, 5)
+}
@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// Rate limiters, reported as the limiter label of RateLimitCount
+const (
+	botLimiter    = "bot"
+	globalLimiter = "global"
+)
+
+// limit waits until the bot limiter, bot.maxRPS, and the limiter shared by
+// every bot, loadtest.maxRPS, let a request to route through, counting the
+// requests each of them holds back
+func (b *SequentialBot) limit(ctx context.Context, route string, opTags map[string]string) error {
+	limiters := []struct {
+		name    string
+		limiter *state.RateLimiter
+	}{
+		{botLimiter, b.rateLimiter},
+		{globalLimiter, b.globalLimiter},
+	}
+
+	for _, l := range limiters {
+		limited, err := l.limiter.Wait(ctx)
+		if limited {
+			tags := metricsTags(route, opTags)
+			tags["limiter"] = l.name
+			for _, mr := range b.metricsReporter {
+				mr.ReportCount(metrics.RateLimitCount, tags, 1)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestRateLimit(t *testing.T) {
+	tables := []struct {
+		name     string
+		bot      *state.RateLimiter
+		global   *state.RateLimiter
+		limiters []string
+	}{
+		{"no limit", nil, nil, nil},
+		{"bot limit", state.NewRateLimiter(50), nil, []string{botLimiter, botLimiter}},
+		{"global limit", nil, state.NewRateLimiter(50), []string{globalLimiter, globalLimiter}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			b.rateLimiter, b.globalLimiter = table.bot, table.global
+			reporter := &countingReporter{counts: map[string]float64{}}
+			b.metricsReporter = []metrics.Reporter{reporter}
+
+			start := time.Now()
+			for i := 0; i < 52; i++ {
+				assert.NoError(t, b.runOperation(context.Background(), &models.Operation{Type: "notify", URI: "room.ping"}))
+			}
+			assert.Equal(t, table.limiters, reporter.limiters)
+			if table.limiters != nil {
+				assert.True(t, time.Since(start) >= 30*time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestRateLimitHonorsContext(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.rateLimiter = state.NewRateLimiter(0.1)
+	assert.NoError(t, b.runOperation(context.Background(), &models.Operation{Type: "request", URI: "room.join"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := b.runOperation(ctx, &models.Operation{Type: "request", URI: "room.join"})
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
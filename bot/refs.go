@@ -0,0 +1,47 @@
+package bot
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+var refExpr = regexp.MustCompile(`\$\{[^}]+\}`)
+
+// strictRefsFromConfig reads expect.strictRefs, which makes expectations
+// fail on ${...} references left unresolved in their expected value instead
+// of comparing them literally. It defaults to true
+func strictRefsFromConfig(config *viper.Viper) bool {
+	return !config.IsSet("expect.strictRefs") || config.GetBool("expect.strictRefs")
+}
+
+// unresolvedRef returns the first ${...} reference found in value, embedded
+// in strings and nested ones included, or an empty string if there is none
+func unresolvedRef(value interface{}) string {
+	switch val := value.(type) {
+	case string:
+		if ref := refExpr.FindString(val); ref != "" {
+			return ref
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ref := unresolvedRef(val[k]); ref != "" {
+				return ref
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			if ref := unresolvedRef(item); ref != "" {
+				return ref
+			}
+		}
+	}
+
+	return ""
+}
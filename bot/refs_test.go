@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestStrictRefs(t *testing.T) {
+	tables := []struct {
+		name   string
+		strict bool
+		value  interface{}
+		typ    string
+		err    string
+	}{
+		{"strict", true, "${gold}", "string", "Unresolved reference ${gold} in expected value"},
+		{"strict nested", true, map[string]interface{}{"gold": "${gold}"}, "object", "Unresolved reference ${gold} in expected value"},
+		{"strict embedded", true, map[string]interface{}{"gold": "${gold} coins"}, "object", "Unresolved reference ${gold} in expected value"},
+		{"lenient embedded compares literally", false, map[string]interface{}{"gold": "${gold} coins"}, "object", "obj differs from expected value:\n~ obj.gold: expected \"${gold} coins\", got 250"},
+		{"lenient compares literally", false, "${gold}", "string", "${gold} != 250"},
+		{"plain value", true, "250", "string", ""},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			resp := Response{"gold": "250", "obj": map[string]interface{}{"gold": float64(250)}}
+			expr := "gold"
+			if table.typ == "object" {
+				expr = "obj"
+			}
+
+			err := validateExpectation(expr, models.ExpectSpecEntry{Type: table.typ, Value: table.value}, resp, nil, &storage{}, table.strict)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
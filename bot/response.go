@@ -6,11 +6,59 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Response ...
 type Response map[string]interface{}
 
+// Metadata holds what is known about a message besides its body: route,
+// id (requests only), size in bytes, receivedAt (RFC3339), latencyMs
+// (requests only) and status (http only). It is accessed in expectations and store specs with the
+// meta. prefix, e.g. meta.latencyMs
+type Metadata map[string]interface{}
+
+const metaPrefix = "meta."
+
+func newMetadata(route string, size int, receivedAt time.Time) Metadata {
+	return Metadata{
+		"route":      route,
+		"size":       size,
+		"receivedAt": receivedAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+func pushMetadata(route string, push *Push) Metadata {
+	return newMetadata(route, len(push.Data), push.ReceivedAt)
+}
+
+// metaExpr returns the metadata key of meta.key and ${meta.key} expressions
+func metaExpr(expr string) (string, bool) {
+	if strings.HasPrefix(expr, "${") && strings.HasSuffix(expr, "}") {
+		expr = expr[2 : len(expr)-1]
+	}
+
+	if !strings.HasPrefix(expr, metaPrefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(expr, metaPrefix), true
+}
+
+// extractResponseValue extracts expr from the response body or, for meta.
+// expressions, from its metadata
+func extractResponseValue(resp Response, meta Metadata, expr string, exprType string) (interface{}, error) {
+	if key, ok := metaExpr(expr); ok {
+		value, found := meta[key]
+		if !found {
+			return nil, fmt.Errorf("metadata '%s' not found", key)
+		}
+		return assertType(value, exprType)
+	}
+
+	return resp.extractValue(Expr(expr), exprType)
+}
+
 // Expr ...
 type Expr string
 
@@ -58,6 +106,10 @@ func mapAccess(term string) (string, string) {
 }
 
 func extractValue(src map[string]interface{}, expr Expr, exprType string) (interface{}, error) {
+	if isJSONPath(string(expr)) {
+		return extractJSONPathValue(src, expr, exprType)
+	}
+
 	tokens := expr.tokenize()
 	var container interface{} = src
 	for i, token := range tokens {
@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+const defaultBackoffMultiplier = 2.0
+
+// pitayaErrorCode returns the code of responses carrying a pitaya error
+func pitayaErrorCode(resp Response) (string, bool) {
+	code, ok := resp["code"].(string)
+	if !ok || !strings.HasPrefix(code, "PIT-") {
+		return "", false
+	}
+
+	return code, true
+}
+
+// retryable returns whether the request outcome should be retried
+func retryable(spec *models.RetrySpec, err error, resp Response) bool {
+	if err != nil {
+		if len(spec.RetryableErrors) == 0 {
+			return true
+		}
+
+		msg := strings.ToLower(err.Error())
+		for _, retryableErr := range spec.RetryableErrors {
+			if strings.Contains(msg, strings.ToLower(retryableErr)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	code, ok := pitayaErrorCode(resp)
+	if !ok {
+		return false
+	}
+
+	for _, retryableErr := range spec.RetryableErrors {
+		if matchesErrorCode(retryableErr, code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesErrorCode returns whether code is pattern, or starts with it when
+// it ends in *
+func matchesErrorCode(pattern, code string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(code, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return code == pattern
+}
+
+func retryReason(err error, resp Response) string {
+	if err != nil {
+		return err.Error()
+	}
+
+	code, _ := pitayaErrorCode(resp)
+	return fmt.Sprintf("error response %s", code)
+}
+
+// backoffDelay returns how long to wait after the given failed attempt
+func backoffDelay(spec *models.BackoffSpec, attempt int) time.Duration {
+	if spec == nil || spec.InitialMs <= 0 {
+		return 0
+	}
+
+	multiplier := spec.Multiplier
+	if multiplier <= 0 {
+		multiplier = defaultBackoffMultiplier
+	}
+
+	delay := float64(spec.InitialMs) * math.Pow(multiplier, float64(attempt-1))
+	if spec.MaxMs > 0 && delay > float64(spec.MaxMs) {
+		delay = float64(spec.MaxMs)
+	}
+
+	return time.Duration(delay) * time.Millisecond
+}
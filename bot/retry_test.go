@@ -0,0 +1,63 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestRetryable(t *testing.T) {
+	tables := []struct {
+		name      string
+		retryable []string
+		err       error
+		resp      Response
+		expected  bool
+	}{
+		{"any transport error", nil, errors.New("connection reset"), nil, true},
+		{"no error response by default", nil, nil, Response{"code": "PIT-500"}, false},
+		{"matching transport error", []string{"Connection Reset"}, errors.New("connection reset by peer"), nil, true},
+		{"other transport error", []string{"connection reset"}, &TimeoutError{Route: "r", Timeout: time.Second}, nil, false},
+		{"error code prefix", []string{"PIT-5*"}, nil, Response{"code": "PIT-503"}, true},
+		{"error code exact", []string{"PIT-404"}, nil, Response{"code": "PIT-404"}, true},
+		{"other error code", []string{"PIT-5*"}, nil, Response{"code": "PIT-404"}, false},
+		{"success response", []string{"PIT-5*"}, nil, Response{"code": "200"}, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			spec := &models.RetrySpec{MaxAttempts: 3, RetryableErrors: table.retryable}
+			assert.Equal(t, table.expected, retryable(spec, table.err, table.resp))
+		})
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	spec := &models.BackoffSpec{InitialMs: 100, MaxMs: 500}
+	assert.Equal(t, 100*time.Millisecond, backoffDelay(spec, 1))
+	assert.Equal(t, 200*time.Millisecond, backoffDelay(spec, 2))
+	assert.Equal(t, 400*time.Millisecond, backoffDelay(spec, 3))
+	assert.Equal(t, 500*time.Millisecond, backoffDelay(spec, 4))
+	assert.Equal(t, time.Duration(0), backoffDelay(nil, 2))
+}
+
+func TestRetryErrorResponses(t *testing.T) {
+	transport := &recordingTransport{responses: []string{`{"code": "PIT-503"}`, `{"code": "PIT-503"}`, `{"code": "200"}`}}
+	b := newTestBot(transport)
+	reporter := &countingReporter{counts: map[string]float64{}}
+	b.metricsReporter = append(b.metricsReporter, reporter)
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:   "request",
+		URI:    "shop.buy",
+		Retry:  &models.RetrySpec{MaxAttempts: 3, Backoff: &models.BackoffSpec{InitialMs: 1}, RetryableErrors: []string{"PIT-5*"}},
+		Expect: models.ExpectSpec{"code": {Type: "string", Value: "200"}},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, transport.sent, 3)
+	assert.Equal(t, float64(2), reporter.counts["retry_count"])
+}
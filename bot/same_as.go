@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// validateSameAs deep compares the value at propertyExpr with a previously
+// stored one, leaving out the Ignore key paths. Arrays are traversed by the
+// ignore paths, so items.updatedAt ignores updatedAt in every item
+func validateSameAs(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata, store *storage) error {
+	if spec.Stored == "" {
+		return fmt.Errorf("Missing stored key for sameAs expectation on %s", propertyExpr)
+	}
+
+	stored, ok := store.Get(spec.Stored)
+	if !ok {
+		return fmt.Errorf("Stored value %s not found", spec.Stored)
+	}
+
+	got, err := extractResponseValue(resp, meta, propertyExpr, "object")
+	if err != nil {
+		if got, err = extractResponseValue(resp, meta, propertyExpr, "array"); err != nil {
+			return fmt.Errorf("sameAs %s must be an object or an array: %s", propertyExpr, err)
+		}
+	}
+
+	expected := normalizeValue(stored)
+	got = normalizeValue(got)
+	for _, path := range spec.Ignore {
+		tokens := strings.Split(path, ".")
+		expected = withoutPath(expected, tokens)
+		got = withoutPath(got, tokens)
+	}
+
+	if diffs := diffNormalized(propertyExpr, expected, got); len(diffs) > 0 {
+		return &DiffError{Path: propertyExpr, Diffs: diffs}
+	}
+
+	return nil
+}
+
+// withoutPath returns a copy of the normalized value without the key path
+func withoutPath(value interface{}, tokens []string) interface{} {
+	switch val := value.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			if k != tokens[0] {
+				ret[k] = item
+			} else if len(tokens) > 1 {
+				ret[k] = withoutPath(item, tokens[1:])
+			}
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, item := range val {
+			ret[i] = withoutPath(item, tokens)
+		}
+		return ret
+	}
+
+	return value
+}
@@ -0,0 +1,598 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+var (
+	// schemaDescriptors is the descriptor set the message of schema
+	// expectations is looked up in
+	schemaDescriptors string
+
+	schemaFilesMutex sync.Mutex
+	schemaFiles      = map[string]map[string]interface{}{}
+
+	schemaPatternsMutex sync.Mutex
+	schemaPatterns      = map[string]*regexp.Regexp{}
+)
+
+// schemaTypes are the JSON Schema types schema expectations know
+var schemaTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// schemaKeywords are the JSON Schema keywords schema expectations support,
+// the annotations among them are ignored
+var schemaKeywords = map[string]bool{
+	"type":                 true,
+	"enum":                 true,
+	"const":                true,
+	"properties":           true,
+	"required":             true,
+	"additionalProperties": true,
+	"items":                true,
+	"minItems":             true,
+	"maxItems":             true,
+	"minLength":            true,
+	"maxLength":            true,
+	"pattern":              true,
+	"minimum":              true,
+	"maximum":              true,
+	"exclusiveMinimum":     true,
+	"exclusiveMaximum":     true,
+	"allOf":                true,
+	"anyOf":                true,
+	"oneOf":                true,
+	"$schema":              true,
+	"$id":                  true,
+	"$comment":             true,
+	"title":                true,
+	"description":          true,
+	"default":              true,
+	"examples":             true,
+}
+
+// SetSchemaDescriptors sets the expect.descriptors descriptor set, it must
+// be called before the bots start
+func SetSchemaDescriptors(path string) {
+	schemaDescriptors = path
+}
+
+// ValidateSchema returns an error when spec is not a schema expectation bots
+// can run. Schema files are only read once bots validate a response
+func ValidateSchema(spec models.ExpectSpecEntry) error {
+	if (spec.Schema == nil) == (spec.Message == "") {
+		return fmt.Errorf("Schema expectations need either a schema or a message")
+	}
+
+	switch schema := spec.Schema.(type) {
+	case nil, string:
+		return nil
+	case map[string]interface{}:
+		return checkSchemaDefinition("schema", schema)
+	}
+
+	return fmt.Errorf("Schema must be an object or the path of a schema file")
+}
+
+// checkSchemaDefinition checks schema and its subschemas only use supported
+// keywords, with valid types and patterns
+func checkSchemaDefinition(path string, schema map[string]interface{}) error {
+	keywords := make([]string, 0, len(schema))
+	for keyword := range schema {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	for _, keyword := range keywords {
+		if !schemaKeywords[keyword] {
+			return fmt.Errorf("Unsupported keyword %s in %s", keyword, path)
+		}
+	}
+
+	types, ok := schemaTypeNames(schema["type"])
+	if !ok {
+		return fmt.Errorf("Invalid %s.type %v", path, schema["type"])
+	}
+	for _, typ := range types {
+		if !schemaTypes[typ] {
+			return fmt.Errorf("Unknown %s.type %s", path, typ)
+		}
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		if _, err := schemaPattern(pattern); err != nil {
+			return fmt.Errorf("Invalid %s.pattern: %s", path, err)
+		}
+	}
+
+	for subpath, subschema := range subschemas(path, schema) {
+		if err := checkSchemaDefinition(subpath, subschema); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// subschemas returns the schemas nested in schema by their path
+func subschemas(path string, schema map[string]interface{}) map[string]map[string]interface{} {
+	ret := map[string]map[string]interface{}{}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		for key, property := range properties {
+			if s, ok := property.(map[string]interface{}); ok {
+				ret[fmt.Sprintf("%s.properties.%s", path, key)] = s
+			}
+		}
+	}
+	for _, keyword := range []string{"items", "additionalProperties"} {
+		if s, ok := schema[keyword].(map[string]interface{}); ok {
+			ret[fmt.Sprintf("%s.%s", path, keyword)] = s
+		}
+	}
+	for _, keyword := range []string{"allOf", "anyOf", "oneOf"} {
+		list, _ := schema[keyword].([]interface{})
+		for idx, item := range list {
+			if s, ok := item.(map[string]interface{}); ok {
+				ret[fmt.Sprintf("%s.%s[%d]", path, keyword, idx)] = s
+			}
+		}
+	}
+
+	return ret
+}
+
+// schemaPattern returns the compiled pattern, compiling each one once
+func schemaPattern(pattern string) (*regexp.Regexp, error) {
+	schemaPatternsMutex.Lock()
+	defer schemaPatternsMutex.Unlock()
+
+	if re, ok := schemaPatterns[pattern]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	schemaPatterns[pattern] = re
+	return re, nil
+}
+
+// schemaTypeNames returns the types a schema type keyword allows, none when
+// it is not set
+func schemaTypeNames(typ interface{}) ([]string, bool) {
+	switch t := typ.(type) {
+	case nil:
+		return nil, true
+	case string:
+		return []string{t}, true
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, item := range t {
+			name, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			names = append(names, name)
+		}
+		return names, true
+	}
+
+	return nil, false
+}
+
+// loadSchema returns the schema of spec, reading and checking schema files
+// once
+func loadSchema(spec models.ExpectSpecEntry) (map[string]interface{}, error) {
+	switch schema := spec.Schema.(type) {
+	case map[string]interface{}:
+		return schema, nil
+	case string:
+		schemaFilesMutex.Lock()
+		defer schemaFilesMutex.Unlock()
+
+		if loaded, ok := schemaFiles[schema]; ok {
+			return loaded, nil
+		}
+
+		data, err := ioutil.ReadFile(schema)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read schema: %s", err)
+		}
+		loaded := map[string]interface{}{}
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return nil, fmt.Errorf("Malformed schema %s: %s", schema, err)
+		}
+		if err := checkSchemaDefinition("schema", loaded); err != nil {
+			return nil, fmt.Errorf("Invalid schema %s: %s", schema, err)
+		}
+		schemaFiles[schema] = loaded
+		return loaded, nil
+	}
+
+	return nil, fmt.Errorf("Schema must be an object or the path of a schema file")
+}
+
+// validateSchema checks the value at propertyExpr, usually the whole
+// $response, against the JSON Schema or the protobuf message of spec,
+// listing every field that does not match
+func validateSchema(propertyExpr string, spec models.ExpectSpecEntry, resp Response, meta Metadata) error {
+	if err := ValidateSchema(spec); err != nil {
+		return err
+	}
+
+	got, err := extractResponseValue(resp, meta, propertyExpr, "schema")
+	if err != nil {
+		return err
+	}
+	got = normalizeValue(got)
+
+	violations := make([]string, 0)
+	if spec.Message != "" {
+		if schemaDescriptors == "" {
+			return fmt.Errorf("Schema message %s needs expect.descriptors", spec.Message)
+		}
+		registry, err := sharedProtoRegistry(schemaDescriptors)
+		if err != nil {
+			return err
+		}
+		msg, ok := registry.messages[spec.Message]
+		if !ok {
+			return fmt.Errorf("Unknown protobuf message %s", spec.Message)
+		}
+		checkProtoMessage(registry, propertyExpr, msg, got, &violations)
+	} else {
+		schema, err := loadSchema(spec)
+		if err != nil {
+			return err
+		}
+		checkSchema(propertyExpr, schema, got, &violations)
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("%s does not match the schema: %s", propertyExpr, strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+// schemaKind returns the JSON Schema type of a normalized value, integers
+// being numbers too
+func schemaKind(value interface{}) string {
+	if kind := argKind(value); kind != "bool" {
+		return kind
+	}
+	return "boolean"
+}
+
+// schemaNumber returns value as a float64 when it is a number
+func schemaNumber(value interface{}) (float64, bool) {
+	if n, ok := value.(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
+
+func isInteger(value interface{}) bool {
+	f, ok := schemaNumber(value)
+	return ok && f == float64(int64(f))
+}
+
+// matchesSchemaType returns whether value is of the JSON Schema type typ
+func matchesSchemaType(typ string, value interface{}) bool {
+	switch typ {
+	case "integer":
+		return isInteger(value)
+	case "number":
+		_, ok := schemaNumber(value)
+		return ok
+	}
+	return schemaKind(value) == typ
+}
+
+// checkSchema appends to violations where value, at path, does not match
+// schema. It supports the type, enum, const, properties, required,
+// additionalProperties, items, minItems, maxItems, minLength, maxLength,
+// pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum, allOf,
+// anyOf and oneOf keywords, ValidateSchema rejects the others
+func checkSchema(path string, schema map[string]interface{}, value interface{}, violations *[]string) {
+	violate := func(format string, args ...interface{}) {
+		*violations = append(*violations, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if types, _ := schemaTypeNames(schema["type"]); len(types) > 0 {
+		matches := false
+		for _, typ := range types {
+			matches = matches || matchesSchemaType(typ, value)
+		}
+		if !matches {
+			violate("expected %s, got %s", strings.Join(types, " or "), schemaKind(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, item := range enum {
+			found = found || reflect.DeepEqual(normalizeValue(item), value)
+		}
+		if !found {
+			violate("%s is not one of %s", formatDiffValue(value), formatDiffValue(enum))
+		}
+	}
+	if expected, ok := schema["const"]; ok && !reflect.DeepEqual(normalizeValue(expected), value) {
+		violate("expected %s, got %s", formatDiffValue(expected), formatDiffValue(value))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		checkSchemaObject(path, schema, val, violations)
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for idx, item := range val {
+				checkSchema(fmt.Sprintf("%s[%d]", path, idx), items, item, violations)
+			}
+		}
+		if min, ok := schemaNumber(schema["minItems"]); ok && float64(len(val)) < min {
+			violate("expected at least %v items, got %d", min, len(val))
+		}
+		if max, ok := schemaNumber(schema["maxItems"]); ok && float64(len(val)) > max {
+			violate("expected at most %v items, got %d", max, len(val))
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(val))
+		if min, ok := schemaNumber(schema["minLength"]); ok && length < min {
+			violate("expected at least %v characters, got %v", min, length)
+		}
+		if max, ok := schemaNumber(schema["maxLength"]); ok && length > max {
+			violate("expected at most %v characters, got %v", max, length)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := schemaPattern(pattern); err != nil {
+				violate("invalid pattern: %s", err)
+			} else if !re.MatchString(val) {
+				violate("%q does not match %s", val, pattern)
+			}
+		}
+	default:
+		if n, ok := schemaNumber(val); ok {
+			checkSchemaNumber(path, schema, n, violations)
+		}
+	}
+
+	checkSchemaCombinators(path, schema, value, violations)
+}
+
+func checkSchemaObject(path string, schema map[string]interface{}, value map[string]interface{}, violations *[]string) {
+	required, _ := schema["required"].([]interface{})
+	for _, item := range required {
+		if key, ok := item.(string); ok {
+			if _, found := value[key]; !found {
+				*violations = append(*violations, fmt.Sprintf("%s.%s: missing required field", path, key))
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fieldPath := fmt.Sprintf("%s.%s", path, key)
+		if property, ok := properties[key]; ok {
+			if s, ok := property.(map[string]interface{}); ok {
+				checkSchema(fieldPath, s, value[key], violations)
+			}
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				*violations = append(*violations, fmt.Sprintf("%s: unexpected field", fieldPath))
+			}
+		case map[string]interface{}:
+			checkSchema(fieldPath, additional, value[key], violations)
+		}
+	}
+}
+
+func checkSchemaNumber(path string, schema map[string]interface{}, value float64, violations *[]string) {
+	bounds := []struct {
+		keyword string
+		holds   func(value, bound float64) bool
+		op      string
+	}{
+		{"minimum", func(v, b float64) bool { return v >= b }, ">="},
+		{"maximum", func(v, b float64) bool { return v <= b }, "<="},
+		{"exclusiveMinimum", func(v, b float64) bool { return v > b }, ">"},
+		{"exclusiveMaximum", func(v, b float64) bool { return v < b }, "<"},
+	}
+
+	for _, bound := range bounds {
+		if b, ok := schemaNumber(schema[bound.keyword]); ok && !bound.holds(value, b) {
+			*violations = append(*violations, fmt.Sprintf("%s: expected %v %s %v", path, value, bound.op, b))
+		}
+	}
+}
+
+// checkSchemaCombinators checks the allOf, anyOf and oneOf subschemas,
+// reporting the violations of each allOf one and only whether value
+// matches none, or more than one, of the others
+func checkSchemaCombinators(path string, schema map[string]interface{}, value interface{}, violations *[]string) {
+	matching := func(keyword string) (int, int) {
+		list, _ := schema[keyword].([]interface{})
+		matches := 0
+		for _, item := range list {
+			if s, ok := item.(map[string]interface{}); ok {
+				v := make([]string, 0)
+				checkSchema(path, s, value, &v)
+				if len(v) == 0 {
+					matches++
+				}
+			}
+		}
+		return matches, len(list)
+	}
+
+	allOf, _ := schema["allOf"].([]interface{})
+	for _, item := range allOf {
+		if s, ok := item.(map[string]interface{}); ok {
+			checkSchema(path, s, value, violations)
+		}
+	}
+	if matches, total := matching("anyOf"); total > 0 && matches == 0 {
+		*violations = append(*violations, fmt.Sprintf("%s: matches none of anyOf", path))
+	}
+	if matches, total := matching("oneOf"); total > 0 && matches != 1 {
+		*violations = append(*violations, fmt.Sprintf("%s: matches %d of oneOf, expected 1", path, matches))
+	}
+}
+
+// checkProtoMessage appends to violations where value, at path, does not
+// match the json form of the protobuf message msg: fields it does not have
+// and values of the wrong type. Missing fields are not violations, as they
+// are the default value
+func checkProtoMessage(registry *protoRegistry, path string, msg *protoMessageType, value interface{}, violations *[]string) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		*violations = append(*violations, fmt.Sprintf("%s: expected message %s, got %s", path, msg.name, schemaKind(value)))
+		return
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		fieldPath := fmt.Sprintf("%s.%s", path, key)
+		field, ok := msg.byName[key]
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%s: unknown field of %s", fieldPath, msg.name))
+			continue
+		}
+		checkProtoField(registry, fieldPath, field, obj[key], violations)
+	}
+}
+
+func checkProtoField(registry *protoRegistry, path string, field *protoField, value interface{}, violations *[]string) {
+	if value == nil {
+		return
+	}
+
+	if entry, ok := registry.messages[field.typeName]; ok && field.typ == protoMessage && entry.mapEntry {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%s: expected map, got %s", path, schemaKind(value)))
+			return
+		}
+		valueField := entry.byNumber[2]
+		for key, item := range obj {
+			if valueField != nil {
+				checkProtoValue(registry, fmt.Sprintf("%s.%s", path, key), valueField, item, violations)
+			}
+		}
+		return
+	}
+
+	if field.repeated {
+		list, ok := value.([]interface{})
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%s: expected array, got %s", path, schemaKind(value)))
+			return
+		}
+		for idx, item := range list {
+			checkProtoValue(registry, fmt.Sprintf("%s[%d]", path, idx), field, item, violations)
+		}
+		return
+	}
+
+	checkProtoValue(registry, path, field, value, violations)
+}
+
+// checkProtoValue checks a single value of field, 64 bit integers may be
+// strings and enums names or numbers, as in the protobuf json mapping
+func checkProtoValue(registry *protoRegistry, path string, field *protoField, value interface{}, violations *[]string) {
+	var expected string
+	switch field.typ {
+	case protoString, protoBytes:
+		if _, ok := value.(string); !ok {
+			expected = "string"
+		}
+	case protoBool:
+		if _, ok := value.(bool); !ok {
+			expected = "boolean"
+		}
+	case protoDouble, protoFloat:
+		if _, ok := schemaNumber(value); !ok {
+			expected = "number"
+		}
+	case protoInt64, protoUint64, protoFixed64, protoSfixed64, protoSint64:
+		number := value
+		if s, ok := value.(string); ok {
+			number = json.Number(s)
+		}
+		if !isInteger(number) {
+			expected = "integer"
+		}
+	case protoEnum:
+		enum := registry.enums[field.typeName]
+		if name, ok := value.(string); ok {
+			if enum != nil {
+				if _, known := enum.numbers[name]; !known {
+					*violations = append(*violations, fmt.Sprintf("%s: unknown %s value %s", path, field.typeName, name))
+				}
+			}
+		} else if !isInteger(value) {
+			expected = "enum " + field.typeName
+		}
+	case protoMessage, protoGroup:
+		msg, ok := registry.messages[field.typeName]
+		if !ok {
+			*violations = append(*violations, fmt.Sprintf("%s: unknown protobuf message %s", path, field.typeName))
+			return
+		}
+		checkProtoMessage(registry, path, msg, value, violations)
+	default:
+		if !isInteger(value) {
+			expected = "integer"
+		}
+	}
+
+	if expected != "" {
+		*violations = append(*violations, fmt.Sprintf("%s: expected %s, got %s", path, expected, schemaKind(value)))
+	}
+}
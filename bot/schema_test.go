@@ -0,0 +1,115 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestValidateSchema(t *testing.T) {
+	player := map[string]interface{}{
+		"type":                 "object",
+		"required":             []interface{}{"id", "name"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"id":    map[string]interface{}{"type": "integer", "minimum": 1},
+			"name":  map[string]interface{}{"type": "string", "minLength": 3, "pattern": "^[a-z]+$"},
+			"role":  map[string]interface{}{"enum": []interface{}{"member", "admin"}},
+			"score": map[string]interface{}{"type": []interface{}{"number", "null"}},
+			"tags":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "maxItems": 2},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "schema")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	schemaFile := filepath.Join(dir, "player.json")
+	assert.NoError(t, ioutil.WriteFile(schemaFile, []byte(`{"type": "object", "required": ["id"]}`), 0644))
+	refFile := filepath.Join(dir, "ref.json")
+	assert.NoError(t, ioutil.WriteFile(refFile, []byte(`{"$ref": "#/definitions/player"}`), 0644))
+
+	tables := []struct {
+		name   string
+		schema interface{}
+		resp   Response
+		err    string
+	}{
+		{"matches", player, Response{"id": float64(7), "name": "knight", "role": "admin", "score": nil, "tags": []interface{}{"a"}}, ""},
+		{"missing and renamed fields", player, Response{"id": float64(7), "nickname": "knight"},
+			"$response does not match the schema: $response.name: missing required field, $response.nickname: unexpected field"},
+		{"wrong types", player, Response{"id": 1.5, "name": "knight", "tags": []interface{}{"a", 2}},
+			"$response does not match the schema: $response.id: expected integer, got number, $response.tags[1]: expected string, got number"},
+		{"constraints", player, Response{"id": float64(0), "name": "Kn", "role": "owner", "tags": []interface{}{"a", "b", "c"}},
+			`$response does not match the schema: $response.id: expected 0 >= 1, $response.name: expected at least 3 characters, got 2, $response.name: "Kn" does not match ^[a-z]+$, $response.role: "owner" is not one of ["member","admin"], $response.tags: expected at most 2 items, got 3`},
+		{"anyOf", map[string]interface{}{"anyOf": []interface{}{map[string]interface{}{"required": []interface{}{"a"}}, map[string]interface{}{"required": []interface{}{"b"}}}},
+			Response{"c": true}, "$response does not match the schema: $response: matches none of anyOf"},
+		{"file", schemaFile, Response{"id": float64(1)}, ""},
+		{"file mismatch", schemaFile, Response{}, "$response does not match the schema: $response.id: missing required field"},
+		{"missing file", filepath.Join(dir, "missing.json"), Response{}, "Unable to read schema: open " + filepath.Join(dir, "missing.json") + ": no such file or directory"},
+		{"unsupported keyword", map[string]interface{}{"properties": map[string]interface{}{"player": map[string]interface{}{"$ref": "#/definitions/player"}}},
+			Response{}, "Unsupported keyword $ref in schema.properties.player"},
+		{"unsupported keyword in file", refFile, Response{}, "Invalid schema " + refFile + ": Unsupported keyword $ref in schema"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			spec := models.ExpectSpecEntry{Type: "schema", Schema: table.schema}
+			err := validateExpectations(models.ExpectSpec{"$response": spec}, table.resp, nil, &storage{}, true)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
+
+func TestValidateSchemaMessage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	descriptors := filepath.Join(dir, "room.pb")
+	assert.NoError(t, ioutil.WriteFile(descriptors, testDescriptorSet(), 0644))
+
+	SetSchemaDescriptors(descriptors)
+	defer SetSchemaDescriptors("")
+
+	tables := []struct {
+		name    string
+		message string
+		resp    Response
+		err     string
+	}{
+		{"matches", "room.JoinRequest", Response{
+			"roomId": "lobby",
+			"seats":  []interface{}{float64(1)},
+			"role":   "ADMIN",
+			"scores": map[string]interface{}{"ann": "10"},
+			"player": map[string]interface{}{"name": "knight", "vip": true},
+			"token":  nil,
+		}, ""},
+		{"renamed field", "room.JoinRequest", Response{"room": "lobby", "player": map[string]interface{}{"nick": "knight"}},
+			"$response does not match the schema: $response.player.nick: unknown field of room.Player, $response.room: unknown field of room.JoinRequest"},
+		{"wrong types", "room.JoinRequest", Response{"seats": float64(1), "role": "OWNER", "scores": map[string]interface{}{"ann": "ten"}, "ratio": "high"},
+			"$response does not match the schema: $response.ratio: expected number, got string, $response.role: unknown room.Role value OWNER, $response.scores.ann: expected integer, got string, $response.seats: expected array, got number"},
+		{"unknown message", "room.Leave", Response{}, "Unknown protobuf message room.Leave"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			spec := models.ExpectSpecEntry{Type: "schema", Message: table.message}
+			err := validateExpectations(models.ExpectSpec{"$response": spec}, table.resp, nil, &storage{}, true)
+			if table.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.EqualError(t, err, table.err)
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// runScript runs the Lua code of a script operation, bounded by its
+// timeout when set. Besides the standard library the script sees:
+//
+//	storage.get(key) and storage.set(key, value)
+//	response, the last request or listen response, nil if none
+//	request(route, args[, timeoutMs]), returning the response
+//	notify(route, args)
+//	log(message), logged at debug level
+//
+// Script errors, including the ones raised with error(), fail the operation
+func (b *SequentialBot) runScript(ctx context.Context, op *models.Operation) error {
+	spec := op.Script
+	if spec == nil {
+		return fmt.Errorf("Missing script spec")
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+
+	if op.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(op.Timeout)*time.Millisecond)
+		defer cancel()
+	}
+	L.SetContext(ctx)
+
+	b.registerScriptAPI(ctx, L, op)
+
+	var err error
+	if spec.File != "" {
+		err = L.DoFile(spec.File)
+	} else {
+		err = L.DoString(spec.Source)
+	}
+	if err != nil {
+		return fmt.Errorf("Script failed: %s", err)
+	}
+
+	return nil
+}
+
+func (b *SequentialBot) registerScriptAPI(ctx context.Context, L *lua.LState, op *models.Operation) {
+	L.SetGlobal("storage", L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"get": func(L *lua.LState) int {
+			value, _ := b.storage.Get(L.CheckString(1))
+			L.Push(toLua(L, value))
+			return 1
+		},
+		"set": func(L *lua.LState) int {
+			b.storage.Set(L.CheckString(1), fromLua(L.CheckAny(2)))
+			return 0
+		},
+	}))
+
+	if b.lastResponse != nil {
+		L.SetGlobal("response", toLua(L, map[string]interface{}(b.lastResponse)))
+	}
+
+	L.SetGlobal("request", L.NewFunction(func(L *lua.LState) int {
+		reqOp := &models.Operation{
+			Type:    "request",
+			URI:     L.CheckString(1),
+			Timeout: L.OptInt(3, 0),
+			Tags:    op.Tags,
+			Retry:   op.Retry,
+			Session: op.Session,
+		}
+		args, _ := fromLua(L.OptTable(2, L.NewTable())).(map[string]interface{})
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+
+		resp, meta, _, err := b.sendRequestWithRetry(ctx, args, reqOp)
+		if err != nil {
+			L.RaiseError("request to %s failed: %s", reqOp.URI, err)
+			return 0
+		}
+		b.lastResponse, b.lastMeta = resp, meta
+
+		value := toLua(L, map[string]interface{}(resp))
+		L.SetGlobal("response", value)
+		L.Push(value)
+		return 1
+	}))
+
+	L.SetGlobal("notify", L.NewFunction(func(L *lua.LState) int {
+		route := L.CheckString(1)
+		args, _ := fromLua(L.OptTable(2, L.NewTable())).(map[string]interface{})
+		if args == nil {
+			args = map[string]interface{}{}
+		}
+
+		client, err := b.session(op.Session)
+		if err != nil {
+			L.RaiseError("notify to %s failed: %s", route, err)
+			return 0
+		}
+		if err := b.limit(ctx, route, op.Tags); err != nil {
+			L.RaiseError("notify to %s failed: %s", route, err)
+			return 0
+		}
+		if err := sendNotify(ctx, args, route, client); err != nil {
+			L.RaiseError("notify to %s failed: %s", route, err)
+		}
+		return 0
+	}))
+
+	L.SetGlobal("log", L.NewFunction(func(L *lua.LState) int {
+		b.logger.Debug(L.ToStringMeta(L.CheckAny(1)).String())
+		return 0
+	}))
+}
+
+// toLua converts decoded json values to Lua values
+func toLua(L *lua.LState, value interface{}) lua.LValue {
+	switch val := value.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(val)
+	case string:
+		return lua.LString(val)
+	case map[string]interface{}:
+		table := L.NewTable()
+		for k, item := range val {
+			table.RawSetString(k, toLua(L, item))
+		}
+		return table
+	case Response:
+		return toLua(L, map[string]interface{}(val))
+	case []interface{}:
+		table := L.NewTable()
+		for _, item := range val {
+			table.Append(toLua(L, item))
+		}
+		return table
+	}
+
+	if number, err := toFloat(value); err == nil {
+		return lua.LNumber(number)
+	}
+
+	return lua.LString(fmt.Sprint(value))
+}
+
+// fromLua converts Lua values to their json counterparts, tables being
+// arrays when they only have consecutive integer keys from 1
+func fromLua(value lua.LValue) interface{} {
+	switch val := value.(type) {
+	case lua.LBool:
+		return bool(val)
+	case lua.LNumber:
+		return float64(val)
+	case lua.LString:
+		return string(val)
+	case *lua.LTable:
+		if n := val.MaxN(); n > 0 && n == countLuaKeys(val) {
+			items := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				items = append(items, fromLua(val.RawGetInt(i)))
+			}
+			return items
+		}
+
+		obj := map[string]interface{}{}
+		val.ForEach(func(k, item lua.LValue) {
+			obj[k.String()] = fromLua(item)
+		})
+		return obj
+	}
+
+	return nil
+}
+
+func countLuaKeys(table *lua.LTable) int {
+	count := 0
+	table.ForEach(func(lua.LValue, lua.LValue) {
+		count++
+	})
+	return count
+}
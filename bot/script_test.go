@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestRunScript(t *testing.T) {
+	tables := []struct {
+		name    string
+		source  string
+		timeout int
+		stored  map[string]interface{}
+		sent    int
+		err     bool
+	}{
+		{"storage", `storage.set("total", storage.get("a") + 2)`, 0, map[string]interface{}{"total": float64(3)}, 0, false},
+		{"tables", `storage.set("items", {1, "two", {three = true}})`, 0,
+			map[string]interface{}{"items": []interface{}{float64(1), "two", map[string]interface{}{"three": true}}}, 0, false},
+		{"requests", `
+			for i = 1, 3 do
+				local resp = request("room.join", {seat = i})
+				if resp.code ~= "200" then error("unexpected code") end
+			end
+			storage.set("code", response.code)`, 0, map[string]interface{}{"code": "200"}, 3, false},
+		{"error", `error("custom validation failed")`, 0, nil, 0, true},
+		{"syntax error", `storage.set(`, 0, nil, 0, true},
+		{"timeout", `while true do end`, 50, nil, 0, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{}
+			b := newTestBot(transport)
+			b.storage.Set("a", float64(1))
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type:    "script",
+				Timeout: table.timeout,
+				Script:  &models.ScriptSpec{Source: table.source},
+			})
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			for k, v := range table.stored {
+				stored, _ := b.storage.Get(k)
+				assert.Equal(t, v, stored)
+			}
+			assert.Len(t, transport.sent, table.sent)
+		})
+	}
+}
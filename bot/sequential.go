@@ -1,17 +1,28 @@
 package bot
 
 import (
+	"context"
 	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+	"github.com/topfreegames/pitaya-bot/tracing"
 )
 
+// defaultAbandonAfter is how long an operation is waited for once its spec
+// ended, unless bot.abandonAfter says otherwise
+const defaultAbandonAfter = time.Second
+
 // SequentialBot defines the struct for the sequential bot that is going to run
 type SequentialBot struct {
-	client          *PClient
+	sessions        *sessions
 	config          *viper.Viper
 	id              int
 	spec            *models.Spec
@@ -19,18 +30,79 @@ type SequentialBot struct {
 	logger          logrus.FieldLogger
 	host            string
 	metricsReporter []metrics.Reporter
+	pauser          *state.Pauser
+	logSampler      *logSampler
+	callDepth       int
+	resultStream    *metrics.ResultStream
+	junit           *metrics.JUnitReport
+	correlations    *correlations
+	stats           *metrics.ConnectionStats
+	checkpointer    *checkpointer
+	resumeFrom      *checkpoint
+	shuffleSeed     int64
+	pacing          *pacing
+	throughput      *state.Throughput
+	rateLimiter     *state.RateLimiter
+	globalLimiter   *state.RateLimiter
+	shared          state.SharedStore
+	pushHandlers    *pushHandlers
+	lastResponse    Response
+	lastMeta        Metadata
+	tracer          *tracing.Tracer
+	continued       int
+	firstContinued  error
+	jumps           int
+	abandoned       bool
+	strictRefs      bool
 }
 
 // NewSequentialBot returns a new sequantial bot instance
-func NewSequentialBot(config *viper.Viper, spec *models.Spec, id int, mr []metrics.Reporter, logger logrus.FieldLogger) (Bot, error) {
+func NewSequentialBot(app *state.App, config *viper.Viper, spec *models.Spec, id int, logger logrus.FieldLogger) (Bot, error) {
 	bot := &SequentialBot{
 		config:          config,
 		spec:            spec,
 		id:              id,
-		storage:         newStorage(config),
+		storage:         newStorage(config, app.SuiteStorage),
+		sessions:        newSessions(),
 		logger:          logger,
 		host:            config.GetString("server.host"),
-		metricsReporter: mr,
+		metricsReporter: metrics.WithTags(app.MetricsReporter, map[string]string{"spec": spec.Name, "botType": botType(spec)}),
+		pauser:          app.Pauser,
+		logSampler:      newLogSampler(config),
+		resultStream:    app.ResultStream,
+		junit:           app.JUnit,
+		correlations:    newCorrelations(),
+		stats:           &metrics.ConnectionStats{},
+		checkpointer:    newCheckpointer(config),
+		shuffleSeed:     time.Now().UnixNano(),
+		throughput:      app.Throughput,
+		rateLimiter:     state.NewRateLimiter(config.GetFloat64("bot.maxRPS")),
+		globalLimiter:   app.RateLimiter,
+		shared:          app.Shared,
+		pushHandlers:    &pushHandlers{},
+		tracer:          app.Tracer,
+		strictRefs:      strictRefsFromConfig(config),
+	}
+
+	if config.IsSet("bot.seed") {
+		bot.shuffleSeed = config.GetInt64("bot.seed") + int64(id)
+	}
+	bot.pacing = newPacing(config, bot.shuffleSeed, app.Throughput)
+
+	if err := seedStorage(bot.storage, config, spec, id, bot.shuffleSeed); err != nil {
+		return nil, err
+	}
+
+	cp, err := bot.checkpointer.load(spec.Name, id)
+	if err != nil {
+		return nil, err
+	}
+	if cp != nil {
+		logger.Infof("Resuming from checkpoint at operation %d", cp.Index)
+		bot.resumeFrom = cp
+		for k, v := range cp.Storage {
+			bot.storage.Set(k, v)
+		}
 	}
 
 	if err := bot.Connect(); err != nil {
@@ -40,6 +112,41 @@ func NewSequentialBot(config *viper.Viper, spec *models.Spec, id int, mr []metri
 	return bot, nil
 }
 
+// botType is the kind of bot running spec, reported as the botType tag
+func botType(spec *models.Spec) string {
+	switch {
+	case spec.Random != nil:
+		return "random"
+	case spec.Cycle != nil:
+		return "cycle"
+	}
+
+	return "sequential"
+}
+
+// seedStorage sets the bot id, the spec and config vars, the matrix
+// combination and the feeder row the bot id starts with
+func seedStorage(store *storage, config *viper.Viper, spec *models.Spec, id int, seed int64) error {
+	store.Set(botIDKey, id)
+	store.withVars(spec.Vars)
+	store.withVars(config.GetStringMap("vars"))
+	for k, v := range spec.Combination(id) {
+		store.Set(k, v)
+	}
+
+	if spec.Feeder != nil {
+		row, err := feederRow(spec, id, seed)
+		if err != nil {
+			return err
+		}
+		for k, v := range row {
+			store.Set(k, v)
+		}
+	}
+
+	return nil
+}
+
 // Initialize initializes the bot
 func (b *SequentialBot) Initialize() error {
 	// TODO
@@ -47,75 +154,345 @@ func (b *SequentialBot) Initialize() error {
 }
 
 // Run runs the bot
-func (b *SequentialBot) Run() error {
+func (b *SequentialBot) Run(ctx context.Context) error {
 	defer b.Disconnect()
 
-	steps := b.spec.SequentialOperations
+	return b.runWithHooks(ctx, b.runSequence)
+}
+
+func (b *SequentialBot) runSequence(ctx context.Context) error {
+	order, err := b.operationOrder()
+	if err != nil {
+		return err
+	}
+
+	start := 0
+	if b.resumeFrom != nil {
+		// the resumed bot has a brand new connection, onResume operations
+		// restore the session state, like authentication, the skipped
+		// operations had set up
+		for idx, step := range b.spec.OnResume {
+			if err := b.runStep(ctx, idx, step); err != nil {
+				return fmt.Errorf("Resume operation failed: %s", err)
+			}
+		}
+		start = b.resumeFrom.Index
+	}
 
-	for _, step := range steps {
-		err := b.runOperation(step)
+	for pos := start; pos < len(order); {
+		next, err := b.runAt(ctx, order, pos)
 		if err != nil {
+			if report := b.correlations.report(); report != "" {
+				b.logger.Warnf("Unmatched correlations: %s", report)
+			}
+			if b.spec.Shuffle {
+				return fmt.Errorf("%s (shuffled order %v, seed %d)", err, order, b.shuffleSeed)
+			}
 			return err
 		}
+
+		if next == pos+1 && b.checkpointer.due(pos+1-start) {
+			b.saveCheckpoint(pos+1, order)
+		}
+		pos = next
 	}
+	b.checkpointer.remove(b.spec.Name, b.id)
 
-	return nil
+	if report := b.correlations.report(); report != "" {
+		return fmt.Errorf("Unmatched correlations: %s", report)
+	}
+
+	return b.continuedError()
 }
 
-func (b *SequentialBot) runRequest(op *models.Operation) error {
-	b.logger.Debug("Executing request to: " + op.URI)
-	route := op.URI
+// runStep waits while the fleet is paused, runs the operation and streams
+// its result
+func (b *SequentialBot) runStep(ctx context.Context, idx int, op *models.Operation) error {
+	if err := b.pauser.Wait(ctx); err != nil {
+		return fmt.Errorf("Spec aborted while paused before operation %d (%s %s): %s", idx, op.Type, op.URI, err)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("Spec aborted before operation %d (%s %s): %s", idx, op.Type, op.URI, err)
+	}
+
+	b.handlePushes()
+
+	tags := metricsTags(op.URI, op.Tags)
+	metrics.ReportEvent(b.metricsReporter, metrics.OperationStartEvent, tags, map[string]interface{}{
+		"bot": b.id, "index": idx, "type": op.Type, "uri": op.URI,
+	})
+
+	start := time.Now()
+	b.stats.AddOperation()
+	err := b.runOperationWithTimeout(ctx, idx, op)
+	if _, ok := err.(*ExpectError); ok {
+		reportError(b.metricsReporter, tags, metrics.ExpectationError)
+	}
+	// jumps are returned as errors but the operation succeeded
+	opErr := err
+	if _, ok := err.(*jumpError); ok {
+		opErr = nil
+	}
+	slaViolation := opErr == nil && b.checkMaxDuration(op, tags, time.Since(start))
+	b.reportOperationEnd(idx, op, tags, start, opErr, slaViolation)
+	b.streamResult(idx, op, start, opErr, slaViolation)
+	if opErr != nil {
+		return opErr
+	}
+
+	b.pacing.wait(ctx, op.PostDelay)
+	return err
+}
+
+// runOperationWithTimeout runs the operation under ctx. When ctx is done,
+// either aborted or past the spec max duration, the operation is waited for
+// up to bot.abandonAfter. One still running then is abandoned, along with
+// the bot, which skips its teardown so nothing races the operation
+func (b *SequentialBot) runOperationWithTimeout(ctx context.Context, idx int, op *models.Operation) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("Panic running operation %d (%s %s): %v\n%s", idx, op.Type, op.URI, r, debug.Stack())
+			}
+		}()
+		done <- b.runOperation(ctx, op)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		select {
+		case <-done:
+		case <-time.After(b.abandonAfter()):
+			b.abandoned = true
+			return fmt.Errorf("Abandoned operation %d (%s %s), still running %v after the spec ended: %s",
+				idx, op.Type, op.URI, b.abandonAfter(), ctx.Err())
+		}
+		if ctx.Err() == context.Canceled {
+			return fmt.Errorf("Spec aborted while running operation %d (%s %s)", idx, op.Type, op.URI)
+		}
+		return fmt.Errorf("Spec exceeded its max duration while running operation %d (%s %s): %s", idx, op.Type, op.URI, ctx.Err())
+	}
+}
+
+// abandonAfter is how long an operation is waited for once its spec ended
+func (b *SequentialBot) abandonAfter() time.Duration {
+	if b.config != nil && b.config.IsSet("bot.abandonAfter") {
+		return b.config.GetDuration("bot.abandonAfter")
+	}
+	return defaultAbandonAfter
+}
+
+func (b *SequentialBot) saveCheckpoint(index int, order []int) {
+	cp := &checkpoint{
+		Spec:    b.spec.Name,
+		Bot:     b.id,
+		Index:   index,
+		Order:   order,
+		Storage: map[string]interface{}(*b.storage),
+		Time:    time.Now().UTC(),
+	}
+
+	if err := b.checkpointer.save(cp); err != nil {
+		b.logger.WithError(err).Error("Failed to save checkpoint")
+	}
+}
+
+// checkMaxDuration reports an SLA violation when the operation took longer
+// than its maxDurationMs, returning whether it did
+func (b *SequentialBot) checkMaxDuration(op *models.Operation, tags map[string]string, elapsed time.Duration) bool {
+	max := time.Duration(op.MaxDurationMs) * time.Millisecond
+	if max <= 0 || elapsed <= max {
+		return false
+	}
+
+	b.logger.Warnf("Operation %s %s took %v, above its max duration of %v", op.Type, op.URI, elapsed, max)
+	for _, mr := range b.metricsReporter {
+		mr.ReportCount(metrics.SLAViolationCount, tags, 1)
+	}
+	return true
+}
+
+// reportOperationEnd reports the end of an operation, with the keys it
+// stored when it succeeded
+func (b *SequentialBot) reportOperationEnd(idx int, op *models.Operation, tags map[string]string, start time.Time, opErr error, slaViolation bool) {
+	fields := map[string]interface{}{
+		"bot":        b.id,
+		"index":      idx,
+		"type":       op.Type,
+		"uri":        op.URI,
+		"durationMs": time.Since(start).Nanoseconds() / 1e6,
+		"result":     "ok",
+	}
+
+	if opErr != nil {
+		fields["result"] = "error"
+		fields["error"] = opErr.Error()
+	} else if len(op.Store) > 0 {
+		stored := make(map[string]interface{}, len(op.Store))
+		for key := range op.Store {
+			if value, ok := b.storage.Get(key); ok {
+				stored[key] = value
+			}
+		}
+		fields["stored"] = stored
+	}
+	if slaViolation {
+		fields["slaViolation"] = true
+	}
+
+	metrics.ReportEvent(b.metricsReporter, metrics.OperationEndEvent, tags, fields)
+}
+
+func (b *SequentialBot) streamResult(idx int, op *models.Operation, start time.Time, opErr error, slaViolation bool) {
+	result := &metrics.OperationResult{
+		Kind:       metrics.OperationResultKind,
+		Time:       start.UTC(),
+		Spec:       b.spec.Name,
+		Bot:        b.id,
+		Index:      idx,
+		Type:       op.Type,
+		URI:        op.URI,
+		DurationMs: time.Since(start).Nanoseconds() / 1e6,
+
+		SLAViolation: slaViolation,
+	}
+	if opErr != nil {
+		result.Error = opErr.Error()
+	}
+
+	if err := b.resultStream.Write(result); err != nil {
+		b.logger.WithError(err).Error("Failed to stream operation result")
+	}
+	b.junit.Add(result)
+}
+
+func (b *SequentialBot) runRequest(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
+	logger.Debug("Executing request to: " + op.URI)
 	args, err := buildArgs(op.Args, b.storage)
 	if err != nil {
 		return err
 	}
+	if debugging(logger) {
+		logger.WithField("args", b.logSampler.mask(args)).Debug("request args")
+	}
 
-	resp, rawResp, err := sendRequest(args, route, b.client, b.metricsReporter)
+	// args are built only once so values generated for them, like
+	// idempotency keys, are the same in every attempt
+	resp, meta, rawResp, err := b.sendRequestWithRetry(ctx, args, op)
 	if err != nil {
 		return err
 	}
+	b.lastResponse, b.lastMeta = resp, meta
 
-	b.logger.Debug("validating expectations")
-	err = validateExpectations(op.Expect, resp, b.storage)
+	if op.ExpectError != nil {
+		if err := checkErrorResponse(op.ExpectError, resp); err != nil {
+			return NewExpectError(err, rawResp, op.ExpectError)
+		}
+	}
+
+	if debugging(logger) {
+		logger.WithField("response", b.logSampler.mask(resp)).Debug("validating expectations")
+	}
+	err = validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs)
 	if err != nil {
 		return NewExpectError(err, rawResp, op.Expect)
 	}
-	b.logger.Debug("received valid response")
+	logger.Debug("received valid response")
 
-	b.logger.Debug("storing data")
-	err = storeData(op.Store, b.storage, resp)
+	logger.Debug("storing data")
+	err = storeData(op.Store, b.storage, resp, meta)
 	if err != nil {
 		return err
 	}
 
-	b.logger.Debug("all done")
+	if op.Correlation != nil {
+		if err := b.registerCorrelation(op.Correlation, args, resp, meta); err != nil {
+			return err
+		}
+	}
+
+	logger.Debug("all done")
 	return nil
 }
 
-func (b *SequentialBot) runNotify(op *models.Operation) error {
-	b.logger.Debug("Executing notify to: " + op.URI)
+func (b *SequentialBot) sendRequestWithRetry(ctx context.Context, args map[string]interface{}, op *models.Operation) (Response, Metadata, []byte, error) {
+	attempts := 1
+	if op.Retry != nil && op.Retry.MaxAttempts > 1 {
+		attempts = op.Retry.MaxAttempts
+	}
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		tracing.SpanFromContext(ctx).SetAttribute("attempt", attempt)
+		if err := b.limit(ctx, op.URI, op.Tags); err != nil {
+			return nil, nil, nil, err
+		}
+		if b.throughput != nil {
+			b.throughput.AddRequest()
+		}
+		resp, meta, rawResp, err := sendRequest(ctx, args, op.URI, time.Duration(op.Timeout)*time.Millisecond, client, b.metricsReporter, op.Tags)
+		if attempt >= attempts || !retryable(op.Retry, err, resp) || expectedError(op.ExpectError, resp) {
+			return resp, meta, rawResp, err
+		}
+
+		delay := backoffDelay(op.Retry.Backoff, attempt)
+		b.logger.Debugf("Request to %s failed on attempt %d of %d, retrying in %v: %s", op.URI, attempt, attempts, delay, retryReason(err, resp))
+		for _, mr := range b.metricsReporter {
+			mr.ReportCount(metrics.RetryCount, metricsTags(op.URI, op.Tags), 1)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, nil, nil, ctx.Err()
+		}
+	}
+}
+
+func (b *SequentialBot) runNotify(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
+	logger.Debug("Executing notify to: " + op.URI)
 	route := op.URI
 	args, err := buildArgs(op.Args, b.storage)
 	if err != nil {
 		return err
 	}
 
-	err = sendNotify(args, route, b.client)
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+	if err := b.limit(ctx, route, op.Tags); err != nil {
+		return err
+	}
+	err = sendNotify(ctx, args, route, client)
 	if err != nil {
 		return err
 	}
 
-	b.logger.Debug("all done")
+	logger.Debug("all done")
 	return nil
 }
 
-func (b *SequentialBot) runFunction(op *models.Operation) error {
+func (b *SequentialBot) runFunction(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
 	fName := op.URI
-	b.logger.Debug("Will execute internal function: ", fName)
+	logger.Debug("Will execute internal function: ", fName)
 
+	var resp Response
 	switch fName {
 	case "disconnect":
-		b.Disconnect()
+		b.sessions.mutex.Lock()
+		b.disconnectSession(op.Session)
+		b.sessions.mutex.Unlock()
 	case "connect":
 		host := b.host
 		args, err := buildArgs(op.Args, b.storage)
@@ -123,61 +500,312 @@ func (b *SequentialBot) runFunction(op *models.Operation) error {
 			return err
 		}
 		if val, ok := args["host"]; ok {
-			b.logger.Debug("Connecting to custom host")
+			logger.Debug("Connecting to custom host")
 			if h, ok := val.(string); ok {
 				host = h
 			}
 		}
-		b.Connect(host)
+		if op.Session == defaultSession {
+			b.Connect(host)
+		} else if err := b.connectNamedSession(op.Session, host); err != nil {
+			return err
+		}
 	case "reconnect":
-		b.Reconnect()
+		b.reconnectSession(op.Session)
+	case "publishKey":
+		if err := b.publishKey(ctx, op); err != nil {
+			return err
+		}
+	case "waitForKey":
+		if err := b.waitForKey(ctx, op); err != nil {
+			return err
+		}
+	case "barrier":
+		if err := b.barrier(ctx, op); err != nil {
+			return err
+		}
+	case "simulateDisconnect":
+		if err := b.simulateDisconnect(ctx, op); err != nil {
+			return err
+		}
+	case "goto":
+		return b.gotoLabel(op)
+	case "markPushes":
+		if err := b.markPushes(op); err != nil {
+			return err
+		}
 	default:
-		return fmt.Errorf("Unknown function: %s", fName)
+		fn, ok := registeredFunction(fName)
+		if !ok {
+			return fmt.Errorf("Unknown function: %s", fName)
+		}
+
+		args, err := buildArgs(op.Args, b.storage)
+		if err != nil {
+			return err
+		}
+		ret, err := fn(args, b.storage)
+		if err != nil {
+			return fmt.Errorf("Function %s failed: %s", fName, err)
+		}
+		resp = Response(ret)
+	}
+
+	// builtin functions are checked against the connection state
+	var meta Metadata
+	if builtinFunctions[fName] {
+		resp, meta = Response{}, b.connectionMetadata(op.Session)
+	}
+
+	if len(op.Expect) > 0 {
+		logger.Debug("validating function expectations")
+		if err := validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+			return fmt.Errorf("Function %s expectation failed: %s", fName, err)
+		}
 	}
 
+	return storeData(op.Store, b.storage, resp, meta)
+}
+
+func (b *SequentialBot) listenToPush(ctx context.Context, op *models.Operation) error {
+	if op.Listen != nil {
+		return b.listenToPushes(ctx, op)
+	}
+
+	logger := b.logSampler.logger(b.logger)
+	logger.Debug("Waiting for push on route: " + op.URI)
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+	resp, meta, err := client.ReceivePush(ctx, op.URI, op.Timeout)
+	if err != nil {
+		return err
+	}
+	b.lastResponse, b.lastMeta = resp, meta
+
+	if op.Correlation != nil {
+		if err := b.matchCorrelation(op.Correlation, op.URI, resp, meta); err != nil {
+			return err
+		}
+	}
+
+	if debugging(logger) {
+		logger.WithField("response", b.logSampler.mask(resp)).Debug("validating expectations")
+	}
+	err = validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs)
+	if err != nil {
+		return err
+	}
+	logger.Debug("received valid response")
+
+	logger.Debug("storing data")
+	err = storeData(op.Store, b.storage, resp, meta)
+	if err != nil {
+		return err
+	}
+
+	logger.Debug("all done")
 	return nil
 }
 
-func (b *SequentialBot) listenToPush(op *models.Operation) error {
-	b.logger.Debug("Waiting for push on route: " + op.URI)
-	resp, err := b.client.ReceivePush(op.URI, op.Timeout)
+func (b *SequentialBot) runCadence(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
+	if op.Cadence == nil {
+		return fmt.Errorf("Missing cadence spec for route %s", op.URI)
+	}
+
+	logger.Debug("Collecting pushes on route: " + op.URI)
+	window := time.Duration(op.Cadence.Window) * time.Millisecond
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+	pushes, err := client.ReceivePushes(ctx, op.URI, window)
 	if err != nil {
 		return err
 	}
 
-	b.logger.Debug("validating expectations")
-	err = validateExpectations(op.Expect, resp, b.storage)
+	logger.Debug("validating cadence")
+	if err := validateCadence(pushes, op.Cadence); err != nil {
+		return fmt.Errorf("Cadence check failed on route %s: %s", op.URI, err)
+	}
+
+	logger.Debug("validating expectations")
+	var (
+		resp Response
+		meta Metadata
+	)
+	for _, push := range pushes {
+		resp, err = push.decode()
+		if err != nil {
+			return err
+		}
+
+		meta = pushMetadata(op.URI, push)
+		err = validateExpectations(op.Expect, resp, meta, b.storage, b.strictRefs)
+		if err != nil {
+			return NewExpectError(err, push.Data, op.Expect)
+		}
+	}
+	logger.Debug("received valid pushes")
+
+	logger.Debug("storing data")
+	err = storeData(op.Store, b.storage, resp, meta)
 	if err != nil {
 		return err
 	}
-	b.logger.Debug("received valid response")
 
-	b.logger.Debug("storing data")
-	err = storeData(op.Store, b.storage, resp)
+	logger.Debug("all done")
+	return nil
+}
+
+func (b *SequentialBot) listenSilent(ctx context.Context, op *models.Operation) error {
+	logger := b.logSampler.logger(b.logger)
+	logger.Debug("Expecting no push on route: " + op.URI)
+	start := time.Now()
+	client, err := b.session(op.Session)
 	if err != nil {
 		return err
 	}
+	pushes, err := client.ReceivePushes(ctx, op.URI, time.Duration(op.Timeout)*time.Millisecond)
+	if err != nil {
+		return err
+	}
+
+	for _, push := range pushes {
+		if len(op.Expect) > 0 {
+			resp, err := push.decode()
+			if err != nil {
+				return err
+			}
+
+			if validateExpectations(op.Expect, resp, pushMetadata(op.URI, push), b.storage, b.strictRefs) != nil {
+				continue
+			}
+		}
+
+		return fmt.Errorf("Expected no push on route %s for %dms, got one after %v: %s",
+			op.URI, op.Timeout, push.ReceivedAt.Sub(start), string(push.Data))
+	}
 
-	b.logger.Debug("all done")
+	logger.Debug("no matching push received")
 	return nil
 }
 
-// StartListening ...
-func (b *SequentialBot) startListening() {
-	b.client.StartListening()
+// connectionMetadata exposes the connection state of session to
+// expectations
+func (b *SequentialBot) connectionMetadata(session string) Metadata {
+	b.sessions.mutex.Lock()
+	client := b.sessions.clients[session]
+	b.sessions.mutex.Unlock()
+
+	state := "disconnected"
+	if client != nil && client.Connected() {
+		state = "connected"
+	}
+
+	return Metadata{"connection": state, "host": b.host}
+}
+
+// operationTypes are the operation types runOperation knows how to run
+var operationTypes = map[string]bool{
+	"request":      true,
+	"notify":       true,
+	"function":     true,
+	"listen":       true,
+	"listenSilent": true,
+	"cadence":      true,
+	"stateMachine": true,
+	"call":         true,
+	"parallel":     true,
+	"loop":         true,
+	"condition":    true,
+	"script":       true,
+	"http":         true,
+	"grpc":         true,
+	"fuzz":         true,
+	"assertPushes": true,
+}
+
+// KnownOperationType returns whether bots are able to run operations of typ
+func KnownOperationType(typ string) bool {
+	return operationTypes[typ]
 }
 
 // TODO - refactor
-func (b *SequentialBot) runOperation(op *models.Operation) error {
+func (b *SequentialBot) runOperation(ctx context.Context, op *models.Operation) error {
+	if len(op.Vars) > 0 {
+		defer b.storage.withVars(op.Vars)()
+	}
+
+	span := tracing.SpanFromContext(ctx).StartChild(strings.TrimSpace(op.Type + " " + op.URI))
+	span.SetAttribute("operation.type", op.Type)
+	span.SetAttribute("route", op.URI)
+	span.SetAttribute("bot.id", b.id)
+	before := b.stats.Snapshot()
+
+	err := b.dispatchOperation(tracing.ContextWithSpan(ctx, span), op)
+	if err == nil {
+		err = b.assertStorage(op)
+	}
+
+	after := b.stats.Snapshot()
+	span.SetAttribute("bytes.sent", after.BytesSent-before.BytesSent)
+	span.SetAttribute("bytes.received", after.BytesReceived-before.BytesReceived)
+	span.Finish(err)
+
+	return err
+}
+
+// assertStorage validates the storage state left by the operation against
+// its assertStorage expectations, keyed by storage key
+func (b *SequentialBot) assertStorage(op *models.Operation) error {
+	if len(op.AssertStorage) == 0 {
+		return nil
+	}
+
+	if err := validateExpectations(op.AssertStorage, Response(*b.storage), nil, b.storage, b.strictRefs); err != nil {
+		return fmt.Errorf("Storage assertion failed after %s %s: %s", op.Type, op.URI, err)
+	}
+
+	return nil
+}
+
+func (b *SequentialBot) dispatchOperation(ctx context.Context, op *models.Operation) error {
 	switch op.Type {
 	case "request":
-		return b.runRequest(op)
+		return b.runRequest(ctx, op)
 	case "notify":
-		return b.runNotify(op)
+		return b.runNotify(ctx, op)
 	case "function":
-		return b.runFunction(op)
+		return b.runFunction(ctx, op)
 	case "listen":
-		return b.listenToPush(op)
+		return b.listenToPush(ctx, op)
+	case "listenSilent":
+		return b.listenSilent(ctx, op)
+	case "cadence":
+		return b.runCadence(ctx, op)
+	case "stateMachine":
+		return b.runStateMachine(ctx, op)
+	case "call":
+		return b.runCall(ctx, op)
+	case "parallel":
+		return b.runParallel(ctx, op)
+	case "loop":
+		return b.runLoop(ctx, op)
+	case "condition":
+		return b.runCondition(ctx, op)
+	case "script":
+		return b.runScript(ctx, op)
+	case "http":
+		return b.runHTTP(ctx, op)
+	case "grpc":
+		return b.runGRPC(ctx, op)
+	case "fuzz":
+		return b.runFuzz(ctx, op)
+	case "assertPushes":
+		return b.assertPushes(ctx, op)
 	}
 
 	return fmt.Errorf("Unknown type: %s", op.Type)
@@ -189,34 +817,56 @@ func (b *SequentialBot) Finalize() error {
 	return nil
 }
 
-// Disconnect ...
+// Disconnect disconnects every session of the bot
 func (b *SequentialBot) Disconnect() {
-	b.client.Disconnect()
+	b.sessions.mutex.Lock()
+	defer b.sessions.mutex.Unlock()
+	for name := range b.sessions.clients {
+		b.disconnectSession(name)
+	}
 }
 
-// Connect ...
+// Connect connects the main session, to hosts[0] when given
 func (b *SequentialBot) Connect(hosts ...string) error {
 	if len(hosts) > 0 {
 		b.host = hosts[0]
 	}
-	if b.client != nil && b.client.Connected() {
+
+	b.sessions.mutex.Lock()
+	defer b.sessions.mutex.Unlock()
+	if client, ok := b.sessions.clients[defaultSession]; ok && client.Connected() {
 		b.logger.Fatal("Bot already connected")
 	}
 
-	client, err := NewPClient(b.host, b.config.GetBool("server.tls"))
-	if err != nil {
+	if err := b.connectSession(defaultSession, b.host); err != nil {
 		b.logger.Error("Unable to create client...")
 		return err
 	}
-
-	b.client = client
-	b.startListening()
 	return nil
 }
 
-// Reconnect ...
+// connectedBots is the number of bots connected, across specs
+var connectedBots int64
+
+// ConnectedBots returns the number of bots connected, across specs
+func ConnectedBots() int64 {
+	return atomic.LoadInt64(&connectedBots)
+}
+
+// reportConnected adds delta to the connected bots and reports their number
+func (b *SequentialBot) reportConnected(delta int64) {
+	connected := atomic.AddInt64(&connectedBots, delta)
+	for _, mr := range b.metricsReporter {
+		mr.ReportGauge(metrics.ConnectedBots, nil, float64(connected))
+	}
+}
+
+// Reconnect reconnects the main session
 func (b *SequentialBot) Reconnect() {
-	b.Disconnect()
-	b.Connect()
-	b.logger.Debug("Reconnect done")
+	b.reconnectSession(defaultSession)
+}
+
+// Stats returns the bot connection stats
+func (b *SequentialBot) Stats() *metrics.ConnectionStats {
+	return b.stats
 }
@@ -0,0 +1,509 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// recordingTransport records the requests sent, fails the first
+// failures of them and replies with responses in turn, or never when silent.
+// Sending takes delay
+type recordingTransport struct {
+	mutex     sync.Mutex
+	delay     time.Duration
+	failures  int
+	responses []string
+	silent    bool
+	sent      []string
+	handler   messageHandler
+}
+
+func (t *recordingTransport) SendRequest(route string, data []byte) (uint, error) {
+	time.Sleep(t.delay)
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.sent = append(t.sent, string(data))
+	if len(t.sent) <= t.failures {
+		return 0, errors.New("connection reset")
+	}
+
+	id := uint(len(t.sent))
+	if t.silent {
+		return id, nil
+	}
+
+	resp := `{"code": "200"}`
+	if len(t.responses) > 0 {
+		resp = t.responses[(len(t.sent)-t.failures-1)%len(t.responses)]
+	}
+	go t.handler(MsgResponseType, id, route, []byte(resp))
+	return id, nil
+}
+
+func (t *recordingTransport) SendNotify(route string, data []byte) error { return nil }
+func (t *recordingTransport) Listen(handler messageHandler)              { t.handler = handler }
+func (t *recordingTransport) Connected() bool                            { return true }
+func (t *recordingTransport) Disconnect()                                {}
+
+func newTestBot(t transport) *SequentialBot {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	pclient := &PClient{
+		client:    t,
+		responses: make(map[uint]chan []byte),
+		pushes:    make(map[string]chan *Push),
+	}
+	pclient.StartListening()
+
+	return &SequentialBot{
+		sessions:     &sessions{clients: map[string]*PClient{defaultSession: pclient}},
+		storage:      &storage{},
+		logger:       logger,
+		spec:         &models.Spec{},
+		pauser:       state.NewPauser(),
+		correlations: newCorrelations(),
+		stats:        &metrics.ConnectionStats{},
+		strictRefs:   true,
+	}
+}
+
+func TestRetryReusesResolvedArgs(t *testing.T) {
+	transport := &recordingTransport{failures: 2}
+	b := newTestBot(transport)
+
+	op := &models.Operation{
+		Type: "request",
+		URI:  "shop.buy",
+		Args: map[string]interface{}{
+			"idempotencyKey": map[string]interface{}{"type": "string", "value": "${random.uuid}"},
+		},
+		Retry: &models.RetrySpec{MaxAttempts: 3},
+	}
+
+	assert.NoError(t, b.runOperation(context.Background(), op))
+	assert.Len(t, transport.sent, 3)
+	assert.Equal(t, transport.sent[0], transport.sent[1])
+	assert.Equal(t, transport.sent[1], transport.sent[2])
+}
+
+func TestAssertStorage(t *testing.T) {
+	tables := []struct {
+		name   string
+		expect models.ExpectSpec
+		err    bool
+	}{
+		{"stored", models.ExpectSpec{"gold": {Type: "int", Value: 250}, "player.name": {Type: "string", Value: "knight"}}, false},
+		{"wrong value", models.ExpectSpec{"gold": {Type: "int", Value: 100}}, true},
+		{"not stored", models.ExpectSpec{"silver": {Type: "int", Value: 250}}, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: []string{`{"gold": 250, "player": {"name": "knight"}}`}}
+			b := newTestBot(transport)
+
+			err := b.runOperation(context.Background(), &models.Operation{
+				Type: "request",
+				URI:  "shop.sell",
+				Store: models.StoreSpec{
+					"gold":   {Type: "int", Value: "gold"},
+					"player": {Type: "object", Value: "player"},
+				},
+				AssertStorage: table.expect,
+			})
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+// countingReporter records the counts reported by metric, the error types,
+// the histogram statuses and the last gauge values
+type countingReporter struct {
+	mutex      sync.Mutex
+	counts     map[string]float64
+	errorTypes []string
+	statuses   []string
+	limiters   []string
+	gauges     map[string]float64
+}
+
+func (r *countingReporter) ReportCount(metric string, tags map[string]string, count float64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.counts[metric] += count
+	switch metric {
+	case metrics.ErrorCount:
+		r.errorTypes = append(r.errorTypes, tags["type"])
+	case metrics.RateLimitCount:
+		r.limiters = append(r.limiters, tags["limiter"])
+	}
+	return nil
+}
+
+func (r *countingReporter) ReportSummary(metric string, tags map[string]string, value float64) error {
+	return nil
+}
+
+func (r *countingReporter) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.statuses = append(r.statuses, tags["status"])
+	return nil
+}
+
+func (r *countingReporter) ReportGauge(metric string, tags map[string]string, value float64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.gauges == nil {
+		r.gauges = map[string]float64{}
+	}
+	r.gauges[metric] = value
+	return nil
+}
+
+func TestReportedMetrics(t *testing.T) {
+	tables := []struct {
+		name       string
+		response   string
+		expect     models.ExpectSpec
+		errorTypes []string
+		statuses   []string
+	}{
+		{"ok response", `{"code": "200"}`, nil, nil, []string{metrics.StatusOK}},
+		{"undecodable response", `not json`, nil, []string{metrics.ResponseError}, []string{metrics.StatusError}},
+		{"failed expectation", `{"code": "200"}`, models.ExpectSpec{"code": {Type: "string", Value: "201"}}, []string{metrics.ExpectationError}, []string{metrics.StatusOK}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{responses: []string{table.response}})
+			reporter := &countingReporter{counts: map[string]float64{}}
+			b.metricsReporter = []metrics.Reporter{reporter}
+
+			b.runStep(context.Background(), 0, &models.Operation{Type: "request", URI: "room.join", Expect: table.expect})
+			assert.Equal(t, table.errorTypes, reporter.errorTypes)
+			assert.Equal(t, table.statuses, reporter.statuses)
+		})
+	}
+}
+
+// eventReporter records the events reported along with their fields
+type eventReporter struct {
+	countingReporter
+	events []string
+	fields []map[string]interface{}
+}
+
+func (r *eventReporter) ReportEvent(event string, tags map[string]string, fields map[string]interface{}) error {
+	r.events = append(r.events, event)
+	r.fields = append(r.fields, fields)
+	return nil
+}
+
+func TestOperationEvents(t *testing.T) {
+	tables := []struct {
+		name     string
+		response string
+		result   string
+		stored   interface{}
+	}{
+		{"ok response", `{"id": "room-1"}`, "ok", map[string]interface{}{"roomId": "room-1"}},
+		{"undecodable response", `not json`, "error", nil},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{responses: []string{table.response}})
+			reporter := &eventReporter{countingReporter: countingReporter{counts: map[string]float64{}}}
+			b.metricsReporter = []metrics.Reporter{reporter}
+
+			b.runStep(context.Background(), 3, &models.Operation{
+				Type:  "request",
+				URI:   "room.join",
+				Store: models.StoreSpec{"roomId": {Type: "string", Value: "id"}},
+			})
+
+			assert.Equal(t, []string{metrics.OperationStartEvent, metrics.OperationEndEvent}, reporter.events)
+			assert.Equal(t, 3, reporter.fields[0]["index"])
+			assert.Equal(t, "room.join", reporter.fields[0]["uri"])
+
+			end := reporter.fields[1]
+			assert.Equal(t, table.result, end["result"])
+			assert.Contains(t, end, "durationMs")
+			if table.stored != nil {
+				assert.Equal(t, table.stored, end["stored"])
+				assert.NotContains(t, end, "error")
+			} else {
+				assert.NotContains(t, end, "stored")
+				assert.NotEmpty(t, end["error"])
+			}
+		})
+	}
+}
+
+func TestMaxDuration(t *testing.T) {
+	RegisterFunction("testSleep", func(map[string]interface{}, Storage) (map[string]interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return nil, nil
+	})
+
+	tables := []struct {
+		name          string
+		maxDurationMs int
+		violation     bool
+	}{
+		{"no max duration", 0, false},
+		{"within max duration", 1000, false},
+		{"above max duration", 5, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			reporter := &eventReporter{countingReporter: countingReporter{counts: map[string]float64{}}}
+			b.metricsReporter = []metrics.Reporter{reporter}
+
+			op := &models.Operation{Type: "function", URI: "testSleep", MaxDurationMs: table.maxDurationMs}
+			assert.NoError(t, b.runStep(context.Background(), 0, op))
+
+			end := reporter.fields[1]
+			assert.Equal(t, "ok", end["result"])
+			if table.violation {
+				assert.Equal(t, float64(1), reporter.counts[metrics.SLAViolationCount])
+				assert.Equal(t, true, end["slaViolation"])
+			} else {
+				assert.Zero(t, reporter.counts[metrics.SLAViolationCount])
+				assert.NotContains(t, end, "slaViolation")
+			}
+		})
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tables := []struct {
+		name           string
+		opTimeout      int
+		requestTimeout time.Duration
+		expected       time.Duration
+	}{
+		{"operation timeout", 20, time.Hour, 20 * time.Millisecond},
+		{"request.timeoutMs", 0, 30 * time.Millisecond, 30 * time.Millisecond},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{silent: true})
+			b.sessions.clients[defaultSession].requestTimeout = table.requestTimeout
+			reporter := &countingReporter{counts: map[string]float64{}}
+			b.metricsReporter = []metrics.Reporter{reporter}
+
+			err := b.runOperation(context.Background(), &models.Operation{Type: "request", URI: "room.join", Timeout: table.opTimeout})
+			assert.Equal(t, &TimeoutError{Route: "room.join", Timeout: table.expected}, err)
+			assert.Equal(t, float64(1), reporter.counts[metrics.TimeoutCount])
+			assert.Equal(t, float64(0), reporter.counts[metrics.ErrorCount])
+		})
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	tables := []struct {
+		name string
+		op   *models.Operation
+	}{
+		{"pending request", &models.Operation{Type: "request", URI: "room.join", Timeout: 60000}},
+		{"pending push", &models.Operation{Type: "listen", URI: "room.joined", Timeout: 60000}},
+		{"silent window", &models.Operation{Type: "listenSilent", URI: "room.left", Timeout: 60000}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{silent: true})
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := b.runOperation(ctx, table.op)
+			assert.Equal(t, context.DeadlineExceeded, err)
+			assert.True(t, time.Since(start) < time.Second)
+		})
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	tables := []struct {
+		name     string
+		abort    bool
+		expected string
+	}{
+		{"max duration", false, "Spec exceeded its max duration while running operation 0 (request room.join)"},
+		{"abort", true, "Spec aborted while running operation 0 (request room.join)"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{delay: 50 * time.Millisecond}
+			b := newTestBot(transport)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if table.abort {
+				time.AfterFunc(5*time.Millisecond, cancel)
+			}
+
+			err := b.runOperationWithTimeout(ctx, 0, &models.Operation{Type: "request", URI: "room.join"})
+			assert.Contains(t, err.Error(), table.expected)
+
+			// the operation returned before the error was reported
+			transport.mutex.Lock()
+			defer transport.mutex.Unlock()
+			assert.Len(t, transport.sent, 1)
+		})
+	}
+}
+
+func TestAbandonedOperation(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	RegisterFunction("testBlock", func(map[string]interface{}, Storage) (map[string]interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.config = viper.New()
+	b.config.Set("bot.abandonAfter", "20ms")
+	b.spec = &models.Spec{
+		SequentialOperations: []*models.Operation{{Type: "function", URI: "testBlock"}},
+		TeardownOperations:   []*models.Operation{{Type: "request", URI: "room.leave"}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := b.runWithHooks(ctx, b.runSequence)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+	assert.Contains(t, err.Error(), "Abandoned operation 0 (function testBlock)")
+	assert.Contains(t, err.Error(), "teardown skipped")
+	assert.True(t, b.abandoned)
+
+	transport.mutex.Lock()
+	defer transport.mutex.Unlock()
+	assert.Empty(t, transport.sent)
+}
+
+func TestRequestMetadata(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+
+	op := &models.Operation{
+		Type: "request",
+		URI:  "shop.buy",
+		Expect: models.ExpectSpec{
+			"meta.route": {Type: "string", Value: "shop.buy"},
+			"code":       {Type: "string", Value: "200"},
+		},
+		Store: models.StoreSpec{
+			"latency": {Type: "int", Value: "meta.latencyMs"},
+			"msgID":   {Type: "int", Value: "${meta.id}"},
+		},
+	}
+
+	assert.NoError(t, b.runOperation(context.Background(), op))
+	_, ok := b.storage.Get("latency")
+	assert.True(t, ok)
+	id, _ := b.storage.Get("msgID")
+	assert.Equal(t, 1, id)
+}
+
+func TestConnectionExpectation(t *testing.T) {
+	tables := []struct {
+		name   string
+		fName  string
+		expect models.ExpectSpecEntry
+		err    bool
+	}{
+		{"connected to host", "reconnect", models.ExpectSpecEntry{Type: "connection", State: "connected", Host: "localhost:3250"}, false},
+		{"wrong host", "reconnect", models.ExpectSpecEntry{Type: "connection", Host: "other:3250"}, true},
+		{"disconnected", "disconnect", models.ExpectSpecEntry{Type: "connection", State: "disconnected"}, false},
+		{"expected connected", "disconnect", models.ExpectSpecEntry{Type: "connection", State: "connected"}, true},
+	}
+
+	fixtures, err := ioutil.TempFile("", "fixtures")
+	assert.NoError(t, err)
+	defer os.Remove(fixtures.Name())
+	fixtures.WriteString("{}")
+	fixtures.Close()
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			b.host = "localhost:3250"
+			b.config = viper.New()
+			b.config.Set("server.transport", "mock")
+			b.config.Set("server.fixtures", fixtures.Name())
+
+			op := &models.Operation{
+				Type:   "function",
+				URI:    table.fName,
+				Expect: models.ExpectSpec{"connection": table.expect},
+			}
+
+			err := b.runOperation(context.Background(), op)
+			if table.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConnectionStats(t *testing.T) {
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.sessions.clients[defaultSession].stats = b.stats
+	b.spec = &models.Spec{SequentialOperations: []*models.Operation{
+		{Type: "request", URI: "room.join", Args: map[string]interface{}{}},
+		{Type: "request", URI: "room.leave", Args: map[string]interface{}{}},
+	}}
+
+	assert.NoError(t, b.Run(context.Background()))
+
+	stats := b.Stats().Snapshot()
+	assert.Equal(t, int64(2), stats.Operations)
+	assert.Equal(t, int64(len(transport.sent[0])+len(transport.sent[1])), stats.BytesSent)
+	assert.Equal(t, int64(2*len(`{"code": "200"}`)), stats.BytesReceived)
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	transport := &recordingTransport{responses: []string{`{"items": ["a", "b", "c", "d"]}`}}
+	b := newTestBot(transport)
+	b.sessions.clients[defaultSession].maxResponseBytes = 16
+
+	err := b.runOperation(context.Background(), &models.Operation{Type: "request", URI: "inventory.list", Args: map[string]interface{}{}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "above client.maxResponseBytes 16")
+
+	go transport.handler(MsgPushType, 0, "inventory.changed", []byte(`{"items": ["a", "b", "c", "d"]}`))
+	err = b.runOperation(context.Background(), &models.Operation{Type: "listen", URI: "inventory.changed", Timeout: 1000})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Push on route inventory.changed")
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultSession is the main session of the bot, the one operations without
+// a session use. It is connected when the bot is created
+const defaultSession = ""
+
+// sessions are the connections of a bot by session name, shared by the
+// branches of its parallel operations
+type sessions struct {
+	mutex   sync.Mutex
+	clients map[string]*PClient
+}
+
+func newSessions() *sessions {
+	return &sessions{clients: map[string]*PClient{}}
+}
+
+// session returns the client of the named session, connecting named
+// sessions to the bot host the first time they are used
+func (b *SequentialBot) session(name string) (*PClient, error) {
+	b.sessions.mutex.Lock()
+	defer b.sessions.mutex.Unlock()
+
+	if client, ok := b.sessions.clients[name]; ok {
+		return client, nil
+	}
+	if name == defaultSession {
+		return nil, fmt.Errorf("Bot is not connected")
+	}
+
+	if err := b.connectSession(name, b.host); err != nil {
+		return nil, fmt.Errorf("Unable to connect session %s: %s", name, err)
+	}
+	return b.sessions.clients[name], nil
+}
+
+// connectNamedSession connects the named session to host
+func (b *SequentialBot) connectNamedSession(name, host string) error {
+	b.sessions.mutex.Lock()
+	defer b.sessions.mutex.Unlock()
+
+	if client, ok := b.sessions.clients[name]; ok && client.Connected() {
+		return fmt.Errorf("Session %s already connected", name)
+	}
+
+	if err := b.connectSession(name, host); err != nil {
+		return fmt.Errorf("Unable to connect session %s: %s", name, err)
+	}
+	return nil
+}
+
+// connectSession opens a connection to host as the named session, with its
+// own responses and pushes. Only the main session counts as a connected
+// bot. Callers hold the sessions mutex
+func (b *SequentialBot) connectSession(name, host string) error {
+	client, err := NewPClient(host, NewPClientOptions(b.config).withHandshake(b.spec.Handshake).withNetwork(b.spec.Network))
+	if err != nil {
+		return err
+	}
+
+	client.stats = b.stats
+	client.metricsReporter = b.metricsReporter
+	b.sessions.clients[name] = client
+	if name == defaultSession {
+		b.reportConnected(1)
+	}
+	b.stats.AddHandshake()
+
+	client.StartListening()
+	if b.pushHandlers != nil {
+		b.pushHandlers.register(client, b.spec.PushHandlers)
+	}
+	return nil
+}
+
+// disconnectSession disconnects the named session, when connected. Callers
+// hold the sessions mutex
+func (b *SequentialBot) disconnectSession(name string) {
+	client, ok := b.sessions.clients[name]
+	if !ok || client.client == nil {
+		return
+	}
+
+	if name == defaultSession {
+		b.reportConnected(-1)
+	}
+	client.Disconnect()
+}
+
+// reconnectSession disconnects the named session and connects it again to
+// the bot host
+func (b *SequentialBot) reconnectSession(name string) {
+	b.sessions.mutex.Lock()
+	defer b.sessions.mutex.Unlock()
+
+	b.disconnectSession(name)
+	if err := b.connectSession(name, b.host); err != nil {
+		b.logger.WithError(err).Errorf("Unable to reconnect session %q", name)
+		return
+	}
+	b.stats.AddReconnect()
+	b.logger.Debug("Reconnect done")
+}
@@ -0,0 +1,67 @@
+package bot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestNamedSessions(t *testing.T) {
+	f, err := ioutil.TempFile("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(mockFixturesJSON)
+	f.Close()
+
+	transport := &recordingTransport{}
+	b := newTestBot(transport)
+	b.config = viper.New()
+	b.config.Set("server.transport", "mock")
+	b.config.Set("server.fixtures", f.Name())
+	defer b.Disconnect()
+
+	tables := []struct {
+		name string
+		op   *models.Operation
+		err  bool
+	}{
+		{"request on a named session", &models.Operation{
+			Type: "request", URI: "room.join", Session: "mobile",
+			Args:   map[string]interface{}{"roomId": map[string]interface{}{"type": "string", "value": "r1"}},
+			Expect: models.ExpectSpec{"code": {Type: "string", Value: "200"}},
+		}, false},
+		{"push of the named session", &models.Operation{Type: "listen", URI: "room.joined", Session: "mobile", Timeout: 500}, false},
+		{"no push on the main session", &models.Operation{Type: "listen", URI: "room.joined", Timeout: 50}, true},
+		{"disconnect the named session", &models.Operation{Type: "function", URI: "disconnect", Session: "mobile"}, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			err := b.runOperation(context.Background(), table.op)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	assert.Empty(t, transport.sent)
+	assert.False(t, b.sessions.clients["mobile"].Connected())
+	assert.True(t, b.sessions.clients[defaultSession].Connected())
+}
+
+func TestConnectNamedSessionTwice(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.sessions.clients["mobile"] = b.sessions.clients[defaultSession]
+
+	err := b.runOperation(context.Background(), &models.Operation{Type: "function", URI: "connect", Session: "mobile"})
+	assert.EqualError(t, err, "Session mobile already connected")
+}
@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// defaultSharedWaitTimeout is how long waitForKey and barrier functions
+// wait when the operation has no timeout
+const defaultSharedWaitTimeout = time.Minute
+
+func sharedWaitContext(ctx context.Context, op *models.Operation) (context.Context, context.CancelFunc) {
+	timeout := defaultSharedWaitTimeout
+	if op.Timeout > 0 {
+		timeout = time.Duration(op.Timeout) * time.Millisecond
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// sharedKeyArgs returns the shared key of a publishKey or waitForKey
+// function and the storage key it maps to, the same one unless storeAs is
+// given
+func sharedKeyArgs(args map[string]interface{}) (string, string, error) {
+	key, ok := args["key"].(string)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("Missing key argument")
+	}
+
+	storeAs := key
+	if val, ok := args["storeAs"].(string); ok && val != "" {
+		storeAs = val
+	}
+
+	return key, storeAs, nil
+}
+
+// publishKey publishes the value arg, or the storage value of key when it
+// is not given, to the shared store
+func (b *SequentialBot) publishKey(ctx context.Context, op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	key, _, err := sharedKeyArgs(args)
+	if err != nil {
+		return fmt.Errorf("publishKey: %s", err)
+	}
+
+	value, ok := args["value"]
+	if !ok {
+		if value, ok = b.storage.Get(key); !ok {
+			return fmt.Errorf("publishKey: variable %s not found", key)
+		}
+	}
+
+	return b.shared.Publish(key, value)
+}
+
+// waitForKey blocks until key is published to the shared store, for up to
+// the operation timeout, and stores its value
+func (b *SequentialBot) waitForKey(ctx context.Context, op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	key, storeAs, err := sharedKeyArgs(args)
+	if err != nil {
+		return fmt.Errorf("waitForKey: %s", err)
+	}
+
+	ctx, cancel := sharedWaitContext(ctx, op)
+	defer cancel()
+
+	value, err := b.shared.WaitFor(ctx, key)
+	if err != nil {
+		return fmt.Errorf("waitForKey %s: %s", key, err)
+	}
+
+	b.storage.Set(storeAs, value)
+	return nil
+}
+
+// barrier blocks until the parties arg bots reached the barrier named by
+// the name arg, for up to the operation timeout
+func (b *SequentialBot) barrier(ctx context.Context, op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	name, ok := args["name"].(string)
+	if !ok || name == "" {
+		return fmt.Errorf("barrier: missing name argument")
+	}
+	parties, ok := args["parties"].(int)
+	if !ok || parties <= 0 {
+		return fmt.Errorf("barrier %s: parties must be a positive int", name)
+	}
+
+	ctx, cancel := sharedWaitContext(ctx, op)
+	defer cancel()
+
+	start := time.Now()
+	if err := b.shared.Barrier(ctx, name, parties); err != nil {
+		return fmt.Errorf("barrier %s: %s", name, err)
+	}
+	b.logger.Debugf("Passed barrier %s after %v", name, time.Since(start))
+
+	return nil
+}
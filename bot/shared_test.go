@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestPublishAndWaitForKey(t *testing.T) {
+	shared := state.NewMemorySharedStore()
+
+	host := newTestBot(&recordingTransport{})
+	host.shared = shared
+	host.storage.Set("matchId", "m42")
+
+	guest := newTestBot(&recordingTransport{})
+	guest.shared = shared
+
+	done := make(chan error)
+	go func() {
+		done <- guest.runOperation(context.Background(), &models.Operation{
+			Type:    "function",
+			URI:     "waitForKey",
+			Timeout: 1000,
+			Args: map[string]interface{}{
+				"key":     map[string]interface{}{"type": "string", "value": "matchId"},
+				"storeAs": map[string]interface{}{"type": "string", "value": "joinId"},
+			},
+		})
+	}()
+
+	assert.NoError(t, host.runOperation(context.Background(), &models.Operation{
+		Type: "function",
+		URI:  "publishKey",
+		Args: map[string]interface{}{"key": map[string]interface{}{"type": "string", "value": "matchId"}},
+	}))
+	assert.NoError(t, <-done)
+
+	joinID, ok := guest.storage.Get("joinId")
+	assert.True(t, ok)
+	assert.Equal(t, "m42", joinID)
+}
+
+func TestWaitForKeyTimeout(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.shared = state.NewMemorySharedStore()
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:    "function",
+		URI:     "waitForKey",
+		Timeout: 10,
+		Args:    map[string]interface{}{"key": map[string]interface{}{"type": "string", "value": "matchId"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestBarrier(t *testing.T) {
+	shared := state.NewMemorySharedStore()
+	barrierOp := &models.Operation{
+		Type:    "function",
+		URI:     "barrier",
+		Timeout: 1000,
+		Args: map[string]interface{}{
+			"name":    map[string]interface{}{"type": "string", "value": "matchStart"},
+			"parties": map[string]interface{}{"type": "int", "value": float64(4)},
+		},
+	}
+
+	done := make(chan error, 4)
+	for i := 0; i < 3; i++ {
+		b := newTestBot(&recordingTransport{})
+		b.shared = shared
+		go func() { done <- b.runOperation(context.Background(), barrierOp) }()
+	}
+
+	select {
+	case <-done:
+		t.Fatal("barrier released before every party arrived")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	last := newTestBot(&recordingTransport{})
+	last.shared = shared
+	assert.NoError(t, last.runOperation(context.Background(), barrierOp))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, <-done)
+	}
+}
+
+func TestBarrierTimeout(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.shared = state.NewMemorySharedStore()
+
+	err := b.runOperation(context.Background(), &models.Operation{
+		Type:    "function",
+		URI:     "barrier",
+		Timeout: 10,
+		Args: map[string]interface{}{
+			"name":    map[string]interface{}{"type": "string", "value": "matchStart"},
+			"parties": map[string]interface{}{"type": "int", "value": float64(2)},
+		},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package bot
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// shuffleOperations returns a random order of ops, as indexes, where every
+// operation comes after the ones it dependsOn
+func shuffleOperations(ops []*models.Operation, r *rand.Rand) ([]int, error) {
+	ids := make(map[string]int, len(ops))
+	for idx, op := range ops {
+		if op.ID == "" {
+			continue
+		}
+		if _, ok := ids[op.ID]; ok {
+			return nil, fmt.Errorf("Duplicated operation id %s", op.ID)
+		}
+		ids[op.ID] = idx
+	}
+
+	pending := make([]int, len(ops))
+	dependents := make([][]int, len(ops))
+	for idx, op := range ops {
+		for _, dep := range op.DependsOn {
+			depIdx, ok := ids[dep]
+			if !ok {
+				return nil, fmt.Errorf("Operation %d depends on unknown operation %s", idx, dep)
+			}
+			pending[idx]++
+			dependents[depIdx] = append(dependents[depIdx], idx)
+		}
+	}
+
+	ready := make([]int, 0)
+	for idx := range ops {
+		if pending[idx] == 0 {
+			ready = append(ready, idx)
+		}
+	}
+
+	order := make([]int, 0, len(ops))
+	for len(ready) > 0 {
+		// ready is kept sorted so the order only depends on the seed
+		sort.Ints(ready)
+		pick := r.Intn(len(ready))
+		idx := ready[pick]
+		ready = append(ready[:pick], ready[pick+1:]...)
+		order = append(order, idx)
+
+		for _, dependent := range dependents[idx] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(ops) {
+		return nil, fmt.Errorf("Operations dependsOn have a cycle")
+	}
+
+	return order, nil
+}
+
+// operationOrder returns the order the bot runs its operations in, shuffled
+// when the spec asks for it. Resumed bots keep the order of their checkpoint
+func (b *SequentialBot) operationOrder() ([]int, error) {
+	steps := b.spec.SequentialOperations
+	if b.resumeFrom != nil && len(b.resumeFrom.Order) == len(steps) {
+		return b.resumeFrom.Order, nil
+	}
+
+	if !b.spec.Shuffle {
+		order := make([]int, len(steps))
+		for idx := range order {
+			order[idx] = idx
+		}
+		return order, nil
+	}
+
+	order, err := shuffleOperations(steps, rand.New(rand.NewSource(b.shuffleSeed)))
+	if err != nil {
+		return nil, err
+	}
+
+	b.logger.Infof("Shuffled operations with seed %d, order: %v", b.shuffleSeed, order)
+	return order, nil
+}
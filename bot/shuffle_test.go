@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestShuffleOperations(t *testing.T) {
+	ops := []*models.Operation{
+		{ID: "login", Type: "request"},
+		{ID: "join", Type: "request", DependsOn: []string{"login"}},
+		{Type: "request", DependsOn: []string{"join"}},
+		{Type: "request"},
+		{Type: "request", DependsOn: []string{"login"}},
+	}
+
+	for seed := int64(0); seed < 50; seed++ {
+		order, err := shuffleOperations(ops, rand.New(rand.NewSource(seed)))
+		assert.NoError(t, err)
+		assert.Len(t, order, len(ops))
+
+		position := make(map[int]int, len(order))
+		for pos, idx := range order {
+			position[idx] = pos
+		}
+		assert.True(t, position[0] < position[1])
+		assert.True(t, position[1] < position[2])
+		assert.True(t, position[0] < position[4])
+
+		again, _ := shuffleOperations(ops, rand.New(rand.NewSource(seed)))
+		assert.Equal(t, order, again)
+	}
+}
+
+func TestShuffleOperationsErrors(t *testing.T) {
+	tables := []struct {
+		name string
+		ops  []*models.Operation
+	}{
+		{"unknown dependency", []*models.Operation{{ID: "a", DependsOn: []string{"b"}}}},
+		{"cycle", []*models.Operation{{ID: "a", DependsOn: []string{"b"}}, {ID: "b", DependsOn: []string{"a"}}}},
+		{"duplicated id", []*models.Operation{{ID: "a"}, {ID: "a"}}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			_, err := shuffleOperations(table.ops, rand.New(rand.NewSource(1)))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// simulateDisconnect modes
+const (
+	disconnectClose     = "close"
+	disconnectReset     = "reset"
+	disconnectBlackhole = "blackhole"
+)
+
+// defaultReconnectAttempts and defaultReconnectBackoff are how simulated
+// disconnects reconnect when the operation has no retry spec
+var (
+	defaultReconnectAttempts = 5
+	defaultReconnectBackoff  = &models.BackoffSpec{InitialMs: 100, MaxMs: 5000}
+)
+
+// simulateDisconnect drops the connection of the operation session the way
+// a flaky network would. The mode arg is close, closing it cleanly, the
+// default, reset, aborting it with a TCP reset, or blackhole, holding every
+// byte sent either way for durationMs. Reset and blackhole need
+// client.faultInjection. A blackholed connection is only dropped when the
+// server closed it meanwhile. With the reconnect arg, a dropped session
+// connects again, up to the operation retry maxAttempts times, waiting its
+// backoff between attempts
+func (b *SequentialBot) simulateDisconnect(ctx context.Context, op *models.Operation) error {
+	args, err := buildArgs(op.Args, b.storage)
+	if err != nil {
+		return err
+	}
+
+	mode, _ := args["mode"].(string)
+	if mode == "" {
+		mode = disconnectClose
+	}
+	reconnect, _ := args["reconnect"].(bool)
+
+	client, err := b.session(op.Session)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case disconnectClose:
+	case disconnectReset:
+		if client.proxy == nil {
+			return fmt.Errorf("simulateDisconnect %s needs client.faultInjection", mode)
+		}
+		client.proxy.reset()
+	case disconnectBlackhole:
+		if client.proxy == nil {
+			return fmt.Errorf("simulateDisconnect %s needs client.faultInjection", mode)
+		}
+		durationMs, ok := args["durationMs"].(int)
+		if !ok || durationMs <= 0 {
+			return fmt.Errorf("simulateDisconnect %s: durationMs must be a positive int", mode)
+		}
+
+		duration := time.Duration(durationMs) * time.Millisecond
+		b.logger.Debugf("Blackholing the connection for %v", duration)
+		client.proxy.blackhole(duration)
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		if !client.proxy.dropped() && client.Connected() {
+			b.logger.Debug("Connection survived the blackhole")
+			return nil
+		}
+	default:
+		return fmt.Errorf("Unknown simulateDisconnect mode: %s", mode)
+	}
+
+	b.sessions.mutex.Lock()
+	b.disconnectSession(op.Session)
+	b.sessions.mutex.Unlock()
+
+	if !reconnect {
+		return nil
+	}
+	return b.reconnectWithBackoff(ctx, op)
+}
+
+// reconnectWithBackoff connects the operation session again, retrying as
+// its retry spec says
+func (b *SequentialBot) reconnectWithBackoff(ctx context.Context, op *models.Operation) error {
+	attempts, backoff := defaultReconnectAttempts, defaultReconnectBackoff
+	if op.Retry != nil {
+		if op.Retry.MaxAttempts > 0 {
+			attempts = op.Retry.MaxAttempts
+		}
+		if op.Retry.Backoff != nil {
+			backoff = op.Retry.Backoff
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		b.sessions.mutex.Lock()
+		err := b.connectSession(op.Session, b.host)
+		b.sessions.mutex.Unlock()
+		if err == nil {
+			b.stats.AddReconnect()
+			b.logger.Debugf("Reconnected on attempt %d", attempt)
+			return nil
+		}
+		if attempt >= attempts {
+			return fmt.Errorf("Unable to reconnect after %d attempts: %s", attempts, err)
+		}
+
+		delay := backoffDelay(backoff, attempt)
+		b.logger.Debugf("Reconnect attempt %d of %d failed, retrying in %v: %s", attempt, attempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func simulateDisconnectOp(args map[string]interface{}) *models.Operation {
+	typed := map[string]interface{}{}
+	for k, v := range args {
+		typ := "string"
+		switch v.(type) {
+		case bool:
+			typ = "bool"
+		case int:
+			typ = "int"
+		}
+		typed[k] = map[string]interface{}{"type": typ, "value": v}
+	}
+
+	return &models.Operation{Type: "function", URI: "simulateDisconnect", Args: typed}
+}
+
+func TestSimulateDisconnect(t *testing.T) {
+	f, err := ioutil.TempFile("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(mockFixturesJSON)
+	f.Close()
+
+	tables := []struct {
+		name      string
+		args      map[string]interface{}
+		connected bool
+		err       string
+	}{
+		{"clean close", map[string]interface{}{}, false, ""},
+		{"close and reconnect", map[string]interface{}{"mode": "close", "reconnect": true}, true, ""},
+		{"reset without fault injection", map[string]interface{}{"mode": "reset"}, true, "simulateDisconnect reset needs client.faultInjection"},
+		{"blackhole without fault injection", map[string]interface{}{"mode": "blackhole", "durationMs": 10}, true, "simulateDisconnect blackhole needs client.faultInjection"},
+		{"unknown mode", map[string]interface{}{"mode": "flaky"}, true, "Unknown simulateDisconnect mode: flaky"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			b := newTestBot(&recordingTransport{})
+			b.config = viper.New()
+			b.config.Set("server.transport", "mock")
+			b.config.Set("server.fixtures", f.Name())
+			defer b.Disconnect()
+
+			err := b.runOperation(context.Background(), simulateDisconnectOp(table.args))
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, table.connected, b.sessions.clients[defaultSession].Connected())
+		})
+	}
+}
+
+func TestSimulateDisconnectReconnectAttempts(t *testing.T) {
+	b := newTestBot(&recordingTransport{})
+	b.config = viper.New()
+	b.config.Set("server.transport", "mock")
+	b.config.Set("server.fixtures", "missing.json")
+
+	op := simulateDisconnectOp(map[string]interface{}{"reconnect": true})
+	op.Retry = &models.RetrySpec{MaxAttempts: 2, Backoff: &models.BackoffSpec{InitialMs: 1}}
+	err := b.runOperation(context.Background(), op)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Unable to reconnect after 2 attempts")
+}
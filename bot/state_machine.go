@@ -0,0 +1,111 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+const defaultMaxTransitions = 100
+
+// runStateMachine interprets a stateMachine operation
+func (b *SequentialBot) runStateMachine(ctx context.Context, op *models.Operation) error {
+	sm := op.StateMachine
+	if sm == nil {
+		return fmt.Errorf("Missing stateMachine spec for operation %s", op.URI)
+	}
+
+	terminal := make(map[string]bool, len(sm.Terminal))
+	for _, name := range sm.Terminal {
+		terminal[name] = true
+	}
+	if len(terminal) == 0 {
+		return fmt.Errorf("State machine has no terminal state")
+	}
+
+	maxTransitions := sm.MaxTransitions
+	if maxTransitions <= 0 {
+		maxTransitions = defaultMaxTransitions
+	}
+
+	current := sm.Initial
+	for transitions := 0; !terminal[current]; transitions++ {
+		if transitions >= maxTransitions {
+			return fmt.Errorf("State machine exceeded %d transitions, stuck at state %s", maxTransitions, current)
+		}
+
+		state, ok := sm.States[current]
+		if !ok {
+			return fmt.Errorf("Unknown state: %s", current)
+		}
+
+		next, err := b.runState(ctx, current, state)
+		if err != nil {
+			return err
+		}
+
+		b.logger.Debugf("State machine transition %s -> %s", current, next)
+		current = next
+	}
+
+	b.logger.Debugf("State machine reached terminal state %s", current)
+	return nil
+}
+
+// runState runs the state trigger and returns the target of the first
+// transition whose guard matches the trigger response
+func (b *SequentialBot) runState(ctx context.Context, name string, state *models.StateSpec) (string, error) {
+	if state.Trigger == nil {
+		return "", fmt.Errorf("State %s has no trigger", name)
+	}
+
+	resp, meta, rawResp, err := b.runTrigger(ctx, state.Trigger)
+	if err != nil {
+		return "", fmt.Errorf("State %s trigger failed: %s", name, err)
+	}
+
+	for _, transition := range state.Transitions {
+		if validateExpectations(transition.Guard, resp, meta, b.storage, b.strictRefs) != nil {
+			continue
+		}
+
+		if err := validateExpectations(transition.Expect, resp, meta, b.storage, b.strictRefs); err != nil {
+			return "", NewExpectError(err, rawResp, transition.Expect)
+		}
+
+		if err := storeData(transition.Store, b.storage, resp, meta); err != nil {
+			return "", err
+		}
+
+		return transition.Target, nil
+	}
+
+	return "", fmt.Errorf("No transition from state %s matched response: %s", name, string(rawResp))
+}
+
+// runTrigger runs a request or listen operation returning its response
+func (b *SequentialBot) runTrigger(ctx context.Context, op *models.Operation) (Response, Metadata, []byte, error) {
+	switch op.Type {
+	case "request":
+		args, err := buildArgs(op.Args, b.storage)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		return b.sendRequestWithRetry(ctx, args, op)
+	case "listen":
+		client, err := b.session(op.Session)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		resp, meta, err := client.ReceivePush(ctx, op.URI, op.Timeout)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		raw, _ := json.Marshal(resp)
+		return resp, meta, raw, nil
+	}
+
+	return nil, nil, nil, fmt.Errorf("Unsupported trigger type: %s", op.Type)
+}
@@ -0,0 +1,68 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func matchmakingMachine(maxTransitions int) *models.Operation {
+	poll := &models.Operation{Type: "request", URI: "match.status"}
+	return &models.Operation{
+		Type: "stateMachine",
+		StateMachine: &models.StateMachineSpec{
+			Initial:        "waiting",
+			Terminal:       []string{"matched"},
+			MaxTransitions: maxTransitions,
+			States: map[string]*models.StateSpec{
+				"waiting": {
+					Trigger: poll,
+					Transitions: []*models.TransitionSpec{
+						{
+							Guard:  models.ExpectSpec{"status": {Type: "string", Value: "matched"}},
+							Store:  models.StoreSpec{"room": {Type: "string", Value: "room"}},
+							Target: "matched",
+						},
+						{
+							Guard:  models.ExpectSpec{"status": {Type: "string", Value: "waiting"}},
+							Target: "waiting",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRunStateMachine(t *testing.T) {
+	tables := []struct {
+		name           string
+		responses      []string
+		maxTransitions int
+		err            bool
+	}{
+		{"reaches terminal", []string{`{"status": "waiting"}`, `{"status": "matched", "room": "r1"}`}, 0, false},
+		{"no transition matches", []string{`{"status": "cancelled"}`}, 0, true},
+		{"exceeds max transitions", []string{`{"status": "waiting"}`}, 5, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			transport := &recordingTransport{responses: table.responses}
+			b := newTestBot(transport)
+			err := b.runOperation(context.Background(), matchmakingMachine(table.maxTransitions))
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Len(t, transport.sent, 2)
+			room, ok := b.storage.Get("room")
+			assert.True(t, ok)
+			assert.Equal(t, "r1", room)
+		})
+	}
+}
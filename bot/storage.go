@@ -1,13 +1,18 @@
 package bot
 
 import (
+	"strings"
+
 	"github.com/spf13/viper"
 )
 
 type storage map[string]interface{}
 
-func newStorage(config *viper.Viper) *storage {
+func newStorage(config *viper.Viper, shared map[string]interface{}) *storage {
 	store := storage{}
+	for k, v := range shared {
+		store[k] = v
+	}
 	return &store
 }
 
@@ -21,3 +26,66 @@ func (s *storage) Set(key string, val interface{}) {
 	i := map[string]interface{}(*s)
 	i[key] = val
 }
+
+// copy returns a shallow copy of the storage
+func (s *storage) copy() *storage {
+	store := make(storage, len(*s))
+	for k, v := range *s {
+		store[k] = v
+	}
+	return &store
+}
+
+const (
+	paramsPrefix = "params."
+	varsPrefix   = "vars."
+)
+
+// withVars binds vars over the current ones until the returned function is
+// called, the vars not rebound stay visible
+func (s *storage) withVars(vars map[string]interface{}) func() {
+	i := map[string]interface{}(*s)
+	outer := map[string]interface{}{}
+	for k, v := range vars {
+		if old, ok := i[varsPrefix+k]; ok {
+			outer[k] = old
+		}
+		i[varsPrefix+k] = v
+	}
+
+	return func() {
+		for k := range vars {
+			if old, ok := outer[k]; ok {
+				i[varsPrefix+k] = old
+				continue
+			}
+			delete(i, varsPrefix+k)
+		}
+	}
+}
+
+// withParams binds params in a new scope, hiding the ones bound by outer
+// scopes until the returned function is called
+func (s *storage) withParams(params map[string]interface{}) func() {
+	i := map[string]interface{}(*s)
+	outer := map[string]interface{}{}
+	for k, v := range i {
+		if strings.HasPrefix(k, paramsPrefix) {
+			outer[k] = v
+			delete(i, k)
+		}
+	}
+
+	for k, v := range params {
+		i[paramsPrefix+k] = v
+	}
+
+	return func() {
+		for k := range params {
+			delete(i, paramsPrefix+k)
+		}
+		for k, v := range outer {
+			i[k] = v
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// suiteBotID identifies the throwaway bot running suite operations
+const suiteBotID = -1
+
+// RunSuiteOperations runs suite setup or teardown operations with a
+// throwaway bot, returning the resulting storage
+func RunSuiteOperations(app *state.App, config *viper.Viper, name string, ops []*models.Operation, logger logrus.FieldLogger) (map[string]interface{}, error) {
+	spec := &models.Spec{Name: name, SequentialOperations: ops}
+	logger = logger.WithField("suite", name)
+
+	b, err := NewSequentialBot(app, config, spec, suiteBotID, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.Run(context.Background()); err != nil {
+		return nil, err
+	}
+
+	// the throwaway bot id must not shadow the id of the bots sharing it
+	store := b.(*SequentialBot).storage.copy()
+	delete(*store, botIDKey)
+
+	return map[string]interface{}(*store), nil
+}
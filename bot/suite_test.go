@@ -0,0 +1,49 @@
+package bot
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestRunSuiteOperations(t *testing.T) {
+	f, err := ioutil.TempFile("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"requests": [{"route": "auth.login", "response": {"token": "t1"}}]}`)
+	f.Close()
+
+	config := viper.New()
+	config.Set("server.transport", "mock")
+	config.Set("server.fixtures", f.Name())
+	app := state.NewApp(config, false)
+
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	ops := []*models.Operation{{
+		Type:  "request",
+		URI:   "auth.login",
+		Store: models.StoreSpec{"token": {Type: "string", Value: "token"}},
+	}}
+	store, err := RunSuiteOperations(app, config, "setup", ops, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, "t1", store["token"])
+	assert.NotContains(t, store, botIDKey)
+
+	app.SuiteStorage = store
+	shared := newStorage(config, app.SuiteStorage)
+	assert.NoError(t, seedStorage(shared, config, &models.Spec{}, 3, 0))
+	id, _ := shared.Get(botIDKey)
+	assert.Equal(t, 3, id)
+	token, _ := shared.Get("token")
+	assert.Equal(t, "t1", token)
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateExpr matches the {{name}} placeholders of template transforms
+var templateExpr = regexp.MustCompile(`\{\{\s*([^}\s]+)\s*\}\}`)
+
+// applyTransforms applies the store transforms, in order, to value. Each
+// transform is a name optionally followed by a colon and its argument:
+//
+//	toString        formats the value as a string
+//	toInt           converts numbers and numeric strings to an int
+//	index:N         picks the Nth array element, negative N counts from the end
+//	template:TEXT   replaces {{value}} in TEXT with the value and other
+//	                {{key}} placeholders with stored values
+func applyTransforms(value interface{}, transforms []string, store *storage) (interface{}, error) {
+	for _, transform := range transforms {
+		name, arg := transform, ""
+		if idx := strings.Index(transform, ":"); idx >= 0 {
+			name, arg = transform[:idx], transform[idx+1:]
+		}
+
+		var err error
+		switch name {
+		case "toString":
+			value = stringify(value)
+		case "toInt":
+			value, err = transformToInt(value)
+		case "index":
+			value, err = transformIndex(value, arg)
+		case "template":
+			value, err = transformTemplate(value, arg, store)
+		default:
+			err = fmt.Errorf("Unknown store transform: %s", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
+
+func stringify(value interface{}) string {
+	switch val := value.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+func transformToInt(value interface{}) (interface{}, error) {
+	switch val := value.(type) {
+	case int:
+		return val, nil
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("toInt failed for %q: %s", val, err)
+		}
+		return i, nil
+	}
+
+	f, err := toFloat(value)
+	if err != nil {
+		return nil, fmt.Errorf("toInt failed: %s", err)
+	}
+
+	return int(f), nil
+}
+
+func transformIndex(value interface{}, arg string) (interface{}, error) {
+	idx, err := strconv.Atoi(arg)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed index transform argument: %s", arg)
+	}
+
+	arr, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("index transform needs an array, got %v", value)
+	}
+
+	if idx < 0 {
+		idx += len(arr)
+	}
+	if idx < 0 || idx >= len(arr) {
+		return nil, fmt.Errorf("index %s out of range for an array of %d elements", arg, len(arr))
+	}
+
+	return arr[idx], nil
+}
+
+func transformTemplate(value interface{}, text string, store *storage) (interface{}, error) {
+	var missing string
+	ret := templateExpr.ReplaceAllStringFunc(text, func(placeholder string) string {
+		key := templateExpr.FindStringSubmatch(placeholder)[1]
+		if key == "value" {
+			return stringify(value)
+		}
+
+		if stored, ok := store.Get(key); ok {
+			return stringify(stored)
+		}
+
+		missing = key
+		return placeholder
+	})
+
+	if missing != "" {
+		return nil, fmt.Errorf("Variable %s not found in template %s", missing, text)
+	}
+
+	return ret, nil
+}
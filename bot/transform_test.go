@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestApplyTransforms(t *testing.T) {
+	tables := []struct {
+		name       string
+		value      interface{}
+		transforms []string
+		expected   interface{}
+		err        bool
+	}{
+		{"no transforms", "abc", nil, "abc", false},
+		{"int to string", 42, []string{"toString"}, "42", false},
+		{"float to string", float64(1234567), []string{"toString"}, "1234567", false},
+		{"string to int", " 17 ", []string{"toInt"}, 17, false},
+		{"float to int", float64(3), []string{"toInt"}, 3, false},
+		{"malformed int", "abc", []string{"toInt"}, nil, true},
+		{"index", []interface{}{"a", "b", "c"}, []string{"index:1"}, "b", false},
+		{"last index", []interface{}{"a", "b", "c"}, []string{"index:-1"}, "c", false},
+		{"index out of range", []interface{}{"a"}, []string{"index:3"}, nil, true},
+		{"index of non array", "a", []string{"index:0"}, nil, true},
+		{"template", "t0k3n", []string{"template:Bearer {{value}}"}, "Bearer t0k3n", false},
+		{"template with stored", float64(7), []string{"template:{{ region }}-{{value}}"}, "eu-7", false},
+		{"template missing", "x", []string{"template:{{other}}"}, nil, true},
+		{"chained", []interface{}{float64(10), float64(20)}, []string{"index:0", "toString", "template:room-{{value}}"}, "room-10", false},
+		{"unknown", "x", []string{"reverse"}, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			store := &storage{}
+			store.Set("region", "eu")
+
+			value, err := applyTransforms(table.value, table.transforms, store)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.expected, value)
+		})
+	}
+}
+
+func TestStoreDataTransforms(t *testing.T) {
+	store := &storage{}
+	resp := Response{"tokens": []interface{}{"first", "second"}}
+
+	err := storeData(models.StoreSpec{
+		"auth": {Type: "array", Value: "tokens", Transform: []string{"index:1", "template:Bearer {{value}}"}},
+	}, store, resp, nil)
+	assert.NoError(t, err)
+
+	auth, ok := store.Get("auth")
+	assert.True(t, ok)
+	assert.Equal(t, "Bearer second", auth)
+}
@@ -0,0 +1,106 @@
+package bot
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/pitaya/client"
+)
+
+// messageHandler receives every message coming from the server
+type messageHandler func(msgType byte, id uint, route string, data []byte)
+
+// transport is the connection used by PClient to talk to the server.
+// Messages are handled from the transport own goroutines, never from within
+// SendRequest
+type transport interface {
+	SendRequest(route string, data []byte) (uint, error)
+	SendNotify(route string, data []byte) error
+	Listen(handler messageHandler)
+	Connected() bool
+	Disconnect()
+}
+
+// pitayaTransport talks to a real server through the pitaya client
+type pitayaTransport struct {
+	client *client.Client
+}
+
+func newPitayaTransport(host string, opts *PClientOptions, tlsConfig *tls.Config) (*pitayaTransport, error) {
+	tlsConfigs := []*tls.Config{}
+	if tlsConfig != nil {
+		tlsConfigs = append(tlsConfigs, tlsConfig)
+	}
+
+	pclient := client.New(logrus.InfoLevel)
+	if opts.Handshake != nil {
+		pclient.SetClientHandshakeData(opts.Handshake)
+	}
+
+	var err error
+	switch opts.Protocol {
+	case "", "tcp":
+		if len(opts.WSHeaders) > 0 {
+			return nil, fmt.Errorf("Websocket headers need the ws or wss protocol")
+		}
+		err = pclient.ConnectTo(host, tlsConfigs...)
+	case "ws", "wss":
+		path := opts.WSPath
+		if path == "" {
+			path = "/"
+		}
+		if len(opts.WSHeaders) == 0 {
+			err = pclient.ConnectToWS(host, path, tlsConfigs...)
+			break
+		}
+
+		// the pitaya client dials websockets with the default handshake,
+		// the headers are added by a local proxy, which does the tls
+		if tlsConfig != nil && tlsConfig.ServerName == "" {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.ServerName, _, _ = net.SplitHostPort(host)
+		}
+		proxy, perr := newWSHeaderProxy(host, opts.WSHeaders, tlsConfig)
+		if perr != nil {
+			return nil, perr
+		}
+		if err = pclient.ConnectToWS(proxy.addr(), path); err != nil {
+			proxy.close()
+		}
+	default:
+		return nil, fmt.Errorf("Unknown protocol: %s", opts.Protocol)
+	}
+	if err != nil {
+		fmt.Println("Error connecting to server")
+		fmt.Println(err)
+		return nil, err
+	}
+
+	return &pitayaTransport{client: pclient}, nil
+}
+
+func (t *pitayaTransport) SendRequest(route string, data []byte) (uint, error) {
+	return t.client.SendRequest(route, data)
+}
+
+func (t *pitayaTransport) SendNotify(route string, data []byte) error {
+	return t.client.SendNotify(route, data)
+}
+
+func (t *pitayaTransport) Listen(handler messageHandler) {
+	go func() {
+		for m := range t.client.IncomingMsgChan {
+			handler(byte(m.Type), m.ID, m.Route, m.Data)
+		}
+	}()
+}
+
+func (t *pitayaTransport) Connected() bool {
+	return t.client.Connected
+}
+
+func (t *pitayaTransport) Disconnect() {
+	t.client.Disconnect()
+}
@@ -0,0 +1,93 @@
+package bot
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// wsHeaderProxy adds headers to the websocket handshake of the pitaya
+// client, which dials websockets with its default handshake. It listens on
+// a local port, adds the headers to the upgrade request of the first
+// connection and forwards it to the server, over tls when tlsConfig is set
+type wsHeaderProxy struct {
+	listener  net.Listener
+	upstream  string
+	headers   map[string]string
+	tlsConfig *tls.Config
+}
+
+// newWSHeaderProxy starts a proxy to upstream on a local port
+func newWSHeaderProxy(upstream string, headers map[string]string, tlsConfig *tls.Config) (*wsHeaderProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to start websocket headers proxy: %s", err)
+	}
+
+	p := &wsHeaderProxy{
+		listener:  listener,
+		upstream:  upstream,
+		headers:   headers,
+		tlsConfig: tlsConfig,
+	}
+	go p.accept()
+	return p, nil
+}
+
+// addr is the address the client connects to instead of the server
+func (p *wsHeaderProxy) addr() string {
+	return p.listener.Addr().String()
+}
+
+func (p *wsHeaderProxy) close() {
+	p.listener.Close()
+}
+
+// accept proxies the first connection, the one of the pitaya client
+func (p *wsHeaderProxy) accept() {
+	client, err := p.listener.Accept()
+	p.listener.Close()
+	if err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(client)
+	req, err := http.ReadRequest(reader)
+	if err != nil {
+		client.Close()
+		return
+	}
+
+	var server net.Conn
+	if p.tlsConfig != nil {
+		server, err = tls.Dial("tcp", p.upstream, p.tlsConfig)
+	} else {
+		server, err = net.Dial("tcp", p.upstream)
+	}
+	if err != nil {
+		client.Close()
+		return
+	}
+
+	req.Host = p.upstream
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+	if err := req.Write(server); err != nil {
+		client.Close()
+		server.Close()
+		return
+	}
+
+	go func() {
+		io.Copy(server, reader)
+		server.Close()
+	}()
+	go func() {
+		io.Copy(client, server)
+		client.Close()
+	}()
+}
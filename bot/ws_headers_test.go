@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsEchoServer echoes websocket messages back, reporting the X-Route header
+// of each handshake
+func wsEchoServer(tlsServer bool) (*httptest.Server, chan string) {
+	routes := make(chan string, 1)
+	upgrader := websocket.Upgrader{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routes <- r.Header.Get("X-Route")
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(typ, msg)
+		}
+	})
+
+	if tlsServer {
+		return httptest.NewTLSServer(handler), routes
+	}
+	return httptest.NewServer(handler), routes
+}
+
+func TestWSHeaderProxy(t *testing.T) {
+	tables := []struct {
+		name string
+		tls  bool
+	}{
+		{"ws", false},
+		{"wss", true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			server, routes := wsEchoServer(table.tls)
+			defer server.Close()
+
+			var tlsConfig *tls.Config
+			if table.tls {
+				tlsConfig = &tls.Config{InsecureSkipVerify: true}
+			}
+			upstream := strings.TrimPrefix(strings.TrimPrefix(server.URL, "http://"), "https://")
+			p, err := newWSHeaderProxy(upstream, map[string]string{"X-Route": "a"}, tlsConfig)
+			assert.NoError(t, err)
+			defer p.close()
+
+			conn, _, err := websocket.DefaultDialer.Dial("ws://"+p.addr()+"/", nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			assert.Equal(t, "a", <-routes)
+
+			assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("hello")))
+			_, msg, err := conn.ReadMessage()
+			assert.NoError(t, err)
+			assert.Equal(t, "hello", string(msg))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/launcher"
+)
+
+var listOnly bool
+
+// fmtCmd represents the fmt command
+var fmtCmd = &cobra.Command{
+	Use:   "fmt [dir]",
+	Short: "Formats and lints spec files",
+	Long: `Formats every spec under dir (./specs/ by default), sorting keys and
+indenting with two spaces, and reports unknown operation types and missing
+required fields. JSON has no comments, so there is nothing to preserve.
+YAML specs are linted but left as written, keeping their comments.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "./specs/"
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		if err := bot.LoadPlugins(config.GetStringSlice("bot.plugins")); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		changed, issues, err := launcher.FormatSpecs(dir, !listOnly)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, path := range changed {
+			fmt.Println(path)
+		}
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+
+		if len(issues) > 0 || (listOnly && len(changed) > 0) {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fmtCmd)
+
+	fmtCmd.Flags().BoolVarP(&listOnly, "list", "l", false, "only list files whose formatting differs, without rewriting them")
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/pitaya-bot/recorder"
+)
+
+var recordOptions recorder.Options
+
+// recordCmd represents the record command
+var recordCmd = &cobra.Command{
+	Use:   "record",
+	Short: "Records client sessions as specs",
+	Long: `Proxies real clients to a pitaya server, tcp or websocket, and writes
+the requests, notifies and pushes of each client session, in the order they
+happened, as a spec replaying it. Requests and notifies keep their args,
+pushes become listen operations and the client handshake becomes the spec
+handshake. Only json payloads keep their args.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		log := logrus.New()
+		log.Formatter = new(logrus.TextFormatter)
+		log.Out = os.Stdout
+		logger := log.WithFields(logrus.Fields{
+			"source":   "pitaya-bot",
+			"function": "record",
+		})
+
+		if recordOptions.Target == "" {
+			recordOptions.Target = config.GetString("server.host")
+		}
+		if err := recorder.NewRecorder(recordOptions, logger).ListenAndServe(); err != nil {
+			logger.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+
+	recordCmd.Flags().StringVar(&recordOptions.Listen, "listen", ":3251", "address clients connect to")
+	recordCmd.Flags().StringVar(&recordOptions.Target, "target", "", "pitaya server the clients are proxied to, defaults to server.host")
+	recordCmd.Flags().BoolVar(&recordOptions.WebSocket, "ws", false, "proxy websocket clients instead of tcp ones")
+	recordCmd.Flags().StringVar(&recordOptions.Path, "path", "/", "websocket path of the server")
+	recordCmd.Flags().StringVar(&recordOptions.Out, "out", "./specs/", "directory the recorded specs are written to")
+}
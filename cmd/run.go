@@ -21,6 +21,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -32,6 +34,15 @@ var (
 	specsDirectory string
 	testDuration   time.Duration
 	reportMetrics  bool
+	resume         bool
+	profiles       = map[string]*string{}
+	profileTime    time.Duration
+	spawnRate      float64
+	dryRun         bool
+	reportHTML     string
+	junit          string
+	showDashboard  bool
+	controlAddress string
 )
 
 // runCmd represents the run command
@@ -40,6 +51,37 @@ var runCmd = &cobra.Command{
 	Short: "Runs the pitaya bot",
 	Long:  `Runs the pitaya bot.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		config.Set("checkpoint.resume", resume)
+		for name, path := range profiles {
+			if *path != "" {
+				config.Set("pprof."+name, *path)
+			}
+		}
+		if profileTime > 0 {
+			config.Set("pprof.duration", profileTime)
+		}
+		if spawnRate > 0 {
+			config.Set("loadtest.spawnRate", spawnRate)
+		}
+		if reportHTML != "" {
+			config.Set("report.htmlPath", reportHTML)
+		}
+		if junit != "" {
+			config.Set("report.junitPath", junit)
+		}
+		if showDashboard {
+			config.Set("dashboard.enabled", true)
+		}
+		if controlAddress != "" {
+			config.Set("control.address", controlAddress)
+		}
+		if dryRun {
+			if err := launcher.DryRun(config, specsDirectory, os.Stdout); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
 		app := state.NewApp(config, reportMetrics)
 		launcher.Launch(app, config, specsDirectory, testDuration.Seconds(), reportMetrics)
 	},
@@ -53,4 +95,15 @@ func init() {
 	runCmd.PersistentFlags().StringVarP(&specsDirectory, "dir", "d", "./specs/", "Spec to run")
 	runCmd.PersistentFlags().DurationVar(&testDuration, "duration", 1*time.Minute, "how long should the test take")
 	runCmd.PersistentFlags().BoolVar(&reportMetrics, "report-metrics", false, "Should metrics be reported")
+	runCmd.PersistentFlags().BoolVar(&resume, "resume", false, "resume bots from their checkpoints in checkpoint.dir")
+	for _, name := range []string{"cpu", "heap", "block", "goroutine"} {
+		profiles[name] = runCmd.PersistentFlags().String(name+"-profile", "", "write a "+name+" profile of the load phase to this file")
+	}
+	runCmd.PersistentFlags().DurationVar(&profileTime, "profile-duration", 0, "stop profiling after this long, defaults to the whole load phase")
+	runCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "print the operations of the first bot of each spec, with resolved args and expectations, without sending anything")
+	runCmd.PersistentFlags().StringVar(&reportHTML, "report-html", "", "write an html report of the run, with charts and per route tables, to this file")
+	runCmd.PersistentFlags().StringVar(&junit, "junit", "", "write the run results as junit xml, for ci servers, to this file")
+	runCmd.PersistentFlags().BoolVar(&showDashboard, "dashboard", false, "show a live dashboard of the run in the terminal, logs are written to dashboard.logFile")
+	runCmd.PersistentFlags().StringVar(&controlAddress, "control-address", "", "serve a web dashboard and control api, to pause, ramp, drain and abort the run, on this address")
+	runCmd.PersistentFlags().Float64Var(&spawnRate, "spawn-rate", 0, "launch bots at this rate, in bots per second, instead of all at once")
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/launcher"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [dir]",
+	Short: "Validates spec files without running them",
+	Long: `Validates every spec under dir (./specs/ by default) without connecting
+to any server: unknown fields and wrong types, what fmt lints, like unknown
+operation types and functions, and references to storage keys no operation
+stores or to undefined vars. Issues of json specs are reported with their
+line.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := "./specs/"
+		if len(args) > 0 {
+			dir = args[0]
+		}
+
+		if err := bot.LoadPlugins(config.GetStringSlice("bot.plugins")); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		issues, err := launcher.ValidateSpecs(dir, config.GetStringMap("vars"))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+
+		if len(issues) > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,96 @@
+package control
+
+// page is the web dashboard, it follows /api/live and calls the control
+// endpoints
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pitaya-bot</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+button { margin-right: 0.5em; }
+table { border-collapse: collapse; margin-top: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f0f0f0; }
+#error { color: #c0392b; }
+</style>
+</head>
+<body>
+<h1>pitaya-bot</h1>
+<p id="status">Connecting...</p>
+<p>
+<button onclick="control('pause')">Pause</button>
+<button onclick="control('resume')">Resume</button>
+<input id="bots" type="number" min="0" placeholder="bots per spec">
+<button id="ramp" onclick="ramp()">Ramp</button>
+<button onclick="if (confirm('Drain the run? Running bots finish, no more are launched')) control('drain')">Drain</button>
+<button onclick="if (confirm('Abort the run? Bots stop before their next operation and run their teardown')) control('abort')">Abort</button>
+</p>
+<p id="error"></p>
+<table>
+<thead><tr><th>Route</th><th>Requests</th><th>p50 ms</th><th>p95 ms</th><th>p99 ms</th><th>Errors</th><th>Error rate</th><th>Pushes</th></tr></thead>
+<tbody id="routes"></tbody>
+</table>
+<script>
+function text(v) { return document.createTextNode(v); }
+
+function render(s) {
+  var state = s.aborted ? 'aborted' : (s.draining ? 'draining' : (s.paused ? 'paused' : 'running'));
+  var target = s.targetBots === null ? 'spec defaults' : s.targetBots;
+  document.getElementById('status').textContent =
+    state + ' for ' + Math.round(s.elapsedMs / 1000) + 's, ' +
+    s.connectedBots + ' bots connected, ' + s.summary.bots + ' finished (' + s.summary.failedBots + ' failed), ' +
+    (s.rampable ? 'target ' + target + ' bots per spec' : 'following a load profile');
+  document.getElementById('ramp').disabled = !s.rampable;
+
+  var routes = document.getElementById('routes');
+  routes.innerHTML = '';
+  s.summary.routes.forEach(function (r) {
+    var row = document.createElement('tr');
+    [r.route, r.requests, Math.round(r.p50Ms), Math.round(r.p95Ms), Math.round(r.p99Ms), r.errors,
+     (r.errorRate * 100).toFixed(2) + '%', r.pushes].forEach(function (v) {
+      var cell = document.createElement('td');
+      cell.appendChild(text(v));
+      row.appendChild(cell);
+    });
+    routes.appendChild(row);
+  });
+}
+
+function request(path, body) {
+  var xhr = new XMLHttpRequest();
+  xhr.open('POST', path);
+  xhr.onload = function () {
+    if (xhr.status !== 200) {
+      document.getElementById('error').textContent = xhr.responseText;
+      return;
+    }
+    document.getElementById('error').textContent = '';
+    render(JSON.parse(xhr.responseText));
+  };
+  xhr.send(body ? JSON.stringify(body) : null);
+}
+
+function control(action) { request('/api/' + action); }
+
+function ramp() {
+  var bots = parseInt(document.getElementById('bots').value, 10);
+  if (isNaN(bots)) return;
+  request('/api/bots', {bots: bots});
+}
+
+function connect() {
+  var ws = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/api/live');
+  ws.onmessage = function (e) { render(JSON.parse(e.data)); };
+  ws.onclose = function () {
+    document.getElementById('status').textContent = 'Disconnected, the run may be over. Reconnecting...';
+    setTimeout(connect, 2000);
+  };
+}
+connect();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,224 @@
+package control
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// liveInterval is how often the status is pushed to websocket clients
+const liveInterval = time.Second
+
+// Status is the state of the run the control server exposes
+type Status struct {
+	ElapsedMs     int64              `json:"elapsedMs"`
+	Paused        bool               `json:"paused"`
+	PausedMs      int64              `json:"pausedMs"`
+	Draining      bool               `json:"draining"`
+	Aborted       bool               `json:"aborted"`
+	Rampable      bool               `json:"rampable"`
+	TargetBots    *int               `json:"targetBots"`
+	ConnectedBots int64              `json:"connectedBots"`
+	Summary       *metrics.RunReport `json:"summary"`
+}
+
+// Server is an http server letting operators watch and steer a run: its
+// status and live metrics, over REST and a websocket, and controls to pause
+// and resume the fleet, ramp the number of bots, drain and abort the run.
+// It also serves a web page doing all of that
+type Server struct {
+	app      *state.App
+	address  string
+	logger   logrus.FieldLogger
+	start    time.Time
+	listener net.Listener
+	upgrader websocket.Upgrader
+}
+
+// NewServer returns a control server of app listening on address
+func NewServer(app *state.App, address string, logger logrus.FieldLogger) *Server {
+	return &Server{
+		app:     app,
+		address: address,
+		logger:  logger,
+		start:   time.Now(),
+	}
+}
+
+// Start listens on the server address and serves in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("Unable to start control server: %s", err)
+	}
+
+	s.listener = listener
+	s.logger.Infof("Control server listening on %s", listener.Addr())
+	go http.Serve(listener, s.Handler())
+	return nil
+}
+
+// Close stops listening
+func (s *Server) Close() error {
+	if s == nil || s.listener == nil {
+		return nil
+	}
+
+	return s.listener.Close()
+}
+
+// Handler is the http handler of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.page)
+	mux.HandleFunc("/api/status", s.get(s.writeStatus))
+	mux.HandleFunc("/api/live", s.live)
+	mux.HandleFunc("/api/pause", s.post(func(w http.ResponseWriter, r *http.Request) {
+		s.app.Pauser.Pause()
+		s.logger.Info("Fleet paused by the control server")
+		s.writeStatus(w, r)
+	}))
+	mux.HandleFunc("/api/resume", s.post(func(w http.ResponseWriter, r *http.Request) {
+		s.app.Pauser.Resume()
+		s.logger.Info("Fleet resumed by the control server")
+		s.writeStatus(w, r)
+	}))
+	mux.HandleFunc("/api/drain", s.post(func(w http.ResponseWriter, r *http.Request) {
+		s.app.Drain()
+		s.logger.Info("Run drained by the control server, the run ends once the running bots finish")
+		s.writeStatus(w, r)
+	}))
+	mux.HandleFunc("/api/abort", s.post(func(w http.ResponseWriter, r *http.Request) {
+		s.app.Abort()
+		s.logger.Warn("Run aborted by the control server, bots stop before their next operation and run their teardown")
+		s.writeStatus(w, r)
+	}))
+	mux.HandleFunc("/api/bots", s.post(s.ramp))
+	return mux
+}
+
+func (s *Server) get(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) post(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// Status returns the current status of the run
+func (s *Server) Status() *Status {
+	elapsed := time.Since(s.start)
+	status := &Status{
+		ElapsedMs:     elapsed.Nanoseconds() / 1e6,
+		Paused:        s.app.Pauser.Paused(),
+		PausedMs:      s.app.Pauser.PausedDuration().Nanoseconds() / 1e6,
+		Rampable:      s.app.BotTarget != nil,
+		ConnectedBots: bot.ConnectedBots(),
+		Summary:       s.app.Summary.Report(elapsed),
+	}
+
+	select {
+	case <-s.app.Draining():
+		status.Draining = true
+	default:
+	}
+	status.Aborted = s.app.Context().Err() != nil
+
+	if s.app.BotTarget != nil {
+		if bots, ok := s.app.BotTarget.Get(); ok {
+			status.TargetBots = &bots
+		}
+	}
+
+	return status
+}
+
+func (s *Server) writeStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Status())
+}
+
+// ramp sets the number of bots each spec runs, from a {"bots": n} body
+func (s *Server) ramp(w http.ResponseWriter, r *http.Request) {
+	if s.app.BotTarget == nil {
+		http.Error(w, "Run follows a load profile, its bots can not be ramped", http.StatusConflict)
+		return
+	}
+
+	var body struct {
+		Bots *int `json:"bots"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Bots == nil || *body.Bots < 0 {
+		http.Error(w, `Body must be {"bots": n}, with n not negative`, http.StatusBadRequest)
+		return
+	}
+
+	s.app.BotTarget.Set(*body.Bots)
+	s.logger.Infof("Bots ramped to %d per spec by the control server", *body.Bots)
+	s.writeStatus(w, r)
+}
+
+// live pushes the status to a websocket client every second
+func (s *Server) live(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		s.logger.WithError(err).Warn("Unable to upgrade control server connection")
+		return
+	}
+	defer conn.Close()
+
+	// the client only reads, a failed read means it went away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(liveInterval)
+	defer ticker.Stop()
+	for {
+		if err := conn.WriteJSON(s.Status()); err != nil {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-closed:
+			return
+		}
+	}
+}
+
+func (s *Server) page(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(page))
+}
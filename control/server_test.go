@@ -0,0 +1,145 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func newTestServer(rampable bool) (*state.App, *httptest.Server) {
+	app := state.NewApp(viper.New(), false)
+	if rampable {
+		app.BotTarget = state.NewBotTarget()
+	}
+
+	s := NewServer(app, "", logrus.New())
+	return app, httptest.NewServer(s.Handler())
+}
+
+func post(t *testing.T, url, body string) (int, *Status) {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, nil
+	}
+
+	var status Status
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&status))
+	return resp.StatusCode, &status
+}
+
+func TestControls(t *testing.T) {
+	app, server := newTestServer(true)
+	defer server.Close()
+
+	code, status := post(t, server.URL+"/api/pause", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Paused)
+	assert.True(t, app.Pauser.Paused())
+
+	code, status = post(t, server.URL+"/api/resume", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, status.Paused)
+
+	code, status = post(t, server.URL+"/api/bots", `{"bots": 25}`)
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Rampable)
+	assert.Equal(t, 25, *status.TargetBots)
+	bots, ok := app.BotTarget.Get()
+	assert.True(t, ok)
+	assert.Equal(t, 25, bots)
+
+	code, status = post(t, server.URL+"/api/drain", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Draining)
+	select {
+	case <-app.Draining():
+	default:
+		t.Fatal("Run should be draining")
+	}
+	assert.False(t, status.Aborted)
+
+	code, status = post(t, server.URL+"/api/abort", "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, status.Aborted)
+	assert.Error(t, app.Context().Err())
+}
+
+func TestRampErrors(t *testing.T) {
+	tables := []struct {
+		name     string
+		rampable bool
+		body     string
+		code     int
+	}{
+		{"load profile", false, `{"bots": 2}`, http.StatusConflict},
+		{"no bots", true, `{}`, http.StatusBadRequest},
+		{"negative bots", true, `{"bots": -1}`, http.StatusBadRequest},
+		{"malformed body", true, `bots`, http.StatusBadRequest},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			app, server := newTestServer(table.rampable)
+			defer server.Close()
+
+			code, _ := post(t, server.URL+"/api/bots", table.body)
+			assert.Equal(t, table.code, code)
+			if app.BotTarget != nil {
+				_, ok := app.BotTarget.Get()
+				assert.False(t, ok)
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	_, server := newTestServer(true)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/pause")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	code, _ := post(t, server.URL+"/api/status", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+
+	resp, err = http.Get(server.URL + "/")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	resp, err = http.Get(server.URL + "/missing")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestLive(t *testing.T) {
+	app, server := newTestServer(false)
+	defer server.Close()
+	app.Pauser.Pause()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/live", nil)
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	var status Status
+	assert.NoError(t, conn.ReadJSON(&status))
+	assert.True(t, status.Paused)
+	assert.False(t, status.Rampable)
+	assert.Nil(t, status.TargetBots)
+	assert.NotNil(t, status.Summary)
+}
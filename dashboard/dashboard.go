@@ -0,0 +1,314 @@
+package dashboard
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/topfreegames/pitaya-bot/metrics"
+)
+
+const (
+	// window is how many seconds the sparklines cover
+	window = 60
+	// rpsWindow is how many seconds the rolling rps averages
+	rpsWindow = 10
+	// sampledBots is how many bots have their operation shown
+	sampledBots = 10
+)
+
+// clear moves the cursor home and clears the terminal
+const clear = "\033[H\033[2J"
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+type second struct {
+	at           int64
+	operations   int
+	failures     int
+	requests     int
+	latencyTotal float64
+}
+
+type botOperation struct {
+	spec    string
+	bot     int
+	typ     string
+	uri     string
+	since   time.Time
+	took    time.Duration
+	running bool
+	failed  bool
+}
+
+// Dashboard redraws the state of the run in the terminal every second:
+// connected bots, the operation of a sample of them, rolling rps, error
+// counters and sparklines of the latest latencies and throughput. It is an
+// EventReporter, fed with the metrics and events of every bot
+//  - implements metrics.EventReporter
+type Dashboard struct {
+	mutex      sync.Mutex
+	out        io.Writer
+	now        func() time.Time
+	start      time.Time
+	connected  float64
+	operations int
+	failures   int
+	errors     map[string]int
+	seconds    [window]second
+	bots       map[string]*botOperation
+
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+// NewDashboard returns a dashboard drawing to out
+func NewDashboard(out io.Writer) *Dashboard {
+	return &Dashboard{
+		out:    out,
+		now:    time.Now,
+		start:  time.Now(),
+		errors: map[string]int{},
+		bots:   map[string]*botOperation{},
+	}
+}
+
+// Start redraws the dashboard every second until Stop is called
+func (d *Dashboard) Start() {
+	if d == nil {
+		return
+	}
+
+	d.start = d.now()
+	d.done = make(chan struct{})
+	d.stopped.Add(1)
+	go func() {
+		defer d.stopped.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.Draw()
+			case <-d.done:
+				d.Draw()
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the dashboard a last time and stops redrawing it
+func (d *Dashboard) Stop() {
+	if d == nil || d.done == nil {
+		return
+	}
+
+	close(d.done)
+	d.stopped.Wait()
+}
+
+// Draw clears the terminal and draws the dashboard
+func (d *Dashboard) Draw() {
+	var b strings.Builder
+	b.WriteString(clear)
+	d.render(&b)
+	io.WriteString(d.out, b.String())
+}
+
+// second is the stats of the current second
+func (d *Dashboard) second() *second {
+	now := d.now().Unix()
+	s := &d.seconds[now%window]
+	if s.at != now {
+		*s = second{at: now}
+	}
+
+	return s
+}
+
+// ReportCount counts errors by type
+//  - implements the ReportCount method of the Reporter interface
+func (d *Dashboard) ReportCount(metric string, tags map[string]string, count float64) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch metric {
+	case metrics.ErrorCount:
+		d.errors[tags["type"]] += int(count)
+	case metrics.TimeoutCount:
+		d.errors["timeout"] += int(count)
+	}
+	return nil
+}
+
+// ReportSummary records response times
+//  - implements the ReportSummary method of the Reporter interface
+func (d *Dashboard) ReportSummary(metric string, tags map[string]string, value float64) error {
+	if metric != metrics.ResponseTime {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	s := d.second()
+	s.requests++
+	s.latencyTotal += value
+	return nil
+}
+
+// ReportHistogram ignores histograms
+//  - implements the ReportHistogram method of the Reporter interface
+func (d *Dashboard) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	return nil
+}
+
+// ReportGauge records the connected bots
+//  - implements the ReportGauge method of the Reporter interface
+func (d *Dashboard) ReportGauge(metric string, tags map[string]string, value float64) error {
+	if metric != metrics.ConnectedBots {
+		return nil
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.connected = value
+	return nil
+}
+
+// ReportEvent tracks the operation of each bot and counts operations
+//  - implements the ReportEvent method of the EventReporter interface
+func (d *Dashboard) ReportEvent(event string, tags map[string]string, fields map[string]interface{}) error {
+	bot, _ := fields["bot"].(int)
+	key := fmt.Sprintf("%s/%d", tags["spec"], bot)
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	switch event {
+	case metrics.OperationStartEvent:
+		typ, _ := fields["type"].(string)
+		uri, _ := fields["uri"].(string)
+		d.bots[key] = &botOperation{spec: tags["spec"], bot: bot, typ: typ, uri: uri, since: d.now(), running: true}
+	case metrics.OperationEndEvent:
+		failed := fields["result"] != "ok"
+		d.operations++
+		s := d.second()
+		s.operations++
+		if failed {
+			d.failures++
+			s.failures++
+		}
+		if op, ok := d.bots[key]; ok {
+			op.running = false
+			op.failed = failed
+			op.took = d.now().Sub(op.since)
+		}
+	}
+	return nil
+}
+
+// series returns, oldest first, the value of each of the last window
+// seconds, zero for seconds without metrics
+func (d *Dashboard) series(value func(*second) float64) []float64 {
+	now := d.now().Unix()
+	values := make([]float64, window)
+	for i := range values {
+		at := now - int64(window-1-i)
+		if s := &d.seconds[at%window]; s.at == at {
+			values[i] = value(s)
+		}
+	}
+
+	return values
+}
+
+// sparkline draws values scaled to the largest of them
+func sparkline(values []float64) string {
+	max := 0.0
+	for _, v := range values {
+		if v > max {
+			max = v
+		}
+	}
+
+	line := make([]rune, len(values))
+	for i, v := range values {
+		if max == 0 {
+			line[i] = sparks[0]
+			continue
+		}
+		line[i] = sparks[int(v/max*float64(len(sparks)-1))]
+	}
+
+	return string(line)
+}
+
+func (d *Dashboard) render(w io.Writer) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	now := d.now()
+	latencies := d.series(func(s *second) float64 {
+		if s.requests == 0 {
+			return 0
+		}
+		return s.latencyTotal / float64(s.requests)
+	})
+	throughput := d.series(func(s *second) float64 { return float64(s.operations) })
+
+	rps := 0.0
+	// the current second is still filling up, it is left out
+	for _, ops := range throughput[window-1-rpsWindow : window-1] {
+		rps += ops
+	}
+	rps /= rpsWindow
+
+	fmt.Fprintf(w, "pitaya-bot  running %v  connected bots %.0f\n\n", now.Sub(d.start).Truncate(time.Second), d.connected)
+	fmt.Fprintf(w, "operations %d  failed %d  rps (%ds) %.1f\n", d.operations, d.failures, rpsWindow, rps)
+	fmt.Fprintf(w, "rps        %s  %.0f/s\n", sparkline(throughput), throughput[window-2])
+	fmt.Fprintf(w, "latency    %s  %.0fms\n\n", sparkline(latencies), latencies[window-2])
+
+	types := make([]string, 0, len(d.errors))
+	for typ := range d.errors {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	errors := make([]string, 0, len(types))
+	for _, typ := range types {
+		errors = append(errors, fmt.Sprintf("%s %d", typ, d.errors[typ]))
+	}
+	if len(errors) == 0 {
+		errors = append(errors, "none")
+	}
+	fmt.Fprintf(w, "errors     %s\n\n", strings.Join(errors, "  "))
+
+	ops := make([]*botOperation, 0, len(d.bots))
+	for _, op := range d.bots {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].spec != ops[j].spec {
+			return ops[i].spec < ops[j].spec
+		}
+		return ops[i].bot < ops[j].bot
+	})
+	if len(ops) > sampledBots {
+		ops = ops[:sampledBots]
+	}
+
+	fmt.Fprintf(w, "operations of %d of %d bots\n", len(ops), len(d.bots))
+	for _, op := range ops {
+		status := fmt.Sprintf("running %v", now.Sub(op.since).Truncate(time.Millisecond))
+		if !op.running {
+			status = fmt.Sprintf("done in %v", op.took.Truncate(time.Millisecond))
+			if op.failed {
+				status = fmt.Sprintf("failed in %v", op.took.Truncate(time.Millisecond))
+			}
+		}
+		fmt.Fprintf(w, "  %-20s %-6d %-8s %-30s %s\n", op.spec, op.bot, op.typ, op.uri, status)
+	}
+}
@@ -0,0 +1,83 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/metrics"
+)
+
+func TestDashboard(t *testing.T) {
+	now := time.Unix(1000, 0)
+	var out bytes.Buffer
+	d := NewDashboard(&out)
+	d.now = func() time.Time { return now }
+	d.start = now
+
+	reporters := metrics.WithTags([]metrics.Reporter{d}, map[string]string{"spec": "lobby"})
+	start := func(bot int, uri string) {
+		metrics.ReportEvent(reporters, metrics.OperationStartEvent, map[string]string{"route": uri}, map[string]interface{}{"bot": bot, "type": "request", "uri": uri})
+	}
+	end := func(bot int, uri, result string) {
+		metrics.ReportEvent(reporters, metrics.OperationEndEvent, map[string]string{"route": uri}, map[string]interface{}{"bot": bot, "result": result})
+	}
+
+	reporters[0].ReportGauge(metrics.ConnectedBots, nil, 2)
+	for i := 0; i < 20; i++ {
+		start(0, "room.join")
+		reporters[0].ReportSummary(metrics.ResponseTime, map[string]string{"route": "room.join"}, 10)
+		end(0, "room.join", "ok")
+	}
+	now = now.Add(time.Second)
+	start(1, "room.join")
+	reporters[0].ReportSummary(metrics.ResponseTime, map[string]string{"route": "room.join"}, 40)
+	reporters[0].ReportCount(metrics.ErrorCount, map[string]string{"route": "room.join", "type": metrics.ExpectationError}, 1)
+	end(1, "room.join", "error")
+	start(0, "shop.buy")
+	now = now.Add(1500 * time.Millisecond)
+
+	d.Draw()
+	screen := out.String()
+	assert.True(t, strings.HasPrefix(screen, clear))
+	assert.Contains(t, screen, "running 2s  connected bots 2")
+	assert.Contains(t, screen, "operations 21  failed 1  rps (10s) 2.1")
+	assert.Contains(t, screen, "errors     expectation 1")
+	assert.Contains(t, screen, "operations of 2 of 2 bots")
+	assert.Regexp(t, `lobby +0 +request +shop\.buy +running 1\.5s`, screen)
+	assert.Regexp(t, `lobby +1 +request +room\.join +failed in 0s`, screen)
+	// the last full second had 1 operation and a 40ms response
+	assert.Contains(t, screen, "1/s")
+	assert.Contains(t, screen, "40ms")
+}
+
+func TestSparkline(t *testing.T) {
+	tables := map[string]struct {
+		values   []float64
+		expected string
+	}{
+		"empty":  {nil, ""},
+		"zeros":  {[]float64{0, 0}, "▁▁"},
+		"scaled": {[]float64{0, 3.5, 7}, "▁▄█"},
+	}
+
+	for name, table := range tables {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.expected, sparkline(table.values))
+		})
+	}
+}
+
+func TestDashboardStartStop(t *testing.T) {
+	var out bytes.Buffer
+	d := NewDashboard(&out)
+	d.Start()
+	d.Stop()
+	assert.Contains(t, out.String(), "pitaya-bot  running")
+
+	var nilDashboard *Dashboard
+	nilDashboard.Start()
+	nilDashboard.Stop()
+}
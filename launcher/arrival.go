@@ -0,0 +1,99 @@
+package launcher
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// arrival draws the startup delay of each bot from a distribution:
+//
+//	uniform      between min and max
+//	normal       around mean with stddev, negative draws are clamped to 0
+//	exponential  with the given mean, modelling independent arrivals
+type arrival struct {
+	distribution string
+	min          time.Duration
+	max          time.Duration
+	mean         time.Duration
+	stddev       time.Duration
+
+	mutex  sync.Mutex
+	random *rand.Rand
+}
+
+// getArrival reads loadtest.arrival, defaulting to a uniform delay of up
+// to 1s. It is seeded by loadtest.arrival.seed, falling back to bot.seed
+func getArrival(config *viper.Viper) (*arrival, error) {
+	a := &arrival{
+		distribution: "uniform",
+		max:          time.Second,
+	}
+
+	if d := config.GetString("loadtest.arrival.distribution"); d != "" {
+		a.distribution = d
+	}
+	if config.IsSet("loadtest.arrival.min") {
+		a.min = config.GetDuration("loadtest.arrival.min")
+	}
+	if config.IsSet("loadtest.arrival.max") {
+		a.max = config.GetDuration("loadtest.arrival.max")
+	}
+	a.mean = config.GetDuration("loadtest.arrival.mean")
+	a.stddev = config.GetDuration("loadtest.arrival.stddev")
+
+	seed := time.Now().UnixNano()
+	if config.IsSet("loadtest.arrival.seed") {
+		seed = config.GetInt64("loadtest.arrival.seed")
+	} else if config.IsSet("bot.seed") {
+		seed = config.GetInt64("bot.seed")
+	}
+	a.random = rand.New(rand.NewSource(seed))
+
+	switch a.distribution {
+	case "uniform":
+		if a.min < 0 || a.max < a.min {
+			return nil, fmt.Errorf("Malformed loadtest.arrival: uniform needs 0 <= min <= max")
+		}
+	case "normal":
+		if a.mean < 0 || a.stddev < 0 {
+			return nil, fmt.Errorf("Malformed loadtest.arrival: normal needs non negative mean and stddev")
+		}
+	case "exponential":
+		if a.mean <= 0 {
+			return nil, fmt.Errorf("Malformed loadtest.arrival: exponential needs a positive mean")
+		}
+	default:
+		return nil, fmt.Errorf("Unknown loadtest.arrival distribution: %s", a.distribution)
+	}
+
+	return a, nil
+}
+
+// delay returns how long the next bot waits before starting
+func (a *arrival) delay() time.Duration {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	var d time.Duration
+	switch a.distribution {
+	case "normal":
+		d = a.mean + time.Duration(a.random.NormFloat64()*float64(a.stddev))
+	case "exponential":
+		d = time.Duration(a.random.ExpFloat64() * float64(a.mean))
+	default:
+		d = a.min
+		if a.max > a.min {
+			d += time.Duration(a.random.Int63n(int64(a.max - a.min)))
+		}
+	}
+
+	if d < 0 {
+		return 0
+	}
+
+	return d
+}
@@ -0,0 +1,70 @@
+package launcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetArrival(t *testing.T) {
+	tables := []struct {
+		name     string
+		settings map[string]interface{}
+		min      time.Duration
+		max      time.Duration
+		err      bool
+	}{
+		{"default uniform", map[string]interface{}{}, 0, time.Second, false},
+		{"uniform", map[string]interface{}{"loadtest.arrival.min": "2s", "loadtest.arrival.max": "3s"}, 2 * time.Second, 3 * time.Second, false},
+		{"normal", map[string]interface{}{
+			"loadtest.arrival.distribution": "normal",
+			"loadtest.arrival.mean":         "1s",
+			"loadtest.arrival.stddev":       "100ms",
+		}, 0, time.Hour, false},
+		{"exponential", map[string]interface{}{
+			"loadtest.arrival.distribution": "exponential",
+			"loadtest.arrival.mean":         "1s",
+		}, 0, time.Hour, false},
+		{"exponential without mean", map[string]interface{}{"loadtest.arrival.distribution": "exponential"}, 0, 0, true},
+		{"unknown", map[string]interface{}{"loadtest.arrival.distribution": "poisson"}, 0, 0, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			config := viper.New()
+			for k, v := range table.settings {
+				config.Set(k, v)
+			}
+
+			a, err := getArrival(config)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			for i := 0; i < 100; i++ {
+				d := a.delay()
+				assert.True(t, d >= table.min && d <= table.max, d.String())
+			}
+		})
+	}
+}
+
+func TestArrivalSeed(t *testing.T) {
+	config := viper.New()
+	config.Set("loadtest.arrival.distribution", "exponential")
+	config.Set("loadtest.arrival.mean", "1s")
+	config.Set("bot.seed", 42)
+
+	a, err := getArrival(config)
+	assert.NoError(t, err)
+	b, err := getArrival(config)
+	assert.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, a.delay(), b.delay())
+	}
+}
@@ -0,0 +1,91 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestSignals(t *testing.T) {
+	app := state.NewApp(viper.New(), false)
+	handleSignals(app, logrus.New())
+
+	waitFor := func(done func() bool) bool {
+		for i := 0; i < 100; i++ {
+			if done() {
+				return true
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		return false
+	}
+	draining := func() bool {
+		select {
+		case <-app.Draining():
+			return true
+		default:
+			return false
+		}
+	}
+
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.True(t, waitFor(app.Pauser.Paused), "SIGUSR1 should pause the fleet")
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.True(t, waitFor(func() bool { return !app.Pauser.Paused() }), "SIGUSR1 should resume the fleet")
+
+	assert.False(t, draining())
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	assert.True(t, waitFor(draining), "SIGUSR2 should drain the run")
+}
+
+func TestInterruptAborts(t *testing.T) {
+	app := state.NewApp(viper.New(), false)
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	handleSignals(app, logger)
+
+	// a second interrupt exits, only one is sent
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGINT))
+	select {
+	case <-app.Context().Done():
+	case <-time.After(time.Second):
+		t.Fatal("SIGINT should abort the run")
+	}
+
+	select {
+	case <-app.Draining():
+	default:
+		t.Fatal("Aborted runs should not launch more bots")
+	}
+}
+
+func TestFollowStopsWhenDraining(t *testing.T) {
+	app := state.NewApp(viper.New(), false)
+	app.Drain()
+
+	// specs without operations fail right away, slots keep running bots
+	// until they are stopped
+	spec := &models.Spec{Name: "empty", NumberOfInstances: 2}
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	r := newProfileRunner(app, spec, viper.New(), nil, logger)
+
+	done := make(chan struct{})
+	go func() {
+		r.follow(newControlTarget(), time.Hour)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("follow should stop once the run is draining")
+	}
+}
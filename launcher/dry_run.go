@@ -0,0 +1,55 @@
+package launcher
+
+import (
+	"io"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/bot"
+)
+
+// DryRun writes the operation plan of the first bot of every spec in
+// specsDirectory, and of the suite setup and teardown, to w. Nothing
+// connects to the server
+func DryRun(config *viper.Viper, specsDirectory string, w io.Writer) error {
+	if err := bot.LoadPlugins(config.GetStringSlice("bot.plugins")); err != nil {
+		return err
+	}
+	if config.IsSet("bot.seed") {
+		bot.SeedGenerators(config.GetInt64("bot.seed"))
+	}
+	if err := bot.SetFakerLocale(config.GetString("faker.locale")); err != nil {
+		return err
+	}
+	bot.SetSchemaDescriptors(config.GetString("expect.descriptors"))
+
+	specs, err := getSpecs(specsDirectory)
+	if err != nil {
+		return err
+	}
+
+	suite, err := readSuite(specsDirectory)
+	if err != nil {
+		return err
+	}
+
+	var shared map[string]interface{}
+	if len(suite.Setup) > 0 {
+		if shared, err = bot.DryRunSuite(config, "setup", suite.Setup, w); err != nil {
+			return err
+		}
+	}
+
+	for _, spec := range specs {
+		if _, err := bot.DryRun(config, spec, 0, shared, w); err != nil {
+			return err
+		}
+	}
+
+	if len(suite.Teardown) > 0 {
+		if _, err := bot.DryRunSuite(config, "teardown", suite.Teardown, w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
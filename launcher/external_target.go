@@ -0,0 +1,124 @@
+package launcher
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+const defaultTargetPollInterval = 5 * time.Second
+
+// externalTarget reads the number of bots each spec should run from a file
+// or an http endpoint whose body is a plain integer. Without a source, each
+// spec runs its own number of instances. The target set by operators on
+// override, when set, takes precedence over both
+type externalTarget struct {
+	source   string
+	interval time.Duration
+	maxBots  int
+	client   *http.Client
+	override *state.BotTarget
+}
+
+// newControlTarget returns the target of runs steered by the control
+// server, polled every second
+func newControlTarget() *externalTarget {
+	return &externalTarget{interval: time.Second}
+}
+
+func (t *externalTarget) String() string {
+	if t.source == "" {
+		return "the control server"
+	}
+	return t.source
+}
+
+func getExternalTarget(config *viper.Viper) (*externalTarget, error) {
+	source := config.GetString("loadtest.target.source")
+	if source == "" {
+		return nil, nil
+	}
+
+	interval := defaultTargetPollInterval
+	if config.IsSet("loadtest.target.pollInterval") {
+		interval = config.GetDuration("loadtest.target.pollInterval")
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("Malformed loadtest.target.pollInterval: must be positive")
+	}
+
+	maxBots := config.GetInt("loadtest.target.maxBots")
+	if maxBots < 0 {
+		return nil, fmt.Errorf("Malformed loadtest.target.maxBots: must not be negative")
+	}
+
+	return &externalTarget{
+		source:   source,
+		interval: interval,
+		maxBots:  maxBots,
+		client:   &http.Client{Timeout: interval},
+	}, nil
+}
+
+func (t *externalTarget) isHTTP() bool {
+	return strings.HasPrefix(t.source, "http://") || strings.HasPrefix(t.source, "https://")
+}
+
+// read returns the current target, clamped to maxBots when set.
+// defaultBots is the target of targets without a source
+func (t *externalTarget) read(defaultBots int) (int, error) {
+	if t.override != nil {
+		if bots, ok := t.override.Get(); ok {
+			return t.clamp(bots), nil
+		}
+	}
+	if t.source == "" {
+		return t.clamp(defaultBots), nil
+	}
+
+	var raw []byte
+	var err error
+	if t.isHTTP() {
+		raw, err = t.fetch()
+	} else {
+		raw, err = ioutil.ReadFile(t.source)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Unable to read bot target from %s: %s", t.source, err)
+	}
+
+	target, err := strconv.Atoi(strings.TrimSpace(string(raw)))
+	if err != nil || target < 0 {
+		return 0, fmt.Errorf("Malformed bot target from %s: %q", t.source, strings.TrimSpace(string(raw)))
+	}
+
+	return t.clamp(target), nil
+}
+
+func (t *externalTarget) clamp(target int) int {
+	if t.maxBots > 0 && target > t.maxBots {
+		return t.maxBots
+	}
+
+	return target
+}
+
+func (t *externalTarget) fetch() ([]byte, error) {
+	resp, err := t.client.Get(t.source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestExternalTargetRead(t *testing.T) {
+	body := "12\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	file, err := ioutil.TempFile("", "target")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+
+	tables := []struct {
+		name    string
+		source  string
+		content string
+		maxBots int
+		target  int
+		err     bool
+	}{
+		{"file", file.Name(), "30", 0, 30, false},
+		{"file capped", file.Name(), "30", 20, 20, false},
+		{"file malformed", file.Name(), "many", 0, 0, true},
+		{"file negative", file.Name(), "-1", 0, 0, true},
+		{"missing file", file.Name() + ".missing", "", 0, 0, true},
+		{"http", server.URL, "12\n", 0, 12, false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.NoError(t, ioutil.WriteFile(file.Name(), []byte(table.content), 0644))
+			body = table.content
+
+			config := viper.New()
+			config.Set("loadtest.target.source", table.source)
+			config.Set("loadtest.target.maxBots", table.maxBots)
+			target, err := getExternalTarget(config)
+			assert.NoError(t, err)
+
+			got, err := target.read(0)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.target, got)
+		})
+	}
+}
+
+func TestExternalTargetOverride(t *testing.T) {
+	tables := []struct {
+		name     string
+		maxBots  int
+		override int
+		set      bool
+		target   int
+	}{
+		{"spec instances", 0, 0, false, 4},
+		{"spec instances capped", 3, 0, false, 3},
+		{"override", 0, 10, true, 10},
+		{"override to zero", 0, 0, true, 0},
+		{"override capped", 6, 10, true, 6},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			target := newControlTarget()
+			target.maxBots = table.maxBots
+			target.override = state.NewBotTarget()
+			if table.set {
+				target.override.Set(table.override)
+			}
+
+			got, err := target.read(4)
+			assert.NoError(t, err)
+			assert.Equal(t, table.target, got)
+		})
+	}
+}
+
+func TestGetExternalTargetUnset(t *testing.T) {
+	target, err := getExternalTarget(viper.New())
+	assert.NoError(t, err)
+	assert.Nil(t, target)
+}
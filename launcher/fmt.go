@@ -0,0 +1,402 @@
+package launcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// SpecIssue is a problem found in a spec file, at Line when known
+type SpecIssue struct {
+	Path  string
+	Line  int
+	Issue string
+}
+
+func (i SpecIssue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", i.Path, i.Line, i.Issue)
+	}
+	return fmt.Sprintf("%s: %s", i.Path, i.Issue)
+}
+
+// FormatSpecs canonicalizes every spec under specsDirectory, rewriting the
+// files when write is set. It returns the files whose formatting changed and
+// the issues found. YAML specs are linted but never rewritten. Files with
+// fields unknown to the spec model are left untouched, as rewriting them
+// would drop those fields
+func FormatSpecs(specsDirectory string, write bool) ([]string, []SpecIssue, error) {
+	changed := make([]string, 0)
+	issues := make([]SpecIssue, 0)
+
+	included, err := includedFiles(specsDirectory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = filepath.Walk(specsDirectory,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !validFile(info) {
+				return nil
+			}
+
+			raw, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			data, err := models.SpecJSON(path, raw)
+			if err != nil {
+				issues = append(issues, SpecIssue{Path: path, Issue: err.Error()})
+				return nil
+			}
+
+			var (
+				formatted  []byte
+				fileIssues []string
+			)
+			if isSuiteFile(specsDirectory, path) {
+				var suite models.Suite
+				formatted, err = formatJSON(data, &suite)
+				fileIssues = lintSuite(&suite)
+			} else {
+				var spec models.Spec
+				formatted, err = formatJSON(data, &spec)
+				if err == nil {
+					fileIssues = lintSpecFile(&spec, path, included[filepath.Clean(path)])
+				}
+			}
+			if err != nil {
+				issues = append(issues, SpecIssue{Path: path, Issue: err.Error()})
+				return nil
+			}
+
+			for _, issue := range fileIssues {
+				issues = append(issues, SpecIssue{Path: path, Issue: issue})
+			}
+
+			// yaml specs are only linted, rewriting them would drop comments
+			if models.IsYAML(path) || bytes.Equal(raw, formatted) {
+				return nil
+			}
+
+			changed = append(changed, path)
+			if write {
+				return ioutil.WriteFile(path, formatted, info.Mode())
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return changed, issues, nil
+}
+
+// formatJSON round trips raw through model, sorting map keys and indenting
+// with two spaces
+func formatJSON(raw []byte, model interface{}) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+
+	if err := decoder.Decode(model); err != nil {
+		return nil, fmt.Errorf("Invalid spec: %s", err)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(model); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// lintSuite reports issues in the suite setup and teardown operations
+func lintSuite(suite *models.Suite) []string {
+	issues := make([]string, 0)
+	for idx, op := range suite.Setup {
+		issues = append(issues, lintOperation(&models.Spec{}, fmt.Sprintf("setup[%d]", idx), op)...)
+	}
+	for idx, op := range suite.Teardown {
+		issues = append(issues, lintOperation(&models.Spec{}, fmt.Sprintf("teardown[%d]", idx), op)...)
+	}
+
+	return issues
+}
+
+// lintSpecFile lints the spec at path, which may call the macros of its
+// includes. Fragments, included by other specs, have no instances
+func lintSpecFile(spec *models.Spec, path string, fragment bool) []string {
+	lintable := *spec
+	if fragment && len(lintable.Matrix) == 0 {
+		lintable.NumberOfInstances = 1
+	}
+
+	resolved := *spec
+	if err := resolveIncludes(&resolved, path, []string{filepath.Clean(path)}); err != nil {
+		return []string{err.Error()}
+	}
+	lintable.Macros = resolved.Macros
+
+	return lintSpec(&lintable)
+}
+
+// lintSpec reports unknown operation types and missing required fields
+func lintSpec(spec *models.Spec) []string {
+	issues := make([]string, 0)
+	if spec.Instances() <= 0 && (len(spec.SequentialOperations) > 0 || spec.Random != nil) {
+		issues = append(issues, "numberOfInstances must be positive")
+	}
+
+	if spec.Cycle != nil && spec.Cycle.Iterations == 0 && spec.MaxDuration <= 0 {
+		issues = append(issues, "cycle without iterations needs maxDuration")
+	}
+
+	issues = append(issues, lintOnError(spec, "onError", spec.OnError)...)
+	labeled := map[string]bool{}
+	for idx, op := range spec.SequentialOperations {
+		path := fmt.Sprintf("sequentialOperations[%d]", idx)
+		issues = append(issues, lintOperation(spec, path, op)...)
+		if op == nil {
+			continue
+		}
+		issues = append(issues, lintOnError(spec, path+".onError", op.OnError)...)
+		if op.Label != "" && labeled[op.Label] {
+			issues = append(issues, fmt.Sprintf("%s: duplicate label %q", path, op.Label))
+		}
+		labeled[op.Label] = true
+	}
+
+	if spec.Random != nil {
+		if spec.Random.Steps == 0 && spec.MaxDuration <= 0 {
+			issues = append(issues, "random without steps needs maxDuration")
+		}
+		for idx, op := range spec.Random.Operations {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("random.operations[%d]", idx), op)...)
+		}
+	}
+
+	if spec.Feeder != nil {
+		if spec.Feeder.File == "" {
+			issues = append(issues, "feeder without file")
+		}
+		switch spec.Feeder.Strategy {
+		case "", "cyclic", "unique", "random":
+		default:
+			issues = append(issues, fmt.Sprintf("unknown feeder strategy %q", spec.Feeder.Strategy))
+		}
+	}
+
+	for idx, handler := range spec.PushHandlers {
+		if handler == nil || handler.Route == "" {
+			issues = append(issues, fmt.Sprintf("pushHandlers[%d]: missing route", idx))
+		}
+	}
+
+	for idx, op := range spec.SetupOperations {
+		issues = append(issues, lintOperation(spec, fmt.Sprintf("setupOperations[%d]", idx), op)...)
+	}
+
+	for idx, op := range spec.TeardownOperations {
+		issues = append(issues, lintOperation(spec, fmt.Sprintf("teardownOperations[%d]", idx), op)...)
+	}
+
+	for idx, op := range spec.OnResume {
+		issues = append(issues, lintOperation(spec, fmt.Sprintf("onResume[%d]", idx), op)...)
+	}
+
+	for name, ops := range spec.Macros {
+		for idx, op := range ops {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("macros.%s[%d]", name, idx), op)...)
+		}
+	}
+
+	return issues
+}
+
+// lintOnError reports invalid onError policies and goto policies to labels
+// no sequential operation has
+func lintOnError(spec *models.Spec, path, policy string) []string {
+	p, err := bot.ParseOnError(policy)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %s", path, err)}
+	}
+	if p.Action != bot.OnErrorGoto {
+		return nil
+	}
+
+	if !hasLabel(spec, p.Label) {
+		return []string{fmt.Sprintf("%s: unknown label %q", path, p.Label)}
+	}
+	return nil
+}
+
+// hasLabel returns whether a sequential operation of spec has label
+func hasLabel(spec *models.Spec, label string) bool {
+	for _, op := range spec.SequentialOperations {
+		if op != nil && op.Label == label {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedExpectKeys returns the keys of expect sorted, so issues are listed
+// in a stable order
+func sortedExpectKeys(expect models.ExpectSpec) []string {
+	keys := make([]string, 0, len(expect))
+	for key := range expect {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// containerOperations run nested operations or scripts and have no uri
+var containerOperations = map[string]bool{
+	"stateMachine": true,
+	"parallel":     true,
+	"loop":         true,
+	"condition":    true,
+	"script":       true,
+}
+
+func lintOperation(spec *models.Spec, path string, op *models.Operation) []string {
+	issues := make([]string, 0)
+	if op == nil {
+		return append(issues, fmt.Sprintf("%s: empty operation", path))
+	}
+
+	if !bot.KnownOperationType(op.Type) {
+		return append(issues, fmt.Sprintf("%s: unknown operation type %q", path, op.Type))
+	}
+
+	switch op.Type {
+	case "function":
+		if !bot.KnownFunction(op.URI) {
+			issues = append(issues, fmt.Sprintf("%s: unknown function %q", path, op.URI))
+		}
+		if op.URI == "goto" {
+			if label, ok := staticArg(op.Args["label"]); ok && !hasLabel(spec, label) {
+				issues = append(issues, fmt.Sprintf("%s: goto unknown label %q", path, label))
+			}
+		}
+	case "call":
+		if _, ok := spec.Macros[op.URI]; !ok {
+			issues = append(issues, fmt.Sprintf("%s: unknown macro %q", path, op.URI))
+		}
+	case "parallel":
+		if len(op.ParallelOperations) == 0 {
+			issues = append(issues, fmt.Sprintf("%s: missing parallelOperations", path))
+		}
+		for idx, parallelOp := range op.ParallelOperations {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("%s.parallelOperations[%d]", path, idx), parallelOp)...)
+		}
+	case "loop":
+		if op.Loop == nil {
+			issues = append(issues, fmt.Sprintf("%s: missing loop", path))
+			break
+		}
+		if op.Loop.Count <= 0 {
+			issues = append(issues, fmt.Sprintf("%s: loop count must be positive", path))
+		}
+		for idx, loopOp := range op.Loop.Operations {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("%s.loop.operations[%d]", path, idx), loopOp)...)
+		}
+	case "condition":
+		if op.Condition == nil {
+			issues = append(issues, fmt.Sprintf("%s: missing condition", path))
+			break
+		}
+		for idx, branchOp := range op.Condition.Then {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("%s.condition.then[%d]", path, idx), branchOp)...)
+		}
+		for idx, branchOp := range op.Condition.Else {
+			issues = append(issues, lintOperation(spec, fmt.Sprintf("%s.condition.else[%d]", path, idx), branchOp)...)
+		}
+	case "listen":
+		if op.Listen == nil {
+			break
+		}
+		switch op.Listen.Mode {
+		case "", "any", "all", "ordered":
+		default:
+			issues = append(issues, fmt.Sprintf("%s: unknown listen mode %q", path, op.Listen.Mode))
+		}
+		if len(op.Listen.Routes) == 0 {
+			issues = append(issues, fmt.Sprintf("%s: missing listen routes", path))
+		}
+	case "http":
+		if op.HTTP == nil {
+			break
+		}
+		switch strings.ToUpper(op.HTTP.Method) {
+		case "", "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+		default:
+			issues = append(issues, fmt.Sprintf("%s: unknown http method %q", path, op.HTTP.Method))
+		}
+	case "script":
+		if op.Script == nil || (op.Script.Source == "") == (op.Script.File == "") {
+			issues = append(issues, fmt.Sprintf("%s: script needs either source or file", path))
+		}
+	case "fuzz":
+		if err := bot.ValidateFuzz(op.Fuzz); err != nil {
+			issues = append(issues, fmt.Sprintf("%s: invalid fuzz: %s", path, err))
+		}
+	case "assertPushes":
+		if err := bot.ValidateAssertPushes(op.AssertPushes); err != nil {
+			issues = append(issues, fmt.Sprintf("%s: invalid assertPushes: %s", path, err))
+		}
+	case "cadence":
+		if op.Cadence == nil {
+			issues = append(issues, fmt.Sprintf("%s: missing cadence", path))
+		}
+	case "stateMachine":
+		if op.StateMachine == nil {
+			issues = append(issues, fmt.Sprintf("%s: missing stateMachine", path))
+			break
+		}
+		for name, state := range op.StateMachine.States {
+			if state.Trigger != nil {
+				issues = append(issues, lintOperation(spec, fmt.Sprintf("%s.states.%s.trigger", path, name), state.Trigger)...)
+			}
+		}
+	}
+
+	if op.ExpectError != nil && op.Type != "request" && op.Type != "fuzz" {
+		issues = append(issues, fmt.Sprintf("%s: expectError only applies to request and fuzz operations", path))
+	}
+
+	for _, propertyExpr := range sortedExpectKeys(op.Expect) {
+		if entry := op.Expect[propertyExpr]; entry.Type == "schema" {
+			if err := bot.ValidateSchema(entry); err != nil {
+				issues = append(issues, fmt.Sprintf("%s: invalid schema expectation on %s: %s", path, propertyExpr, err))
+			}
+		}
+	}
+
+	if op.PostDelay != nil && op.PostDelay.MaxMs > 0 && op.PostDelay.MinMs > op.PostDelay.MaxMs {
+		issues = append(issues, fmt.Sprintf("%s: postDelay minMs is greater than maxMs", path))
+	}
+
+	if op.URI == "" && !containerOperations[op.Type] && op.Listen == nil && op.Type != "assertPushes" {
+		issues = append(issues, fmt.Sprintf("%s: missing uri", path))
+	}
+
+	return issues
+}
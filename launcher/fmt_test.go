@@ -0,0 +1,91 @@
+package launcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+func TestFormatSpec(t *testing.T) {
+	raw := []byte(`{"sequentialOperations": [{"uri": "room.join", "type": "request",
+		"args": {"b": {"type": "int", "value": 10000000000000001}, "a": {"type": "string", "value": "<x>"}}}],
+		"numberOfInstances": 1}`)
+
+	formatted, err := formatJSON(raw, &models.Spec{})
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "numberOfInstances": 1,
+  "sequentialOperations": [
+    {
+      "type": "request",
+      "uri": "room.join",
+      "args": {
+        "a": {
+          "type": "string",
+          "value": "<x>"
+        },
+        "b": {
+          "type": "int",
+          "value": 10000000000000001
+        }
+      }
+    }
+  ]
+}
+`, string(formatted))
+
+	again, err := formatJSON(formatted, &models.Spec{})
+	assert.NoError(t, err)
+	assert.Equal(t, formatted, again)
+
+	_, err = formatJSON([]byte(`{"numberOfInstances": 1, "sequentialOperation": []}`), &models.Spec{})
+	assert.Error(t, err)
+}
+
+func TestLintSpec(t *testing.T) {
+	tables := []struct {
+		name   string
+		raw    string
+		issues []string
+	}{
+		{"valid", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "request", "uri": "a.b"}]}`, []string{}},
+		{"unknown type", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "requets", "uri": "a.b"}]}`,
+			[]string{`sequentialOperations[0]: unknown operation type "requets"`}},
+		{"missing uri", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "listen"}]}`,
+			[]string{"sequentialOperations[0]: missing uri"}},
+		{"unknown macro", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "call", "uri": "buy"}]}`,
+			[]string{`sequentialOperations[0]: unknown macro "buy"`}},
+		{"inverted post delay", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "request", "uri": "a.b", "postDelay": {"minMs": 500, "maxMs": 100}}]}`,
+			[]string{"sequentialOperations[0]: postDelay minMs is greater than maxMs"}},
+		{"unknown feeder strategy", `{"numberOfInstances": 1, "feeder": {"file": "users.csv", "strategy": "shuffled"}}`,
+			[]string{`unknown feeder strategy "shuffled"`}},
+		{"multi route listen", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "listen", "listen": {"mode": "first", "routes": [{"route": "a.b"}]}}]}`,
+			[]string{`sequentialOperations[0]: unknown listen mode "first"`}},
+		{"script without code", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "script", "script": {}}]}`,
+			[]string{"sequentialOperations[0]: script needs either source or file"}},
+		{"unknown fuzz kind", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "fuzz", "uri": "a.b", "fuzz": {"kind": "bitflip"}}]}`,
+			[]string{"sequentialOperations[0]: invalid fuzz: Unknown fuzz kind: bitflip"}},
+		{"goto unknown label", `{"numberOfInstances": 1, "onError": "goto(start)", "sequentialOperations": [{"type": "request", "uri": "a.b", "label": "begin", "onError": "retry(0)"}]}`,
+			[]string{`onError: unknown label "start"`, `sequentialOperations[0].onError: Invalid onError policy "retry(0)": attempts must be a positive int`}},
+		{"goto function", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "request", "uri": "a.b", "label": "a"}, {"type": "request", "uri": "a.b", "label": "a"}, {"type": "function", "uri": "goto", "args": {"label": {"type": "string", "value": "b"}}}]}`,
+			[]string{`sequentialOperations[1]: duplicate label "a"`, `sequentialOperations[2]: goto unknown label "b"`}},
+		{"schema expectation", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "request", "uri": "a.b", "expect": {"$response": {"type": "schema", "schema": {"type": "obj"}}, "code": {"type": "schema"}}}]}`,
+			[]string{"sequentialOperations[0]: invalid schema expectation on $response: Unknown schema.type obj", "sequentialOperations[0]: invalid schema expectation on code: Schema expectations need either a schema or a message"}},
+		{"expectError on notify", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "notify", "uri": "a.b", "expectError": {"code": "PIT-404"}}]}`,
+			[]string{"sequentialOperations[0]: expectError only applies to request and fuzz operations"}},
+		{"assertPushes route twice", `{"numberOfInstances": 1, "sequentialOperations": [{"type": "assertPushes", "assertPushes": {"routes": [{"route": "a.b", "count": 1}, {"route": "a.b", "min": 2}]}}]}`,
+			[]string{"sequentialOperations[0]: invalid assertPushes: AssertPushes route a.b is listed twice"}},
+		{"no instances", `{"sequentialOperations": [{"type": "function", "uri": "reconnect"}]}`,
+			[]string{"numberOfInstances must be positive"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			var spec models.Spec
+			_, err := formatJSON([]byte(table.raw), &spec)
+			assert.NoError(t, err)
+			assert.Equal(t, table.issues, lintSpec(&spec))
+		})
+	}
+}
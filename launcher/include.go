@@ -0,0 +1,133 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// readSpecFile reads the spec at specPath without resolving its includes
+func readSpecFile(specPath string) (*models.Spec, error) {
+	raw, err := ioutil.ReadFile(specPath)
+	if err != nil {
+		return nil, err
+	}
+	if raw, err = models.SpecJSON(specPath, raw); err != nil {
+		return nil, err
+	}
+
+	var spec models.Spec
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return nil, fmt.Errorf("Malformed spec %s: %s", specPath, err)
+	}
+
+	return &spec, nil
+}
+
+// resolveIncludes prepends the operations of the spec includes, and of
+// theirs, to its sequential operations. Included operations get the
+// fragment vars, overridden by the include ones and then by their own
+func resolveIncludes(spec *models.Spec, specPath string, stack []string) error {
+	if len(spec.Include) == 0 {
+		return nil
+	}
+
+	ops := make([]*models.Operation, 0)
+	macros := make(map[string][]*models.Operation, len(spec.Macros))
+	for name, macro := range spec.Macros {
+		macros[name] = macro
+	}
+
+	for _, include := range spec.Include {
+		path := includePath(specPath, include.File)
+		for _, including := range stack {
+			if including == path {
+				return fmt.Errorf("Include cycle: %s includes %s", strings.Join(stack, " -> "), path)
+			}
+		}
+
+		fragment, err := readSpecFile(path)
+		if err != nil {
+			return err
+		}
+		if err := resolveIncludes(fragment, path, append(stack, path)); err != nil {
+			return err
+		}
+
+		for _, op := range fragment.SequentialOperations {
+			included := *op
+			included.Vars = mergeVars(fragment.Vars, include.Vars, op.Vars)
+			ops = append(ops, &included)
+		}
+
+		for name, macro := range fragment.Macros {
+			if _, ok := macros[name]; !ok {
+				macros[name] = macro
+			}
+		}
+	}
+
+	spec.SequentialOperations = append(ops, spec.SequentialOperations...)
+	if len(macros) > 0 {
+		spec.Macros = macros
+	}
+	return nil
+}
+
+// includePath returns the cleaned path of file, relative to the including
+// spec unless absolute
+func includePath(specPath, file string) string {
+	if filepath.IsAbs(file) {
+		return filepath.Clean(file)
+	}
+
+	return filepath.Join(filepath.Dir(specPath), file)
+}
+
+func mergeVars(layers ...map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+	return merged
+}
+
+// includedFiles returns the paths of the fragments included by the specs
+// under specsDirectory, they are not run as specs themselves. Malformed
+// specs are skipped, they fail when read on their own
+func includedFiles(specsDirectory string) (map[string]bool, error) {
+	included := map[string]bool{}
+	err := filepath.Walk(specsDirectory,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !validFile(info) || isSuiteFile(specsDirectory, path) {
+				return nil
+			}
+
+			spec, err := readSpecFile(path)
+			if err != nil {
+				return nil
+			}
+
+			for _, include := range spec.Include {
+				included[includePath(path, include.File)] = true
+			}
+			return nil
+		})
+
+	return included, err
+}
@@ -0,0 +1,55 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "common"), 0755)
+	files := map[string]string{
+		"common/login.json": `{
+			"vars": {"platform": "android", "user": "guest"},
+			"include": ["handshake.json"],
+			"sequentialOperations": [{"type": "request", "uri": "player.login", "vars": {"user": "admin"}}],
+			"macros": {"logout": [{"type": "request", "uri": "player.logout"}]}
+		}`,
+		"common/handshake.json": `{"sequentialOperations": [{"type": "request", "uri": "connector.hello"}]}`,
+		"play.json": `{
+			"numberOfInstances": 1,
+			"include": [{"file": "common/login.json", "vars": {"platform": "ios"}}],
+			"sequentialOperations": [{"type": "call", "uri": "logout"}]
+		}`,
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	specs, err := getSpecs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 1, "fragments are not run as specs")
+
+	ops := specs[0].SequentialOperations
+	assert.Len(t, ops, 3)
+	assert.Equal(t, "connector.hello", ops[0].URI)
+	assert.Equal(t, "player.login", ops[1].URI)
+	assert.Equal(t, map[string]interface{}{"platform": "ios", "user": "admin"}, ops[1].Vars)
+	assert.Equal(t, "logout", ops[2].URI)
+	assert.Contains(t, specs[0].Macros, "logout")
+
+	_, issues, err := FormatSpecs(dir, false)
+	assert.NoError(t, err)
+	assert.Empty(t, issues)
+
+	ioutil.WriteFile(filepath.Join(dir, "common/handshake.json"), []byte(`{"include": ["login.json"]}`), 0644)
+	_, err = readSpec(filepath.Join(dir, "play.json"))
+	assert.Error(t, err, "include cycles are rejected")
+}
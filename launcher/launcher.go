@@ -4,30 +4,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math/rand"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/control"
+	"github.com/topfreegames/pitaya-bot/dashboard"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
 	"github.com/topfreegames/pitaya-bot/runner"
 	"github.com/topfreegames/pitaya-bot/state"
 )
 
+// suiteFiles are the files, at the root of the specs directory, that may
+// hold the suite setup and teardown
+var suiteFiles = []string{"suite.json", "suite.yaml", "suite.yml"}
+
+func readSuite(specsDirectory string) (*models.Suite, error) {
+	for _, name := range suiteFiles {
+		path := filepath.Join(specsDirectory, name)
+		raw, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := models.SpecJSON(path, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		var suite models.Suite
+		if err := json.Unmarshal(data, &suite); err != nil {
+			return nil, fmt.Errorf("Malformed %s: %s", name, err)
+		}
+		return &suite, nil
+	}
+
+	return &models.Suite{}, nil
+}
+
 func readSpec(specPath string) (*models.Spec, error) {
-	raw, err := ioutil.ReadFile(specPath)
+	spec, err := readSpecFile(specPath)
 	if err != nil {
 		return nil, err
 	}
 
-	var spec models.Spec
-	err = json.Unmarshal(raw, &spec)
-	return &spec, err
+	return spec, resolveIncludes(spec, specPath, []string{filepath.Clean(specPath)})
 }
 
 func validFile(info os.FileInfo) bool {
@@ -37,21 +70,35 @@ func validFile(info os.FileInfo) bool {
 	if runtime.GOOS != "windows" && info.Name()[0:1] == "." {
 		return false
 	}
-	if strings.Contains(info.Name(), ".json") {
+	if strings.Contains(info.Name(), ".json") || models.IsYAML(info.Name()) {
 		return true
 	}
 	return false
 }
 
+func isSuiteFile(specsDirectory, path string) bool {
+	for _, name := range suiteFiles {
+		if filepath.Clean(path) == filepath.Join(specsDirectory, name) {
+			return true
+		}
+	}
+	return false
+}
+
 func getSpecs(specsDirectory string) ([]*models.Spec, error) {
+	included, err := includedFiles(specsDirectory)
+	if err != nil {
+		return nil, err
+	}
+
 	ret := make([]*models.Spec, 0)
-	err := filepath.Walk(specsDirectory,
+	err = filepath.Walk(specsDirectory,
 		func(path string, info os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
 
-			if !validFile(info) {
+			if !validFile(info) || isSuiteFile(specsDirectory, path) || included[filepath.Clean(path)] {
 				return nil
 			}
 			spec, err := readSpec(path)
@@ -71,19 +118,24 @@ func getSpecs(specsDirectory string) ([]*models.Spec, error) {
 	return ret, nil
 }
 
-func runClients(app *state.App, spec *models.Spec, config *viper.Viper, logger logrus.FieldLogger) []error {
-	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+// runClients runs the spec bots, paced by spawner when it is set and
+// delayed by arrival otherwise
+func runClients(app *state.App, spec *models.Spec, config *viper.Viper, arrival *arrival, spawner *spawner, logger logrus.FieldLogger) []error {
 	var (
 		errmutex      sync.Mutex
 		wg            sync.WaitGroup
 		compoundError []error
 	)
 
-	for i := 0; i < spec.NumberOfInstances; i++ {
+	for i := 0; i < spec.Instances(); i++ {
+		if spawner != nil && !spawner.wait(app.Draining()) {
+			break
+		}
 		wg.Add(1)
 		go func(i int) {
-			sleepDuration := time.Duration(random.Intn(1000)) * time.Millisecond
-			time.Sleep(sleepDuration)
+			if spawner == nil {
+				time.Sleep(arrival.delay())
+			}
 			if err := runner.Run(app, config, spec, i, logger); err != nil {
 				errmutex.Lock()
 				compoundError = append(compoundError, err)
@@ -97,17 +149,27 @@ func runClients(app *state.App, spec *models.Spec, config *viper.Viper, logger l
 	return compoundError
 }
 
-func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration float64, logger logrus.FieldLogger) []error {
+func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration float64, profile loadProfile, arrival *arrival, spawner *spawner, target *externalTarget, logger logrus.FieldLogger) []error {
 	logger = logger.WithFields(logrus.Fields{
 		"spec": spec.Name,
 	})
 
-	logger.Debugf("Launching %d bots\n", spec.NumberOfInstances)
+	if target != nil {
+		logger.Debugf("Following bot target from %s", target)
+		return newProfileRunner(app, spec, config, spawner, logger).follow(target, time.Duration(duration*float64(time.Second)))
+	}
+
+	if len(profile) > 0 {
+		logger.Debugf("Following load profile for %v", profile.duration())
+		return newProfileRunner(app, spec, config, spawner, logger).run(profile)
+	}
+
+	logger.Debugf("Launching %d bots\n", spec.Instances())
 
 	var compoundError []error
 	start := time.Now().UTC()
 	for {
-		err := runClients(app, spec, config, logger)
+		err := runClients(app, spec, config, arrival, spawner, logger)
 		if err != nil {
 			compoundError = append(compoundError, err...)
 		}
@@ -116,11 +178,120 @@ func runSpec(app *state.App, spec *models.Spec, config *viper.Viper, duration fl
 		if elaspsed.Seconds() > duration {
 			break
 		}
+
+		select {
+		case <-app.Draining():
+			logger.Info("Run draining, not launching more bots")
+			return compoundError
+		default:
+		}
 	}
 
 	return compoundError
 }
 
+// handleSignals toggles the fleet pause flag whenever SIGUSR1 is
+// received and drains the run on SIGUSR2. SIGINT and SIGTERM abort the run
+// gracefully, a second one exits right away
+func handleSignals(app *state.App, logger logrus.FieldLogger) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		aborted := false
+		for sig := range sigs {
+			switch sig {
+			case syscall.SIGUSR1:
+				if app.Pauser.Toggle() {
+					logger.Info("Fleet paused, bots will wait before their next operation")
+				} else {
+					logger.Info("Fleet resumed")
+				}
+			case syscall.SIGUSR2:
+				drain(app, logger)
+			default:
+				if aborted {
+					logger.Warn("Exiting without waiting for the bots teardown")
+					os.Exit(130)
+				}
+				aborted = true
+				app.Abort()
+				logger.Warn("Aborting, bots stop before their next operation and run their teardown, interrupt again to exit right away")
+			}
+		}
+	}()
+}
+
+// drain stops launching bots, the run ends once the running ones finish
+func drain(app *state.App, logger logrus.FieldLogger) {
+	app.Drain()
+	logger.Info("Draining, the run ends once the running bots finish")
+	if app.Pauser.Paused() {
+		logger.Warn("Fleet is paused, running bots only finish once it is resumed")
+	}
+}
+
+// getServerMetricsCheck returns the post-run server metrics check, nil when
+// serverMetrics.url is not set
+func getServerMetricsCheck(config *viper.Viper) (*metrics.ServerMetricsCheck, error) {
+	url := config.GetString("serverMetrics.url")
+	if url == "" {
+		return nil, nil
+	}
+
+	var thresholds []metrics.ServerMetricThreshold
+	if err := config.UnmarshalKey("serverMetrics.thresholds", &thresholds); err != nil {
+		return nil, fmt.Errorf("Invalid server metrics thresholds: %s", err)
+	}
+
+	return metrics.NewServerMetricsCheck(url, thresholds), nil
+}
+
+// getSLACheck returns the SLA check of the sla.assertions, nil when there
+// are none
+func getSLACheck(config *viper.Viper) (*metrics.SLACheck, error) {
+	return metrics.NewSLACheck(config.GetStringSlice("sla.assertions"))
+}
+
+// watchSLA checks the SLA against the metrics so far every interval while
+// the run goes, draining it on the first violation. The returned function
+// stops watching, returning that violation
+func watchSLA(app *state.App, check *metrics.SLACheck, interval time.Duration, runStart time.Time, logger logrus.FieldLogger) func() error {
+	if check == nil || interval <= 0 {
+		return func() error { return nil }
+	}
+
+	done := make(chan struct{})
+	violation := make(chan error, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := check.Verify(app.Summary.Report(time.Since(runStart))); err != nil {
+					logger.WithError(err).Error("SLA violated, stopping the run")
+					violation <- err
+					drain(app, logger)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		select {
+		case err := <-violation:
+			return err
+		default:
+			return nil
+		}
+	}
+}
+
 // Launch launches the bot spec
 func Launch(app *state.App, config *viper.Viper, specsDirectory string, duration float64, shouldReportMetrics bool) {
 	log := logrus.New()
@@ -131,19 +302,154 @@ func Launch(app *state.App, config *viper.Viper, specsDirectory string, duration
 		"function": "launch",
 	})
 
+	if err := bot.LoadPlugins(config.GetStringSlice("bot.plugins")); err != nil {
+		logger.Fatal(err)
+	}
+
+	if config.IsSet("bot.seed") {
+		bot.SeedGenerators(config.GetInt64("bot.seed"))
+	}
+
+	if err := bot.SetFakerLocale(config.GetString("faker.locale")); err != nil {
+		logger.Fatal(err)
+	}
+
+	if err := bot.SetFlagBits(config.GetStringMap("expect.flags")); err != nil {
+		logger.Fatal(err)
+	}
+	bot.SetSchemaDescriptors(config.GetString("expect.descriptors"))
+
+	profile, err := getLoadProfile(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	arrival, err := getArrival(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	spawner, err := getSpawner(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	target, err := getExternalTarget(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if target != nil && len(profile) > 0 {
+		logger.Fatal("loadtest.profile and loadtest.target can not be used together")
+	}
+	controlAddress := config.GetString("control.address")
+	if target == nil && len(profile) == 0 && controlAddress != "" {
+		// bots are ramped by the control server
+		target = newControlTarget()
+	}
+	if target != nil {
+		app.BotTarget = state.NewBotTarget()
+		target.override = app.BotTarget
+	}
+
+	if config.IsSet("shared.backend") {
+		if app.Shared, err = state.NewSharedStore(config); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
 	specs, err := getSpecs(specsDirectory)
 	if err != nil {
 		logger.Fatal(err)
 	}
 	logger.Infof("Found %d specs to be executed", len(specs))
 
+	suite, err := readSuite(specsDirectory)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if len(suite.Setup) > 0 {
+		logger.Info("Running suite setup")
+		app.SuiteStorage, err = bot.RunSuiteOperations(app, config, "setup", suite.Setup, logger)
+		if err != nil {
+			logger.WithError(err).Fatal("Suite setup failed, aborting run")
+		}
+	}
+
+	serverCheck, err := getServerMetricsCheck(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if serverCheck != nil {
+		if err := serverCheck.Start(); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	slaCheck, err := getSLACheck(config)
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if path := config.GetString("report.streamPath"); path != "" {
+		app.ResultStream, err = metrics.NewResultStream(path)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	if config.GetString("report.junitPath") != "" {
+		app.JUnit, err = metrics.NewJUnitReport(config.GetString("report.junitCases"))
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	var dash *dashboard.Dashboard
+	if config.GetBool("dashboard.enabled") {
+		dash = dashboard.NewDashboard(os.Stdout)
+		app.MetricsReporter = append(app.MetricsReporter, dash)
+
+		// the dashboard takes over the terminal, logs go to a file
+		logFile, err := os.OpenFile(config.GetString("dashboard.logFile"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		defer logFile.Close()
+		log.Out = logFile
+		logrus.SetOutput(logFile)
+		dash.Start()
+	}
+
+	var controlServer *control.Server
+	if controlAddress != "" {
+		controlServer = control.NewServer(app, controlAddress, logger)
+		if err := controlServer.Start(); err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	handleSignals(app, logger)
+
+	profiler := getProfiler(config, logger)
+	if err := profiler.start(); err != nil {
+		logger.Fatal(err)
+	}
+
+	rpsController, err := getRPSController(config, app.Throughput, logger)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	rpsController.start()
+
+	runStart := time.Now()
+	stopSLAWatch := watchSLA(app, slaCheck, time.Duration(config.GetInt("sla.checkIntervalMs"))*time.Millisecond, runStart, logger)
 	var wg sync.WaitGroup
 	errmutex := sync.Mutex{}
 	compoundError := []error{}
 	for _, spec := range specs {
 		wg.Add(1)
 		go func(spec *models.Spec) {
-			err := runSpec(app, spec, config, duration, logger)
+			err := runSpec(app, spec, config, duration, profile, arrival, spawner, target, logger)
 			if err != nil {
 				errmutex.Lock()
 				compoundError = append(compoundError, err...)
@@ -154,10 +460,68 @@ func Launch(app *state.App, config *viper.Viper, specsDirectory string, duration
 	}
 
 	wg.Wait()
+	slaViolation := stopSLAWatch()
+	dash.Stop()
+	rpsController.stop()
+	profiler.stop()
 
 	logger.Info("Finished running bots")
+	if len(suite.Teardown) > 0 {
+		logger.Info("Running suite teardown")
+		if _, err := bot.RunSuiteOperations(app, config, "teardown", suite.Teardown, logger); err != nil {
+			compoundError = append(compoundError, fmt.Errorf("Suite teardown failed: %s", err))
+		}
+	}
+	if err := app.ResultStream.Close(); err != nil {
+		logger.WithError(err).Error("Failed to close result stream")
+	}
+	app.Tracer.Close()
+	app.Summary.SetPaused(app.Pauser.PausedDuration())
+	report := app.Summary.Report(time.Since(runStart))
+	report.Print(os.Stdout)
+	if path := config.GetString("report.summaryPath"); path != "" {
+		if err := report.Write(path); err != nil {
+			logger.WithError(err).Error("Failed to write run summary")
+		}
+	}
+	if path := config.GetString("report.htmlPath"); path != "" {
+		if err := report.WriteHTML(path); err != nil {
+			logger.WithError(err).Error("Failed to write html report")
+		}
+	}
+	if path := config.GetString("report.junitPath"); path != "" {
+		if err := app.JUnit.Write(path); err != nil {
+			logger.WithError(err).Error("Failed to write junit report")
+		}
+	}
+	controlServer.Close()
+	for _, mr := range app.MetricsReporter {
+		if jsonl, ok := mr.(*metrics.JSONLReporter); ok {
+			if err := jsonl.Close(); err != nil {
+				logger.WithError(err).Error("Failed to close jsonl stream")
+			}
+		}
+	}
 	app.FinishedExecition = true
 
+	if serverCheck != nil {
+		if err := serverCheck.Verify(); err != nil {
+			compoundError = append(compoundError, err)
+		} else {
+			logger.Info("Server metrics check passed")
+		}
+	}
+
+	if slaCheck != nil {
+		if slaViolation != nil {
+			compoundError = append(compoundError, slaViolation)
+		} else if err := slaCheck.Verify(report); err != nil {
+			compoundError = append(compoundError, err)
+		} else {
+			logger.Info("SLA check passed")
+		}
+	}
+
 	if shouldReportMetrics {
 		logger.Info("Waiting for metrics to be collected...")
 		select {
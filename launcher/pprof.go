@@ -0,0 +1,120 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// profiler captures pprof profiles of the bot process around the load
+// phase. The cpu profile is recorded from start to stop, the heap,
+// goroutine and block profiles are snapshots taken when it stops
+type profiler struct {
+	cpu       string
+	heap      string
+	block     string
+	goroutine string
+	duration  time.Duration
+	cpuFile   *os.File
+	once      sync.Once
+	logger    logrus.FieldLogger
+}
+
+func getProfiler(config *viper.Viper, logger logrus.FieldLogger) *profiler {
+	p := &profiler{
+		cpu:       config.GetString("pprof.cpu"),
+		heap:      config.GetString("pprof.heap"),
+		block:     config.GetString("pprof.block"),
+		goroutine: config.GetString("pprof.goroutine"),
+		duration:  config.GetDuration("pprof.duration"),
+		logger:    logger,
+	}
+
+	if p.cpu == "" && p.heap == "" && p.block == "" && p.goroutine == "" {
+		return nil
+	}
+
+	return p
+}
+
+// start begins profiling, which stops after the configured duration or
+// when stop is called, whichever comes first
+func (p *profiler) start() error {
+	if p == nil {
+		return nil
+	}
+
+	if p.block != "" {
+		runtime.SetBlockProfileRate(1)
+	}
+
+	if p.cpu != "" {
+		f, err := os.Create(p.cpu)
+		if err != nil {
+			return fmt.Errorf("Unable to create cpu profile: %s", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return fmt.Errorf("Unable to start cpu profile: %s", err)
+		}
+		p.cpuFile = f
+	}
+
+	if p.duration > 0 {
+		time.AfterFunc(p.duration, p.stop)
+	}
+
+	p.logger.Info("Started profiling")
+	return nil
+}
+
+// stop writes the profiles, only the first call has any effect
+func (p *profiler) stop() {
+	if p == nil {
+		return
+	}
+
+	p.once.Do(func() {
+		if p.cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := p.cpuFile.Close(); err != nil {
+				p.logger.WithError(err).Error("Failed to write cpu profile")
+			}
+		}
+
+		if p.heap != "" {
+			runtime.GC()
+		}
+		p.writeProfile("heap", p.heap)
+		p.writeProfile("goroutine", p.goroutine)
+		p.writeProfile("block", p.block)
+		if p.block != "" {
+			runtime.SetBlockProfileRate(0)
+		}
+
+		p.logger.Info("Finished profiling")
+	})
+}
+
+func (p *profiler) writeProfile(name, path string) {
+	if path == "" {
+		return
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		p.logger.WithError(err).Errorf("Failed to create %s profile", name)
+		return
+	}
+	defer f.Close()
+
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		p.logger.WithError(err).Errorf("Failed to write %s profile", name)
+	}
+}
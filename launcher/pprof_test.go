@@ -0,0 +1,39 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfiler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pprof")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := viper.New()
+	assert.Nil(t, getProfiler(config, logrus.New()))
+
+	for _, name := range []string{"cpu", "heap", "block", "goroutine"} {
+		config.Set("pprof."+name, filepath.Join(dir, name+".pprof"))
+	}
+
+	p := getProfiler(config, logrus.New())
+	assert.NotNil(t, p)
+	assert.NoError(t, p.start())
+	p.stop()
+	p.stop()
+
+	for _, name := range []string{"cpu", "heap", "block", "goroutine"} {
+		info, err := os.Stat(filepath.Join(dir, name+".pprof"))
+		assert.NoError(t, err, name)
+		if err == nil {
+			assert.True(t, info.Size() > 0, name)
+		}
+	}
+}
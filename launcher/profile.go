@@ -0,0 +1,283 @@
+package launcher
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/runner"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+// LoadStep is a step of the load profile. The number of running bots is
+// linearly interpolated from the previous step target (0 for the first step)
+// to TargetBots over Duration, a zero Duration jumps straight to TargetBots
+type LoadStep struct {
+	Duration   time.Duration `mapstructure:"duration"`
+	TargetBots int           `mapstructure:"targetBots"`
+}
+
+type loadProfile []LoadStep
+
+func getLoadProfile(config *viper.Viper) (loadProfile, error) {
+	var profile loadProfile
+	if !config.IsSet("loadtest.profile") {
+		return profile, nil
+	}
+
+	if err := config.UnmarshalKey("loadtest.profile", &profile); err != nil {
+		return nil, fmt.Errorf("Malformed loadtest.profile: %s", err)
+	}
+
+	for i, step := range profile {
+		if step.Duration < 0 || step.TargetBots < 0 {
+			return nil, fmt.Errorf("Malformed loadtest.profile step %d: duration and targetBots must not be negative", i)
+		}
+	}
+
+	return profile, nil
+}
+
+func (p loadProfile) duration() time.Duration {
+	var total time.Duration
+	for _, step := range p {
+		total += step.Duration
+	}
+
+	return total
+}
+
+// targetAt returns how many bots should be running after elapsed
+func (p loadProfile) targetAt(elapsed time.Duration) int {
+	previous := 0
+	for _, step := range p {
+		if elapsed < step.Duration {
+			progress := float64(elapsed) / float64(step.Duration)
+			return previous + int(float64(step.TargetBots-previous)*progress)
+		}
+
+		elapsed -= step.Duration
+		previous = step.TargetBots
+	}
+
+	return previous
+}
+
+// defaultRestartBackoff and defaultMaxRestartBackoff bound how long a slot
+// waits before running its next bot after one failed, unless
+// loadtest.restart says otherwise. maxProfileErrors is how many bot errors
+// a run returns, the others are only counted
+const (
+	defaultRestartBackoff    = 100 * time.Millisecond
+	defaultMaxRestartBackoff = 10 * time.Second
+	maxProfileErrors         = 100
+)
+
+// profileRunner keeps the number of bots running a spec tracking the target
+// of the load profile. Each slot runs one bot after the other and a slot
+// is only stopped once its current bot finishes. Bot launches are paced by
+// spawner, when set, and slots back off while their bots keep failing
+type profileRunner struct {
+	app     *state.App
+	config  *viper.Viper
+	spec    *models.Spec
+	spawner *spawner
+	logger  logrus.FieldLogger
+
+	mutex         sync.Mutex
+	wg            sync.WaitGroup
+	target        int
+	running       map[int]bool
+	nextID        int
+	compoundError []error
+	omittedErrors int
+}
+
+func newProfileRunner(app *state.App, spec *models.Spec, config *viper.Viper, spawner *spawner, logger logrus.FieldLogger) *profileRunner {
+	return &profileRunner{
+		app:     app,
+		config:  config,
+		spec:    spec,
+		spawner: spawner,
+		logger:  logger,
+		running: map[int]bool{},
+	}
+}
+
+func (r *profileRunner) setTarget(target int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if target != r.target {
+		r.logger.Debugf("Load profile target: %d bots", target)
+	}
+	r.target = target
+
+	for slot := 0; slot < target; slot++ {
+		if r.running[slot] {
+			continue
+		}
+
+		r.running[slot] = true
+		r.wg.Add(1)
+		go r.runSlot(slot)
+	}
+}
+
+// nextBot returns the id of the next bot the slot should run, or false if
+// the slot is above the current target and must stop
+func (r *profileRunner) nextBot(slot int) (int, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if slot >= r.target {
+		delete(r.running, slot)
+		return 0, false
+	}
+
+	id := r.nextID
+	r.nextID++
+	return id, true
+}
+
+// stopSlot marks the slot as stopped, before reaching the end of its bots
+func (r *profileRunner) stopSlot(slot int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.running, slot)
+}
+
+func (r *profileRunner) runSlot(slot int) {
+	defer r.wg.Done()
+	failures := 0
+	for {
+		id, ok := r.nextBot(slot)
+		if !ok {
+			return
+		}
+
+		if !r.spawner.wait(r.app.Draining()) {
+			r.stopSlot(slot)
+			return
+		}
+
+		err := runner.Run(r.app, r.config, r.spec, id, r.logger)
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		r.addError(err)
+		failures++
+		select {
+		case <-time.After(r.restartBackoff(failures)):
+		case <-r.app.Draining():
+		}
+	}
+}
+
+// restartBackoff is how long a slot waits after failures bots failed in a
+// row, doubling from loadtest.restart.backoff up to
+// loadtest.restart.maxBackoff
+func (r *profileRunner) restartBackoff(failures int) time.Duration {
+	backoff, max := defaultRestartBackoff, defaultMaxRestartBackoff
+	if r.config.IsSet("loadtest.restart.backoff") {
+		backoff = r.config.GetDuration("loadtest.restart.backoff")
+	}
+	if r.config.IsSet("loadtest.restart.maxBackoff") {
+		max = r.config.GetDuration("loadtest.restart.maxBackoff")
+	}
+
+	for i := 1; i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
+
+// addError keeps the first maxProfileErrors bot errors and counts the rest
+func (r *profileRunner) addError(err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if len(r.compoundError) < maxProfileErrors {
+		r.compoundError = append(r.compoundError, err)
+		return
+	}
+	r.omittedErrors++
+}
+
+// errors returns the bot errors kept, followed by how many were omitted
+func (r *profileRunner) errors() []error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.omittedErrors > 0 {
+		return append(r.compoundError, fmt.Errorf("%d more bot errors omitted", r.omittedErrors))
+	}
+	return r.compoundError
+}
+
+func (r *profileRunner) run(profile loadProfile) []error {
+	start := time.Now()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	r.setTarget(profile.targetAt(0))
+	for r.tick(ticker) {
+		elapsed := time.Since(start)
+		if elapsed >= profile.duration() {
+			break
+		}
+		r.setTarget(profile.targetAt(elapsed))
+	}
+
+	r.setTarget(0)
+	r.wg.Wait()
+	return r.errors()
+}
+
+// tick waits for the next tick, it returns false once the run is draining
+func (r *profileRunner) tick(ticker *time.Ticker) bool {
+	select {
+	case <-ticker.C:
+		return true
+	case <-r.app.Draining():
+		r.logger.Info("Run draining, not launching more bots")
+		return false
+	}
+}
+
+// follow polls the external target for duration, scaling the bots towards
+// it. The last target is kept while the source can not be read
+func (r *profileRunner) follow(target *externalTarget, duration time.Duration) []error {
+	start := time.Now()
+	ticker := time.NewTicker(target.interval)
+	defer ticker.Stop()
+
+	poll := func() {
+		bots, err := target.read(r.spec.Instances())
+		if err != nil {
+			r.logger.WithError(err).Warn("Keeping the current bot target")
+			return
+		}
+		r.setTarget(bots)
+	}
+
+	poll()
+	for r.tick(ticker) {
+		if time.Since(start) >= duration {
+			break
+		}
+		poll()
+	}
+
+	r.setTarget(0)
+	r.wg.Wait()
+	return r.errors()
+}
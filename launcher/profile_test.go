@@ -0,0 +1,100 @@
+package launcher
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/models"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestLoadProfileTargetAt(t *testing.T) {
+	profile := loadProfile{
+		{Duration: 0, TargetBots: 50},
+		{Duration: time.Minute, TargetBots: 50},
+		{Duration: 2 * time.Minute, TargetBots: 200},
+		{Duration: 5 * time.Minute, TargetBots: 200},
+		{Duration: time.Minute, TargetBots: 0},
+	}
+
+	table := map[string]struct {
+		elapsed time.Duration
+		target  int
+	}{
+		"start":         {0, 50},
+		"holding":       {30 * time.Second, 50},
+		"ramping_up":    {2 * time.Minute, 125},
+		"ramp_done":     {3 * time.Minute, 200},
+		"ramping_down":  {8*time.Minute + 30*time.Second, 100},
+		"after_profile": {10 * time.Minute, 0},
+	}
+
+	for name, tt := range table {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.target, profile.targetAt(tt.elapsed))
+		})
+	}
+
+	assert.Equal(t, 9*time.Minute, profile.duration())
+}
+
+func TestProfileRunnerRestartBackoff(t *testing.T) {
+	config := viper.New()
+	config.Set("loadtest.restart.backoff", "100ms")
+	config.Set("loadtest.restart.maxBackoff", "1s")
+	r := &profileRunner{config: config}
+
+	assert.Equal(t, 100*time.Millisecond, r.restartBackoff(1))
+	assert.Equal(t, 400*time.Millisecond, r.restartBackoff(3))
+	assert.Equal(t, time.Second, r.restartBackoff(5))
+	assert.Equal(t, time.Second, r.restartBackoff(100))
+}
+
+func TestProfileRunnerFailingBots(t *testing.T) {
+	app := state.NewApp(viper.New(), false)
+	config := viper.New()
+	config.Set("loadtest.restart.backoff", "50ms")
+	config.Set("loadtest.restart.maxBackoff", "50ms")
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	// specs without operations fail right away
+	spec := &models.Spec{Name: "empty", NumberOfInstances: 1}
+	r := newProfileRunner(app, spec, config, nil, logger)
+	errs := r.run(loadProfile{{Duration: 0, TargetBots: 1}, {Duration: 500 * time.Millisecond, TargetBots: 1}})
+
+	// the slot runs a bot every 50ms for about a second, not in a tight loop
+	assert.NotEmpty(t, errs)
+	assert.True(t, len(errs) <= 30, "%d bots ran", len(errs))
+}
+
+func TestProfileRunnerDrainDuringArrivalGap(t *testing.T) {
+	app := state.NewApp(viper.New(), false)
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+
+	// the second slot waits an hour for its launch slot
+	spec := &models.Spec{Name: "empty", NumberOfInstances: 2}
+	r := newProfileRunner(app, spec, viper.New(), &spawner{interval: time.Hour}, logger)
+	time.AfterFunc(50*time.Millisecond, app.Drain)
+
+	start := time.Now()
+	r.run(loadProfile{{Duration: 0, TargetBots: 2}, {Duration: time.Minute, TargetBots: 2}})
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
+
+func TestProfileRunnerErrorsCap(t *testing.T) {
+	r := &profileRunner{}
+	for i := 0; i < maxProfileErrors+5; i++ {
+		r.addError(errors.New("bot failed"))
+	}
+
+	errs := r.errors()
+	assert.Len(t, errs, maxProfileErrors+1)
+	assert.EqualError(t, errs[maxProfileErrors], "5 more bot errors omitted")
+}
@@ -0,0 +1,142 @@
+package launcher
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+const (
+	defaultRPSInterval = time.Second
+	// minControlledPacing is where the pacing restarts from when the
+	// bots must slow down with no pacing at all
+	minControlledPacing = 10 * time.Millisecond
+	// maxPacingFactor bounds how much the pacing changes each interval
+	maxPacingFactor = 2.0
+)
+
+// rpsController measures the requests per second all bots send and scales
+// the pacing they wait between operations towards loadtest.rps.target.
+// Operations with a postDelay of their own are not affected
+type rpsController struct {
+	target     float64
+	interval   time.Duration
+	maxPacing  time.Duration
+	throughput *state.Throughput
+	logger     logrus.FieldLogger
+
+	pacing   time.Duration
+	requests int64
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+// getRPSController returns the target rps controller, nil when
+// loadtest.rps.target is not set
+func getRPSController(config *viper.Viper, throughput *state.Throughput, logger logrus.FieldLogger) (*rpsController, error) {
+	target := config.GetFloat64("loadtest.rps.target")
+	if target < 0 {
+		return nil, fmt.Errorf("Malformed loadtest.rps.target: must not be negative")
+	}
+	if target == 0 {
+		return nil, nil
+	}
+
+	c := &rpsController{
+		target:     target,
+		interval:   defaultRPSInterval,
+		maxPacing:  config.GetDuration("loadtest.rps.maxPacing"),
+		throughput: throughput,
+		logger:     logger,
+		pacing:     time.Duration(config.GetInt("bot.pacingMs")) * time.Millisecond,
+	}
+	if config.IsSet("loadtest.rps.interval") {
+		c.interval = config.GetDuration("loadtest.rps.interval")
+	}
+	if c.interval <= 0 {
+		return nil, fmt.Errorf("Malformed loadtest.rps.interval: must be positive")
+	}
+
+	return c, nil
+}
+
+// adjust returns the pacing that moves the measured rps towards the target
+func (c *rpsController) adjust(pacing time.Duration, measured float64) time.Duration {
+	if measured <= 0 {
+		return pacing / 2
+	}
+
+	factor := measured / c.target
+	if factor > maxPacingFactor {
+		factor = maxPacingFactor
+	}
+	if factor < 1/maxPacingFactor {
+		factor = 1 / maxPacingFactor
+	}
+
+	if factor > 1 && pacing < minControlledPacing {
+		pacing = minControlledPacing
+	}
+
+	next := time.Duration(float64(pacing) * factor)
+	if c.maxPacing > 0 && next > c.maxPacing {
+		next = c.maxPacing
+	}
+
+	return next
+}
+
+func (c *rpsController) tick(elapsed time.Duration) {
+	requests := c.throughput.Requests()
+	measured := float64(requests-c.requests) / elapsed.Seconds()
+	c.requests = requests
+
+	c.pacing = c.adjust(c.pacing, measured)
+	c.throughput.SetPacing(c.pacing)
+	c.logger.Debugf("Measured %.1f rps for a target of %.1f, pacing set to %v", measured, c.target, c.pacing)
+
+	if c.pacing == 0 && measured < c.target*0.9 {
+		c.logger.Warnf("Bots can not reach %.1f rps without pacing, measured %.1f rps, more bots are needed", c.target, measured)
+	}
+}
+
+// start controls the pacing every interval until stop is called
+func (c *rpsController) start() {
+	if c == nil {
+		return
+	}
+
+	c.done = make(chan struct{})
+	c.stopped = make(chan struct{})
+	c.requests = c.throughput.Requests()
+	c.throughput.SetPacing(c.pacing)
+
+	go func() {
+		defer close(c.stopped)
+		ticker := time.NewTicker(c.interval)
+		defer ticker.Stop()
+
+		last := time.Now()
+		for {
+			select {
+			case now := <-ticker.C:
+				c.tick(now.Sub(last))
+				last = now
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+func (c *rpsController) stop() {
+	if c == nil {
+		return
+	}
+
+	close(c.done)
+	<-c.stopped
+}
@@ -0,0 +1,71 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/topfreegames/pitaya-bot/state"
+)
+
+func TestGetRPSController(t *testing.T) {
+	config := viper.New()
+	c, err := getRPSController(config, state.NewThroughput(), logrus.New())
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	config.Set("loadtest.rps.target", -1)
+	_, err = getRPSController(config, state.NewThroughput(), logrus.New())
+	assert.Error(t, err)
+
+	config.Set("loadtest.rps.target", 100)
+	config.Set("bot.pacingMs", 50)
+	c, err = getRPSController(config, state.NewThroughput(), logrus.New())
+	assert.NoError(t, err)
+	assert.Equal(t, 50*time.Millisecond, c.pacing)
+	assert.Equal(t, time.Second, c.interval)
+}
+
+func TestRPSControllerAdjust(t *testing.T) {
+	c := &rpsController{target: 100, maxPacing: time.Second}
+
+	tables := []struct {
+		name     string
+		pacing   time.Duration
+		measured float64
+		expected time.Duration
+	}{
+		{"too fast", 100 * time.Millisecond, 150, 150 * time.Millisecond},
+		{"too slow", 100 * time.Millisecond, 50, 50 * time.Millisecond},
+		{"on target", 100 * time.Millisecond, 100, 100 * time.Millisecond},
+		{"capped factor", 100 * time.Millisecond, 1000, 200 * time.Millisecond},
+		{"too fast without pacing", 0, 200, 20 * time.Millisecond},
+		{"capped pacing", 800 * time.Millisecond, 200, time.Second},
+		{"nothing sent", 100 * time.Millisecond, 0, 50 * time.Millisecond},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.Equal(t, table.expected, c.adjust(table.pacing, table.measured))
+		})
+	}
+}
+
+func TestRPSControllerTick(t *testing.T) {
+	logger := logrus.New()
+	logger.Out = ioutil.Discard
+	throughput := state.NewThroughput()
+	c := &rpsController{target: 10, throughput: throughput, logger: logger, pacing: 100 * time.Millisecond}
+
+	for i := 0; i < 20; i++ {
+		throughput.AddRequest()
+	}
+	c.tick(time.Second)
+
+	pacing, ok := throughput.Pacing()
+	assert.True(t, ok)
+	assert.Equal(t, 200*time.Millisecond, pacing)
+}
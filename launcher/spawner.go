@@ -0,0 +1,67 @@
+package launcher
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// spawner paces bot launches to loadtest.spawnRate bots per second, shared
+// by every spec. Launches are not paced when it is nil
+type spawner struct {
+	interval time.Duration
+
+	mutex sync.Mutex
+	next  time.Time
+}
+
+// getSpawner returns the launch pacer, nil when loadtest.spawnRate is not
+// set
+func getSpawner(config *viper.Viper) (*spawner, error) {
+	if !config.IsSet("loadtest.spawnRate") {
+		return nil, nil
+	}
+
+	rate := config.GetFloat64("loadtest.spawnRate")
+	if rate < 0 {
+		return nil, fmt.Errorf("Malformed loadtest.spawnRate: must not be negative")
+	}
+	if rate == 0 {
+		return nil, nil
+	}
+
+	return &spawner{interval: time.Duration(float64(time.Second) / rate)}, nil
+}
+
+// reserve returns when the next bot may be launched, each call taking the
+// next launch slot
+func (s *spawner) reserve(now time.Time) time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	at := s.next
+	if at.Before(now) {
+		at = now
+	}
+	s.next = at.Add(s.interval)
+
+	return at
+}
+
+// wait blocks until the next bot may be launched, it returns false when done
+// is closed first
+func (s *spawner) wait(done <-chan struct{}) bool {
+	if s == nil {
+		return true
+	}
+
+	now := time.Now()
+	select {
+	case <-time.After(s.reserve(now).Sub(now)):
+		return true
+	case <-done:
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package launcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSpawner(t *testing.T) {
+	tables := []struct {
+		name     string
+		settings map[string]interface{}
+		interval time.Duration
+		err      bool
+	}{
+		{"unset", map[string]interface{}{}, 0, false},
+		{"zero", map[string]interface{}{"loadtest.spawnRate": 0}, 0, false},
+		{"rate", map[string]interface{}{"loadtest.spawnRate": 50}, 20 * time.Millisecond, false},
+		{"negative", map[string]interface{}{"loadtest.spawnRate": -1}, 0, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			config := viper.New()
+			for k, v := range table.settings {
+				config.Set(k, v)
+			}
+
+			s, err := getSpawner(config)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			if table.interval == 0 {
+				assert.Nil(t, s)
+				return
+			}
+			assert.Equal(t, table.interval, s.interval)
+		})
+	}
+}
+
+func TestSpawnerReserve(t *testing.T) {
+	s := &spawner{interval: 100 * time.Millisecond}
+	now := time.Now()
+
+	assert.Equal(t, now, s.reserve(now))
+	assert.Equal(t, now.Add(100*time.Millisecond), s.reserve(now))
+	assert.Equal(t, now.Add(200*time.Millisecond), s.reserve(now))
+
+	later := now.Add(time.Minute)
+	assert.Equal(t, later, s.reserve(later))
+}
+
+func TestSpawnerWait(t *testing.T) {
+	var nilSpawner *spawner
+	assert.True(t, nilSpawner.wait(nil))
+
+	s := &spawner{interval: time.Hour}
+	done := make(chan struct{})
+	assert.True(t, s.wait(done))
+
+	time.AfterFunc(10*time.Millisecond, func() { close(done) })
+	start := time.Now()
+	assert.False(t, s.wait(done))
+	assert.True(t, time.Since(start) < time.Second)
+}
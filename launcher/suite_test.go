@@ -0,0 +1,59 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSuite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	suite, err := readSuite(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, suite.Setup)
+
+	ioutil.WriteFile(filepath.Join(dir, "suite.json"), []byte(`{
+		"setup": [{"type": "request", "uri": "tournament.create"}],
+		"teardown": [{"type": "request", "uri": "tournament.delete"}]
+	}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "join.json"), []byte(`{"numberOfInstances": 1}`), 0644)
+
+	suite, err = readSuite(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "tournament.create", suite.Setup[0].URI)
+	assert.Equal(t, "tournament.delete", suite.Teardown[0].URI)
+
+	specs, err := getSpecs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 1)
+	assert.Equal(t, filepath.Join(dir, "join.json"), specs[0].Name)
+}
+
+func TestYAMLSpecs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "suite.yaml"), []byte("setup:\n  - type: request\n    uri: tournament.create\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "join.yml"), []byte("# comment\nnumberOfInstances: 1\nsequentialOperations:\n  - type: request\n    uri: room.join\n"), 0644)
+
+	suite, err := readSuite(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, "tournament.create", suite.Setup[0].URI)
+
+	specs, err := getSpecs(dir)
+	assert.NoError(t, err)
+	assert.Len(t, specs, 1)
+	assert.Equal(t, "room.join", specs[0].SequentialOperations[0].URI)
+
+	changed, issues, err := FormatSpecs(dir, true)
+	assert.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Empty(t, issues)
+}
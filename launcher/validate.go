@@ -0,0 +1,579 @@
+package launcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+var (
+	storeRefExpr = regexp.MustCompile(`^\$store\.(.+)$|\$\{store\.([^{}]+)\}`)
+	varsRefExpr  = regexp.MustCompile(`\$\{vars\.([^{}]+)\}`)
+	issuePath    = regexp.MustCompile(`^([A-Za-z0-9_.\[\]-]+): `)
+
+	unknownFieldExpr = regexp.MustCompile(`unknown field "([^"]+)"`)
+)
+
+// specFile is a spec read by ValidateSpecs
+type specFile struct {
+	path     string
+	data     []byte
+	spec     *models.Spec
+	declared *declarations
+}
+
+// declarations are the storage keys and vars a spec may reference. Specs
+// with scripts, custom functions or feeders may store any key
+type declarations struct {
+	keys    map[string]bool
+	vars    map[string]bool
+	anyKeys bool
+}
+
+func newDeclarations() *declarations {
+	return &declarations{keys: map[string]bool{}, vars: map[string]bool{}}
+}
+
+// hasKey returns whether key, or the value it is nested in, is declared.
+// The keys bound by the bots themselves are always declared
+func (d *declarations) hasKey(key string) bool {
+	if d.anyKeys || d.keys[key] {
+		return true
+	}
+
+	for _, prefix := range []string{"bot.", "params.", "vars."} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	if idx := strings.IndexAny(key, ".["); idx > 0 {
+		return d.keys[key[:idx]]
+	}
+	return false
+}
+
+func (d *declarations) merge(other *declarations) {
+	for k := range other.keys {
+		d.keys[k] = true
+	}
+	for k := range other.vars {
+		d.vars[k] = true
+	}
+	d.anyKeys = d.anyKeys || other.anyKeys
+}
+
+// ValidateSpecs checks every spec under specsDirectory without connecting
+// to any server: the fields and types of the spec model, what fmt lints,
+// and references to storage keys no operation stores and to vars neither
+// the specs nor vars define. Issues have the line they refer to, for json
+// specs, when known
+func ValidateSpecs(specsDirectory string, vars map[string]interface{}) ([]SpecIssue, error) {
+	issues := make([]SpecIssue, 0)
+
+	included, err := includedFiles(specsDirectory)
+	if err != nil {
+		return nil, err
+	}
+
+	suiteDeclared := newDeclarations()
+	for k := range vars {
+		suiteDeclared.vars[k] = true
+	}
+
+	files := make([]*specFile, 0)
+	err = filepath.Walk(specsDirectory,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !validFile(info) {
+				return nil
+			}
+
+			raw, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			data, err := models.SpecJSON(path, raw)
+			if err != nil {
+				issues = append(issues, SpecIssue{Path: path, Issue: err.Error()})
+				return nil
+			}
+
+			if isSuiteFile(specsDirectory, path) {
+				var suite models.Suite
+				if err := decodeStrict(data, &suite); err != nil {
+					issues = append(issues, decodeIssue(path, data, err))
+					return nil
+				}
+				issues = append(issues, fileIssues(path, data, lintSuite(&suite))...)
+				for _, op := range suite.Setup {
+					declareOperation(suiteDeclared, op)
+				}
+				return nil
+			}
+
+			var spec models.Spec
+			if err := decodeStrict(data, &spec); err != nil {
+				issues = append(issues, decodeIssue(path, data, err))
+				return nil
+			}
+			files = append(files, &specFile{path: path, data: data, spec: &spec})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	// fragments may reference what the specs including them declare
+	fragmentDeclared := map[string]*declarations{}
+	for _, file := range files {
+		resolved := *file.spec
+		if err := resolveIncludes(&resolved, file.path, []string{filepath.Clean(file.path)}); err != nil {
+			continue
+		}
+
+		file.declared = declare(&resolved)
+		file.declared.merge(suiteDeclared)
+		if included[filepath.Clean(file.path)] {
+			continue
+		}
+
+		for path := range includeClosure(file.spec, file.path, map[string]bool{}) {
+			if fragmentDeclared[path] == nil {
+				fragmentDeclared[path] = newDeclarations()
+			}
+			fragmentDeclared[path].merge(file.declared)
+		}
+	}
+
+	for _, file := range files {
+		path := filepath.Clean(file.path)
+		found := lintSpecFile(file.spec, file.path, included[path])
+
+		if file.declared != nil {
+			declared := file.declared
+			if included[path] {
+				if fragmentDeclared[path] != nil {
+					declared.merge(fragmentDeclared[path])
+				} else {
+					declared.anyKeys = true
+				}
+			}
+			found = append(found, lintRefs(file.spec, declared)...)
+		}
+
+		issues = append(issues, fileIssues(file.path, file.data, found)...)
+	}
+
+	return issues, nil
+}
+
+func decodeStrict(data []byte, model interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	return decoder.Decode(model)
+}
+
+// decodeIssue reports a decoding error at its line, when known
+func decodeIssue(path string, data []byte, err error) SpecIssue {
+	issue := SpecIssue{Path: path, Issue: fmt.Sprintf("Invalid spec: %s", err)}
+	if models.IsYAML(path) {
+		return issue
+	}
+
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		issue.Line = lineAt(data, e.Offset)
+	case *json.UnmarshalTypeError:
+		issue.Line = lineAt(data, e.Offset)
+	default:
+		// unknown field errors have no offset, the first key with the
+		// field name is the likely culprit
+		if m := unknownFieldExpr.FindStringSubmatch(err.Error()); m != nil {
+			key := regexp.MustCompile(`"` + regexp.QuoteMeta(m[1]) + `"\s*:`)
+			if loc := key.FindIndex(data); loc != nil {
+				issue.Line = lineAt(data, int64(loc[0]))
+			}
+		}
+	}
+	return issue
+}
+
+// fileIssues attaches the line of the path prefixing each issue
+func fileIssues(path string, data []byte, found []string) []SpecIssue {
+	var lines map[string]int
+	if !models.IsYAML(path) {
+		lines = jsonLines(data)
+	}
+
+	issues := make([]SpecIssue, 0, len(found))
+	for _, issue := range found {
+		line := 0
+		if m := issuePath.FindStringSubmatch(issue); m != nil {
+			line = lookupLine(lines, m[1])
+		}
+		issues = append(issues, SpecIssue{Path: path, Line: line, Issue: issue})
+	}
+
+	return issues
+}
+
+// lookupLine returns the line of path or, when lint paths differ from the
+// json ones, of its closest known parent
+func lookupLine(lines map[string]int, path string) int {
+	for path != "" {
+		if line, ok := lines[path]; ok {
+			return line
+		}
+
+		idx := strings.LastIndexAny(path, ".[")
+		if idx < 0 {
+			break
+		}
+		path = path[:idx]
+	}
+
+	return 0
+}
+
+func lineAt(data []byte, offset int64) int {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// jsonLines maps the paths of the json values in data, written like the
+// lint paths, e.g. sequentialOperations[0].args, to their lines
+func jsonLines(data []byte) map[string]int {
+	lines := map[string]int{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if path != "" {
+			lines[path] = lineAt(data, decoder.InputOffset())
+		}
+
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				child := fmt.Sprint(key)
+				if path != "" {
+					child = path + "." + child
+				}
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		case json.Delim('['):
+			for idx := 0; decoder.More(); idx++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, idx)); err != nil {
+					return err
+				}
+			}
+			_, err = decoder.Token()
+		}
+		return err
+	}
+
+	// malformed json keeps the lines found before the error
+	walk("")
+	return lines
+}
+
+// includeClosure returns the paths of the fragments spec includes, directly
+// or through other fragments
+func includeClosure(spec *models.Spec, specPath string, seen map[string]bool) map[string]bool {
+	for _, include := range spec.Include {
+		path := includePath(specPath, include.File)
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		if fragment, err := readSpecFile(path); err == nil {
+			includeClosure(fragment, path, seen)
+		}
+	}
+
+	return seen
+}
+
+// walkOperations calls fn with every operation of spec, nested ones
+// included, and its lint path
+func walkOperations(spec *models.Spec, fn func(path string, op *models.Operation)) {
+	var walk func(path string, op *models.Operation)
+	walk = func(path string, op *models.Operation) {
+		if op == nil {
+			return
+		}
+		fn(path, op)
+
+		for idx, nested := range op.ParallelOperations {
+			walk(fmt.Sprintf("%s.parallelOperations[%d]", path, idx), nested)
+		}
+		if op.Loop != nil {
+			for idx, nested := range op.Loop.Operations {
+				walk(fmt.Sprintf("%s.loop.operations[%d]", path, idx), nested)
+			}
+		}
+		if op.Condition != nil {
+			for idx, nested := range op.Condition.Then {
+				walk(fmt.Sprintf("%s.condition.then[%d]", path, idx), nested)
+			}
+			for idx, nested := range op.Condition.Else {
+				walk(fmt.Sprintf("%s.condition.else[%d]", path, idx), nested)
+			}
+		}
+		if op.StateMachine != nil {
+			for name, state := range op.StateMachine.States {
+				if state != nil {
+					walk(fmt.Sprintf("%s.stateMachine.states.%s.trigger", path, name), state.Trigger)
+				}
+			}
+		}
+	}
+
+	sections := []struct {
+		name string
+		ops  []*models.Operation
+	}{
+		{"sequentialOperations", spec.SequentialOperations},
+		{"setupOperations", spec.SetupOperations},
+		{"teardownOperations", spec.TeardownOperations},
+		{"onResume", spec.OnResume},
+	}
+	if spec.Random != nil {
+		sections = append(sections, struct {
+			name string
+			ops  []*models.Operation
+		}{"random.operations", spec.Random.Operations})
+	}
+	for _, section := range sections {
+		for idx, op := range section.ops {
+			walk(fmt.Sprintf("%s[%d]", section.name, idx), op)
+		}
+	}
+
+	names := make([]string, 0, len(spec.Macros))
+	for name := range spec.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for idx, op := range spec.Macros[name] {
+			walk(fmt.Sprintf("macros.%s[%d]", name, idx), op)
+		}
+	}
+}
+
+// declare returns what spec declares: the keys its operations, push
+// handlers and matrix store and the vars it and its operations define
+func declare(spec *models.Spec) *declarations {
+	d := newDeclarations()
+	d.anyKeys = spec.Feeder != nil
+
+	for k := range spec.Matrix {
+		d.keys[k] = true
+	}
+	for k := range spec.Vars {
+		d.vars[k] = true
+	}
+	for _, include := range spec.Include {
+		for k := range include.Vars {
+			d.vars[k] = true
+		}
+	}
+	for _, handler := range spec.PushHandlers {
+		if handler == nil {
+			continue
+		}
+		for k := range handler.Store {
+			d.keys[k] = true
+		}
+		if handler.Count != "" {
+			d.keys[handler.Count] = true
+		}
+	}
+
+	walkOperations(spec, func(path string, op *models.Operation) {
+		declareOperation(d, op)
+	})
+
+	return d
+}
+
+func declareOperation(d *declarations, op *models.Operation) {
+	for k := range op.Store {
+		d.keys[k] = true
+	}
+	for k := range op.Vars {
+		d.vars[k] = true
+	}
+	if op.Listen != nil {
+		for _, route := range op.Listen.Routes {
+			for k := range route.Store {
+				d.keys[k] = true
+			}
+		}
+	}
+	if op.StateMachine != nil {
+		for _, state := range op.StateMachine.States {
+			if state == nil {
+				continue
+			}
+			for _, transition := range state.Transitions {
+				for k := range transition.Store {
+					d.keys[k] = true
+				}
+			}
+		}
+	}
+
+	switch {
+	case op.Type == "script":
+		d.anyKeys = true
+	case op.Type == "function" && op.URI == "waitForKey":
+		for _, arg := range []string{"storeAs", "key"} {
+			if key, ok := staticArg(op.Args[arg]); ok {
+				d.keys[key] = true
+				break
+			}
+		}
+	case op.Type == "function" && !bot.BuiltinFunction(op.URI):
+		d.anyKeys = true
+	}
+}
+
+// staticArg returns the value of a string arg written without references
+func staticArg(arg interface{}) (string, bool) {
+	if entry, ok := arg.(map[string]interface{}); ok {
+		arg = entry["value"]
+	}
+
+	value, ok := arg.(string)
+	if !ok || strings.Contains(value, "$") {
+		return "", false
+	}
+	return value, true
+}
+
+// lintRefs reports references to storage keys and vars that are not
+// declared
+func lintRefs(spec *models.Spec, declared *declarations) []string {
+	issues := make([]string, 0)
+	walkOperations(spec, func(path string, op *models.Operation) {
+		seen := map[string]bool{}
+		report := func(issue string) {
+			if !seen[issue] {
+				seen[issue] = true
+				issues = append(issues, fmt.Sprintf("%s: %s", path, issue))
+			}
+		}
+
+		for _, value := range operationRefValues(op) {
+			for _, m := range storeRefExpr.FindAllStringSubmatch(value, -1) {
+				if key := m[1] + m[2]; !declared.hasKey(key) {
+					report(fmt.Sprintf("reference to storage key %q that no operation stores", key))
+				}
+			}
+			for _, m := range varsRefExpr.FindAllStringSubmatch(value, -1) {
+				if !declared.vars[m[1]] {
+					report(fmt.Sprintf("reference to undefined var %q", m[1]))
+				}
+			}
+		}
+
+		for _, key := range operationStorageKeys(op) {
+			if !declared.hasKey(key) {
+				report(fmt.Sprintf("expectation on storage key %q that no operation stores", key))
+			}
+		}
+	})
+
+	return issues
+}
+
+// operationRefValues returns the strings of op that may hold references
+func operationRefValues(op *models.Operation) []string {
+	values := []string{op.URI}
+	values = appendStrings(values, op.Args)
+	for _, spec := range []models.ExpectSpec{op.Expect, op.AssertStorage, op.Preconditions} {
+		for _, entry := range spec {
+			values = appendStrings(values, entry.Value)
+		}
+	}
+	if op.HTTP != nil {
+		for _, value := range op.HTTP.Headers {
+			values = append(values, value)
+		}
+	}
+	if op.GRPC != nil {
+		for _, value := range op.GRPC.Metadata {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// operationStorageKeys returns the storage keys expectations of op are
+// keyed by
+func operationStorageKeys(op *models.Operation) []string {
+	keys := make([]string, 0)
+	specs := []models.ExpectSpec{op.AssertStorage, op.Preconditions}
+	if op.Loop != nil {
+		specs = append(specs, op.Loop.Until)
+	}
+	if op.Condition != nil && (op.Condition.Source == "" || op.Condition.Source == "storage") {
+		specs = append(specs, op.Condition.If)
+	}
+
+	for _, spec := range specs {
+		for key := range spec {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendStrings(values []string, value interface{}) []string {
+	switch val := value.(type) {
+	case string:
+		return append(values, val)
+	case map[string]interface{}:
+		for _, item := range val {
+			values = appendStrings(values, item)
+		}
+	case []interface{}:
+		for _, item := range val {
+			values = appendStrings(values, item)
+		}
+	}
+
+	return values
+}
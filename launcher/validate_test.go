@@ -0,0 +1,86 @@
+package launcher
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSpecs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "specs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"login.json": `{
+  "numberOfInstances": 1,
+  "include": ["fragment.json"],
+  "sequentialOperations": [
+    {
+      "type": "request",
+      "uri": "player.login",
+      "store": {"token": {"type": "string", "value": "$response.token"}}
+    },
+    {
+      "type": "request",
+      "uri": "player.me",
+      "args": {
+        "token": {"type": "string", "value": "$store.tokn"},
+        "region": {"type": "string", "value": "${vars.region}"},
+        "version": {"type": "string", "value": "${vars.version}"}
+      },
+      "assertStorage": {"token": {"type": "string", "value": "abc"}}
+    }
+  ]
+}`,
+		"fragment.json": `{"sequentialOperations": [{"type": "request", "uri": "room.join", "args": {"token": {"type": "string", "value": "${store.token}"}}}]}`,
+		"typo.json": `{
+  "numberOfInstances": 1,
+  "sequentialOperations": [
+    {"type": "request", "uri": "player.login", "expct": {}}
+  ]
+}`,
+		"types.json": `{
+  "numberOfInstances": "one"
+}`,
+	}
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	issues, err := ValidateSpecs(dir, map[string]interface{}{"region": "eu"})
+	assert.NoError(t, err)
+
+	found := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		found = append(found, issue.String())
+	}
+	login := filepath.Join(dir, "login.json")
+	assert.ElementsMatch(t, []string{
+		login + `:10: sequentialOperations[1]: reference to storage key "tokn" that no operation stores`,
+		login + `:10: sequentialOperations[1]: reference to undefined var "version"`,
+		filepath.Join(dir, "typo.json") + `:4: Invalid spec: json: unknown field "expct"`,
+		filepath.Join(dir, "types.json") + `:2: Invalid spec: json: cannot unmarshal string into Go struct field Spec.numberOfInstances of type int`,
+	}, found)
+}
+
+func TestJSONLines(t *testing.T) {
+	lines := jsonLines([]byte(`{
+  "sequentialOperations": [
+    {"type": "request"},
+    {
+      "type": "loop",
+      "loop": {"operations": [{"type": "request"}]}
+    }
+  ]
+}`))
+
+	assert.Equal(t, 2, lines["sequentialOperations"])
+	assert.Equal(t, 3, lines["sequentialOperations[0]"])
+	assert.Equal(t, 4, lines["sequentialOperations[1]"])
+	assert.Equal(t, 6, lookupLine(lines, "sequentialOperations[1].loop.operations[0]"))
+	assert.Equal(t, 4, lookupLine(lines, "sequentialOperations[1].unknown"))
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import "sync/atomic"
+
+// ConnectionStats counts the connection usage of a bot, showing how well
+// connections are reused: Handshakes against Operations, bytes exchanged
+// and Reconnects. All methods are safe on a nil ConnectionStats
+type ConnectionStats struct {
+	Handshakes    int64 `json:"handshakes"`
+	Reconnects    int64 `json:"reconnects"`
+	Operations    int64 `json:"operations"`
+	BytesSent     int64 `json:"bytesSent"`
+	BytesReceived int64 `json:"bytesReceived"`
+}
+
+// AddHandshake counts a new connection
+func (s *ConnectionStats) AddHandshake() {
+	if s != nil {
+		atomic.AddInt64(&s.Handshakes, 1)
+	}
+}
+
+// AddReconnect counts a reconnection
+func (s *ConnectionStats) AddReconnect() {
+	if s != nil {
+		atomic.AddInt64(&s.Reconnects, 1)
+	}
+}
+
+// AddOperation counts an operation run
+func (s *ConnectionStats) AddOperation() {
+	if s != nil {
+		atomic.AddInt64(&s.Operations, 1)
+	}
+}
+
+// AddSent counts bytes sent to the server
+func (s *ConnectionStats) AddSent(n int) {
+	if s != nil {
+		atomic.AddInt64(&s.BytesSent, int64(n))
+	}
+}
+
+// AddReceived counts bytes received from the server
+func (s *ConnectionStats) AddReceived(n int) {
+	if s != nil {
+		atomic.AddInt64(&s.BytesReceived, int64(n))
+	}
+}
+
+// Snapshot returns a copy of the current counters
+func (s *ConnectionStats) Snapshot() ConnectionStats {
+	if s == nil {
+		return ConnectionStats{}
+	}
+
+	return ConnectionStats{
+		Handshakes:    atomic.LoadInt64(&s.Handshakes),
+		Reconnects:    atomic.LoadInt64(&s.Reconnects),
+		Operations:    atomic.LoadInt64(&s.Operations),
+		BytesSent:     atomic.LoadInt64(&s.BytesSent),
+		BytesReceived: atomic.LoadInt64(&s.BytesReceived),
+	}
+}
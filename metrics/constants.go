@@ -4,9 +4,47 @@ var (
 	// ResponseTime reports the response time of handlers and rpc
 	ResponseTime = "response_time_ms"
 
-	// ResponseTimeHistogram ...
+	// ResponseTimeHistogram reports the response time of requests in
+	// milliseconds, labeled by response status too
 	ResponseTimeHistogram = "response_time_histogram_ms"
 
-	// ErrorCount reports the number of requests that returned unexpected errors
+	// ErrorCount reports the number of failures, labeled by their type
 	ErrorCount = "error_count"
+
+	// TimeoutCount reports the number of requests whose response did not
+	// arrive in time
+	TimeoutCount = "timeout_count"
+
+	// RetryCount reports the number of retried requests
+	RetryCount = "retry_count"
+
+	// RateLimitCount reports the number of requests held back by a rate
+	// limiter, labeled by the limiter, bot or global
+	RateLimitCount = "rate_limit_count"
+
+	// SLAViolationCount reports the number of operations that succeeded
+	// but took longer than their maxDurationMs
+	SLAViolationCount = "sla_violation_count"
+
+	// PushCount reports the number of pushes received
+	PushCount = "push_count"
+
+	// ConnectedBots reports the number of bots connected to the server
+	ConnectedBots = "connected_bots"
+)
+
+// Error types, reported as the type label of ErrorCount
+const (
+	ResponseError    = "response"
+	ExpectationError = "expectation"
+	HTTPError        = "http"
+	GRPCError        = "grpc"
+)
+
+// Response statuses, reported as the status label of ResponseTimeHistogram.
+// Http responses are labeled by their status code instead
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusTimeout = "timeout"
 )
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 200
+)
+
+// chartSeries is a line of a chart
+type chartSeries struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+// chart is an svg line chart of the run timeline
+type chart struct {
+	Title  string
+	Unit   string
+	Max    float64
+	Width  int
+	Height int
+	Series []chartSeries
+}
+
+// lineChart plots values over the timeline, every series scaled to the
+// largest value of them all
+func lineChart(title, unit string, timeline []*TimelinePoint, series map[string]func(*TimelinePoint) float64, colors map[string]string) *chart {
+	c := &chart{Title: title, Unit: unit, Width: chartWidth, Height: chartHeight}
+	for _, value := range series {
+		for _, p := range timeline {
+			if v := value(p); v > c.Max {
+				c.Max = v
+			}
+		}
+	}
+
+	last := int64(1)
+	if len(timeline) > 0 && timeline[len(timeline)-1].Second > 0 {
+		last = timeline[len(timeline)-1].Second
+	}
+	max := c.Max
+	if max == 0 {
+		max = 1
+	}
+
+	names := make([]string, 0, len(series))
+	for name := range series {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		points := make([]string, 0, len(timeline))
+		for _, p := range timeline {
+			x := float64(p.Second) / float64(last) * chartWidth
+			y := chartHeight - series[name](p)/max*chartHeight
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		c.Series = append(c.Series, chartSeries{Name: name, Color: colors[name], Points: strings.Join(points, " ")})
+	}
+
+	return c
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"percent":  func(v float64) string { return fmt.Sprintf("%.2f%%", v*100) },
+	"ms":       func(v float64) string { return fmt.Sprintf("%.0f", v) },
+	"duration": func(ms int64) time.Duration { return time.Duration(ms) * time.Millisecond },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>pitaya-bot report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #f0f0f0; }
+.passed { color: #2a7d2a; font-weight: bold; }
+.failed { color: #c0392b; font-weight: bold; }
+svg { background: #fafafa; border: 1px solid #ccc; margin-bottom: 0.5em; }
+.legend span { margin-right: 1em; }
+</style>
+</head>
+<body>
+<h1>pitaya-bot report</h1>
+<p>Run took {{duration .Report.DurationMs}}{{if .Report.PausedMs}} (paused {{duration .Report.PausedMs}}){{end}}: {{.Report.Bots}} bots, {{.Report.FailedBots}} failed, {{.Report.BytesSent}} bytes sent, {{.Report.BytesReceived}} bytes received</p>
+
+<h2>Specs</h2>
+<table>
+<tr><th>Spec</th><th>Bots</th><th>Failed bots</th><th>Status</th></tr>
+{{range .Report.Specs}}<tr><td>{{.Spec}}</td><td>{{.Bots}}</td><td>{{.FailedBots}}</td>{{if .Passed}}<td class="passed">passed</td>{{else}}<td class="failed">failed</td>{{end}}</tr>
+{{end}}</table>
+
+{{range .Charts}}<h2>{{.Title}}</h2>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}">
+{{range .Series}}<polyline fill="none" stroke="{{.Color}}" stroke-width="1.5" points="{{.Points}}"/>
+{{end}}</svg>
+<div class="legend">{{range .Series}}<span style="color: {{.Color}}">&#9632; {{.Name}}</span>{{end}}<span>max {{ms .Max}} {{.Unit}}</span></div>
+{{end}}
+
+<h2>Routes</h2>
+<table>
+<tr><th>Route</th><th>Requests</th><th>p50 ms</th><th>p95 ms</th><th>p99 ms</th><th>Errors</th><th>Error rate</th><th>Pushes</th></tr>
+{{range .Report.Routes}}<tr><td>{{.Route}}</td><td>{{.Requests}}</td><td>{{ms .P50Ms}}</td><td>{{ms .P95Ms}}</td><td>{{ms .P99Ms}}</td><td>{{.Errors}}</td><td>{{percent .ErrorRate}}</td><td>{{.Pushes}}</td></tr>
+{{end}}</table>
+
+<h2>Errors</h2>
+{{if .Errors}}<table>
+<tr><th>Route</th><th>Type</th><th>Count</th></tr>
+{{range .Errors}}<tr><td>{{.Route}}</td><td>{{.Type}}</td><td>{{.Count}}</td></tr>
+{{end}}</table>{{else}}<p>No errors</p>{{end}}
+</body>
+</html>
+`))
+
+type errorRow struct {
+	Route string
+	Type  string
+	Count int
+}
+
+// WriteHTML writes the report to path as a self-contained html page, with
+// charts of the response times and throughput over the run
+func (r *RunReport) WriteHTML(path string) error {
+	errors := make([]errorRow, 0)
+	for _, route := range r.Routes {
+		types := make([]string, 0, len(route.ErrorTypes))
+		for typ := range route.ErrorTypes {
+			types = append(types, typ)
+		}
+		sort.Strings(types)
+		for _, typ := range types {
+			errors = append(errors, errorRow{Route: route.Route, Type: typ, Count: route.ErrorTypes[typ]})
+		}
+	}
+
+	charts := []*chart{
+		lineChart("Response time over time", "ms", r.Timeline, map[string]func(*TimelinePoint) float64{
+			"mean": func(p *TimelinePoint) float64 { return p.MeanMs },
+			"max":  func(p *TimelinePoint) float64 { return p.MaxMs },
+		}, map[string]string{"mean": "#2e86c1", "max": "#e67e22"}),
+		lineChart("Requests and errors per second", "per second", r.Timeline, map[string]func(*TimelinePoint) float64{
+			"requests": func(p *TimelinePoint) float64 { return float64(p.Requests) },
+			"errors":   func(p *TimelinePoint) float64 { return float64(p.Errors) },
+		}, map[string]string{"requests": "#27ae60", "errors": "#c0392b"}),
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = htmlReport.Execute(f, map[string]interface{}{
+		"Report": r,
+		"Charts": charts,
+		"Errors": errors,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHTML(t *testing.T) {
+	report := &RunReport{
+		DurationMs: 3000,
+		PausedMs:   1000,
+		Bots:       3,
+		FailedBots: 1,
+		Routes: []*RouteReport{
+			{Route: "room.join", Requests: 10, P50Ms: 12, P95Ms: 40, P99Ms: 55, Errors: 2, ErrorRate: 0.2, ErrorTypes: map[string]int{ExpectationError: 1, "timeout": 1}},
+		},
+		Specs: []*SpecReport{
+			{Spec: "lobby", Bots: 2, Passed: true},
+			{Spec: "<match>", Bots: 1, FailedBots: 1},
+		},
+		Timeline: []*TimelinePoint{
+			{Second: 0, Requests: 4, MeanMs: 10, MaxMs: 20},
+			{Second: 1, Requests: 6, Errors: 2, MeanMs: 30, MaxMs: 55},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "html-report")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "report.html")
+
+	assert.NoError(t, report.WriteHTML(path))
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	html := string(data)
+
+	assert.Contains(t, html, "Run took 3s (paused 1s): 3 bots")
+	assert.Contains(t, html, "<td>room.join</td><td>10</td><td>12</td><td>40</td><td>55</td><td>2</td><td>20.00%</td>")
+	assert.Contains(t, html, `<td>lobby</td><td>2</td><td>0</td><td class="passed">passed</td>`)
+	assert.Contains(t, html, `<td>&lt;match&gt;</td><td>1</td><td>1</td><td class="failed">failed</td>`)
+	assert.Contains(t, html, "<td>room.join</td><td>expectation</td><td>1</td>")
+	assert.Contains(t, html, "<td>room.join</td><td>timeout</td><td>1</td>")
+	// max response times, 20ms then 55ms, end at the top right corner
+	assert.Contains(t, html, `points="0.0,127.3 800.0,0.0"`)
+	assert.NotContains(t, html, "<script")
+}
+
+func TestLineChart(t *testing.T) {
+	tables := map[string]struct {
+		timeline []*TimelinePoint
+		points   string
+	}{
+		"empty":        {nil, ""},
+		"single point": {[]*TimelinePoint{{Second: 0, MeanMs: 5}}, "0.0,0.0"},
+		"zeros":        {[]*TimelinePoint{{Second: 0}, {Second: 2}}, "0.0,200.0 800.0,200.0"},
+		"scaled":       {[]*TimelinePoint{{Second: 0, MeanMs: 5}, {Second: 1, MeanMs: 10}}, "0.0,100.0 800.0,0.0"},
+	}
+
+	for name, table := range tables {
+		t.Run(name, func(t *testing.T) {
+			c := lineChart("title", "ms", table.timeline, map[string]func(*TimelinePoint) float64{
+				"mean": func(p *TimelinePoint) float64 { return p.MeanMs },
+			}, map[string]string{"mean": "#000"})
+			assert.Len(t, c.Series, 1)
+			assert.Equal(t, table.points, c.Series[0].Points)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Events reported by bots to EventReporters
+const (
+	OperationStartEvent = "operationStart"
+	OperationEndEvent   = "operationEnd"
+	FuzzFindingEvent    = "fuzzFinding"
+)
+
+// EventReporter is a Reporter that also receives the events of a run, such
+// as operations starting and ending, with their fields
+type EventReporter interface {
+	Reporter
+	ReportEvent(event string, tags map[string]string, fields map[string]interface{}) error
+}
+
+// ReportEvent reports event to the reporters that are EventReporters
+func ReportEvent(reporters []Reporter, event string, tags map[string]string, fields map[string]interface{}) {
+	for _, r := range reporters {
+		if er, ok := r.(EventReporter); ok {
+			er.ReportEvent(event, tags, fields)
+		}
+	}
+}
+
+// jsonlLine is a line of the JSONLReporter stream. Kind is metric, with
+// Type, Metric and Value set, or event, with Event and Fields set
+type jsonlLine struct {
+	Time   time.Time              `json:"time"`
+	Kind   string                 `json:"kind"`
+	Type   string                 `json:"type,omitempty"`
+	Metric string                 `json:"metric,omitempty"`
+	Value  *float64               `json:"value,omitempty"`
+	Event  string                 `json:"event,omitempty"`
+	Tags   map[string]string      `json:"tags,omitempty"`
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+// JSONLReporter writes every metric and event as a line of json, for
+// external tools to analyze the run
+//  - implements EventReporter
+type JSONLReporter struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+}
+
+// NewJSONLReporter returns a reporter appending to the file at path, or
+// writing to stdout when path is -
+func NewJSONLReporter(path string) (*JSONLReporter, error) {
+	if path == "-" {
+		return newJSONLReporter(os.Stdout, nil), nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open jsonl stream %s: %s", path, err)
+	}
+
+	return newJSONLReporter(file, file), nil
+}
+
+func newJSONLReporter(w io.Writer, closer io.Closer) *JSONLReporter {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return &JSONLReporter{encoder: encoder, closer: closer}
+}
+
+func (r *JSONLReporter) write(line *jsonlLine) error {
+	line.Time = time.Now().UTC()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.encoder.Encode(line)
+}
+
+func (r *JSONLReporter) metric(typ, metric string, tags map[string]string, value float64) error {
+	return r.write(&jsonlLine{Kind: "metric", Type: typ, Metric: metric, Tags: tags, Value: &value})
+}
+
+// ReportCount writes a count line
+//  - implements the ReportCount method of the Reporter interface
+func (r *JSONLReporter) ReportCount(metric string, tags map[string]string, count float64) error {
+	return r.metric("count", metric, tags, count)
+}
+
+// ReportSummary writes a summary line
+//  - implements the ReportSummary method of the Reporter interface
+func (r *JSONLReporter) ReportSummary(metric string, tags map[string]string, value float64) error {
+	return r.metric("summary", metric, tags, value)
+}
+
+// ReportHistogram writes a histogram line
+//  - implements the ReportHistogram method of the Reporter interface
+func (r *JSONLReporter) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	return r.metric("histogram", metric, tags, value)
+}
+
+// ReportGauge writes a gauge line
+//  - implements the ReportGauge method of the Reporter interface
+func (r *JSONLReporter) ReportGauge(metric string, tags map[string]string, value float64) error {
+	return r.metric("gauge", metric, tags, value)
+}
+
+// ReportEvent writes an event line
+//  - implements the ReportEvent method of the EventReporter interface
+func (r *JSONLReporter) ReportEvent(event string, tags map[string]string, fields map[string]interface{}) error {
+	return r.write(&jsonlLine{Kind: "event", Event: event, Tags: tags, Fields: fields})
+}
+
+// Close closes the stream file, stdout is left open
+func (r *JSONLReporter) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closer.Close()
+}
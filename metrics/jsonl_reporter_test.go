@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONLReporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jsonl")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.jsonl")
+
+	reporter, err := NewJSONLReporter(path)
+	assert.NoError(t, err)
+
+	// events keep the tags of tagged reporters
+	reporters := WithTags([]Reporter{reporter}, map[string]string{"spec": "lobby"})
+	ReportEvent(reporters, OperationStartEvent, map[string]string{"route": "room.join"}, map[string]interface{}{"index": 0})
+	reporters[0].ReportSummary(ResponseTime, map[string]string{"route": "room.join"}, 12)
+	reporters[0].ReportCount(ErrorCount, map[string]string{"route": "room.join"}, 0)
+	ReportEvent(reporters, OperationEndEvent, map[string]string{"route": "room.join"}, map[string]interface{}{"result": "ok", "stored": map[string]interface{}{"<token>": "abc"}})
+	assert.NoError(t, reporter.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	lines := make([]map[string]interface{}, 0)
+	raw := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		raw = append(raw, scanner.Text())
+		var line map[string]interface{}
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		lines = append(lines, line)
+	}
+
+	assert.Len(t, lines, 4)
+	assert.Contains(t, raw[3], `"<token>":"abc"`)
+	expected := []struct {
+		kind, name string
+	}{
+		{"event", OperationStartEvent},
+		{"metric", ResponseTime},
+		{"metric", ErrorCount},
+		{"event", OperationEndEvent},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.kind, lines[i]["kind"])
+		if e.kind == "event" {
+			assert.Equal(t, e.name, lines[i]["event"])
+		} else {
+			assert.Equal(t, e.name, lines[i]["metric"])
+			assert.Contains(t, lines[i], "value")
+		}
+		assert.Equal(t, map[string]interface{}{"spec": "lobby", "route": "room.join"}, lines[i]["tags"])
+		assert.NotEmpty(t, lines[i]["time"])
+	}
+	assert.Equal(t, "summary", lines[1]["type"])
+	assert.Equal(t, float64(0), lines[2]["value"])
+	assert.Equal(t, map[string]interface{}{"result": "ok", "stored": map[string]interface{}{"<token>": "abc"}}, lines[3]["fields"])
+}
+
+func TestReportEventSkipsPlainReporters(t *testing.T) {
+	// WithTags wraps a reporter without events, it must not fail
+	reporters := WithTags([]Reporter{NewRunSummary()}, map[string]string{"spec": "lobby"})
+	ReportEvent(reporters, OperationStartEvent, nil, nil)
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// JUnit test case granularities
+const (
+	JUnitBotCases       = "bot"
+	JUnitOperationCases = "operation"
+)
+
+// JUnitReport collects the results of a run as junit test cases, a test
+// suite per spec with a test case per bot or per operation. All methods are
+// noops on a nil JUnitReport
+type JUnitReport struct {
+	mutex      sync.Mutex
+	operations bool
+	bots       []*BotResult
+	results    []*OperationResult
+}
+
+// NewJUnitReport returns a report with a test case per bot or, when cases
+// is JUnitOperationCases, per operation
+func NewJUnitReport(cases string) (*JUnitReport, error) {
+	switch cases {
+	case "", JUnitBotCases:
+		return &JUnitReport{}, nil
+	case JUnitOperationCases:
+		return &JUnitReport{operations: true}, nil
+	}
+
+	return nil, fmt.Errorf("Invalid junit test cases %s, use %s or %s", cases, JUnitBotCases, JUnitOperationCases)
+}
+
+// Add records result, an OperationResult or BotResult
+func (j *JUnitReport) Add(result interface{}) {
+	if j == nil {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	switch r := result.(type) {
+	case *BotResult:
+		j.bots = append(j.bots, r)
+	case *OperationResult:
+		j.results = append(j.results, r)
+	}
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+
+	bot   int
+	index int
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	Time      string           `xml:"time,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+
+	durationMs int64
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+func junitSeconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+// junitFailureOf is the failure of a test case that failed with err, its
+// message is the first line of err and its body the whole of it, with the
+// raw response of failed expectations
+func junitFailureOf(err string) *junitFailure {
+	if err == "" {
+		return nil
+	}
+
+	message := strings.TrimSpace(err)
+	if i := strings.Index(message, "\n"); i >= 0 {
+		message = strings.TrimSpace(message[:i])
+	}
+	return &junitFailure{Message: message, Type: "failure", Body: strings.TrimSpace(err)}
+}
+
+func (j *JUnitReport) testSuites() *junitTestSuites {
+	suites := map[string]*junitTestSuite{}
+	suite := func(spec string) *junitTestSuite {
+		s, ok := suites[spec]
+		if !ok {
+			s = &junitTestSuite{Name: spec}
+			suites[spec] = s
+		}
+		return s
+	}
+
+	for _, bot := range j.bots {
+		s := suite(bot.Spec)
+		s.durationMs += bot.DurationMs
+		if j.operations {
+			continue
+		}
+
+		name := fmt.Sprintf("bot %d", bot.Bot)
+		if bot.Combination != "" {
+			name = fmt.Sprintf("%s %s", name, bot.Combination)
+		}
+		s.TestCases = append(s.TestCases, &junitTestCase{
+			Name:      name,
+			ClassName: bot.Spec,
+			Time:      junitSeconds(bot.DurationMs),
+			Failure:   junitFailureOf(bot.Error),
+			bot:       bot.Bot,
+		})
+	}
+
+	if j.operations {
+		for _, op := range j.results {
+			s := suite(op.Spec)
+			s.TestCases = append(s.TestCases, &junitTestCase{
+				Name:      fmt.Sprintf("bot %d operation %d %s %s", op.Bot, op.Index, op.Type, op.URI),
+				ClassName: op.Spec,
+				Time:      junitSeconds(op.DurationMs),
+				Failure:   junitFailureOf(op.Error),
+				bot:       op.Bot,
+				index:     op.Index,
+			})
+		}
+	}
+
+	report := &junitTestSuites{TestSuites: make([]*junitTestSuite, 0, len(suites))}
+	for _, s := range suites {
+		sort.SliceStable(s.TestCases, func(a, b int) bool {
+			if s.TestCases[a].bot != s.TestCases[b].bot {
+				return s.TestCases[a].bot < s.TestCases[b].bot
+			}
+			return s.TestCases[a].index < s.TestCases[b].index
+		})
+		s.Tests = len(s.TestCases)
+		for _, c := range s.TestCases {
+			if c.Failure != nil {
+				s.Failures++
+			}
+		}
+		s.Time = junitSeconds(s.durationMs)
+
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.TestSuites = append(report.TestSuites, s)
+	}
+	sort.Slice(report.TestSuites, func(a, b int) bool { return report.TestSuites[a].Name < report.TestSuites[b].Name })
+
+	return report
+}
+
+// Write writes the report as junit xml to path
+func (j *JUnitReport) Write(path string) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mutex.Lock()
+	suites := j.testSuites()
+	j.mutex.Unlock()
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append([]byte(xml.Header), data...), 0644)
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJUnitReport(t *testing.T) {
+	expectErr := "\nErr: Expected 200, got 500 \nRawData: {\"code\":500} \nExpected: {\"$response.code\":200}\n"
+	tables := map[string]struct {
+		cases    string
+		names    []string
+		failures int
+	}{
+		"bot cases":       {JUnitBotCases, []string{"bot 0", "bot 1 region=us"}, 1},
+		"default cases":   {"", []string{"bot 0", "bot 1 region=us"}, 1},
+		"operation cases": {JUnitOperationCases, []string{"bot 0 operation 0 request room.join", "bot 1 operation 0 request room.join", "bot 1 operation 1 listen room.joined"}, 1},
+	}
+
+	for name, table := range tables {
+		t.Run(name, func(t *testing.T) {
+			report, err := NewJUnitReport(table.cases)
+			assert.NoError(t, err)
+
+			report.Add(&OperationResult{Spec: "lobby", Bot: 1, Index: 1, Type: "listen", URI: "room.joined", DurationMs: 5, Error: expectErr})
+			report.Add(&OperationResult{Spec: "lobby", Bot: 1, Index: 0, Type: "request", URI: "room.join", DurationMs: 10})
+			report.Add(&OperationResult{Spec: "lobby", Bot: 0, Index: 0, Type: "request", URI: "room.join", DurationMs: 12})
+			report.Add(&BotResult{Spec: "lobby", Bot: 1, DurationMs: 1500, Combination: "region=us", Error: expectErr})
+			report.Add(&BotResult{Spec: "lobby", Bot: 0, DurationMs: 500})
+
+			dir, err := ioutil.TempDir("", "junit")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+			path := filepath.Join(dir, "results.xml")
+			assert.NoError(t, report.Write(path))
+
+			data, err := ioutil.ReadFile(path)
+			assert.NoError(t, err)
+			var suites junitTestSuites
+			assert.NoError(t, xml.Unmarshal(data, &suites))
+
+			assert.Len(t, suites.TestSuites, 1)
+			suite := suites.TestSuites[0]
+			assert.Equal(t, "lobby", suite.Name)
+			assert.Equal(t, "2.000", suite.Time)
+			assert.Equal(t, len(table.names), suite.Tests)
+			assert.Equal(t, table.failures, suite.Failures)
+			assert.Equal(t, table.failures, suites.Failures)
+
+			names := make([]string, 0, len(suite.TestCases))
+			for _, c := range suite.TestCases {
+				names = append(names, c.Name)
+				assert.Equal(t, "lobby", c.ClassName)
+				if c.Failure != nil {
+					assert.Equal(t, "Err: Expected 200, got 500", c.Failure.Message)
+					assert.Contains(t, c.Failure.Body, `RawData: {"code":500}`)
+				}
+			}
+			assert.Equal(t, table.names, names)
+		})
+	}
+}
+
+func TestNewJUnitReportInvalidCases(t *testing.T) {
+	_, err := NewJUnitReport("spec")
+	assert.EqualError(t, err, "Invalid junit test cases spec, use bot or operation")
+}
+
+func TestJUnitReportNil(t *testing.T) {
+	var report *JUnitReport
+	report.Add(&BotResult{Spec: "lobby"})
+	assert.NoError(t, report.Write(filepath.Join(os.TempDir(), "never-written.xml")))
+}
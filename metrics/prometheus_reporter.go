@@ -15,15 +15,44 @@ var (
 	once               sync.Once
 )
 
+// metricLabels are the labels some metrics have besides the route and tags
+var metricLabels = map[string][]string{
+	ResponseTimeHistogram: {"status"},
+	ErrorCount:            {"type"},
+	RateLimitCount:        {"limiter"},
+}
+
+// defaultBuckets are the response time histogram buckets, in milliseconds,
+// used when none are configured
+var defaultBuckets = prometheus.ExponentialBuckets(1, 2, 15)
+
 // PrometheusReporter reports metrics to prometheus
 type PrometheusReporter struct {
 	game                  string
+	labels                []string
+	buckets               []float64
 	countReportersMap     map[string]*prometheus.CounterVec
 	summaryReportersMap   map[string]*prometheus.SummaryVec
 	histogramReportersMap map[string]*prometheus.HistogramVec
 	gaugeReportersMap     map[string]*prometheus.GaugeVec
 }
 
+// labelsOf are the labels metric is registered with
+func (p *PrometheusReporter) labelsOf(metric string) []string {
+	return append(append([]string{}, p.labels...), metricLabels[metric]...)
+}
+
+// withLabels fills the labels of metric missing from tags and drops unknown
+// ones, as prometheus requires the exact label set declared on registration
+func (p *PrometheusReporter) withLabels(metric string, tags map[string]string) prometheus.Labels {
+	labels := prometheus.Labels{}
+	for _, label := range p.labelsOf(metric) {
+		labels[label] = tags[label]
+	}
+
+	return labels
+}
+
 func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 	constLabels["game"] = p.game
 	constLabels["clientType"] = "pitaya-bot"
@@ -38,7 +67,7 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 			Objectives:  map[float64]float64{0.7: 0.02, 0.95: 0.005, 0.99: 0.001},
 			ConstLabels: constLabels,
 		},
-		[]string{"route"},
+		p.labels,
 	)
 
 	p.histogramReportersMap[ResponseTimeHistogram] = prometheus.NewHistogramVec(
@@ -46,10 +75,11 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
 			Subsystem:   "handler",
 			Name:        ResponseTimeHistogram,
-			Help:        "histogram of the time to process a msg in nanoseconds",
+			Help:        "histogram of the time to process a msg in milliseconds",
+			Buckets:     p.buckets,
 			ConstLabels: constLabels,
 		},
-		[]string{"route"},
+		p.labelsOf(ResponseTimeHistogram),
 	)
 
 	p.countReportersMap[ErrorCount] = prometheus.NewCounterVec(
@@ -57,10 +87,76 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
 			Subsystem:   "handler",
 			Name:        ErrorCount,
-			Help:        "the error count",
+			Help:        "the error count by type",
+			ConstLabels: constLabels,
+		},
+		p.labelsOf(ErrorCount),
+	)
+
+	p.countReportersMap[TimeoutCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        TimeoutCount,
+			Help:        "the request timeout count",
+			ConstLabels: constLabels,
+		},
+		p.labels,
+	)
+
+	p.countReportersMap[RetryCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        RetryCount,
+			Help:        "the request retry count",
+			ConstLabels: constLabels,
+		},
+		p.labels,
+	)
+
+	p.countReportersMap[RateLimitCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        RateLimitCount,
+			Help:        "the count of requests held back by a rate limiter",
+			ConstLabels: constLabels,
+		},
+		p.labelsOf(RateLimitCount),
+	)
+
+	p.countReportersMap[SLAViolationCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        SLAViolationCount,
+			Help:        "the count of operations slower than their max duration",
+			ConstLabels: constLabels,
+		},
+		p.labels,
+	)
+
+	p.countReportersMap[PushCount] = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "handler",
+			Name:        PushCount,
+			Help:        "the push count",
+			ConstLabels: constLabels,
+		},
+		p.labels,
+	)
+
+	p.gaugeReportersMap[ConnectedBots] = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace:   fmt.Sprintf("pitaya_bot_%s", p.game),
+			Subsystem:   "client",
+			Name:        ConnectedBots,
+			Help:        "the number of connected bots",
 			ConstLabels: constLabels,
 		},
-		[]string{"route"},
+		p.labels,
 	)
 
 	toRegister := make([]prometheus.Collector, 0)
@@ -76,6 +172,10 @@ func (p *PrometheusReporter) registerMetrics(constLabels map[string]string) {
 		toRegister = append(toRegister, c)
 	}
 
+	for _, c := range p.histogramReportersMap {
+		toRegister = append(toRegister, c)
+	}
+
 	prometheus.MustRegister(toRegister...)
 }
 
@@ -87,10 +187,25 @@ func metricsReporterHandler(prometheusHandler http.Handler, postHandlerAction fu
 }
 
 // GetPrometheusReporter gets the prometheus reporter singleton
-func GetPrometheusReporter(game string, port int, constLabels map[string]string, postMetricsScrapeAction func()) *PrometheusReporter {
+// tags are the operation tag keys reported as extra labels besides the route
+// and buckets the response time histogram buckets, in milliseconds
+func GetPrometheusReporter(game string, port int, constLabels map[string]string, tags []string, buckets []float64, postMetricsScrapeAction func()) *PrometheusReporter {
 	once.Do(func() {
+		labels := []string{"route"}
+		for _, tag := range tags {
+			if tag != "route" {
+				labels = append(labels, tag)
+			}
+		}
+
+		if len(buckets) == 0 {
+			buckets = defaultBuckets
+		}
+
 		prometheusReporter = &PrometheusReporter{
 			game:                  game,
+			labels:                labels,
+			buckets:               buckets,
 			countReportersMap:     make(map[string]*prometheus.CounterVec),
 			summaryReportersMap:   make(map[string]*prometheus.SummaryVec),
 			histogramReportersMap: make(map[string]*prometheus.HistogramVec),
@@ -113,7 +228,7 @@ func GetPrometheusReporter(game string, port int, constLabels map[string]string,
 func (p *PrometheusReporter) ReportSummary(metric string, labels map[string]string, value float64) error {
 	sum := p.summaryReportersMap[metric]
 	if sum != nil {
-		sum.With(labels).Observe(value)
+		sum.With(p.withLabels(metric, labels)).Observe(value)
 		return nil
 	}
 	return constants.ErrMetricNotKnown
@@ -124,7 +239,7 @@ func (p *PrometheusReporter) ReportSummary(metric string, labels map[string]stri
 func (p *PrometheusReporter) ReportHistogram(metric string, labels map[string]string, value float64) error {
 	sum := p.histogramReportersMap[metric]
 	if sum != nil {
-		sum.With(labels).Observe(value)
+		sum.With(p.withLabels(metric, labels)).Observe(value)
 		return nil
 	}
 	return constants.ErrMetricNotKnown
@@ -135,7 +250,7 @@ func (p *PrometheusReporter) ReportHistogram(metric string, labels map[string]st
 func (p *PrometheusReporter) ReportCount(metric string, labels map[string]string, count float64) error {
 	cnt := p.countReportersMap[metric]
 	if cnt != nil {
-		cnt.With(labels).Add(count)
+		cnt.With(p.withLabels(metric, labels)).Add(count)
 		return nil
 	}
 	return constants.ErrMetricNotKnown
@@ -146,7 +261,7 @@ func (p *PrometheusReporter) ReportCount(metric string, labels map[string]string
 func (p *PrometheusReporter) ReportGauge(metric string, labels map[string]string, value float64) error {
 	g := p.gaugeReportersMap[metric]
 	if g != nil {
-		g.With(labels).Set(value)
+		g.With(p.withLabels(metric, labels)).Set(value)
 		return nil
 	}
 	return constants.ErrMetricNotKnown
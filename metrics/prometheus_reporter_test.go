@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLabels(t *testing.T) {
+	p := &PrometheusReporter{labels: []string{"route", "mode"}}
+	tags := map[string]string{"route": "room.join", "status": "ok", "type": "response", "limiter": "bot", "other": "x"}
+
+	tables := []struct {
+		metric string
+		labels prometheus.Labels
+	}{
+		{ResponseTime, prometheus.Labels{"route": "room.join", "mode": ""}},
+		{ResponseTimeHistogram, prometheus.Labels{"route": "room.join", "mode": "", "status": "ok"}},
+		{ErrorCount, prometheus.Labels{"route": "room.join", "mode": "", "type": "response"}},
+		{RateLimitCount, prometheus.Labels{"route": "room.join", "mode": "", "limiter": "bot"}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.metric, func(t *testing.T) {
+			assert.Equal(t, table.labels, p.withLabels(table.metric, tags))
+		})
+	}
+}
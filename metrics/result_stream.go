@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Result kinds
+const (
+	OperationResultKind = "operation"
+	BotResultKind       = "bot"
+)
+
+// OperationResult is the outcome of a single bot operation
+type OperationResult struct {
+	Kind       string    `json:"kind"`
+	Time       time.Time `json:"time"`
+	Spec       string    `json:"spec"`
+	Bot        int       `json:"bot"`
+	Index      int       `json:"index"`
+	Type       string    `json:"type"`
+	URI        string    `json:"uri"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	// SLAViolation is set when the operation succeeded but took longer
+	// than its maxDurationMs
+	SLAViolation bool `json:"slaViolation,omitempty"`
+}
+
+// BotResult is the outcome of a whole bot run along with its connection
+// stats
+type BotResult struct {
+	Kind       string    `json:"kind"`
+	Time       time.Time `json:"time"`
+	Spec       string    `json:"spec"`
+	Bot        int       `json:"bot"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+	// Combination is the matrix combination run by the bot
+	Combination string `json:"combination,omitempty"`
+	ConnectionStats
+}
+
+// ResultStream appends operation results to a file as newline delimited
+// json while the run goes on, each line is written at once so a crash only
+// loses the operations not finished yet
+type ResultStream struct {
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewResultStream creates or appends to the file at path
+func NewResultStream(path string) (*ResultStream, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open result stream %s: %s", path, err)
+	}
+
+	return &ResultStream{file: file}, nil
+}
+
+// Write appends result, an OperationResult or BotResult, to the stream, it
+// is a noop on a nil stream
+func (s *ResultStream) Write(result interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.file.Write(line)
+	return err
+}
+
+// Close closes the stream file
+func (s *ResultStream) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultStream(t *testing.T) {
+	dir, err := ioutil.TempDir("", "result-stream")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "results.ndjson")
+
+	stream, err := NewResultStream(path)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.Write(&OperationResult{Spec: "a", Index: 0, Type: "request", URI: "room.join"}))
+	assert.NoError(t, stream.Write(&OperationResult{Spec: "a", Index: 1, Type: "listen", URI: "room.joined", Error: "timeout"}))
+
+	// written lines are readable before the stream is closed
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	results := make([]*OperationResult, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var result OperationResult
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &result))
+		results = append(results, &result)
+	}
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "room.join", results[0].URI)
+	assert.Equal(t, "timeout", results[1].Error)
+	assert.NoError(t, stream.Close())
+}
@@ -0,0 +1,351 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// RunSummary aggregates the metrics of a run, by route, into its end of run
+// report. It is a Reporter, so it sees every metric the bots report
+type RunSummary struct {
+	mutex      sync.Mutex
+	routes     map[string]*routeStats
+	specs      map[string]*SpecReport
+	timeline   map[int64]*timelineStats
+	bots       int
+	failedBots int
+	connection ConnectionStats
+	paused     time.Duration
+}
+
+type routeStats struct {
+	latencies     []float64
+	timeouts      int
+	errors        int
+	errorTypes    map[string]int
+	pushes        int
+	slaViolations int
+}
+
+type timelineStats struct {
+	requests int
+	errors   int
+	total    float64
+	max      float64
+}
+
+// RouteReport is the summary of a route: the requests answered or timed
+// out, their latency percentiles in milliseconds, errors, pushes and the
+// operations slower than their maxDurationMs, which are not errors
+type RouteReport struct {
+	Route     string  `json:"route"`
+	Requests  int     `json:"requests"`
+	P50Ms     float64 `json:"p50Ms"`
+	P95Ms     float64 `json:"p95Ms"`
+	P99Ms     float64 `json:"p99Ms"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	Pushes    int     `json:"pushes"`
+	// SLAViolations counts the operations slower than their maxDurationMs
+	SLAViolations int `json:"slaViolations"`
+	// ErrorTypes counts the errors by type, timeouts included
+	ErrorTypes map[string]int `json:"errorTypes,omitempty"`
+}
+
+// SpecReport is the outcome of the bots of a spec, it passed when none of
+// them failed
+type SpecReport struct {
+	Spec       string `json:"spec"`
+	Bots       int    `json:"bots"`
+	FailedBots int    `json:"failedBots"`
+	Passed     bool   `json:"passed"`
+}
+
+// TimelinePoint is a second of the run, Second counted from the first one
+// with metrics. MeanMs and MaxMs are the response times of that second
+type TimelinePoint struct {
+	Second   int64   `json:"second"`
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	MeanMs   float64 `json:"meanMs"`
+	MaxMs    float64 `json:"maxMs"`
+}
+
+// RunReport is the end of run report. Total sums up every route. PausedMs
+// is how long the fleet was paused, within DurationMs
+type RunReport struct {
+	DurationMs    int64            `json:"durationMs"`
+	PausedMs      int64            `json:"pausedMs"`
+	Bots          int              `json:"bots"`
+	FailedBots    int              `json:"failedBots"`
+	BytesSent     int64            `json:"bytesSent"`
+	BytesReceived int64            `json:"bytesReceived"`
+	Total         *RouteReport     `json:"total"`
+	Routes        []*RouteReport   `json:"routes"`
+	Specs         []*SpecReport    `json:"specs"`
+	Timeline      []*TimelinePoint `json:"timeline"`
+}
+
+// NewRunSummary is the RunSummary constructor
+func NewRunSummary() *RunSummary {
+	return &RunSummary{
+		routes:   map[string]*routeStats{},
+		specs:    map[string]*SpecReport{},
+		timeline: map[int64]*timelineStats{},
+	}
+}
+
+func (s *RunSummary) route(tags map[string]string) *routeStats {
+	route := tags["route"]
+	stats, ok := s.routes[route]
+	if !ok {
+		stats = &routeStats{errorTypes: map[string]int{}}
+		s.routes[route] = stats
+	}
+
+	return stats
+}
+
+// second is the stats of the current second of the run
+func (s *RunSummary) second() *timelineStats {
+	now := time.Now().Unix()
+	stats, ok := s.timeline[now]
+	if !ok {
+		stats = &timelineStats{}
+		s.timeline[now] = stats
+	}
+
+	return stats
+}
+
+// AddBot counts a finished bot of spec and the bytes it exchanged
+func (s *RunSummary) AddBot(spec string, stats ConnectionStats, failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	specReport, ok := s.specs[spec]
+	if !ok {
+		specReport = &SpecReport{Spec: spec}
+		s.specs[spec] = specReport
+	}
+
+	s.bots++
+	specReport.Bots++
+	if failed {
+		s.failedBots++
+		specReport.FailedBots++
+	}
+	s.connection.BytesSent += stats.BytesSent
+	s.connection.BytesReceived += stats.BytesReceived
+}
+
+// ReportCount counts errors, timeouts, pushes and SLA violations
+//  - implements the ReportCount method of the Reporter interface
+func (s *RunSummary) ReportCount(metric string, tags map[string]string, count float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch metric {
+	case ErrorCount:
+		stats := s.route(tags)
+		stats.errors += int(count)
+		stats.errorTypes[tags["type"]] += int(count)
+		s.second().errors += int(count)
+	case TimeoutCount:
+		stats := s.route(tags)
+		stats.timeouts += int(count)
+		stats.errors += int(count)
+		stats.errorTypes["timeout"] += int(count)
+		s.second().errors += int(count)
+	case PushCount:
+		s.route(tags).pushes += int(count)
+	case SLAViolationCount:
+		s.route(tags).slaViolations += int(count)
+	}
+
+	return nil
+}
+
+// ReportSummary records response times
+//  - implements the ReportSummary method of the Reporter interface
+func (s *RunSummary) ReportSummary(metric string, tags map[string]string, value float64) error {
+	if metric != ResponseTime {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	stats := s.route(tags)
+	stats.latencies = append(stats.latencies, value)
+
+	second := s.second()
+	second.requests++
+	second.total += value
+	if value > second.max {
+		second.max = value
+	}
+	return nil
+}
+
+// ReportHistogram ignores histograms, ReportSummary already sees the same
+// response times
+//  - implements the ReportHistogram method of the Reporter interface
+func (s *RunSummary) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	return nil
+}
+
+// ReportGauge ignores gauges
+//  - implements the ReportGauge method of the Reporter interface
+func (s *RunSummary) ReportGauge(metric string, tags map[string]string, value float64) error {
+	return nil
+}
+
+// SetPaused sets how long the fleet was paused during the run
+func (s *RunSummary) SetPaused(paused time.Duration) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.paused = paused
+}
+
+// Report builds the report of a run that took duration
+func (s *RunSummary) Report(duration time.Duration) *RunReport {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &RunReport{
+		DurationMs:    duration.Nanoseconds() / 1e6,
+		PausedMs:      s.paused.Nanoseconds() / 1e6,
+		Bots:          s.bots,
+		FailedBots:    s.failedBots,
+		BytesSent:     s.connection.BytesSent,
+		BytesReceived: s.connection.BytesReceived,
+		Routes:        make([]*RouteReport, 0, len(s.routes)),
+		Specs:         make([]*SpecReport, 0, len(s.specs)),
+		Timeline:      make([]*TimelinePoint, 0, len(s.timeline)),
+	}
+
+	total := &routeStats{errorTypes: map[string]int{}}
+	for route, stats := range s.routes {
+		report.Routes = append(report.Routes, stats.report(route))
+
+		total.latencies = append(total.latencies, stats.latencies...)
+		total.timeouts += stats.timeouts
+		total.errors += stats.errors
+		total.pushes += stats.pushes
+		total.slaViolations += stats.slaViolations
+		for typ, count := range stats.errorTypes {
+			total.errorTypes[typ] += count
+		}
+	}
+	sort.Slice(report.Routes, func(i, j int) bool { return report.Routes[i].Route < report.Routes[j].Route })
+	report.Total = total.report("")
+
+	for _, spec := range s.specs {
+		r := *spec
+		r.Passed = r.FailedBots == 0
+		report.Specs = append(report.Specs, &r)
+	}
+	sort.Slice(report.Specs, func(i, j int) bool { return report.Specs[i].Spec < report.Specs[j].Spec })
+
+	seconds := make([]int64, 0, len(s.timeline))
+	for second := range s.timeline {
+		seconds = append(seconds, second)
+	}
+	sort.Slice(seconds, func(i, j int) bool { return seconds[i] < seconds[j] })
+	for _, second := range seconds {
+		stats := s.timeline[second]
+		point := &TimelinePoint{
+			Second:   second - seconds[0],
+			Requests: stats.requests,
+			Errors:   stats.errors,
+			MaxMs:    stats.max,
+		}
+		if stats.requests > 0 {
+			point.MeanMs = stats.total / float64(stats.requests)
+		}
+		report.Timeline = append(report.Timeline, point)
+	}
+
+	return report
+}
+
+// report builds the report of the route stats
+func (stats *routeStats) report(route string) *RouteReport {
+	latencies := append([]float64{}, stats.latencies...)
+	sort.Float64s(latencies)
+
+	r := &RouteReport{
+		Route:    route,
+		Requests: len(latencies) + stats.timeouts,
+		P50Ms:    percentile(latencies, 0.5),
+		P95Ms:    percentile(latencies, 0.95),
+		P99Ms:    percentile(latencies, 0.99),
+		Errors:   stats.errors,
+		Pushes:   stats.pushes,
+
+		SLAViolations: stats.slaViolations,
+	}
+	if len(stats.errorTypes) > 0 {
+		r.ErrorTypes = make(map[string]int, len(stats.errorTypes))
+		for typ, count := range stats.errorTypes {
+			r.ErrorTypes[typ] = count
+		}
+	}
+	if r.Requests > 0 {
+		r.ErrorRate = float64(r.Errors) / float64(r.Requests)
+	}
+
+	return r
+}
+
+// percentile returns the nearest rank percentile p of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// Print writes the report as a table
+func (r *RunReport) Print(w io.Writer) {
+	paused := ""
+	if r.PausedMs > 0 {
+		paused = fmt.Sprintf(" (paused %v)", time.Duration(r.PausedMs)*time.Millisecond)
+	}
+	fmt.Fprintf(w, "Run took %v%s: %d bots, %d failed, %d bytes sent, %d bytes received\n",
+		time.Duration(r.DurationMs)*time.Millisecond, paused, r.Bots, r.FailedBots, r.BytesSent, r.BytesReceived)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ROUTE\tREQUESTS\tP50 MS\tP95 MS\tP99 MS\tERRORS\tERROR RATE\tPUSHES\tSLA VIOLATIONS")
+	for _, route := range r.Routes {
+		fmt.Fprintf(tw, "%s\t%d\t%.0f\t%.0f\t%.0f\t%d\t%.2f%%\t%d\t%d\n",
+			route.Route, route.Requests, route.P50Ms, route.P95Ms, route.P99Ms, route.Errors, route.ErrorRate*100, route.Pushes, route.SLAViolations)
+	}
+	tw.Flush()
+}
+
+// Write writes the report as json to path
+func (r *RunReport) Write(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
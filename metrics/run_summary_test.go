@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunSummary(t *testing.T) {
+	summary := NewRunSummary()
+	join := map[string]string{"route": "room.join"}
+	for i := 1; i <= 100; i++ {
+		summary.ReportSummary(ResponseTime, join, float64(i))
+		summary.ReportHistogram(ResponseTimeHistogram, join, float64(i))
+	}
+	summary.ReportCount(ErrorCount, join, 4)
+	summary.ReportCount(TimeoutCount, join, 1)
+	summary.ReportCount(PushCount, map[string]string{"route": "room.joined"}, 3)
+	summary.ReportCount(SLAViolationCount, join, 2)
+	summary.AddBot("lobby", ConnectionStats{BytesSent: 10, BytesReceived: 20}, false)
+	summary.AddBot("lobby", ConnectionStats{BytesSent: 5, BytesReceived: 7}, true)
+	summary.SetPaused(500 * time.Millisecond)
+
+	report := summary.Report(2 * time.Second)
+	assert.Equal(t, int64(2000), report.DurationMs)
+	assert.Equal(t, int64(500), report.PausedMs)
+	assert.Equal(t, 2, report.Bots)
+	assert.Equal(t, 1, report.FailedBots)
+	assert.Equal(t, int64(15), report.BytesSent)
+	assert.Equal(t, int64(27), report.BytesReceived)
+
+	assert.Len(t, report.Routes, 2)
+	route := report.Routes[0]
+	assert.Equal(t, "room.join", route.Route)
+	assert.Equal(t, 101, route.Requests)
+	assert.Equal(t, float64(50), route.P50Ms)
+	assert.Equal(t, float64(95), route.P95Ms)
+	assert.Equal(t, float64(99), route.P99Ms)
+	assert.Equal(t, 5, route.Errors)
+	assert.Equal(t, map[string]int{"": 4, "timeout": 1}, route.ErrorTypes)
+	assert.InDelta(t, 5.0/101, route.ErrorRate, 1e-9)
+
+	assert.Equal(t, "room.joined", report.Routes[1].Route)
+	assert.Equal(t, 3, report.Routes[1].Pushes)
+	assert.Equal(t, 0, report.Routes[1].Requests)
+	assert.Equal(t, float64(0), report.Routes[1].ErrorRate)
+
+	assert.Equal(t, 101, report.Total.Requests)
+	assert.Equal(t, float64(95), report.Total.P95Ms)
+	assert.Equal(t, 5, report.Total.Errors)
+	assert.Equal(t, 3, report.Total.Pushes)
+	assert.Equal(t, 2, report.Total.SLAViolations)
+
+	assert.Equal(t, []*SpecReport{{Spec: "lobby", Bots: 2, FailedBots: 1, Passed: false}}, report.Specs)
+
+	requests := 0
+	for _, point := range report.Timeline {
+		requests += point.Requests
+		assert.True(t, point.MaxMs >= point.MeanMs)
+	}
+	assert.Equal(t, 100, requests)
+	assert.Equal(t, int64(0), report.Timeline[0].Second)
+
+	var out bytes.Buffer
+	report.Print(&out)
+	assert.Contains(t, out.String(), "Run took 2s (paused 500ms): 2 bots, 1 failed")
+	assert.Regexp(t, `room\.join +101 +50 +95 +99 +5 +4\.95% +0 +2`, out.String())
+
+	dir, err := ioutil.TempDir("", "run-summary")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "summary.json")
+
+	assert.NoError(t, report.Write(path))
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	var written RunReport
+	assert.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, *report.Routes[0], *written.Routes[0])
+	assert.Equal(t, int64(500), written.PausedMs)
+}
+
+func TestPercentile(t *testing.T) {
+	tables := map[string]struct {
+		values   []float64
+		p        float64
+		expected float64
+	}{
+		"empty":  {nil, 0.5, 0},
+		"single": {[]float64{7}, 0.99, 7},
+		"median": {[]float64{1, 2, 3, 4}, 0.5, 2},
+		"tail":   {[]float64{1, 2, 3, 4}, 0.99, 4},
+	}
+
+	for name, table := range tables {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, table.expected, percentile(table.values, table.p))
+		})
+	}
+}
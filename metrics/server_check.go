@@ -0,0 +1,203 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerMetricThreshold is how much a server metric may grow during a run.
+// All series of Metric matching Labels are summed
+type ServerMetricThreshold struct {
+	Metric      string            `mapstructure:"metric"`
+	Labels      map[string]string `mapstructure:"labels"`
+	MaxIncrease float64           `mapstructure:"maxIncrease"`
+}
+
+// ServerMetricsCheck scrapes the server prometheus endpoint before and
+// after a run, failing if any metric grew beyond its threshold
+type ServerMetricsCheck struct {
+	url        string
+	thresholds []ServerMetricThreshold
+	baseline   []float64
+	client     *http.Client
+}
+
+type metricSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// NewServerMetricsCheck is the ServerMetricsCheck constructor
+func NewServerMetricsCheck(url string, thresholds []ServerMetricThreshold) *ServerMetricsCheck {
+	return &ServerMetricsCheck{
+		url:        url,
+		thresholds: thresholds,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Start scrapes the baseline values
+func (c *ServerMetricsCheck) Start() error {
+	values, err := c.scrape()
+	if err != nil {
+		return err
+	}
+
+	c.baseline = values
+	return nil
+}
+
+// Verify scrapes the metrics again and compares them with the baseline
+func (c *ServerMetricsCheck) Verify() error {
+	values, err := c.scrape()
+	if err != nil {
+		return err
+	}
+
+	violations := make([]string, 0)
+	for i, threshold := range c.thresholds {
+		increase := values[i] - c.baseline[i]
+		if increase > threshold.MaxIncrease {
+			violations = append(violations, fmt.Sprintf("%s increased by %v (max %v)",
+				threshold.Metric, increase, threshold.MaxIncrease))
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("Server metrics check failed: %s", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
+
+// scrape returns the current value of each threshold metric
+func (c *ServerMetricsCheck) scrape() ([]float64, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to scrape server metrics: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Unable to scrape server metrics: status %d", resp.StatusCode)
+	}
+
+	samples, err := parseMetrics(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(c.thresholds))
+	for i, threshold := range c.thresholds {
+		for _, sample := range samples {
+			if sample.matches(threshold) {
+				values[i] += sample.value
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func (s *metricSample) matches(threshold ServerMetricThreshold) bool {
+	if s.name != threshold.Metric {
+		return false
+	}
+
+	for k, v := range threshold.Labels {
+		if s.labels[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// parseMetrics parses the prometheus text exposition format
+func parseMetrics(r io.Reader) ([]*metricSample, error) {
+	samples := make([]*metricSample, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples, scanner.Err()
+}
+
+func parseSample(line string) (*metricSample, error) {
+	sample := &metricSample{labels: map[string]string{}}
+
+	rest := line
+	if idx := strings.IndexAny(line, "{ "); idx >= 0 && line[idx] == '{' {
+		sample.name = line[:idx]
+		end := strings.LastIndex(line, "}")
+		if end < idx {
+			return nil, fmt.Errorf("Malformed metric line: %s", line)
+		}
+
+		if err := parseLabels(line[idx+1:end], sample.labels); err != nil {
+			return nil, fmt.Errorf("Malformed metric line %s: %s", line, err)
+		}
+		rest = line[end+1:]
+	} else {
+		fields := strings.Fields(line)
+		sample.name = fields[0]
+		rest = strings.TrimPrefix(line, fields[0])
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("Malformed metric line: %s", line)
+	}
+
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("Malformed metric value in line %s: %s", line, err)
+	}
+	sample.value = value
+
+	return sample, nil
+}
+
+func parseLabels(raw string, labels map[string]string) error {
+	for len(strings.TrimSpace(raw)) > 0 {
+		raw = strings.TrimLeft(raw, " ,")
+		eq := strings.Index(raw, "=")
+		if eq < 0 || len(raw) < eq+2 || raw[eq+1] != '"' {
+			return fmt.Errorf("malformed labels")
+		}
+
+		name := strings.TrimSpace(raw[:eq])
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(raw) && raw[i] != '"'; i++ {
+			if raw[i] == '\\' && i+1 < len(raw) {
+				i++
+			}
+			value.WriteByte(raw[i])
+		}
+		if i >= len(raw) {
+			return fmt.Errorf("unterminated label value")
+		}
+
+		labels[name] = value.String()
+		raw = raw[i+1:]
+	}
+
+	return nil
+}
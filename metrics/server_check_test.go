@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMetrics(t *testing.T) {
+	body := `# HELP errors_total Errors
+# TYPE errors_total counter
+errors_total{route="room.join",code="PIT-500"} 3
+errors_total{route="room.leave",code="PIT-500"} 2 1600000000000
+up 1
+`
+	samples, err := parseMetrics(strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Len(t, samples, 3)
+	assert.Equal(t, "errors_total", samples[0].name)
+	assert.Equal(t, map[string]string{"route": "room.join", "code": "PIT-500"}, samples[0].labels)
+	assert.Equal(t, float64(3), samples[0].value)
+	assert.Equal(t, float64(2), samples[1].value)
+	assert.Equal(t, "up", samples[2].name)
+	assert.Equal(t, float64(1), samples[2].value)
+}
+
+func TestServerMetricsCheck(t *testing.T) {
+	tables := []struct {
+		name      string
+		threshold ServerMetricThreshold
+		after     string
+		err       bool
+	}{
+		{"within threshold", ServerMetricThreshold{Metric: "errors_total", MaxIncrease: 2}, "1", false},
+		{"above threshold", ServerMetricThreshold{Metric: "errors_total", MaxIncrease: 2}, "5", true},
+		{"other labels ignored", ServerMetricThreshold{Metric: "errors_total", Labels: map[string]string{"route": "b"}}, "5", false},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			value := "0"
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "errors_total{route=\"a\"} %s\nerrors_total{route=\"b\"} 1\n", value)
+			}))
+			defer server.Close()
+
+			check := NewServerMetricsCheck(server.URL, []ServerMetricThreshold{table.threshold})
+			assert.NoError(t, check.Start())
+
+			value = table.after
+			err := check.Verify()
+			if table.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
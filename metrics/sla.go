@@ -0,0 +1,193 @@
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	slaAssertionExpr = regexp.MustCompile(`^\s*([a-zA-Z0-9]+)(?:\[([^\]]+)\])?\s*(<=|>=|==|!=|<|>)\s*(.+?)\s*$`)
+	slaOperandExpr   = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9]*)(?:\[([^\]]+)\])?$`)
+	slaValueExpr     = regexp.MustCompile(`^(-?[0-9]*\.?[0-9]+)(ms|s|%)?$`)
+)
+
+// slaMetrics are the metrics SLA assertions may compare, latencies in
+// milliseconds and rates between 0 and 1
+var slaMetrics = map[string]func(r *RunReport, route *RouteReport) float64{
+	"requestsSent":   func(r *RunReport, route *RouteReport) float64 { return float64(route.Requests) },
+	"errors":         func(r *RunReport, route *RouteReport) float64 { return float64(route.Errors) },
+	"errorRate":      func(r *RunReport, route *RouteReport) float64 { return route.ErrorRate },
+	"timeouts":       func(r *RunReport, route *RouteReport) float64 { return float64(route.ErrorTypes["timeout"]) },
+	"p50Latency":     func(r *RunReport, route *RouteReport) float64 { return route.P50Ms },
+	"p95Latency":     func(r *RunReport, route *RouteReport) float64 { return route.P95Ms },
+	"p99Latency":     func(r *RunReport, route *RouteReport) float64 { return route.P99Ms },
+	"pushesReceived": func(r *RunReport, route *RouteReport) float64 { return float64(route.Pushes) },
+	"slaViolations":  func(r *RunReport, route *RouteReport) float64 { return float64(route.SLAViolations) },
+	"bots":           func(r *RunReport, route *RouteReport) float64 { return float64(r.Bots) },
+	"failedBots":     func(r *RunReport, route *RouteReport) float64 { return float64(r.FailedBots) },
+}
+
+// slaOperand is a side of an SLA assertion, a metric, of Route or of the
+// whole run when empty, or a constant Value
+type slaOperand struct {
+	Metric string
+	Route  string
+	Value  float64
+}
+
+func parseSLAOperand(metric, route string) (*slaOperand, error) {
+	if _, ok := slaMetrics[metric]; !ok {
+		return nil, fmt.Errorf("Unknown SLA metric %s", metric)
+	}
+	return &slaOperand{Metric: metric, Route: route}, nil
+}
+
+// value returns the operand value in report
+func (o *slaOperand) value(report *RunReport) float64 {
+	if o.Metric == "" {
+		return o.Value
+	}
+
+	route := report.Total
+	if o.Route != "" {
+		route = &RouteReport{Route: o.Route}
+		for _, r := range report.Routes {
+			if r.Route == o.Route {
+				route = r
+			}
+		}
+	}
+	if route == nil {
+		route = &RouteReport{}
+	}
+
+	return slaMetrics[o.Metric](report, route)
+}
+
+func (o *slaOperand) String() string {
+	if o.Metric == "" {
+		return strconv.FormatFloat(o.Value, 'f', -1, 64)
+	}
+	if o.Route != "" {
+		return fmt.Sprintf("%s[%s]", o.Metric, o.Route)
+	}
+	return o.Metric
+}
+
+// SLAAssertion compares a run metric to a constant or to another metric
+type SLAAssertion struct {
+	expr     string
+	left     *slaOperand
+	operator string
+	right    *slaOperand
+}
+
+// ParseSLAAssertion parses assertions like p95Latency < 200ms,
+// errorRate < 1% or pushesReceived >= requestsSent. Metrics are of the
+// whole run, or of a route written in brackets, as in
+// p99Latency[room.join] <= 1s. Durations are in ms or s, rates in % or
+// between 0 and 1
+func ParseSLAAssertion(expr string) (*SLAAssertion, error) {
+	m := slaAssertionExpr.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("Invalid SLA assertion %q", expr)
+	}
+
+	left, err := parseSLAOperand(m[1], m[2])
+	if err != nil {
+		return nil, fmt.Errorf("Invalid SLA assertion %q: %s", expr, err)
+	}
+
+	a := &SLAAssertion{expr: strings.TrimSpace(expr), left: left, operator: m[3]}
+	if v := slaValueExpr.FindStringSubmatch(m[4]); v != nil {
+		value, _ := strconv.ParseFloat(v[1], 64)
+		switch v[2] {
+		case "s":
+			value *= 1000
+		case "%":
+			value /= 100
+		}
+		a.right = &slaOperand{Value: value}
+		return a, nil
+	}
+
+	o := slaOperandExpr.FindStringSubmatch(m[4])
+	if o == nil {
+		return nil, fmt.Errorf("Invalid SLA assertion %q", expr)
+	}
+	if a.right, err = parseSLAOperand(o[1], o[2]); err != nil {
+		return nil, fmt.Errorf("Invalid SLA assertion %q: %s", expr, err)
+	}
+	return a, nil
+}
+
+// Check returns an error when report violates the assertion
+func (a *SLAAssertion) Check(report *RunReport) error {
+	left, right := a.left.value(report), a.right.value(report)
+
+	var ok bool
+	switch a.operator {
+	case "<":
+		ok = left < right
+	case "<=":
+		ok = left <= right
+	case ">":
+		ok = left > right
+	case ">=":
+		ok = left >= right
+	case "==":
+		ok = left == right
+	case "!=":
+		ok = left != right
+	}
+	if ok {
+		return nil
+	}
+
+	if a.right.Metric != "" {
+		return fmt.Errorf("%s (%s was %v, %s was %v)", a.expr, a.left, left, a.right, right)
+	}
+	return fmt.Errorf("%s (%s was %v)", a.expr, a.left, left)
+}
+
+// SLACheck verifies a run report meets every SLA assertion
+type SLACheck struct {
+	assertions []*SLAAssertion
+}
+
+// NewSLACheck parses the SLA assertions, nil when there are none
+func NewSLACheck(exprs []string) (*SLACheck, error) {
+	if len(exprs) == 0 {
+		return nil, nil
+	}
+
+	c := &SLACheck{}
+	for _, expr := range exprs {
+		a, err := ParseSLAAssertion(expr)
+		if err != nil {
+			return nil, err
+		}
+		c.assertions = append(c.assertions, a)
+	}
+
+	return c, nil
+}
+
+// Verify checks the report against every assertion, listing all the
+// violated ones
+func (c *SLACheck) Verify(report *RunReport) error {
+	violations := make([]string, 0)
+	for _, a := range c.assertions {
+		if err := a.Check(report); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("SLA check failed: %s", strings.Join(violations, ", "))
+	}
+
+	return nil
+}
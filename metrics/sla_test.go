@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSLAAssertion(t *testing.T) {
+	tables := []struct {
+		expr  string
+		left  string
+		op    string
+		right string
+		err   string
+	}{
+		{"p95Latency < 200ms", "p95Latency", "<", "200", ""},
+		{"p99Latency[room.join] <= 1.5s", "p99Latency[room.join]", "<=", "1500", ""},
+		{"errorRate < 1%", "errorRate", "<", "0.01", ""},
+		{"pushesReceived >= requestsSent", "pushesReceived", ">=", "requestsSent", ""},
+		{"failedBots == 0", "failedBots", "==", "0", ""},
+		{"p90Latency < 200ms", "", "", "", `Invalid SLA assertion "p90Latency < 200ms": Unknown SLA metric p90Latency`},
+		{"errorRate ~ 1%", "", "", "", `Invalid SLA assertion "errorRate ~ 1%"`},
+		{"errorRate < 1h", "", "", "", `Invalid SLA assertion "errorRate < 1h"`},
+	}
+
+	for _, table := range tables {
+		t.Run(table.expr, func(t *testing.T) {
+			a, err := ParseSLAAssertion(table.expr)
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, table.left, a.left.String())
+			assert.Equal(t, table.op, a.operator)
+			assert.Equal(t, table.right, a.right.String())
+		})
+	}
+}
+
+func TestSLACheckVerify(t *testing.T) {
+	report := &RunReport{
+		Bots:       10,
+		FailedBots: 1,
+		Total:      &RouteReport{Requests: 200, P95Ms: 150, Errors: 4, ErrorRate: 0.02, Pushes: 150},
+		Routes: []*RouteReport{
+			{Route: "room.join", Requests: 100, P95Ms: 250, Errors: 4, ErrorRate: 0.04},
+			{Route: "room.leave", Requests: 100, P95Ms: 50, Pushes: 150},
+		},
+	}
+
+	tables := []struct {
+		name       string
+		assertions []string
+		err        string
+	}{
+		{"met", []string{"p95Latency < 200ms", "errorRate <= 2%", "bots > 5"}, ""},
+		{"route latency", []string{"p95Latency[room.leave] < 100ms", "p95Latency[room.join] < 200ms"},
+			"SLA check failed: p95Latency[room.join] < 200ms (p95Latency[room.join] was 250)"},
+		{"metric comparison", []string{"pushesReceived >= requestsSent"},
+			"SLA check failed: pushesReceived >= requestsSent (pushesReceived was 150, requestsSent was 200)"},
+		{"every violation", []string{"errorRate < 1%", "failedBots == 0"},
+			"SLA check failed: errorRate < 1% (errorRate was 0.02), failedBots == 0 (failedBots was 1)"},
+		{"unknown route", []string{"requestsSent[room.chat] > 0"},
+			"SLA check failed: requestsSent[room.chat] > 0 (requestsSent[room.chat] was 0)"},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			check, err := NewSLACheck(table.assertions)
+			assert.NoError(t, err)
+			err = check.Verify(report)
+			if table.err != "" {
+				assert.EqualError(t, err, table.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNoSLACheck(t *testing.T) {
+	check, err := NewSLACheck(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, check)
+}
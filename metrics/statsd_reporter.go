@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-go/statsd"
+)
+
+// StatsdReporter reports metrics to a statsd server, tags are sent as
+// dogstatsd tags
+type StatsdReporter struct {
+	client *statsd.Client
+	rate   float64
+}
+
+// NewStatsdReporter returns a reporter sending metrics to the statsd server
+// at address, prefixed by prefix and tagged with constTags. Rate is the
+// sample rate, 1 sends every metric
+func NewStatsdReporter(address, prefix string, constTags map[string]string, rate float64) (*StatsdReporter, error) {
+	client, err := statsd.New(address)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create statsd client for %s: %s", address, err)
+	}
+	client.Namespace = prefix
+	client.Tags = statsdTags(constTags)
+
+	if rate <= 0 {
+		rate = 1
+	}
+
+	return &StatsdReporter{client: client, rate: rate}, nil
+}
+
+// statsdTags formats tags as sorted key:value dogstatsd tags
+func statsdTags(tags map[string]string) []string {
+	formatted := make([]string, 0, len(tags))
+	for k, v := range tags {
+		formatted = append(formatted, fmt.Sprintf("%s:%s", k, v))
+	}
+	sort.Strings(formatted)
+
+	return formatted
+}
+
+// ReportCount reports a count metric
+//  - implements the ReportCount method of the Reporter interface
+func (s *StatsdReporter) ReportCount(metric string, tags map[string]string, count float64) error {
+	return s.client.Count(metric, int64(count), statsdTags(tags), s.rate)
+}
+
+// ReportSummary reports a summary metric as a histogram
+//  - implements the ReportSummary method of the Reporter interface
+func (s *StatsdReporter) ReportSummary(metric string, tags map[string]string, value float64) error {
+	return s.client.Histogram(metric, value, statsdTags(tags), s.rate)
+}
+
+// ReportHistogram reports a histogram metric
+//  - implements the ReportHistogram method of the Reporter interface
+func (s *StatsdReporter) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	return s.client.Histogram(metric, value, statsdTags(tags), s.rate)
+}
+
+// ReportGauge reports a gauge metric
+//  - implements the ReportGauge method of the Reporter interface
+func (s *StatsdReporter) ReportGauge(metric string, tags map[string]string, value float64) error {
+	return s.client.Gauge(metric, value, statsdTags(tags), s.rate)
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsdReporter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	r, err := NewStatsdReporter(conn.LocalAddr().String(), "bot.", map[string]string{"game": "poker"}, 1)
+	assert.NoError(t, err)
+	reporters := WithTags([]Reporter{r}, map[string]string{"spec": "login", "botType": "sequential"})
+
+	tables := []struct {
+		name   string
+		report func(Reporter) error
+		packet string
+	}{
+		{"count", func(r Reporter) error {
+			return r.ReportCount(ErrorCount, map[string]string{"route": "room.join", "type": "response"}, 1)
+		},
+			"bot.error_count:1|c|#game:poker,botType:sequential,route:room.join,spec:login,type:response"},
+		{"histogram", func(r Reporter) error {
+			return r.ReportHistogram(ResponseTimeHistogram, map[string]string{"route": "room.join"}, 12)
+		},
+			"bot.response_time_histogram_ms:12.000000|h|#game:poker,botType:sequential,route:room.join,spec:login"},
+		{"untagged gauge", func(r Reporter) error { return r.ReportGauge(ConnectedBots, nil, 3) },
+			"bot.connected_bots:3.000000|g|#game:poker"},
+	}
+
+	buf := make([]byte, 1024)
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			assert.NoError(t, table.report(reporters[0]))
+
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			assert.NoError(t, err)
+			assert.Equal(t, table.packet, strings.TrimSpace(string(buf[:n])))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package metrics
+
+// taggedReporter adds constant tags to the metrics it reports
+type taggedReporter struct {
+	Reporter
+	tags map[string]string
+}
+
+// WithTags wraps reporters so they add tags to every metric, unless the
+// metric sets them itself. Gauges, counting bots process wide, are left
+// untagged
+func WithTags(reporters []Reporter, tags map[string]string) []Reporter {
+	if len(reporters) == 0 {
+		return reporters
+	}
+
+	wrapped := make([]Reporter, len(reporters))
+	for i, r := range reporters {
+		wrapped[i] = &taggedReporter{Reporter: r, tags: tags}
+	}
+
+	return wrapped
+}
+
+func (t *taggedReporter) merge(tags map[string]string) map[string]string {
+	merged := make(map[string]string, len(tags)+len(t.tags))
+	for k, v := range t.tags {
+		merged[k] = v
+	}
+	for k, v := range tags {
+		merged[k] = v
+	}
+
+	return merged
+}
+
+func (t *taggedReporter) ReportCount(metric string, tags map[string]string, count float64) error {
+	return t.Reporter.ReportCount(metric, t.merge(tags), count)
+}
+
+func (t *taggedReporter) ReportSummary(metric string, tags map[string]string, value float64) error {
+	return t.Reporter.ReportSummary(metric, t.merge(tags), value)
+}
+
+func (t *taggedReporter) ReportHistogram(metric string, tags map[string]string, value float64) error {
+	return t.Reporter.ReportHistogram(metric, t.merge(tags), value)
+}
+
+func (t *taggedReporter) ReportGauge(metric string, tags map[string]string, value float64) error {
+	return t.Reporter.ReportGauge(metric, tags, value)
+}
+
+func (t *taggedReporter) ReportEvent(event string, tags map[string]string, fields map[string]interface{}) error {
+	if er, ok := t.Reporter.(EventReporter); ok {
+		return er.ReportEvent(event, t.merge(tags), fields)
+	}
+
+	return nil
+}
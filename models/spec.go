@@ -1,12 +1,224 @@
 package models
 
-// Spec defines the bots' spec
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Spec defines the bots' spec. MaxDuration caps, in milliseconds, how long
+// a single bot may take to run all of its operations. Macros are operation
+// sequences run by call operations. Matrix runs one bot per combination of
+// its values instead of NumberOfInstances bots, each combination is set in
+// the bot storage. OnResume operations run before a bot resumes from a
+// checkpoint, restoring the session state of its new connection. Shuffle
+// runs the operations in a random order honoring their dependsOn, seeded by
+// bot.seed plus the bot id. Cycle runs the operations in a loop instead of
+// once. Random replaces the sequential operations by randomly picked ones.
+// Feeder sets a row of a data file in the storage of each bot.
+// SetupOperations run before and TeardownOperations after the operations
+// of each bot, teardown even when they fail. Handshake overrides the
+// client.handshake fields it sets and Network the client.network ones. PushHandlers consume their routes in
+// the background for the whole run, listen operations must not wait on them.
+// Vars are referenced as ${vars.name}, the config vars override them and
+// the vars of an operation override both while it runs. Include fragments
+// run before the sequential operations. OnError is the default onError
+// policy of the sequential operations
 type Spec struct {
-	Name                 string              `json:"name"`
-	NumberOfInstances    int                 `json:"numberOfInstances"`
-	PreRun               *InitialDefinitions `json:"preRun,omitempty"`
-	SequentialOperations []*Operation        `json:"sequentialOperations,omitempty"`
-	PostRun              *FinalDefinitions   `json:"postRun,omitempty"`
+	Name                 string                   `json:"name,omitempty"`
+	NumberOfInstances    int                      `json:"numberOfInstances"`
+	MaxDuration          int                      `json:"maxDuration,omitempty"`
+	PreRun               *InitialDefinitions      `json:"preRun,omitempty"`
+	SequentialOperations []*Operation             `json:"sequentialOperations,omitempty"`
+	PostRun              *FinalDefinitions        `json:"postRun,omitempty"`
+	Macros               map[string][]*Operation  `json:"macros,omitempty"`
+	Matrix               map[string][]interface{} `json:"matrix,omitempty"`
+	OnResume             []*Operation             `json:"onResume,omitempty"`
+	Shuffle              bool                     `json:"shuffle,omitempty"`
+	Cycle                *CycleSpec               `json:"cycle,omitempty"`
+	Random               *RandomSpec              `json:"random,omitempty"`
+	Feeder               *FeederSpec              `json:"feeder,omitempty"`
+	SetupOperations      []*Operation             `json:"setupOperations,omitempty"`
+	TeardownOperations   []*Operation             `json:"teardownOperations,omitempty"`
+	Handshake            *HandshakeSpec           `json:"handshake,omitempty"`
+	Network              *NetworkSpec             `json:"network,omitempty"`
+	PushHandlers         []*PushHandlerSpec       `json:"pushHandlers,omitempty"`
+	Vars                 map[string]interface{}   `json:"vars,omitempty"`
+	Include              []*IncludeSpec           `json:"include,omitempty"`
+	OnError              string                   `json:"onError,omitempty"`
+}
+
+// NetworkSpec defines the network conditions bots connect through: a
+// profile, 3g, 4g or poorWifi, and the latency, jitter, bandwidth and loss
+// rate overriding it. Latency and jitter are one way, in milliseconds, and
+// bandwidth is in kilobits per second each way, 0 meaning no cap. LossRate
+// is the probability of a packet being lost, delaying the data it carries by
+// a TCP retransmission
+type NetworkSpec struct {
+	Profile       string  `json:"profile,omitempty"`
+	LatencyMs     int     `json:"latencyMs,omitempty"`
+	JitterMs      int     `json:"jitterMs,omitempty"`
+	BandwidthKbps int     `json:"bandwidthKbps,omitempty"`
+	LossRate      float64 `json:"lossRate,omitempty"`
+}
+
+// IncludeSpec is a spec fragment, File being relative to the including
+// spec. Its sequential operations run before the including spec ones, with
+// the fragment vars overridden by Vars, and its macros are added to the
+// including spec ones. It is written as the file path alone when without
+// vars
+type IncludeSpec struct {
+	File string                 `json:"file"`
+	Vars map[string]interface{} `json:"vars,omitempty"`
+}
+
+type includeSpec IncludeSpec
+
+// UnmarshalJSON reads the file path alone or the whole include
+func (i *IncludeSpec) UnmarshalJSON(data []byte) error {
+	var file string
+	if err := json.Unmarshal(data, &file); err == nil {
+		*i = IncludeSpec{File: file}
+		return nil
+	}
+
+	return json.Unmarshal(data, (*includeSpec)(i))
+}
+
+// MarshalJSON writes the file path alone when there are no vars
+func (i *IncludeSpec) MarshalJSON() ([]byte, error) {
+	if len(i.Vars) == 0 {
+		return json.Marshal(i.File)
+	}
+
+	return json.Marshal((*includeSpec)(i))
+}
+
+// PushHandlerSpec defines how the pushes on Route are handled: checked
+// against Expect, stored by Store and counted in the Count storage key.
+// They are handled before the operation following their arrival, failed
+// expectations fail the bot once its operations are done
+type PushHandlerSpec struct {
+	Route  string     `json:"route"`
+	Expect ExpectSpec `json:"expect,omitempty"`
+	Store  StoreSpec  `json:"store,omitempty"`
+	Count  string     `json:"count,omitempty"`
+}
+
+// HandshakeSpec is the client data sent in the pitaya handshake, servers
+// may reject old Version or BuildNumber values. User is custom data
+type HandshakeSpec struct {
+	Platform    string                 `json:"platform,omitempty"`
+	LibVersion  string                 `json:"libVersion,omitempty"`
+	BuildNumber string                 `json:"buildNumber,omitempty"`
+	Version     string                 `json:"version,omitempty"`
+	User        map[string]interface{} `json:"user,omitempty"`
+}
+
+// FeederSpec defines the csv file, with a header line, or json array of
+// objects whose rows are fed to the bots, relative to the spec file.
+// Strategy is how rows are assigned: cyclic (the default) wraps around
+// them, unique gives each bot its own row and fails once they run out and
+// random picks one, seeded like shuffled specs
+type FeederSpec struct {
+	File     string `json:"file"`
+	Strategy string `json:"strategy,omitempty"`
+}
+
+// CycleSpec defines how many times a cycle bot runs the sequential
+// operations, 0 loops until the spec maxDuration. ResetStorage restores the
+// storage the bot started with before each iteration, it is kept otherwise
+type CycleSpec struct {
+	Iterations   int  `json:"iterations,omitempty"`
+	ResetStorage bool `json:"resetStorage,omitempty"`
+}
+
+// RandomSpec defines the operations a random bot picks from, Steps times
+// or until the spec maxDuration when it is 0. Each operation is picked
+// proportionally to its weight, 1 by default, among the ones whose
+// preconditions hold
+type RandomSpec struct {
+	Steps      int          `json:"steps,omitempty"`
+	Operations []*Operation `json:"operations"`
+}
+
+// Combination is a set of matrix values
+type Combination map[string]interface{}
+
+// String returns the combination as sorted key=value pairs
+func (c Combination) String() string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, c[k])
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// Combinations returns the cross product of the matrix values, in a stable
+// order, or nil when the spec has no matrix
+func (s *Spec) Combinations() []Combination {
+	if len(s.Matrix) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(s.Matrix))
+	for k := range s.Matrix {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	combinations := []Combination{{}}
+	for _, k := range keys {
+		expanded := make([]Combination, 0, len(combinations)*len(s.Matrix[k]))
+		for _, c := range combinations {
+			for _, v := range s.Matrix[k] {
+				next := Combination{k: v}
+				for ck, cv := range c {
+					next[ck] = cv
+				}
+				expanded = append(expanded, next)
+			}
+		}
+		combinations = expanded
+	}
+
+	return combinations
+}
+
+// Combination returns the matrix combination run by the bot with the given
+// id, nil when the spec has no matrix
+func (s *Spec) Combination(id int) Combination {
+	combinations := s.Combinations()
+	if len(combinations) == 0 || id < 0 {
+		return nil
+	}
+
+	return combinations[id%len(combinations)]
+}
+
+// Instances returns how many bots run the spec
+func (s *Spec) Instances() int {
+	if len(s.Matrix) > 0 {
+		return len(s.Combinations())
+	}
+
+	return s.NumberOfInstances
+}
+
+// Suite defines operations run once per run, setup before any bot starts
+// and teardown after all of them finish. What setup stores is available to
+// the storage of every bot
+type Suite struct {
+	Setup    []*Operation `json:"setup,omitempty"`
+	Teardown []*Operation `json:"teardown,omitempty"`
 }
 
 // InitialDefinitions are set before running each bot
@@ -19,31 +231,322 @@ type FinalDefinitions struct {
 	Function string `json:"function,omitempty"`
 }
 
-// StoreSpecEntry ...
+// StoreSpecEntry stores the response value at Value, a dotted field path,
+// a meta. expression or a JSONPath expression such as
+// $.items[?(@.type=='sword')].id. Transform lists the transformations
+// applied, in order, before storing it: toString, toInt, index:N and
+// template:TEXT, where {{value}} is the value and {{key}} a stored one
 type StoreSpecEntry struct {
-	Type  string `json:"type"`
-	Value string `json:"value"`
+	Type      string   `json:"type"`
+	Value     string   `json:"value"`
+	Transform []string `json:"transform,omitempty"`
 }
 
 // StoreSpec ...
 type StoreSpec map[string]StoreSpecEntry
 
 // ExpectSpecEntry ...
+// ExactKeys, used with the object type, asserts the object has exactly those
+// keys, Value may then be omitted. State and Host are used by the connection
+// type, which asserts the bot connection state after function operations.
+// Format and WithinLast are used by the datetime type. Capture stores the
+// actual value under that name once it matches, Value may then be omitted to
+// only check the type. Capturing expectations are validated first so later
+// ones can refer to what they stored. Set and Unset list the flags the
+// flags type expects on or off in an integer bitfield. Cases, used by the
+// discriminator type, maps each value of the field to the expectations
+// responses of that kind must meet. The sameAs type deep compares the value
+// with the Stored one, leaving out the Ignore key paths. Value may also be
+// an operator expression instead of a literal: $gt, $gte, $lt, $lte, $ne,
+// $in, $regex, $exists and $length, e.g. {"$gt": 100}. The schema type
+// validates the whole value against Schema, a JSON Schema or the path of
+// one, or against the Message protobuf type of expect.descriptors
 type ExpectSpecEntry struct {
-	Type  string      `json:"type"`
-	Value interface{} `json:"value"`
+	Type       string                `json:"type"`
+	Value      interface{}           `json:"value,omitempty"`
+	ExactKeys  []string              `json:"exactKeys,omitempty"`
+	State      string                `json:"state,omitempty"`
+	Host       string                `json:"host,omitempty"`
+	Format     string                `json:"format,omitempty"`
+	WithinLast string                `json:"withinLast,omitempty"`
+	Capture    string                `json:"capture,omitempty"`
+	Set        []string              `json:"set,omitempty"`
+	Unset      []string              `json:"unset,omitempty"`
+	Cases      map[string]ExpectSpec `json:"cases,omitempty"`
+	Stored     string                `json:"stored,omitempty"`
+	Ignore     []string              `json:"ignore,omitempty"`
+	Schema     interface{}           `json:"schema,omitempty"`
+	Message    string                `json:"message,omitempty"`
 }
 
-// ExpectSpec  ...
+// ExpectSpec maps response values, addressed like StoreSpecEntry values,
+// to their expectations
 type ExpectSpec map[string]ExpectSpecEntry
 
-// Operation defines an operation the bot may execute
+// CadenceSpec defines the expected interval between consecutive pushes,
+// all values are in milliseconds
+type CadenceSpec struct {
+	Interval  int `json:"interval"`
+	Tolerance int `json:"tolerance"`
+	Window    int `json:"window"`
+}
+
+// RetrySpec defines how many times a failed request is attempted, waiting
+// Backoff between attempts. RetryableErrors restricts which failures are
+// retried: transport errors containing one of them, case insensitive, and
+// responses whose pitaya error code equals one of them, or starts with it
+// when it ends with *, e.g. PIT-5*. Any transport error, and no error
+// response, is retried when it is empty
+type RetrySpec struct {
+	MaxAttempts     int          `json:"maxAttempts"`
+	Backoff         *BackoffSpec `json:"backoff,omitempty"`
+	RetryableErrors []string     `json:"retryableErrors,omitempty"`
+}
+
+// BackoffSpec defines an exponential backoff, in milliseconds, starting at
+// InitialMs and multiplied by Multiplier (2 by default) after each attempt
+// up to MaxMs, when set
+type BackoffSpec struct {
+	InitialMs  int     `json:"initialMs"`
+	MaxMs      int     `json:"maxMs,omitempty"`
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// Operation defines an operation the bot may execute. Timeout, in
+// milliseconds, is how long listen and request operations wait, requests
+// default to request.timeoutMs. Tags are reported as extra metric
+// dimensions, only the keys listed in the metrics.tags config are kept and
+// every distinct value creates a new time series, so values must come from a
+// small bounded set (feature names, never ids). DependsOn lists the
+// ids of the operations that must run before this one in shuffled specs.
+// AssertStorage expectations are checked against the bot storage, keyed by
+// storage key, once the operation and its store directives are done.
+// Weight and Preconditions, keyed by storage key too, are used by random bots.
+// ParallelOperations are run concurrently by parallel operations. PostDelay
+// is the think time waited after the operation, bot.pacingMs by default.
+// String args may embed ${store.key}, ${bot.id}, ${params.name} and
+// generator references, e.g. player_${store.userId}_${bot.id}, and the
+// $uuid, $randInt(min,max), $randString(n), $now and $botId functions,
+// either as the whole value or embedded as ${randInt(1,100)}. The
+// publishKey function publishes the key arg storage value, or the value
+// arg, to the storage shared by all bots and waitForKey blocks, up to
+// Timeout, until it is published, storing it under key or storeAs. The
+// barrier function blocks, up to Timeout, until the parties arg bots
+// reached the barrier named by the name arg. The simulateDisconnect
+// function drops the connection as a flaky network would and, with the
+// reconnect arg, connects again retrying as Retry says. Session names the
+// connection of the bot request, notify, listen, cadence, script and
+// connect, disconnect, reconnect and simulateDisconnect function
+// operations use, the main one when empty. Named sessions connect the
+// first time they are used. Fuzz operations send the malformed packet
+// described by Fuzz, expecting the server reaction it describes within
+// Timeout. Operations succeeding in more than MaxDurationMs are reported as
+// SLA violations, which do not fail them. OnError is what a failed
+// sequential operation does: abort the spec, the default, continue with the
+// next operation, retry, or retry(n), running it again up to 3, or n, times
+// in total waiting its retry backoff in between, or goto(label), carrying
+// on from the operation whose Label is label. Continued failures still fail
+// the bot once its operations end. The goto function jumps to the
+// sequential operation labeled by its label arg, from a sequential
+// operation or a condition, loop or call nested in one. Request and fuzz
+// operations with ExpectError need a pitaya error response, failing on
+// successful ones, Expect and Store then apply to the error response.
+// The markPushes function marks, under its name arg, the moment the
+// assertPushes operations of its session count pushes from
 type Operation struct {
+	ID      string                 `json:"id,omitempty"`
 	Type    string                 `json:"type"`
-	Timeout int                    `json:"timeout"`
-	URI     string                 `json:"uri"`
-	Args    map[string]interface{} `json:"args"`
-	Expect  ExpectSpec             `json:"expect"`
-	Store   StoreSpec              `json:"store"`
-	Change  map[string]interface{} `json:"change"`
+	Timeout int                    `json:"timeout,omitempty"`
+	URI     string                 `json:"uri,omitempty"`
+	Args    map[string]interface{} `json:"args,omitempty"`
+	Expect  ExpectSpec             `json:"expect,omitempty"`
+	Store   StoreSpec              `json:"store,omitempty"`
+	Change  map[string]interface{} `json:"change,omitempty"`
+	Cadence *CadenceSpec           `json:"cadence,omitempty"`
+	Tags    map[string]string      `json:"tags,omitempty"`
+	Retry   *RetrySpec             `json:"retry,omitempty"`
+	Vars    map[string]interface{} `json:"vars,omitempty"`
+	Session string                 `json:"session,omitempty"`
+
+	MaxDurationMs int    `json:"maxDurationMs,omitempty"`
+	OnError       string `json:"onError,omitempty"`
+	Label         string `json:"label,omitempty"`
+
+	PostDelay *DelaySpec  `json:"postDelay,omitempty"`
+	Listen    *ListenSpec `json:"listen,omitempty"`
+	Script    *ScriptSpec `json:"script,omitempty"`
+	HTTP      *HTTPSpec   `json:"http,omitempty"`
+	GRPC      *GRPCSpec   `json:"grpc,omitempty"`
+	Fuzz      *FuzzSpec   `json:"fuzz,omitempty"`
+
+	ExpectError  *ExpectErrorSpec  `json:"expectError,omitempty"`
+	AssertPushes *AssertPushesSpec `json:"assertPushes,omitempty"`
+
+	AssertStorage ExpectSpec `json:"assertStorage,omitempty"`
+	Weight        float64    `json:"weight,omitempty"`
+	Preconditions ExpectSpec `json:"preconditions,omitempty"`
+
+	ParallelOperations []*Operation   `json:"parallelOperations,omitempty"`
+	Loop               *LoopSpec      `json:"loop,omitempty"`
+	Condition          *ConditionSpec `json:"condition,omitempty"`
+
+	DependsOn    []string          `json:"dependsOn,omitempty"`
+	Correlation  *CorrelationSpec  `json:"correlation,omitempty"`
+	StateMachine *StateMachineSpec `json:"stateMachine,omitempty"`
+}
+
+// ExpectErrorSpec defines the pitaya error response an operation expects.
+// Code is its code, or a code prefix ending in *, as in PIT-4*, any code
+// when empty. Message is its exact msg and Metadata values its metadata
+// must hold
+type ExpectErrorSpec struct {
+	Code     string            `json:"code,omitempty"`
+	Message  string            `json:"message,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// AssertPushesSpec defines the pushes an assertPushes operation expects
+// on each of Routes since the Since marker, or since they started being
+// buffered when empty. With Ordered the pushes of each route must all have
+// arrived after those of the routes listed before it
+type AssertPushesSpec struct {
+	Since   string           `json:"since,omitempty"`
+	Ordered bool             `json:"ordered,omitempty"`
+	Routes  []*PushCountSpec `json:"routes"`
+}
+
+// PushCountSpec defines how many pushes are expected on Route: exactly
+// Count, or between Min and Max when either is set, Max 0 meaning no upper
+// bound
+type PushCountSpec struct {
+	Route string `json:"route"`
+	Count int    `json:"count"`
+	Min   int    `json:"min,omitempty"`
+	Max   int    `json:"max,omitempty"`
+}
+
+// ListenSpec defines the routes a listen operation waits pushes on,
+// instead of its uri, sharing its timeout. Mode is any, completing on the
+// first push, all (the default), needing one push per route in any order,
+// or ordered, needing them in the listed order
+type ListenSpec struct {
+	Mode   string         `json:"mode,omitempty"`
+	Routes []*ListenRoute `json:"routes"`
+}
+
+// ScriptSpec defines the Lua code a script operation runs, inline in
+// Source or read from File
+type ScriptSpec struct {
+	Source string `json:"source,omitempty"`
+	File   string `json:"file,omitempty"`
+}
+
+// HTTPSpec defines the method, GET by default, and headers of an http
+// operation request
+type HTTPSpec struct {
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// FuzzSpec defines the malformed packet a fuzz operation sends. Kind is
+// truncated, a request packet cut short of the length its header claims,
+// packetType, a packet of an unknown type, messageType, a message of an
+// unknown type, oversized, a request whose body has Size bytes, garbage,
+// Size random bytes, or malformedBody, a request whose body the server
+// serializer cannot decode. Outcome is the expected server reaction:
+// disconnect, the default, closing the connection, response, answering the
+// request with a response checked against the operation expectations, or
+// survive, keeping the connection open. Kind args instead sends Requests
+// well formed requests, 100 by default, each with the operation args with
+// one field mutated by one of Mutations: typeFlip, boundary, longString or
+// null, all of them by default. Seed makes the mutations reproducible
+type FuzzSpec struct {
+	Kind    string `json:"kind"`
+	Size    int    `json:"size,omitempty"`
+	Outcome string `json:"outcome,omitempty"`
+
+	Requests  int      `json:"requests,omitempty"`
+	Mutations []string `json:"mutations,omitempty"`
+	Seed      int64    `json:"seed,omitempty"`
+}
+
+// GRPCSpec overrides the grpc.address server and grpc.descriptors
+// descriptor set of a grpc operation. Metadata is sent with the call
+type GRPCSpec struct {
+	Address     string            `json:"address,omitempty"`
+	Descriptors string            `json:"descriptors,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+}
+
+// ListenRoute defines the expectations and store of the pushes on Route
+type ListenRoute struct {
+	Route  string     `json:"route"`
+	Expect ExpectSpec `json:"expect,omitempty"`
+	Store  StoreSpec  `json:"store,omitempty"`
+}
+
+// DelaySpec defines a delay of Ms milliseconds or, when MaxMs is set, drawn
+// uniformly between MinMs and MaxMs
+type DelaySpec struct {
+	Ms    int `json:"ms,omitempty"`
+	MinMs int `json:"minMs,omitempty"`
+	MaxMs int `json:"maxMs,omitempty"`
+}
+
+// LoopSpec defines the operations a loop repeats, at most Count times with
+// IntervalMs between iterations. Until, keyed by storage key, is checked
+// after each iteration and ends the loop once it holds
+type LoopSpec struct {
+	Count      int          `json:"count"`
+	IntervalMs int          `json:"intervalMs,omitempty"`
+	Operations []*Operation `json:"operations"`
+	Until      ExpectSpec   `json:"until,omitempty"`
+}
+
+// ConditionSpec defines the operations a condition runs, Then when the If
+// expectations hold and Else otherwise. Source is what If is checked
+// against, storage (the default, keyed by storage key) or response, the
+// last request or listen response
+type ConditionSpec struct {
+	Source string       `json:"source,omitempty"`
+	If     ExpectSpec   `json:"if"`
+	Then   []*Operation `json:"then,omitempty"`
+	Else   []*Operation `json:"else,omitempty"`
+}
+
+// CorrelationSpec links pushes to the requests that caused them. A request
+// registers the Value it sent (args.<field>) or received (response
+// expression) as an outstanding id of Group, a listen extracts Value from the
+// push and marks the matching outstanding id as handled. Type defaults to string
+type CorrelationSpec struct {
+	Group string `json:"group"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// StateMachineSpec defines a flow driven by pushes and request results.
+// Starting at Initial, each state runs its trigger and moves through the
+// first transition whose guard matches, until a terminal state is reached.
+// MaxTransitions guards against flows that never terminate
+type StateMachineSpec struct {
+	Initial        string                `json:"initial"`
+	Terminal       []string              `json:"terminal"`
+	MaxTransitions int                   `json:"maxTransitions"`
+	States         map[string]*StateSpec `json:"states"`
+}
+
+// StateSpec defines a state. Trigger is a request or listen operation whose
+// response is matched against the transitions guards
+type StateSpec struct {
+	Trigger     *Operation        `json:"trigger"`
+	Transitions []*TransitionSpec `json:"transitions"`
+}
+
+// TransitionSpec defines a transition to Target, taken when the trigger
+// response matches Guard. Expect and Store are applied when it is taken
+type TransitionSpec struct {
+	Guard  ExpectSpec `json:"guard,omitempty"`
+	Expect ExpectSpec `json:"expect,omitempty"`
+	Store  StoreSpec  `json:"store,omitempty"`
+	Target string     `json:"target"`
 }
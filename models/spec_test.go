@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpecCombinations(t *testing.T) {
+	spec := &Spec{
+		NumberOfInstances: 10,
+		Matrix: map[string][]interface{}{
+			"platform": {"ios", "android"},
+			"region":   {"us", "eu", "br"},
+		},
+	}
+
+	combinations := spec.Combinations()
+	assert.Len(t, combinations, 6)
+	assert.Equal(t, 6, spec.Instances())
+	assert.Equal(t, "platform=ios,region=us", combinations[0].String())
+	assert.Equal(t, "platform=ios,region=eu", combinations[1].String())
+	assert.Equal(t, "platform=android,region=br", combinations[5].String())
+	assert.Equal(t, combinations[1], spec.Combination(7))
+
+	noMatrix := &Spec{NumberOfInstances: 3}
+	assert.Nil(t, noMatrix.Combinations())
+	assert.Nil(t, noMatrix.Combination(0))
+	assert.Equal(t, 3, noMatrix.Instances())
+}
+
+func TestIncludeSpecJSON(t *testing.T) {
+	tables := []struct {
+		name    string
+		raw     string
+		include IncludeSpec
+	}{
+		{"path", `"common/login.json"`, IncludeSpec{File: "common/login.json"}},
+		{"with vars", `{"file":"common/login.json","vars":{"user":"admin"}}`,
+			IncludeSpec{File: "common/login.json", Vars: map[string]interface{}{"user": "admin"}}},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			var include IncludeSpec
+			assert.NoError(t, json.Unmarshal([]byte(table.raw), &include))
+			assert.Equal(t, table.include, include)
+
+			raw, err := json.Marshal(&include)
+			assert.NoError(t, err)
+			assert.Equal(t, table.raw, string(raw))
+		})
+	}
+}
+
+func TestSpecJSON(t *testing.T) {
+	raw := []byte(`
+# joins a room
+numberOfInstances: 2
+sequentialOperations:
+  - type: request
+    uri: room.room.join
+    expect:
+      "$response.code":
+        type: string
+        value: "200"
+`)
+
+	data, err := SpecJSON("join.yaml", raw)
+	assert.NoError(t, err)
+
+	var spec Spec
+	assert.NoError(t, json.Unmarshal(data, &spec))
+	assert.Equal(t, 2, spec.NumberOfInstances)
+	assert.Equal(t, "room.room.join", spec.SequentialOperations[0].URI)
+	assert.Equal(t, "200", spec.SequentialOperations[0].Expect["$response.code"].Value)
+
+	data, err = SpecJSON("join.json", []byte(`{}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+
+	_, err = SpecJSON("join.yml", []byte("a: [1"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// IsYAML returns whether the spec file at path is written in yaml
+func IsYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	}
+
+	return false
+}
+
+// SpecJSON returns the json of the spec file at path, converting it when
+// written in yaml so specs and suites are decoded the same way either way
+func SpecJSON(path string, raw []byte) ([]byte, error) {
+	if !IsYAML(path) {
+		return raw, nil
+	}
+
+	var value interface{}
+	if err := yaml.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("Malformed yaml %s: %s", path, err)
+	}
+
+	return json.Marshal(jsonValue(value))
+}
+
+// jsonValue converts the maps decoded from yaml, keyed by interface{}, to
+// maps keyed by string
+func jsonValue(value interface{}) interface{} {
+	switch val := value.(type) {
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			ret[fmt.Sprint(k)] = jsonValue(item)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for i, item := range val {
+			ret[i] = jsonValue(item)
+		}
+		return ret
+	}
+
+	return value
+}
@@ -0,0 +1,154 @@
+package recorder
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// pitaya packet types
+const (
+	handshakePacket    byte = 0x01
+	handshakeAckPacket byte = 0x02
+	heartbeatPacket    byte = 0x03
+	dataPacket         byte = 0x04
+	kickPacket         byte = 0x05
+)
+
+// pitaya message types
+const (
+	requestMessage  byte = 0x00
+	notifyMessage   byte = 0x01
+	responseMessage byte = 0x02
+	pushMessage     byte = 0x03
+)
+
+const (
+	packetHeadLength = 4
+
+	routeCompressedMask = 0x01
+	messageTypeMask     = 0x07
+	gzipMask            = 0x10
+	errorMask           = 0x20
+)
+
+// packet is a pitaya packet, raw holds it as read from the wire
+type packet struct {
+	typ  byte
+	data []byte
+	raw  []byte
+}
+
+// message is a decoded pitaya data packet
+type message struct {
+	typ   byte
+	id    uint
+	route string
+	data  []byte
+	err   bool
+}
+
+// readPacket reads the next packet from r: a type byte, the 3 bytes big
+// endian body length and the body
+func readPacket(r io.Reader) (*packet, error) {
+	head := make([]byte, packetHeadLength)
+	if _, err := io.ReadFull(r, head); err != nil {
+		return nil, err
+	}
+
+	if head[0] < handshakePacket || head[0] > kickPacket {
+		return nil, fmt.Errorf("Invalid packet type %d", head[0])
+	}
+
+	size := int(head[1])<<16 | int(head[2])<<8 | int(head[3])
+	raw := make([]byte, packetHeadLength+size)
+	copy(raw, head)
+	if _, err := io.ReadFull(r, raw[packetHeadLength:]); err != nil {
+		return nil, err
+	}
+
+	return &packet{typ: head[0], data: raw[packetHeadLength:], raw: raw}, nil
+}
+
+// decodeMessage decodes a data packet body. Compressed routes are looked up
+// in routes, the dictionary the server sent in its handshake
+func decodeMessage(data []byte, routes map[uint16]string) (*message, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Empty message")
+	}
+
+	flag := data[0]
+	offset := 1
+	m := &message{typ: (flag >> 1) & messageTypeMask, err: flag&errorMask == errorMask}
+	if m.typ > pushMessage {
+		return nil, fmt.Errorf("Invalid message type %d", m.typ)
+	}
+
+	if m.typ == requestMessage || m.typ == responseMessage {
+		var shift uint
+		for {
+			if offset >= len(data) {
+				return nil, errors.New("Truncated message id")
+			}
+			b := data[offset]
+			offset++
+			m.id |= uint(b&0x7f) << shift
+			if b < 0x80 {
+				break
+			}
+			shift += 7
+		}
+	}
+
+	if m.typ != responseMessage {
+		if flag&routeCompressedMask == routeCompressedMask {
+			if offset+2 > len(data) {
+				return nil, errors.New("Truncated message route")
+			}
+			code := binary.BigEndian.Uint16(data[offset:])
+			route, ok := routes[code]
+			if !ok {
+				return nil, fmt.Errorf("Unknown compressed route %d", code)
+			}
+			m.route = route
+			offset += 2
+		} else {
+			if offset >= len(data) {
+				return nil, errors.New("Truncated message route")
+			}
+			length := int(data[offset])
+			offset++
+			if offset+length > len(data) {
+				return nil, errors.New("Truncated message route")
+			}
+			m.route = string(data[offset : offset+length])
+			offset += length
+		}
+	}
+
+	m.data = data[offset:]
+	if flag&gzipMask == gzipMask {
+		inflated, err := inflate(m.data)
+		if err != nil {
+			return nil, err
+		}
+		m.data = inflated
+	}
+
+	return m, nil
+}
+
+// inflate decompresses the zlib data pitaya compresses messages with
+func inflate(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to inflate message: %s", err)
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,91 @@
+package recorder
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodePacket(typ byte, data []byte) []byte {
+	raw := []byte{typ, byte(len(data) >> 16), byte(len(data) >> 8), byte(len(data))}
+	return append(raw, data...)
+}
+
+// encodeMessage encodes a message with an uncompressed route
+func encodeMessage(typ byte, id uint, route string, data []byte) []byte {
+	raw := []byte{typ << 1}
+	if typ == requestMessage || typ == responseMessage {
+		for id >= 0x80 {
+			raw = append(raw, byte(id&0x7f)|0x80)
+			id >>= 7
+		}
+		raw = append(raw, byte(id))
+	}
+	if typ != responseMessage {
+		raw = append(raw, byte(len(route)))
+		raw = append(raw, route...)
+	}
+
+	return append(raw, data...)
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestReadPacket(t *testing.T) {
+	stream := append(encodePacket(heartbeatPacket, nil), encodePacket(dataPacket, []byte("body"))...)
+	r := bytes.NewReader(stream)
+
+	p, err := readPacket(r)
+	assert.NoError(t, err)
+	assert.Equal(t, heartbeatPacket, p.typ)
+	assert.Empty(t, p.data)
+
+	p, err = readPacket(r)
+	assert.NoError(t, err)
+	assert.Equal(t, dataPacket, p.typ)
+	assert.Equal(t, []byte("body"), p.data)
+	assert.Equal(t, stream[4:], p.raw)
+
+	_, err = readPacket(bytes.NewReader([]byte{0x09, 0, 0, 0}))
+	assert.Error(t, err)
+}
+
+func TestDecodeMessage(t *testing.T) {
+	routes := map[uint16]string{7: "room.room.join"}
+
+	tables := []struct {
+		name    string
+		data    []byte
+		message *message
+		err     bool
+	}{
+		{"request", encodeMessage(requestMessage, 300, "player.login", []byte(`{}`)), &message{typ: requestMessage, id: 300, route: "player.login", data: []byte(`{}`)}, false},
+		{"notify", encodeMessage(notifyMessage, 0, "room.ping", nil), &message{typ: notifyMessage, route: "room.ping", data: []byte{}}, false},
+		{"error response", append([]byte{responseMessage<<1 | errorMask, 5}, `{"code":"500"}`...), &message{typ: responseMessage, id: 5, data: []byte(`{"code":"500"}`), err: true}, false},
+		{"compressed route", []byte{pushMessage<<1 | routeCompressedMask, 0, 7, '{', '}'}, &message{typ: pushMessage, route: "room.room.join", data: []byte(`{}`)}, false},
+		{"gzipped data", append([]byte{pushMessage<<1 | gzipMask, 1, 'r'}, deflate([]byte(`{"a":1}`))...), &message{typ: pushMessage, route: "r", data: []byte(`{"a":1}`)}, false},
+		{"unknown compressed route", []byte{pushMessage<<1 | routeCompressedMask, 0, 8}, nil, true},
+		{"truncated route", []byte{notifyMessage << 1, 10, 'a'}, nil, true},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			m, err := decodeMessage(table.data, routes)
+			if table.err {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, table.message, m)
+		})
+	}
+}
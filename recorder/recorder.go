@@ -0,0 +1,221 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+// Options configures a Recorder. It listens on Listen and proxies every
+// client to Target, over websockets on Path when WebSocket is set and over
+// tcp otherwise. The spec of each session is written to the Out directory
+type Options struct {
+	Listen    string
+	Target    string
+	WebSocket bool
+	Path      string
+	Out       string
+}
+
+// Recorder is a proxy between real clients and a pitaya server, recording
+// each client session as a spec
+type Recorder struct {
+	options  Options
+	logger   logrus.FieldLogger
+	mutex    sync.Mutex
+	listener net.Listener
+	files    int
+}
+
+// NewRecorder returns a new recorder
+func NewRecorder(options Options, logger logrus.FieldLogger) *Recorder {
+	if options.Path == "" {
+		options.Path = "/"
+	}
+
+	return &Recorder{options: options, logger: logger}
+}
+
+// ListenAndServe accepts clients until Close is called
+func (r *Recorder) ListenAndServe() error {
+	listener, err := net.Listen("tcp", r.options.Listen)
+	if err != nil {
+		return err
+	}
+
+	return r.Serve(listener)
+}
+
+// Serve accepts clients on listener until Close is called
+func (r *Recorder) Serve(listener net.Listener) error {
+	r.mutex.Lock()
+	r.listener = listener
+	r.mutex.Unlock()
+
+	r.logger.Infof("Recording sessions of clients connecting to %s, proxied to %s", listener.Addr(), r.options.Target)
+	if r.options.WebSocket {
+		mux := http.NewServeMux()
+		mux.HandleFunc(r.options.Path, r.serveWebSocket)
+		return http.Serve(listener, mux)
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go r.serveTCP(conn)
+	}
+}
+
+// Close stops accepting clients, the open sessions are still recorded
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.listener == nil {
+		return nil
+	}
+	return r.listener.Close()
+}
+
+func (r *Recorder) serveTCP(client net.Conn) {
+	defer client.Close()
+
+	server, err := net.Dial("tcp", r.options.Target)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Unable to connect to %s", r.options.Target)
+		return
+	}
+	defer server.Close()
+
+	s := newSession(r.logger.WithField("client", client.RemoteAddr().String()))
+	done := make(chan struct{}, 2)
+	relay := func(from io.Reader, to net.Conn, record func(*packet)) {
+		defer func() { done <- struct{}{} }()
+		for {
+			p, err := readPacket(from)
+			if err != nil {
+				return
+			}
+			record(p)
+			if _, err := to.Write(p.raw); err != nil {
+				return
+			}
+		}
+	}
+	go relay(client, server, s.clientPacket)
+	go relay(server, client, s.serverPacket)
+
+	// either side closing ends the session
+	<-done
+	r.save(s)
+}
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+func (r *Recorder) serveWebSocket(w http.ResponseWriter, req *http.Request) {
+	client, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		r.logger.WithError(err).Error("Unable to upgrade client connection")
+		return
+	}
+	defer client.Close()
+
+	url := fmt.Sprintf("ws://%s%s", r.options.Target, r.options.Path)
+	server, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		r.logger.WithError(err).Errorf("Unable to connect to %s", url)
+		return
+	}
+	defer server.Close()
+
+	s := newSession(r.logger.WithField("client", client.RemoteAddr().String()))
+	done := make(chan struct{}, 2)
+	relay := func(from, to *websocket.Conn, record func(*packet)) {
+		defer func() { done <- struct{}{} }()
+		for {
+			typ, data, err := from.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			// a message may carry several packets
+			reader := bytes.NewReader(data)
+			for reader.Len() > 0 {
+				p, err := readPacket(reader)
+				if err != nil {
+					break
+				}
+				record(p)
+			}
+
+			if err := to.WriteMessage(typ, data); err != nil {
+				return
+			}
+		}
+	}
+	go relay(client, server, s.clientPacket)
+	go relay(server, client, s.serverPacket)
+
+	<-done
+	r.save(s)
+}
+
+// save writes the session spec to the first free recorded-<n>.json file
+// of the out directory
+func (r *Recorder) save(s *session) {
+	spec := s.spec()
+	if len(spec.SequentialOperations) == 0 {
+		r.logger.Info("Session ended without operations, not writing a spec")
+		return
+	}
+
+	var data bytes.Buffer
+	encoder := json.NewEncoder(&data)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(spec); err != nil {
+		r.logger.WithError(err).Error("Unable to encode recorded spec")
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if err := os.MkdirAll(r.options.Out, 0755); err != nil {
+		r.logger.WithError(err).Error("Unable to create recorded specs directory")
+		return
+	}
+
+	for {
+		r.files++
+		path := filepath.Join(r.options.Out, fmt.Sprintf("recorded-%d.json", r.files))
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			r.logger.WithError(err).Error("Unable to write recorded spec")
+			return
+		}
+
+		_, err = f.Write(data.Bytes())
+		f.Close()
+		if err != nil {
+			r.logger.WithError(err).Error("Unable to write recorded spec")
+			return
+		}
+		r.logger.Infof("Recorded %d operations to %s", len(spec.SequentialOperations), path)
+		return
+	}
+}
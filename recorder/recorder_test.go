@@ -0,0 +1,93 @@
+package recorder
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecorder(t *testing.T) {
+	out, err := ioutil.TempDir("", "recorded")
+	assert.NoError(t, err)
+	defer os.RemoveAll(out)
+
+	// the server answers the handshake and pushes once it gets the login
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer server.Close()
+	go func() {
+		conn, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			p, err := readPacket(conn)
+			if err != nil {
+				return
+			}
+			switch p.typ {
+			case handshakePacket:
+				conn.Write(encodePacket(handshakePacket, []byte(`{"code":200,"sys":{"dict":{"room.room.join":7}}}`)))
+			case dataPacket:
+				m, _ := decodeMessage(p.data, nil)
+				if m.typ == requestMessage {
+					conn.Write(encodePacket(dataPacket, encodeMessage(responseMessage, m.id, "", []byte(`{"code":200}`))))
+					conn.Write(encodePacket(dataPacket, []byte{pushMessage<<1 | routeCompressedMask, 0, 7, '{', '}'}))
+				}
+			}
+		}
+	}()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	r := NewRecorder(Options{Target: server.Addr().String(), Out: out}, logrus.New())
+	go r.Serve(listener)
+	defer r.Close()
+
+	client, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	client.Write(encodePacket(handshakePacket, []byte(`{"sys":{"platform":"android","clientVersion":"1.2"},"user":{"name":"bot"}}`)))
+	_, err = readPacket(client)
+	assert.NoError(t, err)
+	client.Write(encodePacket(dataPacket, encodeMessage(requestMessage, 1, "player.login", []byte(`{"name":"bot","level":3,"tags":["a"],"guild":null}`))))
+	for i := 0; i < 2; i++ {
+		_, err = readPacket(client)
+		assert.NoError(t, err)
+	}
+	client.Write(encodePacket(dataPacket, encodeMessage(notifyMessage, 0, "room.ping", nil)))
+	client.Close()
+
+	path := filepath.Join(out, "recorded-1.json")
+	var data []byte
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, err = ioutil.ReadFile(path); err == nil && len(data) > 0 {
+			break
+		}
+	}
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+  "numberOfInstances": 1,
+  "handshake": {"platform": "android", "version": "1.2", "user": {"name": "bot"}},
+  "sequentialOperations": [
+    {
+      "type": "request",
+      "uri": "player.login",
+      "args": {
+        "name": {"type": "string", "value": "bot"},
+        "level": {"type": "int", "value": 3},
+        "tags": {"type": "array", "value": [{"type": "string", "value": "a"}]}
+      }
+    },
+    {"type": "listen", "uri": "room.room.join"},
+    {"type": "notify", "uri": "room.ping"}
+  ]
+}`, string(data))
+}
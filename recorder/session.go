@@ -0,0 +1,190 @@
+package recorder
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+	"github.com/topfreegames/pitaya-bot/models"
+)
+
+// clientHandshake is the handshake a pitaya client sends
+type clientHandshake struct {
+	Sys struct {
+		Platform    string `json:"platform"`
+		LibVersion  string `json:"libVersion"`
+		BuildNumber string `json:"clientBuildNumber"`
+		Version     string `json:"clientVersion"`
+	} `json:"sys"`
+	User map[string]interface{} `json:"user"`
+}
+
+// serverHandshake is the handshake response of a pitaya server, its dict
+// maps the routes to their compressed codes
+type serverHandshake struct {
+	Sys struct {
+		Dict map[string]uint16 `json:"dict"`
+	} `json:"sys"`
+}
+
+// session records the operations of a client session from the packets
+// both sides exchange. Client requests and notifies become request and
+// notify operations, server pushes become listen operations, in the order
+// they are seen
+type session struct {
+	mutex      sync.Mutex
+	logger     logrus.FieldLogger
+	handshake  *models.HandshakeSpec
+	routes     map[uint16]string
+	operations []*models.Operation
+}
+
+func newSession(logger logrus.FieldLogger) *session {
+	return &session{
+		logger:     logger,
+		routes:     map[uint16]string{},
+		operations: make([]*models.Operation, 0),
+	}
+}
+
+// clientPacket records a packet sent by the client
+func (s *session) clientPacket(p *packet) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch p.typ {
+	case handshakePacket:
+		var hs clientHandshake
+		if err := unmarshalHandshake(p.data, &hs); err != nil {
+			s.logger.WithError(err).Warn("Unable to decode client handshake")
+			return
+		}
+		s.handshake = &models.HandshakeSpec{
+			Platform:    hs.Sys.Platform,
+			LibVersion:  hs.Sys.LibVersion,
+			BuildNumber: hs.Sys.BuildNumber,
+			Version:     hs.Sys.Version,
+			User:        hs.User,
+		}
+	case dataPacket:
+		m, err := decodeMessage(p.data, s.routes)
+		if err != nil {
+			s.logger.WithError(err).Warn("Unable to decode client message")
+			return
+		}
+
+		switch m.typ {
+		case requestMessage:
+			s.record("request", m)
+		case notifyMessage:
+			s.record("notify", m)
+		}
+	}
+}
+
+// serverPacket records a packet sent by the server
+func (s *session) serverPacket(p *packet) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch p.typ {
+	case handshakePacket:
+		var hs serverHandshake
+		if err := unmarshalHandshake(p.data, &hs); err != nil {
+			s.logger.WithError(err).Warn("Unable to decode server handshake")
+			return
+		}
+		for route, code := range hs.Sys.Dict {
+			s.routes[code] = route
+		}
+	case dataPacket:
+		m, err := decodeMessage(p.data, s.routes)
+		if err != nil {
+			s.logger.WithError(err).Warn("Unable to decode server message")
+			return
+		}
+
+		if m.typ == pushMessage {
+			s.operations = append(s.operations, &models.Operation{Type: "listen", URI: m.route})
+		}
+	}
+}
+
+func (s *session) record(typ string, m *message) {
+	op := &models.Operation{Type: typ, URI: m.route}
+	if len(m.data) > 0 {
+		var data map[string]interface{}
+		if err := json.Unmarshal(m.data, &data); err != nil {
+			s.logger.Warnf("Args of %s %s are not json, recording it without args", typ, m.route)
+		} else {
+			op.Args = s.typedArgs(m.route, data)
+		}
+	}
+
+	s.operations = append(s.operations, op)
+}
+
+// typedArgs converts a message body to spec args, every value along with
+// its type. Null values are dropped
+func (s *session) typedArgs(route string, data map[string]interface{}) map[string]interface{} {
+	args := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if arg := s.typedArg(route, v); arg != nil {
+			args[k] = arg
+		}
+	}
+
+	return args
+}
+
+func (s *session) typedArg(route string, value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case string:
+		return map[string]interface{}{"type": "string", "value": v}
+	case bool:
+		return map[string]interface{}{"type": "bool", "value": v}
+	case float64:
+		if v != math.Trunc(v) {
+			s.logger.Warnf("Args of %s have the fractional number %v, recording it as an int", route, v)
+		}
+		return map[string]interface{}{"type": "int", "value": int(v)}
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if arg := s.typedArg(route, item); arg != nil {
+				items = append(items, arg)
+			}
+		}
+		return map[string]interface{}{"type": "array", "value": items}
+	case map[string]interface{}:
+		return map[string]interface{}{"type": "object", "value": s.typedArgs(route, v)}
+	}
+
+	return nil
+}
+
+// spec is the spec replaying the session with a single bot
+func (s *session) spec() *models.Spec {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return &models.Spec{
+		NumberOfInstances:    1,
+		Handshake:            s.handshake,
+		SequentialOperations: s.operations,
+	}
+}
+
+// unmarshalHandshake decodes handshake data, compressed by newer servers
+func unmarshalHandshake(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err == nil {
+		return nil
+	}
+
+	inflated, err := inflate(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(inflated, v)
+}
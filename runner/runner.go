@@ -1,16 +1,42 @@
 package runner
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"runtime/debug"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	pbot "github.com/topfreegames/pitaya-bot/bot"
+	"github.com/topfreegames/pitaya-bot/metrics"
 	"github.com/topfreegames/pitaya-bot/models"
 	"github.com/topfreegames/pitaya-bot/state"
 )
 
+func streamBotResult(app *state.App, spec *models.Spec, id int, bot pbot.Bot, start time.Time, runErr error, logger logrus.FieldLogger) {
+	result := &metrics.BotResult{
+		Kind:            metrics.BotResultKind,
+		Time:            start.UTC(),
+		Spec:            spec.Name,
+		Bot:             id,
+		DurationMs:      time.Since(start).Nanoseconds() / 1e6,
+		ConnectionStats: bot.Stats().Snapshot(),
+	}
+	if combination := spec.Combination(id); combination != nil {
+		result.Combination = combination.String()
+	}
+	if runErr != nil {
+		result.Error = runErr.Error()
+	}
+
+	if err := app.ResultStream.Write(result); err != nil {
+		logger.WithError(err).Error("Failed to stream bot result")
+	}
+	app.JUnit.Add(result)
+}
+
 // Run runs a bot according to the spec
 func Run(app *state.App, config *viper.Viper, spec *models.Spec, id int, log logrus.FieldLogger) error {
 	logger := log.WithFields(logrus.Fields{
@@ -18,6 +44,10 @@ func Run(app *state.App, config *viper.Viper, spec *models.Spec, id int, log log
 		"function": "run",
 		"botId":    id,
 	})
+	combination := spec.Combination(id)
+	if combination != nil {
+		logger = logger.WithField("combination", combination.String())
+	}
 
 	var err error
 	defer func() {
@@ -32,9 +62,23 @@ func Run(app *state.App, config *viper.Viper, spec *models.Spec, id int, log log
 
 	var bot pbot.Bot
 	logger.Infof("Starting bot with id: %d", id)
-	if spec.SequentialOperations != nil {
+	if spec.Random != nil {
+		logger.Debug("Found random operations")
+		bot, err = pbot.NewRandomBot(app, config, spec, id, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create bot")
+			return err
+		}
+	} else if spec.SequentialOperations != nil && spec.Cycle != nil {
+		logger.Debug("Found cycled sequential operations")
+		bot, err = pbot.NewStatefulCycleBot(app, config, spec, id, logger)
+		if err != nil {
+			logger.WithError(err).Error("Failed to create bot")
+			return err
+		}
+	} else if spec.SequentialOperations != nil {
 		logger.Debug("Found sequential operations")
-		bot, err = pbot.NewSequentialBot(config, spec, id, app.MetricsReporter, logger)
+		bot, err = pbot.NewSequentialBot(app, config, spec, id, logger)
 		if err != nil {
 			logger.WithError(err).Error("Failed to create bot")
 			return err
@@ -53,17 +97,31 @@ func Run(app *state.App, config *viper.Viper, spec *models.Spec, id int, log log
 		return err
 	}
 
-	err = bot.Run()
-	if err != nil {
-		return err
+	ctx := app.Context()
+	if spec.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(spec.MaxDuration)*time.Millisecond)
+		defer cancel()
 	}
 
+	start := time.Now()
+	runErr := bot.Run(ctx)
+	streamBotResult(app, spec, id, bot, start, runErr, logger)
+	app.Summary.AddBot(spec.Name, bot.Stats().Snapshot(), runErr != nil)
+
 	err = bot.Finalize()
 	if err != nil {
 		logger.WithError(err).Error("Failed to finalize bot")
 		return err
 	}
 
+	if runErr != nil {
+		if combination != nil {
+			return fmt.Errorf("Combination %s: %s", combination, runErr)
+		}
+		return runErr
+	}
+
 	logger.Debug("Finished running")
 
 	return err
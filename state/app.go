@@ -1,11 +1,14 @@
 package state
 
 import (
+	"context"
 	"fmt"
 	"sync"
 
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 	"github.com/topfreegames/pitaya-bot/metrics"
+	"github.com/topfreegames/pitaya-bot/tracing"
 )
 
 // App is the struct that holds the app global data shared between packages
@@ -14,7 +17,22 @@ type App struct {
 	ChannelClosed     bool
 	DieChan           chan struct{}
 	MetricsReporter   []metrics.Reporter
+	Pauser            *Pauser
+	Throughput        *Throughput
+	RateLimiter       *RateLimiter
+	Shared            SharedStore
+	ResultStream      *metrics.ResultStream
+	Summary           *metrics.RunSummary
+	JUnit             *metrics.JUnitReport
+	BotTarget         *BotTarget
+	Tracer            *tracing.Tracer
+	SuiteStorage      map[string]interface{}
 	Mu                sync.Mutex
+
+	drainOnce sync.Once
+	drainChan chan struct{}
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 // NewApp is the NewApp constructor
@@ -25,22 +43,80 @@ func NewApp(config *viper.Viper, shouldReportMetrics bool) *App {
 	app := &App{
 		FinishedExecition: false,
 		DieChan:           make(chan struct{}),
+		Pauser:            NewPauser(),
+		Throughput:        NewThroughput(),
+		RateLimiter:       NewRateLimiter(config.GetFloat64("loadtest.maxRPS")),
+		Shared:            NewMemorySharedStore(),
+		Summary:           metrics.NewRunSummary(),
+		drainChan:         make(chan struct{}),
+	}
+	app.ctx, app.cancel = context.WithCancel(context.Background())
+
+	if endpoint := config.GetString("tracing.endpoint"); endpoint != "" {
+		app.Tracer = tracing.NewTracer(endpoint, config.GetString("tracing.serviceName"), config.GetFloat64("tracing.sampleRate"), logrus.WithField("source", "tracing"))
 	}
 
 	if shouldReportMetrics {
 		fmt.Println("[INFO] Will report metrics")
-		mr := []metrics.Reporter{
-			metrics.GetPrometheusReporter(game, prometheusPort, map[string]string{}, func() {
-				defer app.Mu.Unlock()
-				app.Mu.Lock()
-				if app.FinishedExecition && !app.ChannelClosed {
-					app.ChannelClosed = true
-					close(app.DieChan)
+		var buckets []float64
+		if err := config.UnmarshalKey("metrics.buckets", &buckets); err != nil {
+			fmt.Printf("[WARN] Invalid metrics.buckets, using the default ones: %s\n", err)
+		}
+		reporters := []string{"prometheus"}
+		if config.IsSet("metrics.reporters") {
+			reporters = config.GetStringSlice("metrics.reporters")
+		}
+
+		mr := make([]metrics.Reporter, 0, len(reporters))
+		for _, reporter := range reporters {
+			switch reporter {
+			case "prometheus":
+				mr = append(mr, metrics.GetPrometheusReporter(game, prometheusPort, map[string]string{}, config.GetStringSlice("metrics.tags"), buckets, func() {
+					defer app.Mu.Unlock()
+					app.Mu.Lock()
+					if app.FinishedExecition && !app.ChannelClosed {
+						app.ChannelClosed = true
+						close(app.DieChan)
+					}
+				}))
+			case "statsd":
+				r, err := metrics.NewStatsdReporter(config.GetString("statsd.address"), config.GetString("statsd.prefix"), config.GetStringMapString("statsd.tags"), config.GetFloat64("statsd.rate"))
+				if err != nil {
+					fmt.Printf("[WARN] Not reporting metrics to statsd: %s\n", err)
+					continue
+				}
+				mr = append(mr, r)
+			case "jsonl":
+				r, err := metrics.NewJSONLReporter(config.GetString("jsonl.path"))
+				if err != nil {
+					fmt.Printf("[WARN] Not reporting metrics to jsonl: %s\n", err)
+					continue
 				}
-			}),
+				mr = append(mr, r)
+			default:
+				fmt.Printf("[WARN] Unknown metrics reporter %s\n", reporter)
+			}
 		}
 		app.MetricsReporter = mr
+
+		// the run waits for prometheus to scrape the last metrics, there is
+		// nothing to wait for without it
+		if !hasPrometheus(reporters) {
+			app.ChannelClosed = true
+			close(app.DieChan)
+		}
 	}
+	app.MetricsReporter = append(app.MetricsReporter, app.Summary)
 
 	return app
 }
+
+func hasPrometheus(reporters []string) bool {
+	for _, reporter := range reporters {
+		if reporter == "prometheus" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,41 @@
+package state
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAppWithoutPrometheus(t *testing.T) {
+	config := viper.New()
+	config.Set("metrics.reporters", []string{"statsd"})
+	config.Set("statsd.address", "localhost:8125")
+
+	app := NewApp(config, true)
+
+	// the run must not wait for a scrape that never comes
+	select {
+	case <-app.DieChan:
+	default:
+		t.Fatal("DieChan should be closed when prometheus is not a reporter")
+	}
+	assert.True(t, app.ChannelClosed)
+}
+
+func TestAbort(t *testing.T) {
+	app := NewApp(viper.New(), false)
+	app.Pauser.Pause()
+	assert.NoError(t, app.Context().Err())
+
+	app.Abort()
+	app.Abort()
+
+	assert.Error(t, app.Context().Err())
+	assert.False(t, app.Pauser.Paused(), "teardowns must not be held by a paused fleet")
+	select {
+	case <-app.Draining():
+	default:
+		t.Fatal("Aborted runs should be draining")
+	}
+}
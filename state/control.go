@@ -0,0 +1,62 @@
+package state
+
+import (
+	"context"
+	"sync"
+)
+
+// BotTarget is the number of bots each spec runs, set by operators while
+// the run goes on. Runs following a bot target use it over their own
+// target once it is set, the App has none when the run can not follow it
+type BotTarget struct {
+	mu   sync.Mutex
+	bots int
+	set  bool
+}
+
+// NewBotTarget is the BotTarget constructor
+func NewBotTarget() *BotTarget {
+	return &BotTarget{}
+}
+
+// Set sets the number of bots each spec runs
+func (t *BotTarget) Set(bots int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bots = bots
+	t.set = true
+}
+
+// Get returns the number of bots each spec runs and whether it was set
+func (t *BotTarget) Get() (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.bots, t.set
+}
+
+// Drain stops the run from launching new bots, running bots finish their
+// current run. Draining twice is a noop
+func (a *App) Drain() {
+	a.drainOnce.Do(func() {
+		close(a.drainChan)
+	})
+}
+
+// Draining is closed once the run is drained
+func (a *App) Draining() <-chan struct{} {
+	return a.drainChan
+}
+
+// Abort drains the run and cancels the context of the running bots, they
+// stop before their next operation and run their teardown operations. A
+// paused fleet is resumed so teardowns are not held
+func (a *App) Abort() {
+	a.Drain()
+	a.cancel()
+	a.Pauser.Resume()
+}
+
+// Context is the context bots run with, done once the run is aborted
+func (a *App) Context() context.Context {
+	return a.ctx
+}
@@ -0,0 +1,96 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pauser holds the fleet-wide pause flag. Bots wait on it before starting
+// each operation, their connections are kept alive by the client heartbeat
+type Pauser struct {
+	mu          sync.Mutex
+	paused      bool
+	resumeChan  chan struct{}
+	pausedAt    time.Time
+	pausedTotal time.Duration
+}
+
+// NewPauser is the Pauser constructor
+func NewPauser() *Pauser {
+	return &Pauser{}
+}
+
+// Pause suspends new operations until Resume is called
+func (p *Pauser) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return
+	}
+
+	p.paused = true
+	p.pausedAt = time.Now()
+	p.resumeChan = make(chan struct{})
+}
+
+// Resume lets bots start new operations again
+func (p *Pauser) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return
+	}
+
+	p.paused = false
+	p.pausedTotal += time.Since(p.pausedAt)
+	close(p.resumeChan)
+}
+
+// Toggle pauses a running fleet or resumes a paused one, returning whether
+// the fleet is now paused
+func (p *Pauser) Toggle() bool {
+	if p.Paused() {
+		p.Resume()
+		return false
+	}
+
+	p.Pause()
+	return true
+}
+
+// Paused returns if the fleet is currently paused
+func (p *Pauser) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// Wait blocks while the fleet is paused or until ctx is done
+func (p *Pauser) Wait(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.paused {
+		p.mu.Unlock()
+		return nil
+	}
+	resumeChan := p.resumeChan
+	p.mu.Unlock()
+
+	select {
+	case <-resumeChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// PausedDuration returns for how long the fleet has been paused in total
+func (p *Pauser) PausedDuration() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return p.pausedTotal + time.Since(p.pausedAt)
+	}
+
+	return p.pausedTotal
+}
@@ -0,0 +1,37 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPauserWaitBlocksUntilResume(t *testing.T) {
+	p := NewPauser()
+	assert.True(t, p.Toggle())
+
+	done := make(chan error)
+	go func() { done <- p.Wait(context.Background()) }()
+
+	select {
+	case <-done:
+		t.Fatal("Wait returned while paused")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.False(t, p.Toggle())
+	assert.NoError(t, <-done)
+	assert.True(t, p.PausedDuration() >= 20*time.Millisecond)
+}
+
+func TestPauserWaitHonorsContext(t *testing.T) {
+	p := NewPauser()
+	p.Pause()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, p.Wait(ctx))
+}
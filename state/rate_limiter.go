@@ -0,0 +1,84 @@
+package state
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket letting through rps requests per second,
+// in bursts of up to a second worth of them. All methods are noops on a nil
+// RateLimiter
+type RateLimiter struct {
+	mu     sync.Mutex
+	rps    float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewRateLimiter returns a limiter of rps requests per second, nil when rps
+// is not positive
+func NewRateLimiter(rps float64) *RateLimiter {
+	if rps <= 0 {
+		return nil
+	}
+
+	burst := math.Max(1, math.Floor(rps))
+	return &RateLimiter{
+		rps:    rps,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+		now:    time.Now,
+	}
+}
+
+// reserve takes a token, returning how long to wait until it is available
+func (l *RateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rps)
+	l.last = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-l.tokens / l.rps * float64(time.Second))
+}
+
+// cancel gives back a token taken by reserve
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+}
+
+// Wait blocks until a request may be sent or ctx is done, returning whether
+// the request was held back
+func (l *RateLimiter) Wait(ctx context.Context) (bool, error) {
+	if l == nil {
+		return false, nil
+	}
+
+	delay := l.reserve()
+	if delay <= 0 {
+		return false, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		l.cancel()
+		return true, ctx.Err()
+	}
+}
@@ -0,0 +1,71 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewRateLimiter(2)
+	l.now = func() time.Time { return now }
+	l.last = now
+
+	tables := []struct {
+		name    string
+		elapsed time.Duration
+		delay   time.Duration
+	}{
+		{"first of burst", 0, 0},
+		{"second of burst", 0, 0},
+		{"bucket empty", 0, 500 * time.Millisecond},
+		{"still in debt", 0, time.Second},
+		{"refilled", 2 * time.Second, 0},
+	}
+
+	for _, table := range tables {
+		t.Run(table.name, func(t *testing.T) {
+			now = now.Add(table.elapsed)
+			assert.Equal(t, table.delay, l.reserve())
+		})
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(50)
+	for i := 0; i < 50; i++ {
+		limited, err := l.Wait(context.Background())
+		assert.NoError(t, err)
+		assert.False(t, limited)
+	}
+
+	start := time.Now()
+	limited, err := l.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, limited)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond)
+}
+
+func TestRateLimiterWaitHonorsContext(t *testing.T) {
+	l := NewRateLimiter(0.1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	limited, err := l.Wait(ctx)
+	assert.True(t, limited)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestNilRateLimiter(t *testing.T) {
+	assert.Nil(t, NewRateLimiter(0))
+
+	var l *RateLimiter
+	limited, err := l.Wait(context.Background())
+	assert.NoError(t, err)
+	assert.False(t, limited)
+}
@@ -0,0 +1,264 @@
+package state
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultRedisPollInterval = 100 * time.Millisecond
+	defaultRedisPoolSize     = 10
+)
+
+// redisSharedStore is a SharedStore kept in redis, values are stored as
+// json so numbers are read back as floats. It speaks the few RESP commands
+// it needs over a pool of connections
+type redisSharedStore struct {
+	address      string
+	prefix       string
+	pollInterval time.Duration
+
+	// idle connections. Commands dial a new one when none is idle, and
+	// close theirs when poolSize connections are already idle
+	idle chan *redisConn
+}
+
+// redisConn is a connection of the pool
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newRedisSharedStore(config *viper.Viper) (*redisSharedStore, error) {
+	address := config.GetString("shared.redis.address")
+	if address == "" {
+		return nil, fmt.Errorf("Redis shared store needs shared.redis.address")
+	}
+
+	poolSize := defaultRedisPoolSize
+	if config.IsSet("shared.redis.poolSize") {
+		poolSize = config.GetInt("shared.redis.poolSize")
+	}
+	if poolSize <= 0 {
+		return nil, fmt.Errorf("Redis shared store needs a positive shared.redis.poolSize")
+	}
+
+	s := &redisSharedStore{
+		address:      address,
+		prefix:       config.GetString("shared.redis.prefix"),
+		pollInterval: defaultRedisPollInterval,
+		idle:         make(chan *redisConn, poolSize),
+	}
+	if config.IsSet("shared.redis.pollInterval") {
+		s.pollInterval = config.GetDuration("shared.redis.pollInterval")
+	}
+
+	if _, err := s.command("PING"); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// command sends a command and returns its reply, nil for missing values.
+// Connections are dropped on errors other than redis error replies
+func (s *redisSharedStore) command(args ...string) (interface{}, error) {
+	c, err := s.get()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := c.do(args)
+	if err != nil {
+		if _, ok := err.(redisError); ok {
+			s.put(c)
+		} else {
+			c.conn.Close()
+		}
+		return nil, fmt.Errorf("Redis command %s failed: %s", args[0], err)
+	}
+
+	s.put(c)
+	return reply, nil
+}
+
+// get returns an idle connection, or dials a new one
+func (s *redisSharedStore) get() (*redisConn, error) {
+	select {
+	case c := <-s.idle:
+		return c, nil
+	default:
+	}
+
+	conn, err := net.DialTimeout("tcp", s.address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to redis: %s", err)
+	}
+	return &redisConn{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// put makes c idle again, closing it when the pool is full
+func (s *redisSharedStore) put(c *redisConn) {
+	select {
+	case s.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+func (c *redisConn) do(args []string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return readRESP(c.reader)
+}
+
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// readRESP reads a simple string, error, integer or bulk string reply
+func readRESP(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("malformed bulk reply %s", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:size]), nil
+	}
+
+	return nil, fmt.Errorf("unsupported reply %s", line)
+}
+
+// Publish sets key
+func (s *redisSharedStore) Publish(key string, value interface{}) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.command("SET", s.prefix+key, string(raw))
+	return err
+}
+
+// Get returns the value of key
+func (s *redisSharedStore) Get(key string) (interface{}, bool, error) {
+	reply, err := s.command("GET", s.prefix+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+
+	raw, ok := reply.(string)
+	if !ok {
+		return nil, false, fmt.Errorf("Unexpected redis reply for %s: %v", key, reply)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, false, fmt.Errorf("Malformed shared value %s: %s", key, err)
+	}
+
+	return value, true, nil
+}
+
+// WaitFor polls key until it is published or ctx is done
+func (s *redisSharedStore) WaitFor(ctx context.Context, key string) (interface{}, error) {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		value, ok, err := s.Get(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return value, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Barrier counts arrivals at name with INCR, the nth arrival belongs to
+// the group released once the count reaches the end of its group
+func (s *redisSharedStore) Barrier(ctx context.Context, name string, parties int) error {
+	key := s.prefix + "barrier:" + name
+	reply, err := s.command("INCR", key)
+	if err != nil {
+		return err
+	}
+
+	arrival, ok := reply.(int64)
+	if !ok {
+		return fmt.Errorf("Unexpected redis reply for barrier %s: %v", name, reply)
+	}
+	release := ((arrival-1)/int64(parties) + 1) * int64(parties)
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		reply, err := s.command("GET", key)
+		if err != nil {
+			return err
+		}
+
+		count, err := strconv.ParseInt(fmt.Sprintf("%v", reply), 10, 64)
+		if err != nil {
+			return fmt.Errorf("Malformed barrier %s count: %v", name, reply)
+		}
+		if count >= release {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
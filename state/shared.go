@@ -0,0 +1,135 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// SharedStore holds values published by a bot for the other bots of the
+// run to read and coordinates their barriers. A barrier releases its bots
+// in groups of parties, once the last one of each group arrives
+type SharedStore interface {
+	Publish(key string, value interface{}) error
+	Get(key string) (interface{}, bool, error)
+	WaitFor(ctx context.Context, key string) (interface{}, error)
+	Barrier(ctx context.Context, name string, parties int) error
+}
+
+// NewSharedStore returns the shared.backend store: memory, shared by the
+// bots of this process, or redis, shared by every process using the same
+// shared.redis.address and shared.redis.prefix
+func NewSharedStore(config *viper.Viper) (SharedStore, error) {
+	switch backend := config.GetString("shared.backend"); backend {
+	case "", "memory":
+		return NewMemorySharedStore(), nil
+	case "redis":
+		return newRedisSharedStore(config)
+	default:
+		return nil, fmt.Errorf("Unknown shared.backend: %s", backend)
+	}
+}
+
+// MemorySharedStore is the in-process SharedStore
+type MemorySharedStore struct {
+	mu       sync.Mutex
+	values   map[string]interface{}
+	waiters  map[string]chan struct{}
+	barriers map[string]*memoryBarrier
+}
+
+// memoryBarrier is the group of bots currently waiting at a barrier
+type memoryBarrier struct {
+	arrived int
+	release chan struct{}
+}
+
+// NewMemorySharedStore is the MemorySharedStore constructor
+func NewMemorySharedStore() *MemorySharedStore {
+	return &MemorySharedStore{
+		values:   map[string]interface{}{},
+		waiters:  map[string]chan struct{}{},
+		barriers: map[string]*memoryBarrier{},
+	}
+}
+
+// Publish sets key, waking up the bots waiting for it
+func (s *MemorySharedStore) Publish(key string, value interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = value
+	if waiter, ok := s.waiters[key]; ok {
+		close(waiter)
+		delete(s.waiters, key)
+	}
+
+	return nil
+}
+
+// Get returns the value of key
+func (s *MemorySharedStore) Get(key string) (interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+// WaitFor blocks until key is published or ctx is done
+func (s *MemorySharedStore) WaitFor(ctx context.Context, key string) (interface{}, error) {
+	s.mu.Lock()
+	if value, ok := s.values[key]; ok {
+		s.mu.Unlock()
+		return value, nil
+	}
+
+	waiter, ok := s.waiters[key]
+	if !ok {
+		waiter = make(chan struct{})
+		s.waiters[key] = waiter
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-waiter:
+		value, _, err := s.Get(key)
+		return value, err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Barrier blocks until parties bots, this one included, arrived at name or
+// ctx is done
+func (s *MemorySharedStore) Barrier(ctx context.Context, name string, parties int) error {
+	s.mu.Lock()
+	barrier, ok := s.barriers[name]
+	if !ok {
+		barrier = &memoryBarrier{release: make(chan struct{})}
+		s.barriers[name] = barrier
+	}
+
+	barrier.arrived++
+	if barrier.arrived >= parties {
+		close(barrier.release)
+		delete(s.barriers, name)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-barrier.release:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if s.barriers[name] == barrier {
+			barrier.arrived--
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
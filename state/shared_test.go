@@ -0,0 +1,207 @@
+package state
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemorySharedStoreWaitFor(t *testing.T) {
+	s := NewMemorySharedStore()
+
+	done := make(chan interface{})
+	go func() {
+		value, err := s.WaitFor(context.Background(), "matchId")
+		assert.NoError(t, err)
+		done <- value
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, s.Publish("matchId", "m1"))
+	assert.Equal(t, "m1", <-done)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := s.WaitFor(ctx, "other")
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+// fakeRedis serves GET, SET, INCR and PING from memory
+func fakeRedis(t *testing.T) string {
+	address, _ := fakeRedisConns(t)
+	return address
+}
+
+// fakeRedisConns is fakeRedis, also counting the connections it accepted.
+// GETs of keys starting with slow take 50ms
+func fakeRedisConns(t *testing.T) (string, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	var conns int32
+	values := map[string]string{}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&conns, 1)
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					n, _ := strconv.Atoi(strings.TrimSpace(line[1:]))
+					args := make([]string, n)
+					for i := range args {
+						r.ReadString('\n')
+						arg, _ := r.ReadString('\n')
+						args[i] = strings.TrimSuffix(arg, "\r\n")
+					}
+
+					if args[0] == "GET" && strings.HasPrefix(args[1], "slow") {
+						time.Sleep(50 * time.Millisecond)
+					}
+
+					mu.Lock()
+					switch args[0] {
+					case "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					case "SET":
+						values[args[1]] = args[2]
+						conn.Write([]byte("+OK\r\n"))
+					case "INCR":
+						n, _ := strconv.Atoi(values[args[1]])
+						values[args[1]] = strconv.Itoa(n + 1)
+						conn.Write([]byte(":" + values[args[1]] + "\r\n"))
+					case "GET":
+						if v, ok := values[args[1]]; ok {
+							conn.Write([]byte("$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"))
+						} else {
+							conn.Write([]byte("$-1\r\n"))
+						}
+					}
+					mu.Unlock()
+				}
+			}(conn)
+		}
+	}()
+
+	return listener.Addr().String(), &conns
+}
+
+func TestRedisSharedStore(t *testing.T) {
+	config := viper.New()
+	config.Set("shared.backend", "redis")
+	config.Set("shared.redis.address", fakeRedis(t))
+	config.Set("shared.redis.prefix", "test:")
+	config.Set("shared.redis.pollInterval", "5ms")
+
+	s, err := NewSharedStore(config)
+	assert.NoError(t, err)
+
+	_, ok, err := s.Get("matchId")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	done := make(chan interface{})
+	go func() {
+		value, err := s.WaitFor(context.Background(), "matchId")
+		assert.NoError(t, err)
+		done <- value
+	}()
+
+	assert.NoError(t, s.Publish("matchId", map[string]interface{}{"id": "m1", "size": 4}))
+	assert.Equal(t, map[string]interface{}{"id": "m1", "size": float64(4)}, <-done)
+}
+
+func TestRedisSharedStorePool(t *testing.T) {
+	address, conns := fakeRedisConns(t)
+	config := viper.New()
+	config.Set("shared.backend", "redis")
+	config.Set("shared.redis.address", address)
+	config.Set("shared.redis.poolSize", 2)
+
+	s, err := NewSharedStore(config)
+	assert.NoError(t, err)
+
+	// sequential commands reuse the idle connection
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, s.Publish("matchId", i))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns))
+
+	// concurrent commands do not wait for each other
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := s.Get("slowKey")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.True(t, time.Since(start) < 150*time.Millisecond, "took %v", time.Since(start))
+	assert.Equal(t, 2, len(s.(*redisSharedStore).idle))
+}
+
+func TestUnknownSharedBackend(t *testing.T) {
+	config := viper.New()
+	config.Set("shared.backend", "etcd")
+	_, err := NewSharedStore(config)
+	assert.Error(t, err)
+}
+
+func TestRedisBarrier(t *testing.T) {
+	config := viper.New()
+	config.Set("shared.backend", "redis")
+	config.Set("shared.redis.address", fakeRedis(t))
+	config.Set("shared.redis.pollInterval", "5ms")
+
+	s, err := NewSharedStore(config)
+	assert.NoError(t, err)
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- s.Barrier(context.Background(), "start", 3) }()
+	}
+
+	select {
+	case <-done:
+		t.Fatal("barrier released before every party arrived")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.NoError(t, s.Barrier(context.Background(), "start", 3))
+	assert.NoError(t, <-done)
+	assert.NoError(t, <-done)
+}
+
+func TestMemoryBarrierGenerations(t *testing.T) {
+	s := NewMemorySharedStore()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for round := 0; round < 2; round++ {
+		done := make(chan error)
+		go func() { done <- s.Barrier(ctx, "round", 2) }()
+		assert.NoError(t, s.Barrier(ctx, "round", 2))
+		assert.NoError(t, <-done)
+	}
+}
@@ -0,0 +1,50 @@
+package state
+
+import (
+	"sync"
+	"time"
+)
+
+// Throughput counts the requests sent by all bots and holds the pacing the
+// target rps controller sets for them. Bots keep their own pacing while it
+// is not controlled
+type Throughput struct {
+	mu         sync.Mutex
+	requests   int64
+	pacing     time.Duration
+	controlled bool
+}
+
+// NewThroughput is the Throughput constructor
+func NewThroughput() *Throughput {
+	return &Throughput{}
+}
+
+// AddRequest counts a sent request
+func (t *Throughput) AddRequest() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests++
+}
+
+// Requests returns how many requests were sent so far
+func (t *Throughput) Requests() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requests
+}
+
+// SetPacing sets the pacing every bot waits after its operations
+func (t *Throughput) SetPacing(pacing time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pacing = pacing
+	t.controlled = true
+}
+
+// Pacing returns the controlled pacing, false while it is not controlled
+func (t *Throughput) Pacing() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pacing, t.controlled
+}
@@ -0,0 +1,115 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// span kinds, as numbered by OTLP
+const (
+	kindInternal = 1
+	kindClient   = 3
+)
+
+// Span is a timed operation of a trace. All methods are safe on a nil Span,
+// which is what unsampled traces hand out
+type Span struct {
+	tracer   *Tracer
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     int
+	start    time.Time
+	end      time.Time
+	err      error
+
+	mutex      sync.Mutex
+	attributes map[string]interface{}
+}
+
+// StartChild starts a client span, child of s
+func (s *Span) StartChild(name string) *Span {
+	if s == nil {
+		return nil
+	}
+
+	child := &Span{tracer: s.tracer, traceID: s.traceID, parentID: s.spanID, name: name, start: time.Now(), kind: kindClient}
+	rand.Read(child.spanID[:])
+	return child
+}
+
+// SetAttribute sets an attribute of the span, a string, int, float64 or bool
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.attributes == nil {
+		s.attributes = map[string]interface{}{}
+	}
+	s.attributes[key] = value
+}
+
+// Finish ends the span, failed when err is set, and queues it for export
+func (s *Span) Finish(err error) {
+	if s == nil {
+		return
+	}
+
+	s.end = time.Now()
+	s.err = err
+	s.tracer.enqueue(s)
+}
+
+// Traceparent is the W3C trace context header propagating the span
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("00-%s-%s-01", hexID(s.traceID[:]), hexID(s.spanID[:]))
+}
+
+// otlp is the OTLP json form of the span
+func (s *Span) otlp() map[string]interface{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	span := map[string]interface{}{
+		"traceId":           hexID(s.traceID[:]),
+		"spanId":            hexID(s.spanID[:]),
+		"name":              s.name,
+		"kind":              s.kind,
+		"startTimeUnixNano": fmt.Sprint(s.start.UnixNano()),
+		"endTimeUnixNano":   fmt.Sprint(s.end.UnixNano()),
+		"attributes":        otlpAttributes(s.attributes),
+		"status":            map[string]interface{}{"code": 1},
+	}
+	if s.parentID != [8]byte{} {
+		span["parentSpanId"] = hexID(s.parentID[:])
+	}
+	if s.err != nil {
+		span["status"] = map[string]interface{}{"code": 2, "message": s.err.Error()}
+	}
+
+	return span
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying s
+func ContextWithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+// SpanFromContext returns the span ctx carries, nil when it has none
+func SpanFromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	batchSize     = 512
+	flushInterval = time.Second
+)
+
+// Tracer exports spans to an OpenTelemetry collector, in batches, with the
+// OTLP/HTTP json protocol. Traces are sampled as a whole with SampleRate.
+// A nil Tracer traces nothing
+type Tracer struct {
+	endpoint   string
+	service    string
+	sampleRate float64
+	logger     logrus.FieldLogger
+	client     *http.Client
+
+	mutex   sync.Mutex
+	pending []*Span
+	flushed chan struct{}
+	done    chan struct{}
+	closed  sync.WaitGroup
+}
+
+// NewTracer returns a tracer exporting to the collector at endpoint, as
+// service, and starts its exporter
+func NewTracer(endpoint, service string, sampleRate float64, logger logrus.FieldLogger) *Tracer {
+	if sampleRate <= 0 || sampleRate > 1 {
+		sampleRate = 1
+	}
+
+	t := &Tracer{
+		endpoint:   strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service:    service,
+		sampleRate: sampleRate,
+		logger:     logger,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		flushed:    make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+
+	t.closed.Add(1)
+	go t.export()
+	return t
+}
+
+// StartTrace starts the root span of a new trace, nil when the trace is
+// not sampled
+func (t *Tracer) StartTrace(name string) *Span {
+	if t == nil || !sampled(t.sampleRate) {
+		return nil
+	}
+
+	s := &Span{tracer: t, name: name, start: time.Now(), kind: kindInternal}
+	rand.Read(s.traceID[:])
+	rand.Read(s.spanID[:])
+	return s
+}
+
+// Close exports the pending spans and stops the exporter
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+
+	close(t.done)
+	t.closed.Wait()
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	t.mutex.Lock()
+	t.pending = append(t.pending, s)
+	full := len(t.pending) >= batchSize
+	t.mutex.Unlock()
+
+	if full {
+		select {
+		case t.flushed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) export() {
+	defer t.closed.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flushed:
+		case <-t.done:
+			t.flush()
+			return
+		}
+		t.flush()
+	}
+}
+
+func (t *Tracer) flush() {
+	t.mutex.Lock()
+	spans := t.pending
+	t.pending = nil
+	t.mutex.Unlock()
+
+	if len(spans) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(t.request(spans))
+	if err != nil {
+		t.logger.WithError(err).Error("Unable to encode spans")
+		return
+	}
+
+	resp, err := t.client.Post(t.endpoint, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.logger.WithError(err).Warnf("Unable to export %d spans", len(spans))
+		return
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		t.logger.Warnf("Exporting %d spans failed with status %d", len(spans), resp.StatusCode)
+	}
+}
+
+// request is the OTLP export request of spans
+func (t *Tracer) request(spans []*Span) map[string]interface{} {
+	encoded := make([]map[string]interface{}, len(spans))
+	for i, s := range spans {
+		encoded[i] = s.otlp()
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": t.service}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "pitaya-bot"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+}
+
+// sampled draws whether a trace is sampled at rate
+func sampled(rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+
+	var b [8]byte
+	rand.Read(b[:])
+	n := uint64(0)
+	for _, v := range b {
+		n = n<<8 | uint64(v)
+	}
+	return float64(n>>11)/float64(1<<53) < rate
+}
+
+func otlpAttributes(attributes map[string]interface{}) []interface{} {
+	encoded := make([]interface{}, 0, len(attributes))
+	for k, v := range attributes {
+		var value map[string]interface{}
+		switch val := v.(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": val}
+		case int:
+			value = map[string]interface{}{"intValue": fmt.Sprint(val)}
+		case int64:
+			value = map[string]interface{}{"intValue": fmt.Sprint(val)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": val}
+		case bool:
+			value = map[string]interface{}{"boolValue": val}
+		default:
+			value = map[string]interface{}{"stringValue": fmt.Sprint(val)}
+		}
+		encoded = append(encoded, map[string]interface{}{"key": k, "value": value})
+	}
+
+	return encoded
+}
+
+func hexID(id []byte) string {
+	return hex.EncodeToString(id)
+}
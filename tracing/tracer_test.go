@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracer(t *testing.T) {
+	requests := make(chan map[string]interface{}, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		var body map[string]interface{}
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+		requests <- body
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(collector.URL, "bots", 1, logrus.New())
+	root := tracer.StartTrace("spec login")
+	child := root.StartChild("request player.login")
+	child.SetAttribute("route", "player.login")
+	child.SetAttribute("attempt", 2)
+	assert.Regexp(t, regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`), child.Traceparent())
+	child.Finish(errors.New("timeout"))
+	root.Finish(nil)
+	tracer.Close()
+
+	body := <-requests
+	resource := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "bots"}}},
+		resource["resource"].(map[string]interface{})["attributes"])
+
+	spans := resource["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	assert.Len(t, spans, 2)
+	exported, parent := spans[0].(map[string]interface{}), spans[1].(map[string]interface{})
+	assert.Equal(t, "request player.login", exported["name"])
+	assert.Equal(t, parent["traceId"], exported["traceId"])
+	assert.Equal(t, parent["spanId"], exported["parentSpanId"])
+	assert.NotContains(t, parent, "parentSpanId")
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "timeout"}, exported["status"])
+	assert.ElementsMatch(t, []interface{}{
+		map[string]interface{}{"key": "route", "value": map[string]interface{}{"stringValue": "player.login"}},
+		map[string]interface{}{"key": "attempt", "value": map[string]interface{}{"intValue": "2"}},
+	}, exported["attributes"])
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	span := tracer.StartTrace("spec")
+	child := span.StartChild("op")
+	child.SetAttribute("route", "r")
+	child.Finish(nil)
+	assert.Nil(t, child)
+	assert.Empty(t, child.Traceparent())
+	tracer.Close()
+}